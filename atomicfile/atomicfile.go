@@ -0,0 +1,131 @@
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BackupExt is appended to the name of a file to get the name of its backup.
+const BackupExt = ".bak"
+
+// File is written to a temporary file in the directory of the file it
+// replaces. The file is only replaced, by renaming the temporary file, when
+// Commit is called. Closing a File that isn't committed removes the temporary
+// file and leaves the original untouched, so an error or an interrupt halfway
+// never results in an empty or partially written file.
+type File struct {
+	// Backup keeps the original file as name.bak when the file is committed.
+	Backup bool
+
+	name string
+	tmp  *os.File
+	done bool
+}
+
+// Create returns a File that replaces the file name when committed. A new
+// file gets mode 0644, an existing file keeps its mode.
+func Create(name string) (f *File, err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &File{name: name, tmp: tmp}, nil
+}
+
+// Name returns the name of the file replaced.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Write writes p to the temporary file.
+func (f *File) Write(p []byte) (int, error) {
+	return f.tmp.Write(p)
+}
+
+// Commit flushes the temporary file to disk and renames it to the name of the
+// file, first linking the original file to its backup if requested.
+func (f *File) Commit() (err error) {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	defer func() {
+		if err != nil {
+			os.Remove(f.tmp.Name())
+		}
+	}()
+	if err = f.tmp.Sync(); err != nil {
+		f.tmp.Close()
+		return
+	}
+	if err = f.tmp.Close(); err != nil {
+		return
+	}
+	if f.Backup {
+		if err = backup(f.name); err != nil {
+			return
+		}
+	}
+	return os.Rename(f.tmp.Name(), f.name)
+}
+
+// Close removes the temporary file when the file isn't committed.
+func (f *File) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.tmp.Close()
+	return os.Remove(f.tmp.Name())
+}
+
+// backup replaces name.bak by the file name, if it exists.
+func backup(name string) (err error) {
+	bak := name + BackupExt
+	if err = os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = os.Link(name, bak); err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	// Hard links aren't supported everywhere, fall back to a copy
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := Create(bak)
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+	if _, err = io.Copy(dst, src); err != nil {
+		return
+	}
+	return dst.Commit()
+}
+
+// WriteFile replaces the file name by the contents of r, the file is only
+// replaced when all of r is written successfully.
+func WriteFile(name string, r io.Reader, backup bool) (err error) {
+	f, err := Create(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Backup = backup
+	if _, err = io.Copy(f, r); err != nil {
+		return
+	}
+	return f.Commit()
+}
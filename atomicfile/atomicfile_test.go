@@ -0,0 +1,97 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func expectFile(t *testing.T, name, content string) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("Expected %q to contain %q got %q", name, content, data)
+	}
+}
+
+func expectNoTemp(t *testing.T, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("Expected temporary file %q to be removed", e.Name())
+		}
+	}
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "fr.strings")
+	if err := os.WriteFile(name, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Backup = true
+	if _, err = f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, name, "old")
+	if err = f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Errorf("Expected Close after Commit to succeed got %v", err)
+	}
+
+	expectFile(t, name, "new")
+	expectFile(t, name+BackupExt, "old")
+	expectNoTemp(t, dir)
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode of the original file to be kept got %v", info.Mode())
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "fr.strings")
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte("half")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectFile(t, name, "old")
+	expectNoTemp(t, dir)
+	if _, err = os.Stat(name + BackupExt); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup without commit got %v", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "new.strings")
+	if err := WriteFile(name, strings.NewReader("new"), true); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, name, "new")
+	if _, err := os.Stat(name + BackupExt); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup of a new file got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package autofix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/placeholder"
+)
+
+// Fix is a mechanical correction of a translation. Apply receives the source
+// and target text and returns the corrected target. Format specifiers in both
+// texts have been replaced by runes from the Unicode private use area, so a
+// fix can never change them. Languages lists the languages (e.g. "fr") the
+// fix applies to, a fix without Languages applies to all of them.
+type Fix struct {
+	Name        string
+	Description string
+	Languages   []string
+	Apply       func(source, target string) string
+}
+
+// AppliesTo reports whether the fix should be applied to translations in lang.
+// Regional languages like "fr-CA" match fixes for "fr".
+func (f *Fix) AppliesTo(lang string) bool {
+	if len(f.Languages) == 0 {
+		return true
+	}
+	base := strings.ToLower(lang)
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base = base[:i]
+	}
+	for _, l := range f.Languages {
+		if l == base {
+			return true
+		}
+	}
+	return false
+}
+
+// Change records the fixes that changed a single translation.
+type Change struct {
+	ID     string
+	Fixes  []string
+	Before string
+	After  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%q: %s: %q -> %q", c.ID, strings.Join(c.Fixes, ","), c.Before, c.After)
+}
+
+// Fixer applies a list of fixes to translations and keeps a record of the
+// changes made.
+type Fixer struct {
+	Fixes []*Fix
+
+	mutex   sync.Mutex
+	changes []Change
+}
+
+// New returns a Fixer with all fixes from the fixes list that apply to
+// language lang.
+func New(lang string, fixes ...*Fix) *Fixer {
+	f := &Fixer{}
+	for _, fix := range fixes {
+		if fix.AppliesTo(lang) {
+			f.Fixes = append(f.Fixes, fix)
+		}
+	}
+	return f
+}
+
+// Changes returns the changes made so far.
+func (f *Fixer) Changes() []Change {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]Change(nil), f.changes...)
+}
+
+// Apply runs all fixes over the unescaped target text and returns the fixed
+// text together with the names of the fixes that changed it.
+func (f *Fixer) Apply(source, target string) (fixed string, applied []string) {
+	src, _ := protect(source)
+	tgt, originals := protect(target)
+	for _, fix := range f.Fixes {
+		if t := fix.Apply(src, tgt); t != tgt {
+			tgt = t
+			applied = append(applied, fix.Name)
+		}
+	}
+	if len(applied) == 0 {
+		return target, nil
+	}
+	fixed = restore(tgt, originals)
+	// Belt and braces, a fix must never alter the format specifiers.
+	if strings.Join(placeholder.List(fixed), "") != strings.Join(placeholder.List(target), "") {
+		return target, nil
+	}
+	return
+}
+
+// FixMessages will take the messages emitted by TranslateMessages, where Ctx
+// holds the source text and Str holds the translation, and apply the fixes to
+// the Str of every message. Missing messages, and fuzzy messages whose Str is
+// still the source text, are passed on unchanged as they don't contain a
+// translation yet. The changes made are recorded and can be retrieved using
+// Changes once the returned channel has been drained.
+func (f *Fixer) FixMessages(ctx context.Context, srcChan <-chan dotstrings.Message) <-chan dotstrings.Message {
+	dstChan := make(chan dotstrings.Message, 3)
+
+	fixer := func(srcChan <-chan dotstrings.Message, dstChan chan<- dotstrings.Message) {
+		defer close(dstChan)
+		for m := range srcChan {
+			if !m.Untranslated() {
+				f.fixMessage(&m)
+			}
+			select {
+			case dstChan <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go fixer(srcChan, dstChan)
+	return dstChan
+}
+
+func (f *Fixer) fixMessage(m *dotstrings.Message) {
+	source, err := dotstrings.StringsUnescape(m.Ctx)
+	if err != nil {
+		return
+	}
+	target, err := dotstrings.StringsUnescape(m.Str)
+	if err != nil {
+		return
+	}
+	fixed, applied := f.Apply(source, target)
+	if len(applied) == 0 {
+		return
+	}
+	f.mutex.Lock()
+	f.changes = append(f.changes, Change{ID: m.ID, Fixes: applied, Before: target, After: fixed})
+	f.mutex.Unlock()
+	m.Str = dotstrings.StringsEscape(fixed)
+}
+
+// privateUse is the first rune used to stand in for a format specifier.
+const privateUse = '\uE000'
+
+// protect replaces the format specifiers in s by runes from the private use
+// area. These are neither letters, spaces nor punctuation so fixes leave them
+// alone.
+func protect(s string) (protected string, originals []string) {
+	locs := placeholder.Find(s)
+	if locs == nil {
+		return s, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(s[last:loc[0]])
+		b.WriteRune(privateUse + rune(len(originals)))
+		originals = append(originals, s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), originals
+}
+
+// restore undoes protect.
+func restore(s string, originals []string) string {
+	if originals == nil {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if n := int(r - privateUse); n >= 0 && n < len(originals) {
+			b.WriteString(originals[n])
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
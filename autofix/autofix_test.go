@@ -0,0 +1,79 @@
+package autofix
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+var tvFixes = []struct {
+	lang, source, target, expect string
+}{
+	{"fr", "Open…", "Ouvrir", "Ouvrir…"},
+	{"fr", "Open...", "Ouvrir.", "Ouvrir…"},
+	{"es", "Save as...", "Guardar como…", "Guardar como…"},
+	{"fr", " items", "éléments", " éléments"},
+	{"fr", "Name: ", "Nom :", "Nom\u202F: "},
+	{"fr", "Name:", "Nom:", "Nom\u202F:"},
+	{"fr", "Starts at 10:30", "Commence à 10:30", "Commence à 10:30"},
+	{"fr", "Visit http://example.com", "Visitez http://example.com", "Visitez http://example.com"},
+	{"fr", "Open x.com?a=1&b=2", "Ouvrir x.com?a=1&b=2", "Ouvrir x.com?a=1&b=2"},
+	{"fr", "Search ?q=test", "Rechercher ?q=test", "Rechercher ?q=test"},
+	{"fr", "Run a;b", "Exécuter a;b", "Exécuter a;b"},
+	{"fr", `Open C:\Users`, `Ouvrir C:\Users`, `Ouvrir C:\Users`},
+	{"fr", "Open ~/Documents/a:b", "Ouvrir ~/Documents/a:b", "Ouvrir ~/Documents/a:b"},
+	{"fr", "Time %1$@:%2$@", "Heure %1$@:%2$@", "Heure %1$@:%2$@"},
+	{"fr", "{d, date, ::yMMMd}", "{d, date, ::yMMMd}", "{d, date, ::yMMMd}"},
+	{"fr", "What?!", "Quoi?!", "Quoi\u202F?!"},
+	{"fr", `Say "hi"!`, "Dites «\u202Fsalut\u202F»!", "Dites «\u202Fsalut\u202F»\u202F!"},
+	{"fr", `Open "%@"?`, "Ouvrir « %@ »?", "Ouvrir «\u202F%@\u202F»\u202F?"},
+	{"fr", `Name "%@":`, "Nom «\u202F%@\u202F» :", "Nom «\u202F%@\u202F»\u202F:"},
+	{"fr", `Open "%@"?`, `Ouvrir "%@" ?`, "Ouvrir «\u202F%@\u202F»\u202F?"},
+	{"fr-CA", "Delete %1$@?", "Supprimer %1$@?", "Supprimer %1$@\u202F?"},
+	{"de", `Open "%@"`, `"%@" öffnen`, "„%@“ öffnen"},
+	{"de", "Can't open", "Kann's nicht öffnen", "Kann’s nicht öffnen"},
+	{"en-GB", `Can't open "%@"`, `Can't open "%@"`, `Can't open "%@"`},
+	{"fr", "100%% done", "100%% terminé!", "100%% terminé\u202F!"},
+}
+
+func TestFixes(t *testing.T) {
+	for i, tv := range tvFixes {
+		f := New(tv.lang, DefaultFixes()...)
+		fixed, _ := f.Apply(tv.source, tv.target)
+		if fixed != tv.expect {
+			t.Errorf("Expected tvFixes[%d] to be fixed into %q got %q", i, tv.expect, fixed)
+		}
+	}
+}
+
+func TestFixMessages(t *testing.T) {
+	srcChan := make(chan dotstrings.Message, 4)
+	srcChan <- dotstrings.Message{ID: "open", Ctx: "Open…", Str: "Ouvrir"}
+	srcChan <- dotstrings.Message{Fuzzy: true, Missing: true, ID: "save", Ctx: "Save…", Str: "Save…"}
+	srcChan <- dotstrings.Message{ID: "quote", Ctx: `Open \"%@\"`, Str: `Ouvrir \"%@\"`}
+	srcChan <- dotstrings.Message{Fuzzy: true, ID: "close", Ctx: `Close \"%@\"`, Str: `Close \"%@\"`}
+	close(srcChan)
+
+	f := New("fr", DefaultFixes()...)
+	var msgs []dotstrings.Message
+	for m := range f.FixMessages(context.Background(), srcChan) {
+		msgs = append(msgs, m)
+	}
+	if msgs[0].Str != "Ouvrir…" {
+		t.Errorf("Expected ellipsis to be added got %q", msgs[0].Str)
+	}
+	if msgs[1].Str != "Save…" {
+		t.Errorf("Expected missing message to be left alone got %q", msgs[1].Str)
+	}
+	if msgs[2].Str != "Ouvrir «\u202F%@\u202F»" {
+		t.Errorf("Expected guillemets got %q", msgs[2].Str)
+	}
+	if msgs[3].Str != `Close \"%@\"` {
+		t.Errorf("Expected untranslated fuzzy message to be left alone got %q", msgs[3].Str)
+	}
+	changes := f.Changes()
+	if len(changes) != 2 || changes[1].Fixes[0] != "quotes" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
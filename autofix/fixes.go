@@ -0,0 +1,204 @@
+package autofix
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	nnbsp = '\u202F' // narrow no-break space
+	nbsp  = '\u00A0' // no-break space
+)
+
+// DefaultFixes returns all built-in fixes. Use New to select the ones that
+// apply to a specific language.
+func DefaultFixes() []*Fix {
+	return []*Fix{
+		Ellipsis,
+		Whitespace,
+		FrenchQuotes,
+		FrenchSpacing,
+		GermanQuotes,
+		Apostrophes,
+	}
+}
+
+var (
+	// Ellipsis adds the trailing ellipsis of the source to a target that
+	// lacks it, e.g. for menu items that open a dialog.
+	Ellipsis = &Fix{
+		Name:        "ellipsis",
+		Description: "add missing trailing …",
+		Apply: func(source, target string) string {
+			src := strings.TrimRightFunc(source, unicode.IsSpace)
+			if !strings.HasSuffix(src, "…") && !strings.HasSuffix(src, "...") {
+				return target
+			}
+			body := strings.TrimRightFunc(target, unicode.IsSpace)
+			if len(body) == 0 || strings.HasSuffix(body, "…") || strings.HasSuffix(body, "...") || strings.HasSuffix(body, "⋯") {
+				return target
+			}
+			// Replace a single trailing period, it was most likely meant as an ellipsis.
+			body = strings.TrimSuffix(body, ".")
+			return body + "…" + target[len(strings.TrimRightFunc(target, unicode.IsSpace)):]
+		},
+	}
+
+	// Whitespace copies leading and trailing whitespace of the source to a
+	// target that dropped it.
+	Whitespace = &Fix{
+		Name:        "whitespace",
+		Description: "restore leading and trailing whitespace dropped from the source",
+		Apply: func(source, target string) string {
+			body := strings.TrimSpace(target)
+			if len(body) == 0 {
+				return target
+			}
+			lead := target[:len(target)-len(strings.TrimLeftFunc(target, unicode.IsSpace))]
+			trail := target[len(strings.TrimRightFunc(target, unicode.IsSpace)):]
+			if len(lead) == 0 {
+				lead = source[:len(source)-len(strings.TrimLeftFunc(source, unicode.IsSpace))]
+			}
+			if len(trail) == 0 {
+				trail = source[len(strings.TrimRightFunc(source, unicode.IsSpace)):]
+			}
+			return lead + body + trail
+		},
+	}
+
+	// FrenchQuotes replaces pairs of ASCII double quotes with guillemets.
+	FrenchQuotes = &Fix{
+		Name:        "quotes",
+		Description: `replace "…" with « … »`,
+		Languages:   []string{"fr"},
+		Apply: func(source, target string) string {
+			return replaceQuotes(target, "«"+string(nnbsp), string(nnbsp)+"»")
+		},
+	}
+
+	// FrenchSpacing puts a narrow no-break space before the high punctuation
+	// marks : ; ! ? and inside guillemets, replacing an ordinary space.
+	FrenchSpacing = &Fix{
+		Name:        "spacing",
+		Description: "use a narrow no-break space before : ; ! ? and inside « »",
+		Languages:   []string{"fr"},
+		Apply:       frenchSpacing,
+	}
+
+	// GermanQuotes replaces pairs of ASCII double quotes with German quotes.
+	GermanQuotes = &Fix{
+		Name:        "quotes",
+		Description: `replace "…" with „…“`,
+		Languages:   []string{"de"},
+		Apply: func(source, target string) string {
+			return replaceQuotes(target, "„", "“")
+		},
+	}
+
+	// Apostrophes replaces straight apostrophes within words with typographic ones.
+	Apostrophes = &Fix{
+		Name:        "apostrophes",
+		Description: "replace ' with ’ within words",
+		Languages:   []string{"de", "fr"},
+		Apply: func(source, target string) string {
+			var b strings.Builder
+			var prev rune
+			for i, r := range target {
+				if r == '\'' {
+					next, _ := utf8.DecodeRuneInString(target[i+1:])
+					if unicode.IsLetter(prev) && (unicode.IsLetter(next) || next == utf8.RuneError || unicode.IsSpace(next)) {
+						r = '’'
+					}
+				}
+				b.WriteRune(r)
+				prev = r
+			}
+			return b.String()
+		},
+	}
+)
+
+// replaceQuotes replaces every pair of ASCII double quotes in s by open and
+// close. Nothing is changed when the quotes are not paired.
+func replaceQuotes(s, open, close string) string {
+	if n := strings.Count(s, `"`); n == 0 || n%2 != 0 {
+		return s
+	}
+	var b strings.Builder
+	opening := true
+	for _, r := range s {
+		if r != '"' {
+			b.WriteRune(r)
+			continue
+		}
+		if opening {
+			b.WriteString(open)
+		} else {
+			b.WriteString(close)
+		}
+		opening = !opening
+	}
+	return b.String()
+}
+
+func frenchSpacing(source, target string) string {
+	runes := []rune(target)
+	var out []rune
+	for i, r := range runes {
+		switch r {
+		case ':', ';', '!', '?', '»':
+			if i == 0 || skipSpacing(runes, i) {
+				break
+			}
+			// Replace an ordinary space, or insert one after a word or a
+			// closing guillemet.
+			if last := len(out) - 1; out[last] == ' ' || out[last] == nbsp {
+				out[last] = nnbsp
+			} else if (out[last] == '»' && r != '»') || (out[last] != nnbsp && !unicode.IsSpace(out[last]) && !unicode.IsPunct(out[last])) {
+				out = append(out, nnbsp)
+			}
+		}
+		out = append(out, r)
+		if r == '«' && i+1 < len(runes) {
+			if runes[i+1] == ' ' || runes[i+1] == nbsp {
+				runes[i+1] = nnbsp
+			} else if runes[i+1] != nnbsp {
+				out = append(out, nnbsp)
+			}
+		}
+	}
+	return string(out)
+}
+
+// skipSpacing reports whether the punctuation at runes[i] is part of
+// something other than a sentence. Like a time (10:30), a list in code (a;b)
+// or a placeholder (%1$@:%2$@) where it is followed by more of the word, or
+// a word without spaces that looks like a URL (x.com?a=1) or a path.
+func skipSpacing(runes []rune, i int) bool {
+	if runes[i] == '»' {
+		return false
+	}
+	if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && !strings.ContainsRune(":;!?.…»)\"'”’", runes[i+1]) {
+		return true
+	}
+	start, end := i, i
+	for start > 0 && !unicode.IsSpace(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && !unicode.IsSpace(runes[end]) {
+		end++
+	}
+	return technical(string(runes[start:end]))
+}
+
+// technical reports whether the word looks like a URL, a path, a query string
+// or an ICU skeleton rather than text.
+func technical(word string) bool {
+	for _, prefix := range []string{"/", "~/", "./", "../"} {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(word, "://") || strings.Contains(word, "::") || strings.ContainsAny(word, `\=&`)
+}
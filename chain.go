@@ -0,0 +1,48 @@
+package translate
+
+import (
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+// Memory is the translation memory of a single locale.
+type Memory struct {
+	Locale       string
+	Translations map[string]dotstrings.Message
+}
+
+// Chain is the fallback chain of a locale, like de-AT → de. Its first memory
+// holds the translations of the locale itself, the others are the fallbacks
+// in order. A translation missing from a memory is taken from the next
+// memory that has it.
+type Chain []Memory
+
+// NewChain returns a chain that consists of just the translations of a
+// single locale.
+func NewChain(translations map[string]dotstrings.Message) Chain {
+	return Chain{{Translations: translations}}
+}
+
+// Lookup returns the translation of id found first in the chain and the
+// locale of the memory that supplied it. The locale is empty when the
+// translation was found in the first memory. Untranslated messages, as
+// written for missing translations, are skipped.
+func (c Chain) Lookup(id string) (m dotstrings.Message, locale string, ok bool) {
+	for i, memory := range c {
+		if m, ok = memory.Translations[id]; ok && !m.Untranslated() {
+			if i > 0 {
+				locale = memory.Locale
+			}
+			return
+		}
+	}
+	return dotstrings.Message{}, "", false
+}
+
+// Translations returns the translations of the locale itself, the first
+// memory of the chain.
+func (c Chain) Translations() map[string]dotstrings.Message {
+	if len(c) == 0 {
+		return nil
+	}
+	return c[0].Translations
+}
@@ -0,0 +1,222 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/placeholder"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// Kinds of findings.
+const (
+	Missing      = "missing"
+	Fuzzy        = "fuzzy"
+	Obsolete     = "obsolete"
+	Placeholders = "placeholders"
+)
+
+// Kinds lists the kinds of findings in the order they are reported.
+var Kinds = []string{Missing, Fuzzy, Obsolete, Placeholders}
+
+// Limits holds the maximum percentage of the entries of a locale that may be
+// of every kind before the check of the locale fails. A limit of 0 fails on
+// the first finding of the kind, a limit of 100 never fails.
+type Limits map[string]float64
+
+// Thresholds holds the limits per locale, JSON encoded as
+//
+//	{"*": {"fuzzy": 10}, "ja": {"missing": 5}, "pt": {"missing": 2}}
+//
+// The limits of "*" apply to every locale. A locale like pt-BR uses the
+// limits of pt-BR, then those of pt and then those of "*", per kind. Kinds
+// without a limit default to 0. Locales are matched as given and in their
+// canonical form, so pt_BR uses the limits of pt-BR too.
+type Thresholds map[string]Limits
+
+// Limit returns the limit of kind for the locale code.
+func (t Thresholds) Limit(code, kind string) float64 {
+	candidates := []string{code}
+	if l, err := locale.Parse(code); err == nil {
+		candidates = append(candidates, l.String(), l.Language())
+	}
+	for _, l := range append(candidates, "*") {
+		if limit, ok := t[l][kind]; ok {
+			return limit
+		}
+	}
+	return 0
+}
+
+// Validate returns an error for unknown kinds and limits out of range.
+func (t Thresholds) Validate() error {
+	for locale, limits := range t {
+		for kind, limit := range limits {
+			if !contains(Kinds, kind) {
+				return fmt.Errorf("Unknown kind %q for locale %q, one of %q expected", kind, locale, Kinds)
+			}
+			if limit < 0 || limit > 100 {
+				return fmt.Errorf("Limit %v of %q for locale %q is not a percentage", limit, kind, locale)
+			}
+		}
+	}
+	return nil
+}
+
+// File is a source .strings file and its translation in a target locale.
+type File struct {
+	Source string
+	Target string
+}
+
+// Finding is an entry that is not completely translated, or an obsolete
+// translation.
+type Finding struct {
+	File    string `json:"file"`
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.File, f.ID, f.Kind, f.Message)
+}
+
+// Failure is a kind of finding exceeding its limit.
+type Failure struct {
+	Kind    string  `json:"kind"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+	Limit   float64 `json:"limit"`
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%d %s entries (%.1f%%) exceed the limit of %.1f%%", f.Count, f.Kind, f.Percent, f.Limit)
+}
+
+// Locale is the outcome of checking all files of a locale.
+type Locale struct {
+	Locale string `json:"locale"`
+	translate.Stats
+	// Invalid is the number of translated entries that don't have the
+	// placeholders of their source.
+	Invalid  int       `json:"invalid"`
+	Findings []Finding `json:"findings"`
+	Failures []Failure `json:"failures"`
+}
+
+// Failed returns true when any kind of finding exceeds its limit.
+func (l *Locale) Failed() bool {
+	return len(l.Failures) > 0
+}
+
+// Count returns the number of findings of kind.
+func (l *Locale) Count(kind string) int {
+	switch kind {
+	case Missing:
+		return l.Missing
+	case Fuzzy:
+		return l.Fuzzy
+	case Obsolete:
+		return l.Obsolete
+	case Placeholders:
+		return l.Invalid
+	}
+	return 0
+}
+
+// CheckLocale translates the source of every file with its target the way
+// TranslateMessages does, without writing anything, and compares the findings
+// with the thresholds of locale. A target file that doesn't exist has all its
+// entries missing.
+func CheckLocale(ctx context.Context, locale string, files []File, t Thresholds) (l *Locale, err error) {
+	l = &Locale{Locale: locale, Findings: []Finding{}, Failures: []Failure{}}
+	for _, f := range files {
+		if err = l.checkFile(ctx, f); err != nil {
+			return
+		}
+	}
+	for _, kind := range Kinds {
+		n := l.Count(kind)
+		limit := t.Limit(locale, kind)
+		percent := 0.0
+		if l.Total > 0 {
+			percent = 100 * float64(n) / float64(l.Total)
+		}
+		if n > 0 && percent > limit {
+			l.Failures = append(l.Failures, Failure{Kind: kind, Count: n, Percent: percent, Limit: limit})
+		}
+	}
+	return
+}
+
+func (l *Locale) checkFile(ctx context.Context, f File) (err error) {
+	translations, err := dotstrings.LoadTargetMessagesMapFromFile(f.Target)
+	if os.IsNotExist(err) {
+		translations, err = map[string]dotstrings.Message{}, nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to load %q (%v)", f.Target, err)
+	}
+
+	srcFile, err := os.Open(f.Source)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Load, translate and count the messages asynchronously
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	msgChan = translate.TranslateMessages(ctx, msgChan, translate.NewChain(translations))
+	msgChan = translate.CountMessages(ctx, msgChan, &l.Stats, translations)
+
+	// Synchronously collect the findings
+	used := make(map[string]bool)
+	for m := range msgChan {
+		used[m.ID] = true
+		switch {
+		case m.Missing:
+			l.Findings = append(l.Findings, Finding{File: f.Target, ID: m.ID, Kind: Missing, Message: "no translation"})
+		case m.Fuzzy:
+			l.Findings = append(l.Findings, Finding{File: f.Target, ID: m.ID, Kind: Fuzzy, Message: "translation needs review"})
+		}
+		if !m.Missing {
+			if err := placeholder.Compare(m.Ctx, m.Str); err != nil {
+				l.Invalid++
+				l.Findings = append(l.Findings, Finding{File: f.Target, ID: m.ID, Kind: Placeholders, Message: err.Error()})
+			}
+		}
+	}
+	if err = stage.Wait(ctx, cancel, errChan); err != nil {
+		return fmt.Errorf("Failed to load %q (%v)", f.Source, err)
+	}
+
+	var obsolete []string
+	for id := range translations {
+		if !used[id] {
+			obsolete = append(obsolete, id)
+		}
+	}
+	sort.Strings(obsolete)
+	for _, id := range obsolete {
+		l.Findings = append(l.Findings, Finding{File: f.Target, ID: id, Kind: Obsolete, Message: "not in " + f.Source})
+	}
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/internal/testutil"
+)
+
+func newFiles(t *testing.T) []File {
+	dir := t.TempDir()
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n\n/* Count */\n\"count\" = \"%d files\";\n\n/* Quit */\n\"quit\" = \"Quit\";\n")
+	tgt := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n\n/* Fuzzy */\n/* Save */\n\"save\" = \"Enregistrer\";\n\n/* %d files */\n\"count\" = \"fichiers\";\n\n/* Gone */\n\"gone\" = \"Parti\";\n")
+	return []File{{Source: src, Target: tgt}}
+}
+
+var tvLimit = []struct {
+	locale, kind string
+	limit        float64
+}{
+	{"pt-BR", Missing, 1},
+	{"pt-PT", Missing, 2},
+	{"pt_BR", Missing, 1},
+	{"pt_AO", Missing, 2},
+	{"ja", Missing, 0},
+	{"ja", Fuzzy, 10},
+}
+
+func TestLimit(t *testing.T) {
+	th := Thresholds{"*": {Fuzzy: 10}, "pt": {Missing: 2}, "pt-BR": {Missing: 1}}
+	for i, tv := range tvLimit {
+		if limit := th.Limit(tv.locale, tv.kind); limit != tv.limit {
+			t.Errorf("Expected limit %v for tvLimit[%d] got %v", tv.limit, i, limit)
+		}
+	}
+	if err := (Thresholds{"*": {"typos": 1}}).Validate(); err == nil {
+		t.Error("Expected error for unknown kind")
+	}
+	if err := (Thresholds{"*": {Missing: 120}}).Validate(); err == nil {
+		t.Error("Expected error for limit out of range")
+	}
+}
+
+func TestCheckLocale(t *testing.T) {
+	files := newFiles(t)
+	l, err := CheckLocale(context.Background(), "fr", files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, n := range map[string]int{Missing: 1, Fuzzy: 1, Obsolete: 1, Placeholders: 1} {
+		if l.Count(kind) != n {
+			t.Errorf("Expected %d %s entries got %d", n, kind, l.Count(kind))
+		}
+	}
+	if len(l.Failures) != len(Kinds) {
+		t.Errorf("Expected every kind to fail got %v", l.Failures)
+	}
+
+	// 1 of 4 entries is 25%
+	l, err = CheckLocale(context.Background(), "fr", files, Thresholds{"fr": {Missing: 25, Fuzzy: 25, Obsolete: 25, Placeholders: 25}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Failed() {
+		t.Errorf("Expected findings within the limits to pass got %v", l.Failures)
+	}
+}
+
+func TestCheckTranslated(t *testing.T) {
+	dir := t.TempDir()
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n")
+	tgt := filepath.Join(dir, "fr.strings")
+
+	// The target is written by translate without any translations, so all
+	// its entries are fuzzy copies of the source.
+	srcFile, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcFile.Close()
+	tgtFile, err := os.Create(tgt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tgtFile.Close()
+	if _, err = translate.TranslateMessagesFile(context.Background(), srcFile, translate.NewChain(nil), tgtFile); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := CheckLocale(context.Background(), "fr", []File{{Source: src, Target: tgt}}, Thresholds{"fr": {Missing: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Count(Missing) != 2 || l.Count(Fuzzy) != 0 {
+		t.Errorf("Expected the untranslated entries to be missing got %d missing and %d fuzzy", l.Count(Missing), l.Count(Fuzzy))
+	}
+	if !l.Failed() {
+		t.Errorf("Expected the missing threshold to fail")
+	}
+}
+
+func TestReport(t *testing.T) {
+	l, err := CheckLocale(context.Background(), "fr", newFiles(t), Thresholds{"*": {Fuzzy: 100, Obsolete: 100, Placeholders: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Report{Locales: []*Locale{l}}
+	if !r.Failed() {
+		t.Fatal("Expected report to fail on the missing entry")
+	}
+
+	buf := &bytes.Buffer{}
+	if err = r.WriteGitHub(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "::error file=") || !strings.Contains(buf.String(), "title=fr missing::quit") {
+		t.Errorf("Expected error annotation for the missing entry got %q", buf)
+	}
+	if !strings.Contains(buf.String(), "::warning file=") {
+		t.Errorf("Expected warning annotations for the other findings got %q", buf)
+	}
+
+	buf.Reset()
+	if err = r.WriteJUnit(buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err = xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Tests != len(Kinds) || doc.Failures != 1 {
+		t.Errorf("Expected %d tests with 1 failure got %d with %d", len(Kinds), doc.Tests, doc.Failures)
+	}
+}
@@ -0,0 +1,172 @@
+package check
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report contains the outcome of checking every locale.
+type Report struct {
+	Locales []*Locale `json:"locales"`
+}
+
+// Failed returns true when any locale failed its check.
+func (r *Report) Failed() bool {
+	for _, l := range r.Locales {
+		if l.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// failed returns true when the findings of kind exceed the limit of l.
+func (l *Locale) failed(kind string) bool {
+	for _, f := range l.Failures {
+		if f.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes a line per failure of every locale to w, followed by a
+// summary line per locale.
+func (r *Report) WriteText(w io.Writer) (err error) {
+	for _, l := range r.Locales {
+		for _, f := range l.Failures {
+			if _, err = fmt.Fprintf(w, "%s: %v\n", l.Locale, f); err != nil {
+				return
+			}
+		}
+	}
+	for _, l := range r.Locales {
+		status := "ok"
+		if l.Failed() {
+			status = "FAIL"
+		}
+		_, err = fmt.Fprintf(w, "%-4s %s: %d entries, %d missing, %d fuzzy, %d obsolete, %d invalid placeholders\n", status, l.Locale, l.Total, l.Missing, l.Fuzzy, l.Obsolete, l.Invalid)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteJSON writes the report as a JSON document to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	doc := struct {
+		Failed bool `json:"failed"`
+		*Report
+	}{r.Failed(), r}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&doc)
+}
+
+// WriteGitHub writes the findings as GitHub Actions workflow commands to w,
+// so they show up as annotations of the files. Findings of a kind that
+// exceeds its limit are errors, the others are warnings. Every failure is
+// added as an error without a file.
+func (r *Report) WriteGitHub(w io.Writer) (err error) {
+	for _, l := range r.Locales {
+		for _, f := range l.Findings {
+			level := "warning"
+			if l.failed(f.Kind) {
+				level = "error"
+			}
+			_, err = fmt.Fprintf(w, "::%s file=%s,title=%s::%s\n", level, ghProperty(f.File), ghProperty(l.Locale+" "+f.Kind), ghData(f.ID+": "+f.Message))
+			if err != nil {
+				return
+			}
+		}
+		for _, f := range l.Failures {
+			if _, err = fmt.Fprintf(w, "::error title=%s::%s\n", ghProperty("Localization check "+l.Locale), ghData(f.String())); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ghData escapes s for the message of a workflow command.
+func ghData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ghProperty escapes s for a property of a workflow command.
+func ghProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// JUnit XML elements, see https://llg.cubic.org/docs/junit/
+type (
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Name     string       `xml:"name,attr"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	}
+	junitCase struct {
+		ClassName string         `xml:"classname,attr"`
+		Name      string         `xml:"name,attr"`
+		Failures  []junitFailure `xml:"failure"`
+	}
+	junitFailure struct {
+		Type    string `xml:"type,attr"`
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// WriteJUnit writes the report as JUnit XML to w so CI systems can display
+// it. Every locale becomes a test suite with a test case per kind of finding
+// that fails when the kind exceeds its limit. The findings of a failed test
+// case are listed in its failure.
+func (r *Report) WriteJUnit(w io.Writer) (err error) {
+	doc := junitSuites{Name: "check"}
+	for _, l := range r.Locales {
+		suite := junitSuite{Name: l.Locale}
+		for _, kind := range Kinds {
+			tc := junitCase{ClassName: l.Locale, Name: kind}
+			for _, f := range l.Failures {
+				if f.Kind != kind {
+					continue
+				}
+				var text strings.Builder
+				for _, finding := range l.Findings {
+					if finding.Kind == kind {
+						fmt.Fprintln(&text, finding)
+					}
+				}
+				tc.Failures = append(tc.Failures, junitFailure{Type: kind, Message: f.String(), Text: text.String()})
+				suite.Failures++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(&doc); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "\n")
+	return
+}
@@ -0,0 +1,13 @@
+// Autofix is an alias of translate autofix, kept for existing scripts.
+// Run "translate help" for the documentation of the subcommands.
+package main
+
+import (
+	"os"
+
+	"github.com/simpleapps-eu/translate/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Legacy(cli.NewEnv(), "autofix", os.Args[1:]))
+}
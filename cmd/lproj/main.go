@@ -0,0 +1,13 @@
+// Lproj is an alias of translate lproj, kept for existing scripts.
+// Run "translate help" for the documentation of the subcommands.
+package main
+
+import (
+	"os"
+
+	"github.com/simpleapps-eu/translate/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Legacy(cli.NewEnv(), "lproj", os.Args[1:]))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/simpleapps-eu/translate/mt/mttest"
+)
+
+var addr string
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "  Serves a local machine translation endpoint for offline development.")
+		fmt.Fprintln(os.Stderr, "  Every text is pseudo translated by prefixing it with the target language.")
+		fmt.Fprintln(os.Stderr, "  Point xlate at it with: xlate -mt http://localhost:8089/ ...")
+		fmt.Fprintln(os.Stderr, "")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&addr, "addr", "localhost:8089", "address to listen on")
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	log.Printf("Machine translation stub listening on http://%s/", addr)
+	log.Fatal(http.ListenAndServe(addr, mttest.NewHandler(mttest.Pseudo)))
+}
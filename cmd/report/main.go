@@ -0,0 +1,13 @@
+// Report is an alias of translate report, kept for existing scripts.
+// Run "translate help" for the documentation of the subcommands.
+package main
+
+import (
+	"os"
+
+	"github.com/simpleapps-eu/translate/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Legacy(cli.NewEnv(), "report", os.Args[1:]))
+}
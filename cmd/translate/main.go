@@ -0,0 +1,80 @@
+/*
+Translate manages the localization of Apple .strings files, XLIFF files and
+templates.
+
+Usage:
+
+	translate [-C dir] [-q] [-backup] <command> [flags] [arguments]
+
+The commands are:
+
+	convert    convert between .strings and XLIFF files, or normalize a .strings file
+	translate  translate a source file using a translation memory or XLIFF file
+	fuzzy      count, export and import the fuzzy strings of a translation
+	format     make a strings file generated by genstrings suitable as source strings file
+	template   execute a text template with JSON data
+	extract    collect the translatable strings of templates and sources into source strings or PO files
+	ib         export the strings of storyboard and xib files, or find obsolete translations of them
+	xcstrings  export and import the strings of a locale of an Xcode String Catalog
+	lint       check the quality of translations
+	autofix    fix mechanical issues in translations
+	plurals    add the plural forms a PO file lacks for its language, marked fuzzy
+	report     report the translation progress of every locale
+	lproj      process every table of every locale in a resource directory
+	sync       update all files of the project described by a configuration file
+	check      fail when translations are missing, fuzzy, obsolete or have invalid placeholders
+
+Every command has its own flags, use "translate <command> -h" or
+"translate help <command>" to list them. The flags are named the same in all
+commands: -source is a file in the source language, -target a file in the
+target language, -tm a translation memory, -xliff an XLIFF file, -out the file
+written and -lang the target language. Files can be given as - to read
+standard input or write standard output. Progress messages are written to
+standard error and are suppressed by -q.
+
+Files are written to a temporary file that only replaces the file once the
+command succeeded, so a failure or an interrupt never leaves a file empty or
+partially written.
+
+The global flags are:
+
+	-C dir   change to dir before running the command
+	-q       don't write progress messages
+	-backup  keep the previous version of every file written as <file>.bak
+
+Exit codes:
+
+	0  success
+	1  the command ran but found issues, e.g. lint issues of -fail severity,
+	   glossary violations or locales failing check
+	2  invalid flags or arguments, or the command failed
+
+The commands xliff, xlate, fuzzy, stringsfmt, tplex, lint, report, autofix and
+lproj are aliases of these subcommands, kept for existing scripts. Like before,
+xliff, xlate, fuzzy, stringsfmt and tplex exit with 1 when the command failed:
+
+	xliff -source en.strings -xliff fr.xlf -out fr.strings  is  translate translate -source en.strings -xliff fr.xlf -target fr.strings
+	xliff ...                                               is  translate convert ...
+	xlate ...                                               is  translate translate ...
+	fuzzy -export ...                                       is  translate fuzzy export ...
+	fuzzy -import ...                                       is  translate fuzzy import ...
+	fuzzy -glossary ...                                     is  translate fuzzy terms ...
+	fuzzy ...                                               is  translate fuzzy count ...
+	stringsfmt ...                                          is  translate format ...
+	tplex ...                                               is  translate template ...
+	lproj -translate ...                                    is  translate lproj translate ...
+	lproj -export dir ...                                   is  translate lproj export ... dir
+	lproj -import dir ...                                   is  translate lproj import ... dir
+	lproj ...                                               is  translate lproj count ...
+*/
+package main
+
+import (
+	"os"
+
+	"github.com/simpleapps-eu/translate/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(cli.NewEnv(), os.Args[1:]))
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/check"
+	"github.com/simpleapps-eu/translate/lint"
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+// Config declares the localization setup of a project. It is stored as a JSON
+// document, by convention in a file named translate.json in the root of the
+// project.
+//
+//	{
+//	  "sourceLocale": "en",
+//	  "targetLocales": ["de", "fr", "pt-BR"],
+//	  "locales": {"pt-BR": "pt_BR"},
+//	  "extract": [["genstrings", "-o", "Resources/en.lproj", "Sources/*.m"]],
+//	  "files": [{"format": "strings", "path": "Resources/{locale}.lproj/*.strings"}],
+//	  "memory": "tm/{locale}.strings",
+//	  "xliff": "xliff/{locale}/{table}.xlf",
+//	  "checks": {"disable": ["length"], "fail": "error", "glossary": "glossary.json"},
+//	  "thresholds": {"*": {"fuzzy": 10}, "ja": {"missing": 5}},
+//	  "fallbacks": {"de-AT": ["de"], "pt-PT": ["pt-BR"]}
+//	}
+type Config struct {
+	// Dir is the directory relative paths in the configuration are resolved
+	// against, LoadFile sets it to the directory of the configuration file.
+	Dir string `json:"-"`
+
+	SourceLocale  string   `json:"sourceLocale"`
+	TargetLocales []string `json:"targetLocales"`
+	// Locales maps a locale to the code used for it in file paths, locales
+	// that are not mapped use their code in Convention.
+	Locales map[string]string `json:"locales,omitempty"`
+	// Convention is the convention of the locale codes in file paths, one of
+	// locale.Conventions, like gettext for pt_BR or android for pt-rBR. By
+	// default locales use their own name as code.
+	Convention string `json:"convention,omitempty"`
+	// Extract lists the commands that (re)generate the source files, they are
+	// run in Dir.
+	Extract [][]string `json:"extract,omitempty"`
+	Files   []Files    `json:"files"`
+	// Memory is the path pattern of an additional translation memory per
+	// locale, its translations are used for entries the target file doesn't
+	// have yet.
+	Memory string `json:"memory,omitempty"`
+	// XLIFF is the path pattern of the XLIFF file exported for every target
+	// file, no XLIFF files are exported when it is empty.
+	XLIFF  string  `json:"xliff,omitempty"`
+	Checks *Checks `json:"checks,omitempty"`
+	// Thresholds are the limits used by translate check, see
+	// check.Thresholds.
+	Thresholds check.Thresholds `json:"thresholds,omitempty"`
+	// Fallbacks are the fallback chains of the target locales, entries a
+	// target locale doesn't translate are taken from its fallback locales.
+	// Locales without a chain fall back to their CLDR parents, see
+	// locale.Fallbacks.
+	Fallbacks locale.Fallbacks `json:"fallbacks,omitempty"`
+}
+
+// Files declares a set of files of a single format. Path is a pattern that
+// contains {locale}, which is replaced by the code of a locale, and may contain
+// the wildcards * and ? in its other path elements. The source files are found
+// by matching Path for the source locale, every wildcard then matches the same
+// text in the path of the target file.
+type Files struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+}
+
+// Checks declares the lint rules run on the target files.
+type Checks struct {
+	Disable []string                 `json:"disable,omitempty"`
+	Levels  map[string]lint.Severity `json:"levels,omitempty"`
+	// Fail is the minimum severity of an issue that fails a sync, it defaults
+	// to error.
+	Fail     *lint.Severity `json:"fail,omitempty"`
+	Glossary string         `json:"glossary,omitempty"`
+}
+
+// Formats lists the file formats supported in a configuration.
+var Formats = []string{"strings"}
+
+const localeVar = "{locale}"
+
+// LoadFile reads the configuration from the file name.
+func LoadFile(name string) (c *Config, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	c, err = Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	c.Dir = filepath.Dir(name)
+	return
+}
+
+// Load reads the configuration from r and validates it.
+func Load(r io.Reader) (c *Config, err error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	c = &Config{}
+	if err = decoder.Decode(c); err != nil {
+		return nil, err
+	}
+	if err = c.validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (c *Config) validate() error {
+	if len(c.SourceLocale) == 0 {
+		return fmt.Errorf("No sourceLocale specified")
+	}
+	if len(c.TargetLocales) == 0 {
+		return fmt.Errorf("No targetLocales specified")
+	}
+	source, err := locale.Parse(c.SourceLocale)
+	if err != nil {
+		return fmt.Errorf("Invalid sourceLocale (%v)", err)
+	}
+	for _, l := range c.TargetLocales {
+		target, err := locale.Parse(l)
+		if err != nil {
+			return fmt.Errorf("Invalid targetLocales (%v)", err)
+		}
+		if target.Same(source) {
+			return fmt.Errorf("Source locale %q can't be a target locale", l)
+		}
+	}
+	if _, err = source.Format(c.Convention); err != nil {
+		return err
+	}
+	if len(c.Files) == 0 {
+		return fmt.Errorf("No files specified")
+	}
+	for _, f := range c.Files {
+		if !contains(Formats, f.Format) {
+			return fmt.Errorf("Unsupported format %q, one of %q expected", f.Format, Formats)
+		}
+		if !strings.Contains(f.Path, localeVar) {
+			return fmt.Errorf("Path %q doesn't contain %s", f.Path, localeVar)
+		}
+		if _, err := regexp.Compile(pathRegexp(f.Path)); err != nil {
+			return fmt.Errorf("Invalid path %q (%v)", f.Path, err)
+		}
+	}
+	if len(c.Memory) > 0 && !strings.Contains(c.Memory, localeVar) {
+		return fmt.Errorf("Memory %q doesn't contain %s", c.Memory, localeVar)
+	}
+	if len(c.XLIFF) > 0 && !strings.Contains(c.XLIFF, localeVar) {
+		return fmt.Errorf("XLIFF %q doesn't contain %s", c.XLIFF, localeVar)
+	}
+	for _, cmd := range c.Extract {
+		if len(cmd) == 0 {
+			return fmt.Errorf("Empty extract command")
+		}
+	}
+	if err = c.Fallbacks.Validate(); err != nil {
+		return fmt.Errorf("Invalid fallbacks (%v)", err)
+	}
+	return c.Thresholds.Validate()
+}
+
+// Code returns the code used for the locale l in file paths.
+func (c *Config) Code(l string) string {
+	if code, ok := c.Locales[l]; ok {
+		return code
+	}
+	if len(c.Convention) > 0 {
+		if parsed, err := locale.Parse(l); err == nil {
+			if code, err := parsed.Format(c.Convention); err == nil && len(code) > 0 {
+				return code
+			}
+		}
+	}
+	return l
+}
+
+// Path resolves name against Dir.
+func (c *Config) Path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.Dir, filepath.FromSlash(name))
+}
+
+// Expand replaces {locale} in pattern by the code of locale and every other
+// {name} by vars[name], the result is resolved against Dir.
+func (c *Config) Expand(pattern, locale string, vars map[string]string) string {
+	s := strings.ReplaceAll(pattern, localeVar, c.Code(locale))
+	for name, value := range vars {
+		s = strings.ReplaceAll(s, "{"+name+"}", value)
+	}
+	return c.Path(s)
+}
+
+// Pair is a source file together with the matching file of a target locale.
+type Pair struct {
+	Format string
+	Source string
+	Target string
+}
+
+// Sources returns the files of the source locale matching the path pattern
+// of f, resolved against Dir.
+func (c *Config) Sources(f Files) ([]string, error) {
+	return filepath.Glob(c.Expand(f.Path, c.SourceLocale, nil))
+}
+
+// Pairs returns the source files of f paired with their target file in
+// locale. The target files don't need to exist.
+func (c *Config) Pairs(f Files, locale string) (pairs []Pair, err error) {
+	sources, err := c.Sources(f)
+	if err != nil {
+		return
+	}
+	re := regexp.MustCompile(pathRegexp(filepath.ToSlash(c.Expand(f.Path, c.SourceLocale, nil))))
+	for _, src := range sources {
+		m := re.FindStringSubmatch(filepath.ToSlash(src))
+		if m == nil {
+			return nil, fmt.Errorf("Failed to match %q with %q", src, f.Path)
+		}
+		pairs = append(pairs, Pair{Format: f.Format, Source: src, Target: fillWildcards(c.Expand(f.Path, locale, nil), m[1:])})
+	}
+	return
+}
+
+// pathRegexp turns the glob pattern into a regular expression with a group
+// for every wildcard.
+func pathRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString("([^/]*)")
+		case '?':
+			b.WriteString("([^/])")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// fillWildcards replaces the wildcards in pattern, in order, by values.
+func fillWildcards(pattern string, values []string) string {
+	var b strings.Builder
+	for _, r := range pattern {
+		if (r == '*' || r == '?') && len(values) > 0 {
+			b.WriteString(values[0])
+			values = values[1:]
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	return index(list, s) >= 0
+}
+
+// index returns the index of the first occurrence of s in list, or -1 when
+// list doesn't contain s.
+func index(list []string, s string) int {
+	for i, l := range list {
+		if l == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/internal/testutil"
+	"github.com/simpleapps-eu/translate/lint"
+)
+
+var tvInvalid = []string{
+	`{"targetLocales": ["fr"], "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["en"], "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr"], "files": [{"format": "po", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr"], "files": [{"format": "strings", "path": "en.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr"], "files": [], "unknown": true}`,
+	`{"sourceLocale": "en", "targetLocales": ["en-US"], "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "english", "targetLocales": ["fr"], "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr_"], "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr"], "convention": "java", "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr-CA"], "fallbacks": {"fr-CA": ["fr_CA"]}, "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+	`{"sourceLocale": "en", "targetLocales": ["fr-CA"], "fallbacks": {"fr-CA": ["fr-"]}, "files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}]}`,
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for i, tv := range tvInvalid {
+		if _, err := Load(strings.NewReader(tv)); err == nil {
+			t.Errorf("Expected tvInvalid[%d] to fail", i)
+		}
+	}
+}
+
+func TestPairs(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(dir, "Resources", "en.lproj", "Localizable.strings"), "")
+	testutil.WriteStrings(t, filepath.Join(dir, "Resources", "en.lproj", "Errors.strings"), "")
+
+	c := &Config{Dir: dir, SourceLocale: "en", Locales: map[string]string{"pt-BR": "pt_BR"}}
+	pairs, err := c.Pairs(Files{Format: "strings", Path: "Resources/{locale}.lproj/*.strings"}, "pt-BR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("Expected 2 pairs got %v", pairs)
+	}
+	expect := filepath.Join(dir, "Resources", "pt_BR.lproj", "Errors.strings")
+	if pairs[0].Target != expect {
+		t.Errorf("Expected target %q got %q", expect, pairs[0].Target)
+	}
+}
+
+func TestCode(t *testing.T) {
+	c := &Config{Convention: "android", Locales: map[string]string{"pt-BR": "pt_BR"}}
+	for l, expect := range map[string]string{"pt-BR": "pt_BR", "zh-CN": "zh-rCN", "sr-Latn": "b+sr+Latn", "fr": "fr"} {
+		if code := c.Code(l); code != expect {
+			t.Errorf("Expected code %q for %q got %q", expect, l, code)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(dir, "en.lproj", "Localizable.strings"),
+		"/* No comment provided by engineer. */\n\"Open\" = \"Open\";\n\n/* Save the file */\n\"save\" = \"save\";\n\n/* Delete */\n\"delete\" = \"Delete\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "fr.lproj", "Localizable.strings"),
+		"/* Open */\n\"Open\" = \"Ouvrir  \";\n\n/* Gone */\n\"gone\" = \"Parti\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "tm", "fr.strings"),
+		"/* Save the file */\n\"save\" = \"Enregistrer le fichier\";\n")
+	config := `{
+		"sourceLocale": "en",
+		"targetLocales": ["fr"],
+		"files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}],
+		"memory": "tm/{locale}.strings",
+		"xliff": "xliff/{locale}/{table}.xlf",
+		"checks": {"disable": ["length"]}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "translate.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadFile(filepath.Join(dir, "translate.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, report, err := c.Sync(context.Background(), SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result got %d", len(results))
+	}
+	r := results[0]
+	if r.Total != 3 || r.Translated != 2 || r.Missing != 1 || r.Obsolete != 1 || r.Files != 1 || r.XLIFF != 1 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+
+	source, err := dotstrings.LoadMessagesMapFromFile(filepath.Join(dir, "en.lproj", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source["save"].Str != "Save the file" || source["Open"].Ctx != "Open" {
+		t.Errorf("Expected the source file to be normalized got %v", source)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "xliff", "fr", "Localizable.xlf")); err != nil {
+		t.Errorf("Expected XLIFF file to be exported (%v)", err)
+	}
+
+	if n := report.Count(lint.Warning); n != 1 {
+		t.Errorf("Expected 1 whitespace warning got %v", report.Issues())
+	}
+}
+
+func TestSyncFallbacks(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(dir, "en.lproj", "Localizable.strings"),
+		"/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "de.lproj", "Localizable.strings"),
+		"/* Open */\n\"open\" = \"Öffnen\";\n\n/* Save */\n\"save\" = \"Speichern\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "de-AT.lproj", "Localizable.strings"),
+		"/* Open */\n\"open\" = \"Aufmachen\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "pt-BR.lproj", "Localizable.strings"),
+		"/* Save */\n\"save\" = \"Salvar\";\n")
+	config := `{
+		"sourceLocale": "en",
+		"targetLocales": ["de", "de-AT", "pt-BR", "pt-PT"],
+		"files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}],
+		"fallbacks": {"pt-PT": ["pt-BR"]}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "translate.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadFile(filepath.Join(dir, "translate.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _, err := c.Sync(context.Background(), SyncOptions{NoExtract: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[1]; r.Locale != "de-AT" || r.Translated != 2 || r.Fallback != 1 {
+		t.Errorf("Unexpected result for de-AT %+v", r)
+	}
+	if r := results[3]; r.Locale != "pt-PT" || r.Translated != 1 || r.Fallback != 1 || r.Missing != 1 {
+		t.Errorf("Unexpected result for pt-PT %+v", r)
+	}
+
+	at, err := dotstrings.LoadTargetMessagesMapFromFile(filepath.Join(dir, "de-AT.lproj", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := at["save"]; m.Str != "Speichern" || m.Locale != "de" {
+		t.Errorf("Expected save to fall back to de got %+v", m)
+	}
+	if m := at["open"]; m.Str != "Aufmachen" || len(m.Locale) != 0 {
+		t.Errorf("Expected open to keep its own translation got %+v", m)
+	}
+	pt, err := dotstrings.LoadTargetMessagesMapFromFile(filepath.Join(dir, "pt-PT.lproj", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := pt["save"]; m.Str != "Salvar" || m.Locale != "pt-BR" {
+		t.Errorf("Expected save to fall back to pt-BR got %+v", m)
+	}
+}
@@ -0,0 +1,369 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/atomicfile"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/glossary"
+	"github.com/simpleapps-eu/translate/lint"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// Result is the outcome of a sync for a single target locale.
+type Result struct {
+	Locale string
+	translate.Stats
+	// Files is the number of target files written.
+	Files int
+	// XLIFF is the number of XLIFF files written.
+	XLIFF int
+}
+
+// SyncOptions controls which steps Sync performs.
+type SyncOptions struct {
+	// NoExtract skips running the extract commands.
+	NoExtract bool
+	// Output receives the output of the extract commands, it defaults to
+	// os.Stdout.
+	Output io.Writer
+	// Backup keeps the previous version of every file replaced as
+	// name.bak.
+	Backup bool
+}
+
+// Sync brings all files of the project up to date in a single step. It runs
+// the extract commands, normalizes the source files the way stringsfmt does,
+// updates the target files of every target locale using TranslateMessages
+// with the fallback chain of the locale, exports the target files as XLIFF and finally runs the checks on them. The
+// report is nil when no checks are configured. Sync stops when ctx is done,
+// the files already written are complete.
+func (c *Config) Sync(ctx context.Context, opts SyncOptions) (results []Result, report *lint.Report, err error) {
+	if !opts.NoExtract {
+		if err = c.RunExtract(ctx, opts.Output); err != nil {
+			return
+		}
+	}
+
+	var pairs [][]Pair
+	for _, locale := range c.TargetLocales {
+		var lp []Pair
+		for _, f := range c.Files {
+			var p []Pair
+			if p, err = c.Pairs(f, locale); err != nil {
+				return
+			}
+			lp = append(lp, p...)
+		}
+		pairs = append(pairs, lp)
+	}
+
+	for _, f := range c.Files {
+		var sources []string
+		if sources, err = c.Sources(f); err != nil {
+			return
+		}
+		for _, src := range sources {
+			if err = NormalizeFile(ctx, src, opts.Backup); err != nil {
+				return
+			}
+		}
+	}
+
+	if c.Checks != nil {
+		report = &lint.Report{}
+	}
+
+	memories := make([]map[string]dotstrings.Message, len(c.TargetLocales))
+	for i, locale := range c.TargetLocales {
+		if memories[i], err = c.memory(locale); err != nil {
+			return
+		}
+	}
+
+	for i, locale := range c.TargetLocales {
+
+		var linter *lint.Linter
+		if c.Checks != nil {
+			if linter, err = c.Linter(locale); err != nil {
+				return
+			}
+		}
+
+		res := Result{Locale: locale}
+		for k, pair := range pairs[i] {
+			var translations map[string]dotstrings.Message
+			if translations, err = loadTarget(pair.Target); err != nil {
+				return
+			}
+			var chain translate.Chain
+			if chain, err = c.chain(pairs, memories, i, k, translations); err != nil {
+				return
+			}
+			var stats translate.Stats
+			if stats, err = UpdateTarget(ctx, pair, chain, translations, opts.Backup); err != nil {
+				return
+			}
+			res.Stats.Add(stats)
+			res.Files++
+
+			if len(c.XLIFF) > 0 {
+				if err = c.ExportXLIFF(ctx, pair, locale, opts.Backup); err != nil {
+					return
+				}
+				res.XLIFF++
+			}
+
+			if linter != nil {
+				if err = checkPair(ctx, linter, report, pair, chain.Translations(), locale); err != nil {
+					return
+				}
+			}
+		}
+		results = append(results, res)
+	}
+	return
+}
+
+// RunExtract runs the extract commands in Dir, writing their output to w. A
+// command still running when ctx is done is killed.
+func (c *Config) RunExtract(ctx context.Context, w io.Writer) error {
+	if w == nil {
+		w = os.Stdout
+	}
+	for _, args := range c.Extract {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = c.Dir
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("Extract command %q failed (%v)", strings.Join(args, " "), err)
+		}
+	}
+	return nil
+}
+
+// NormalizeFile formats the source .strings file name in place using
+// FormatMessages, keeping the original as name.bak when backup is set.
+func NormalizeFile(ctx context.Context, name string, backup bool) (err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buf := &bytes.Buffer{}
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(file))
+	msgChan = dotstrings.FormatMessages(ctx, msgChan)
+	_, err = dotstrings.SaveMessages(msgChan, buf)
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		return fmt.Errorf("Failed to load %q (%v)", name, e)
+	}
+	if err != nil {
+		return
+	}
+	return writeFile(name, buf, backup)
+}
+
+// UpdateTarget translates the source file of pair using chain and writes the
+// result to the target file of pair, creating its directory when needed. Only
+// unused translations of the existing target file, given by translations,
+// count as obsolete. The original target file is kept as a .bak file when
+// backup is set.
+func UpdateTarget(ctx context.Context, pair Pair, chain translate.Chain, translations map[string]dotstrings.Message, backup bool) (stats translate.Stats, err error) {
+	srcFile, err := os.Open(pair.Source)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buf := &bytes.Buffer{}
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	msgChan = translate.TranslateMessages(ctx, msgChan, chain)
+	msgChan = translate.CountMessages(ctx, msgChan, &stats, translations)
+	_, err = dotstrings.SaveMessages(msgChan, buf)
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", pair.Source, e)
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = writeFile(pair.Target, buf, backup)
+	return
+}
+
+// ExportXLIFF converts the target file of pair into the XLIFF file given by
+// the XLIFF pattern. Besides {locale} the pattern can contain {table}, the
+// name of the source file without its extension.
+func (c *Config) ExportXLIFF(ctx context.Context, pair Pair, locale string, backup bool) (err error) {
+	base := filepath.Base(pair.Source)
+	table := strings.TrimSuffix(base, filepath.Ext(base))
+	xlfName := c.Expand(c.XLIFF, locale, map[string]string{"table": table})
+
+	tgtFile, err := os.Open(pair.Target)
+	if err != nil {
+		return
+	}
+	defer tgtFile.Close()
+
+	buf := &bytes.Buffer{}
+	tf := &xliff.TranslationFile{Original: base, SourceLanguage: c.SourceLocale, Datatype: "x-strings", TargetLanguage: locale}
+	if _, err = translate.ConvertTargetFile(ctx, tgtFile, tf, buf); err != nil {
+		return fmt.Errorf("Failed to convert %q (%v)", pair.Target, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(xlfName), 0755); err != nil {
+		return
+	}
+	return atomicfile.WriteFile(xlfName, buf, backup)
+}
+
+// Linter returns a linter for the target files of locale with the default
+// rules, configured by Checks.
+func (c *Config) Linter(locale string) (linter *lint.Linter, err error) {
+	linter = lint.New(lint.DefaultRules()...)
+	if c.Checks == nil {
+		return
+	}
+	if len(c.Checks.Glossary) > 0 {
+		var g *glossary.Glossary
+		if g, err = glossary.LoadFile(c.Path(c.Checks.Glossary)); err != nil {
+			return
+		}
+		linter.Rules = append(linter.Rules, lint.Glossary(g, locale))
+	}
+	if err = linter.Disable(c.Checks.Disable...); err != nil {
+		return
+	}
+	for name, level := range c.Checks.Levels {
+		linter.Levels[name] = level
+	}
+	return
+}
+
+// FailSeverity returns the minimum severity of an issue that fails a sync.
+func (checks *Checks) FailSeverity() lint.Severity {
+	if checks.Fail == nil {
+		return lint.Error
+	}
+	return *checks.Fail
+}
+
+// checkPair checks the translations of the target file of pair.
+func checkPair(ctx context.Context, linter *lint.Linter, report *lint.Report, pair Pair, translations map[string]dotstrings.Message, locale string) (err error) {
+	srcFile, err := os.Open(pair.Source)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	for res := range linter.CheckEntries(ctx, lint.PairMessages(ctx, msgChan, translations, pair.Target, locale)) {
+		report.Add(res)
+	}
+	return stage.Wait(ctx, cancel, errChan)
+}
+
+// memory loads the translation memory of locale, it is empty when no memory
+// is configured or the file doesn't exist.
+func (c *Config) memory(locale string) (memory map[string]dotstrings.Message, err error) {
+	if len(c.Memory) == 0 {
+		return
+	}
+	name := c.Expand(c.Memory, locale, nil)
+	memory, err = dotstrings.LoadMessagesMapFromFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	return
+}
+
+// chain returns the translation chain of the k-th target file of the i-th
+// target locale: the translations of the file completed by the memory of the
+// locale, followed by the same target file of every fallback locale that is
+// a target locale too, completed by its memory.
+func (c *Config) chain(pairs [][]Pair, memories []map[string]dotstrings.Message, i, k int, translations map[string]dotstrings.Message) (chain translate.Chain, err error) {
+	locale := c.TargetLocales[i]
+	chain = translate.Chain{{Locale: locale, Translations: withMemory(translations, memories[i])}}
+	for _, fallback := range c.Fallbacks.Chain(locale)[1:] {
+		j := index(c.TargetLocales, fallback)
+		if j < 0 {
+			continue
+		}
+		var fbTranslations map[string]dotstrings.Message
+		if fbTranslations, err = loadTarget(pairs[j][k].Target); err != nil {
+			return
+		}
+		chain = append(chain, translate.Memory{Locale: fallback, Translations: withMemory(fbTranslations, memories[j])})
+	}
+	return
+}
+
+// loadTarget loads the existing target file tgtName, including its fuzzy
+// entries. A target file that doesn't exist yet is empty.
+func loadTarget(tgtName string) (translations map[string]dotstrings.Message, err error) {
+	translations, err = dotstrings.LoadTargetMessagesMapFromFile(tgtName)
+	if os.IsNotExist(err) {
+		translations, err = map[string]dotstrings.Message{}, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", tgtName, err)
+	}
+	return
+}
+
+// withMemory returns translations completed with the translations in memory
+// for IDs it doesn't have.
+func withMemory(translations, memory map[string]dotstrings.Message) map[string]dotstrings.Message {
+	if len(memory) == 0 {
+		return translations
+	}
+	merged := make(map[string]dotstrings.Message, len(translations)+len(memory))
+	for id, m := range memory {
+		merged[id] = m
+	}
+	for id, m := range translations {
+		merged[id] = m
+	}
+	return merged
+}
+
+// writeFile atomically replaces the file name with the UTF-8 contents of buf
+// encoded as UTF-16, creating its directory when needed.
+func writeFile(name string, buf *bytes.Buffer, backup bool) (err error) {
+	if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return
+	}
+	file, err := atomicfile.Create(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Backup = backup
+	if _, err = buf.WriteTo(dotstrings.NewWriterUTF16(file)); err != nil {
+		return
+	}
+	return file.Commit()
+}
@@ -1,9 +1,12 @@
 package translate
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
 	"github.com/simpleapps-eu/translate/xliff"
 )
 
@@ -14,131 +17,132 @@ const (
 	fromTarget
 )
 
-func convertMessagesToTranslationUnits(from fromType, msgChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
-
-	dstChan := make(chan xliff.TranslationUnit, 3)
-	errChan := make(chan error, 1)
+func convertMessagesToTranslationUnits(ctx context.Context, from fromType, msgChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
+	hasTargetLanguage := len(tf.TargetLanguage) > 0
 
 	n := 0
-	translator := func(from fromType, msgChan <-chan dotstrings.Message, tf *xliff.TranslationFile, dstChan chan<- xliff.TranslationUnit, errChan chan<- error) {
-		defer close(dstChan)
-		defer close(errChan)
+	return stage.MapErr(ctx, msgChan, func(m dotstrings.Message) (tu xliff.TranslationUnit, err error) {
+		n++
+		id, e := xliff.XMLEscapeStrict(m.ID)
+		if e != nil {
+			err = fmt.Errorf("Failed to xml escape Message.Id for string %d (%v)", n, e)
+			return
+		}
 
-		hasTargetLanguage := len(tf.TargetLanguage) > 0
+		var source, target, note string
 
-		for m := range msgChan {
-			id, e := xliff.XMLEscapeStrict(m.ID)
+		switch from {
+		case fromSource:
+			note, e = dotstrings.StringsUnescape(m.Ctx)
 			if e != nil {
-				errChan <- fmt.Errorf("Failed to xml escape Message.Id for string %d (%v)", n+1, e)
+				err = fmt.Errorf("Failed to strings unescape Message.Ctx for string %d (%v)", n, e)
 				return
 			}
 
-			var source, target, note string
+			note = xliff.XMLEscapeLoose(note)
 
-			switch from {
-			case fromSource:
-				note, e = dotstrings.StringsUnescape(m.Ctx)
-				if e != nil {
-					errChan <- fmt.Errorf("Failed to strings unescape Message.Ctx for string %d (%v)", n+1, e)
-					return
-				}
+			source, e = dotstrings.StringsUnescape(m.Str)
+			if e != nil {
+				err = fmt.Errorf("Failed to strings unescape Message.Str for string %d (%v)", n, e)
+				return
+			}
 
-				note = xliff.XMLEscapeLoose(note)
+			source = xliff.XMLEscapeLoose(source)
 
-				source, e = dotstrings.StringsUnescape(m.Str)
-				if e != nil {
-					errChan <- fmt.Errorf("Failed to strings unescape Message.Str for string %d (%v)", n+1, e)
-					return
-				}
+			// if hasTargetLanguage {
+			// 	target = source
+			// }
+
+		case fromTarget:
+			source, e = dotstrings.StringsUnescape(m.Ctx)
+			if e != nil {
+				err = fmt.Errorf("Failed to strings unescape Message.Ctx for string %d (%v)", n, e)
+				return
+			}
 
-				source = xliff.XMLEscapeLoose(source)
+			source = xliff.XMLEscapeLoose(source)
 
-				// if hasTargetLanguage {
-				// 	target = source
-				// }
+			if hasTargetLanguage {
 
-			case fromTarget:
-				source, e = dotstrings.StringsUnescape(m.Ctx)
+				target, e = dotstrings.StringsUnescape(m.Str)
 				if e != nil {
-					errChan <- fmt.Errorf("Failed to strings unescape Message.Ctx for string %d (%v)", n+1, e)
+					err = fmt.Errorf("Failed to strings unescape Message.Str for string %d (%v)", n, e)
 					return
 				}
 
-				source = xliff.XMLEscapeLoose(source)
-
-				if hasTargetLanguage {
-
-					target, e = dotstrings.StringsUnescape(m.Str)
-					if e != nil {
-						errChan <- fmt.Errorf("Failed to strings unescape Message.Str for string %d (%v)", n+1, e)
-						return
-					}
-
-					target = xliff.XMLEscapeLoose(target)
-				}
+				target = xliff.XMLEscapeLoose(target)
 			}
-
-			dstChan <- xliff.TranslationUnit{File: tf, ID: id, Source: source, Target: target, Note: note}
-			n++
 		}
-	}
 
-	go translator(from, msgChan, tf, dstChan, errChan)
-	return dstChan, errChan
+		tu = xliff.TranslationUnit{File: tf, ID: id, Source: source, Target: target, Note: note}
+		return
+	})
 }
 
 // ConvertSourceMessagesToTranslationUnits will convert a channel containing dotstrings
 // Messages into XLIFF translation units.
 // If the passed in translation file has a TargetLanguage set then a translation unit
 // will also contain the Str field from the Message copied into Target field.
-func ConvertSourceMessagesToTranslationUnits(srcChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
-	return convertMessagesToTranslationUnits(fromSource, srcChan, tf)
+func ConvertSourceMessagesToTranslationUnits(ctx context.Context, srcChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
+	return convertMessagesToTranslationUnits(ctx, fromSource, srcChan, tf)
 }
 
 // ConvertTargetMessagesToTranslationUnits will convert a channel containing dotstrings
 // Messages into XLIFF translation units.
 // If the passed in translation file has a TargetLanguage set then a translation unit
 // will also contain the Str field from the Message copied into Target field.
-func ConvertTargetMessagesToTranslationUnits(tgtChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
-	return convertMessagesToTranslationUnits(fromTarget, tgtChan, tf)
+func ConvertTargetMessagesToTranslationUnits(ctx context.Context, tgtChan <-chan dotstrings.Message, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
+	return convertMessagesToTranslationUnits(ctx, fromTarget, tgtChan, tf)
 }
 
-// ConvertTranslationUnitsToSourceMessages will take ID, Source and Note fields of a translation unit and create a message out of it where the
-// Note is used as the Ctx, the ID as the ID and the Source as the Str. The channel of messages can then be save to a source .strings file.
-func ConvertTranslationUnitsToSourceMessages(xliffChan <-chan xliff.TranslationUnit) <-chan dotstrings.Message {
-
-	msgChan := make(chan dotstrings.Message, 3)
-
-	converter := func(xliffChan <-chan xliff.TranslationUnit, msgChan chan<- dotstrings.Message) {
-		defer close(msgChan)
-		for x := range xliffChan {
-			id := dotstrings.StringsEscape(xliff.XMLUnescape(x.ID))
-			source := dotstrings.StringsEscape(xliff.XMLUnescape(x.Source))
-			note := dotstrings.StringsEscape(xliff.XMLUnescape(x.Note))
-			msgChan <- dotstrings.Message{ID: id, Str: source, Ctx: note}
-		}
+// ConvertSourceFile reads the messages from the UTF-16 encoded source .strings
+// file srcFile and writes them as translation units of tf to the XLIFF file
+// xlfFile. The function returns the number of translation units written.
+func ConvertSourceFile(ctx context.Context, srcFile io.Reader, tf *xliff.TranslationFile, xlfFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgChan, errChan1 := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	unitChan, errChan2 := ConvertSourceMessagesToTranslationUnits(ctx, msgChan, tf)
+	n, err = xliff.SaveTranslationUnits(unitChan, xlfFile)
+	if e := stage.Wait(ctx, cancel, errChan1, errChan2); e != nil {
+		err = e
+	}
+	return
+}
 
+// ConvertTargetFile reads the messages from the UTF-16 encoded target .strings
+// file tgtFile and writes them as translation units of tf to the XLIFF file
+// xlfFile. The function returns the number of translation units written.
+func ConvertTargetFile(ctx context.Context, tgtFile io.Reader, tf *xliff.TranslationFile, xlfFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgChan, errChan1 := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(tgtFile))
+	unitChan, errChan2 := ConvertTargetMessagesToTranslationUnits(ctx, msgChan, tf)
+	n, err = xliff.SaveTranslationUnits(unitChan, xlfFile)
+	if e := stage.Wait(ctx, cancel, errChan1, errChan2); e != nil {
+		err = e
 	}
-	go converter(xliffChan, msgChan)
-	return msgChan
+	return
+}
+
+// ConvertTranslationUnitsToSourceMessages will take ID, Source and Note fields of a translation unit and create a message out of it where the
+// Note is used as the Ctx, the ID as the ID and the Source as the Str. The channel of messages can then be save to a source .strings file.
+func ConvertTranslationUnitsToSourceMessages(ctx context.Context, xliffChan <-chan xliff.TranslationUnit) <-chan dotstrings.Message {
+	return stage.Map(ctx, xliffChan, func(x xliff.TranslationUnit) dotstrings.Message {
+		id := dotstrings.StringsEscape(xliff.XMLUnescape(x.ID))
+		source := dotstrings.StringsEscape(xliff.XMLUnescape(x.Source))
+		note := dotstrings.StringsEscape(xliff.XMLUnescape(x.Note))
+		return dotstrings.Message{ID: id, Str: source, Ctx: note}
+	})
 }
 
 // ConvertTranslationUnitsToTargetMessages will take ID, Source and Target fields of a translation unit and create a message out of it where the
 // Source is used as the Ctx, the ID as the ID and the Target as the Str. The channel of messages can then be save to a target .strings file.
-func ConvertTranslationUnitsToTargetMessages(xliffChan <-chan xliff.TranslationUnit) <-chan dotstrings.Message {
-
-	msgChan := make(chan dotstrings.Message, 3)
-
-	converter := func(xliffChan <-chan xliff.TranslationUnit, msgChan chan<- dotstrings.Message) {
-		defer close(msgChan)
-		for x := range xliffChan {
-			id := dotstrings.StringsEscape(xliff.XMLUnescape(x.ID))
-			source := dotstrings.StringsEscape(xliff.XMLUnescape(x.Source))
-			target := dotstrings.StringsEscape(xliff.XMLUnescape(x.Target))
-			msgChan <- dotstrings.Message{ID: id, Str: target, Ctx: source}
-		}
-
-	}
-	go converter(xliffChan, msgChan)
-	return msgChan
+func ConvertTranslationUnitsToTargetMessages(ctx context.Context, xliffChan <-chan xliff.TranslationUnit) <-chan dotstrings.Message {
+	return stage.Map(ctx, xliffChan, func(x xliff.TranslationUnit) dotstrings.Message {
+		id := dotstrings.StringsEscape(xliff.XMLUnescape(x.ID))
+		source := dotstrings.StringsEscape(xliff.XMLUnescape(x.Source))
+		target := dotstrings.StringsEscape(xliff.XMLUnescape(x.Target))
+		return dotstrings.Message{ID: id, Str: target, Ctx: source}
+	})
 }
@@ -2,7 +2,6 @@ package dotstrings
 
 import (
 	"strconv"
-	"strings"
 )
 
 func StringsUnescape(s string) (t string, err error) {
@@ -10,5 +9,9 @@ func StringsUnescape(s string) (t string, err error) {
 }
 
 func StringsEscape(s string) string {
-	return strings.Trim(strconv.Quote(s), `"`)
+	// Only strip the enclosing quotes, strings.Trim would also eat the quote
+	// of an escaped \" at the end of s. QuoteToGraphic keeps typographic
+	// spaces like the no-break space as they are instead of escaping them.
+	q := strconv.QuoteToGraphic(s)
+	return q[1 : len(q)-1]
 }
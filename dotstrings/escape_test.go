@@ -56,3 +56,29 @@ func TestStringsEscaping(t *testing.T) {
 	}
 
 }
+
+var tvStringsEscape = []struct {
+	s, escaped string
+}{
+	{`Say "hi"`, `Say \"hi\"`},
+	{`"quoted"`, `\"quoted\"`},
+	{"tab\there\n", `tab\there\n`},
+	{"bell\a", `bell\a`},
+	// Typographic spaces are written as they are, invisible format
+	// characters are escaped.
+	{"Nom\u00A0:", "Nom\u00A0:"},
+	{"«\u202F%@\u202F»", "«\u202F%@\u202F»"},
+	{"zero\u200Bwidth", `zero\u200bwidth`},
+}
+
+func TestStringsEscape(t *testing.T) {
+	for i, tv := range tvStringsEscape {
+		escaped := StringsEscape(tv.s)
+		if escaped != tv.escaped {
+			t.Errorf("Expected tvStringsEscape[%d] to escape to %q got %q", i, tv.escaped, escaped)
+		}
+		if s, err := StringsUnescape(escaped); err != nil || s != tv.s {
+			t.Errorf("Expected tvStringsEscape[%d] to unescape to %q got %q (%v)", i, tv.s, s, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dotstrings
+
+import "context"
+
+// NoComment is the comment genstrings writes for entries whose comment is an
+// empty string.
+const NoComment = "No comment provided by engineer."
+
+// FormatMessages will asynchronously turn the messages of a strings file
+// generated by genstrings into messages suitable to be used as a source
+// strings file for the translation tools. Source messages are never fuzzy.
+func FormatMessages(ctx context.Context, srcChan <-chan Message) <-chan Message {
+	dstChan := make(chan Message, 3)
+
+	formatter := func(srcChan <-chan Message, dstChan chan<- Message) {
+		defer close(dstChan)
+		for m := range srcChan {
+			if m.Ctx == NoComment {
+				// Situation 1.
+				//
+				// NSLocalizableString(key,comment) entry in source has key set
+				// but comment is an empty string.
+				// e.g. NSLocalizableString(@"Loading...",@"")
+				//
+				// Entry as generated by genstrings:
+				//
+				// 	/* No comment provided by engineer. */
+				// 	"Loading..." = "Loading...";
+				//
+				// We'll change that into:
+				//
+				// 	/* Loading... */
+				// 	"Loading..." = "Loading...";
+				//
+				m.Ctx = m.Str
+			} else if m.ID == m.Str {
+				// Situation 2.
+				//
+				// NSLocalizableString(key,comment) entry in source has key that contains
+				// an id and the comment contains the text to translate (context).
+				// e.g. NSLocalizableString(@"message_could_not_reach_desktop",@"Could not reach the Desktop.")
+				//
+				// Entry as generated by genstrings:
+				//
+				// 	/* Could not reach the Desktop. */
+				// 	"message_could_not_reach_desktop" = "message_could_not_reach_desktop";
+				//
+				// we'll change that into
+				//
+				// 	/* Could not reach the Desktop. */
+				// 	"message_could_not_reach_desktop" = "Could not reach the Desktop.";
+				//
+				m.Str = m.Ctx
+			}
+			m.Fuzzy = false
+			select {
+			case dstChan <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go formatter(srcChan, dstChan)
+	return dstChan
+}
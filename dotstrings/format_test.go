@@ -0,0 +1,37 @@
+package dotstrings
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFormatMessages(t *testing.T) {
+	const genstrings = `/* No comment provided by engineer. */
+"Loading..." = "Loading...";
+
+/* Could not reach the Desktop. */
+"message_could_not_reach_desktop" = "message_could_not_reach_desktop";
+
+/* Fuzzy */
+/* Title of the window */
+"window_title" = "Notes";
+`
+	msgChan, errChan := LoadMessages(context.Background(), strings.NewReader(genstrings))
+	var msgs []Message
+	for m := range FormatMessages(context.Background(), msgChan) {
+		msgs = append(msgs, m)
+	}
+	if err, _ := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages got %d", len(msgs))
+	}
+	ExpectEqual(msgs[0].Ctx, "Loading...", func(e string) { t.Error(e) })
+	ExpectEqual(msgs[1].Str, "Could not reach the Desktop.", func(e string) { t.Error(e) })
+	ExpectEqual(msgs[2].Str, "Notes", func(e string) { t.Error(e) })
+	if msgs[2].Fuzzy {
+		t.Error("Expected formatted message not to be fuzzy")
+	}
+}
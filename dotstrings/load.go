@@ -2,9 +2,13 @@ package dotstrings
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"iter"
 	"os"
+
+	"github.com/simpleapps-eu/translate/stage"
 )
 
 // LoadMessagesMapFromFile uses the given filename to open the messages file
@@ -25,8 +29,10 @@ func LoadMessagesMapFromFile(filename string) (messages map[string]Message, err
 // The function returns a map with the messages once all messages have been read.
 func LoadMessagesMap(tmReader io.Reader) (messages map[string]Message, err error) {
 	messages = make(map[string]Message)
-	msgChan, errChan := LoadMessages(tmReader)
-	for tm := range msgChan {
+	for tm, e := range NewReader(tmReader).All() {
+		if e != nil {
+			return messages, e
+		}
 		if tm.Fuzzy {
 			err = fmt.Errorf("Encountered a fuzzy translation for ID %q", tm.ID)
 			return
@@ -37,45 +43,102 @@ func LoadMessagesMap(tmReader io.Reader) (messages map[string]Message, err error
 		}
 		messages[tm.ID] = tm
 	}
-	err, _ = <-errChan
+	return
+}
+
+// LoadTargetMessagesMapFromFile uses the given filename to open a target
+// messages file and reads all messages from it. Unlike LoadMessagesMapFromFile
+// it accepts fuzzy messages so the state of a translation can be inspected.
+func LoadTargetMessagesMapFromFile(filename string) (messages map[string]Message, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	messages, err = LoadTargetMessagesMap(NewReaderUTF16(file))
+	return
+}
+
+// LoadTargetMessagesMap will read all the entries from the messages file,
+// including the fuzzy ones. If it encounters an error it will return with the
+// error instead of continuing.
+func LoadTargetMessagesMap(tmReader io.Reader) (messages map[string]Message, err error) {
+	messages = make(map[string]Message)
+	for tm, e := range NewReader(tmReader).All() {
+		if e != nil {
+			return messages, e
+		}
+		if _, present := messages[tm.ID]; present {
+			err = fmt.Errorf("Encountered a duplicated ID %q", tm.ID)
+			return
+		}
+		messages[tm.ID] = tm
+	}
 	return
 }
 
 // LoadMessages reads the data provided by io.Reader and outputs messages on
 // a channel it returns. This function will run asynchronously and return before
-// the whole stream has been processed.
-func LoadMessages(r io.Reader) (<-chan Message, <-chan error) {
-	c := make(chan Message, 3)
-	e := make(chan error, 1)
+// the whole stream has been processed. Reading stops at the first error or when
+// ctx is done, the error (or ctx.Err()) is sent on the error channel before the
+// message channel is closed.
+func LoadMessages(ctx context.Context, r io.Reader) (<-chan Message, <-chan error) {
+	return stage.Load(ctx, NewReader(r).All())
+}
+
+// Reader reads the messages of a .strings file one at a time.
+type Reader struct {
+	s *bufio.Scanner
+	// marker is set by the split function for Fuzzy and Fallback markers.
+	marker bool
+}
 
+// NewReader returns a Reader reading messages from the (not UTF16 encoded)
+// data provided by r.
+func NewReader(r io.Reader) *Reader {
 	s := bufio.NewScanner(r)
-	s.Split(Split())
+	reader := &Reader{s: s}
+	s.Split(split(&reader.marker))
+	return reader
+}
 
-	reader := func(outChan chan<- Message, s *bufio.Scanner, errChan chan<- error) {
-		defer close(outChan)
-		defer close(errChan)
-		for s.Scan() {
-			m := Message{}
-			if IsFuzzyToken(s.Text()) {
+// Next returns the next message. It returns io.EOF when there are no more
+// messages.
+func (r *Reader) Next() (m Message, err error) {
+	s := r.s
+	for s.Scan() {
+		if r.marker {
+			if locale, ok := FallbackToken(s.Text()); ok {
+				m.Locale = locale
+			} else {
 				m.Fuzzy = true
-				if !s.Scan() {
-					continue
-				}
 			}
-			m.Ctx = s.Text()
+			continue
+		}
+		m.Ctx = s.Text()
+		if s.Scan() {
+			m.ID = s.Text()
 			if s.Scan() {
-				m.ID = s.Text()
-				if s.Scan() {
-					m.Str = s.Text()
-					outChan <- m
-				}
+				m.Str = s.Text()
+				return
 			}
 		}
-		if e := s.Err(); e != nil {
-			errChan <- e
-		}
 	}
+	if err = s.Err(); err == nil {
+		err = io.EOF
+	}
+	return Message{}, err
+}
 
-	go reader(c, s, e)
-	return c, e
+// All returns an iterator over the remaining messages. The iteration stops
+// at the end of the data or after yielding the first error.
+func (r *Reader) All() iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for {
+			m, err := r.Next()
+			if err == io.EOF || !yield(m, err) || err != nil {
+				return
+			}
+		}
+	}
 }
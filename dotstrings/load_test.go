@@ -0,0 +1,173 @@
+package dotstrings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const loadData = `/* Open the file */
+"open" = "Open";
+
+/* Fuzzy */
+/* Save the file */
+"save" = "Enregistrer";
+
+/* Fuzzy */
+/* Fallback: fr */
+/* Close the file */
+"close" = "Fermer";
+
+/* Fallback: xx */
+"fallback" = "Fallback";
+
+/* Fuzzy */
+/* Fallback: de */
+"quit" = "Quit";
+
+`
+
+func TestReaderWriter(t *testing.T) {
+	r := NewReader(strings.NewReader(loadData))
+	buf := &strings.Builder{}
+	w := NewWriter(buf)
+	for m, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF got %v", err)
+	}
+	messages, err := LoadTargetMessagesMap(strings.NewReader(loadData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := messages["close"]; m.Locale != "fr" || !m.Fuzzy || m.Ctx != "Close the file" {
+		t.Errorf("Expected the fuzzy fallback message got %+v", m)
+	}
+	// A comment of the same form directly before the ID is the context.
+	if m := messages["fallback"]; len(m.Locale) > 0 || m.Fuzzy || m.Ctx != "Fallback: xx" {
+		t.Errorf("Expected the source comment to be the context got %+v", m)
+	}
+	if m := messages["quit"]; len(m.Locale) > 0 || !m.Fuzzy || m.Ctx != "Fallback: de" {
+		t.Errorf("Expected the comment after the fuzzy marker to be the context got %+v", m)
+	}
+	ExpectEqual(buf.String(), loadData, func(e string) { t.Error(e) })
+
+	// The markers are found the same when the data arrives byte by byte.
+	bytewise, err := LoadTargetMessagesMap(iotest.OneByteReader(strings.NewReader(loadData)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bytewise, messages) {
+		t.Errorf("Expected %+v got %+v", messages, bytewise)
+	}
+}
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestSaveMessagesError(t *testing.T) {
+	for _, n := range []int{1, 1000} {
+		msgChan := make(chan Message)
+		go func() {
+			defer close(msgChan)
+			for m := range NewReader(strings.NewReader(messagesData(n))).All() {
+				msgChan <- m
+			}
+		}()
+		// All messages are received after the error, so the sender finishes
+		if _, err := SaveMessages(msgChan, failWriter{}); err == nil {
+			t.Errorf("Expected the write error for %d messages", n)
+		}
+	}
+}
+
+func messagesData(n int) string {
+	b := &strings.Builder{}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(b, "/* Text %d */\n\"id%d\" = \"Text %d\";\n\n", i, i, i)
+	}
+	return b.String()
+}
+
+func BenchmarkLoadMessages(b *testing.B) {
+	data := messagesData(1000)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		msgChan, errChan := LoadMessages(context.Background(), strings.NewReader(data))
+		for range msgChan {
+		}
+		if err := <-errChan; err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	data := messagesData(1000)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		for _, err := range NewReader(strings.NewReader(data)).All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	var msgs []Message
+	for m, err := range NewReader(strings.NewReader(messagesData(1000))).All() {
+		if err != nil {
+			b.Fatal(err)
+		}
+		msgs = append(msgs, m)
+	}
+	var n int64
+	for i := 0; i < b.N; i++ {
+		cw := &countWriter{}
+		w := NewWriter(cw)
+		for _, m := range msgs {
+			w.Write(m)
+		}
+		n = cw.n
+	}
+	b.SetBytes(n)
+}
+
+func BenchmarkReaderUTF16(b *testing.B) {
+	buf := &strings.Builder{}
+	w := NewWriterUTF16(buf)
+	io.WriteString(w, messagesData(1000))
+	data := buf.String()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		for _, err := range NewReader(NewReaderUTF16(strings.NewReader(data))).All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// countWriter discards what is written to it but counts the bytes.
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -24,7 +24,22 @@ type Message struct {
 	// the target Ctx and Str will contain the source Str.
 	// Set in messages emited by the TranslateMessages function.
 	Missing bool
-	Ctx     string
-	ID      string
-	Str     string
+	// Locale is the locale of the fallback translation memory that supplied
+	// the translation, it is empty for translations of the target locale
+	// itself.
+	// Set in messages emited by the TranslateMessages function, and in
+	// messages loaded from a strings file that are preceeded with a
+	// "Fallback: de" comment. It is written as such a comment.
+	Locale string
+	Ctx    string
+	ID     string
+	Str    string
+}
+
+// Untranslated reports whether the target message m holds no translation.
+// Either it is Missing or it is the fuzzy copy of the source Str that
+// TranslateMessages writes for a missing translation, which is read back as
+// a Fuzzy message with the source Str as both Ctx and Str.
+func (m Message) Untranslated() bool {
+	return m.Missing || (m.Fuzzy && m.Str == m.Ctx)
 }
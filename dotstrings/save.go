@@ -1,8 +1,8 @@
 package dotstrings
 
 import (
+	"bufio"
 	"io"
-	"text/template"
 )
 
 // SaveMessages will take a channel with messages and stream them to character
@@ -10,11 +10,57 @@ import (
 // The goroutine feeding srcChan should close the channel once it has finished.
 // The closing of the channel indicates to SaveMessages that it can finish too.
 // The function returns the number of messages it has written to the dstWriter.
-func SaveMessages(srcChan <-chan Message, dstWriter io.Writer) (n int) {
-	entryTpl := template.Must(template.New("strings").Parse("{{if .Fuzzy}}/* Fuzzy */\n{{end}}/* {{.Ctx}} */\n\"{{.ID}}\" = \"{{.Str}}\";\n\n"))
+// The messages are written to dstWriter in large blocks. After a write error
+// the remaining messages are received but not written, the error is
+// returned.
+func SaveMessages(srcChan <-chan Message, dstWriter io.Writer) (n int, err error) {
+	bw := bufio.NewWriter(dstWriter)
+	w := NewWriter(bw)
 	for src := range srcChan {
-		entryTpl.Execute(dstWriter, src)
-		n++
+		if err != nil {
+			continue
+		}
+		if err = w.Write(src); err == nil {
+			n++
+		}
 	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return
+}
+
+// Writer writes messages to a (not UTF16 encoded) .strings file.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter returns a Writer writing messages to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes message m. Every message is written to the underlying writer
+// with a single Write call.
+func (w *Writer) Write(m Message) (err error) {
+	b := w.buf[:0]
+	if m.Fuzzy {
+		b = append(b, "/* Fuzzy */\n"...)
+	}
+	if len(m.Locale) > 0 {
+		b = append(b, "/* "+fallbackPrefix...)
+		b = append(b, m.Locale...)
+		b = append(b, " */\n"...)
+	}
+	b = append(b, "/* "...)
+	b = append(b, m.Ctx...)
+	b = append(b, " */\n\""...)
+	b = append(b, m.ID...)
+	b = append(b, "\" = \""...)
+	b = append(b, m.Str...)
+	b = append(b, "\";\n\n"...)
+	w.buf = b
+	_, err = w.w.Write(b)
 	return
 }
@@ -2,6 +2,7 @@ package dotstrings
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"strings"
@@ -15,10 +16,29 @@ func IsFuzzyToken(token string) bool {
 	return strings.EqualFold(token, "fuzzy")
 }
 
+// fallbackPrefix starts the comment that marks a translation taken from a
+// fallback translation memory, like /* Fallback: de */.
+const fallbackPrefix = "Fallback: "
+
+// FallbackToken returns the locale of tokens like "Fallback: de" that mark a
+// translation taken from a fallback translation memory. Such a token is only
+// a marker when it is followed by the context comment of the entry, a comment
+// of that form directly before the ID is the context itself.
+func FallbackToken(token string) (locale string, ok bool) {
+	locale, ok = strings.CutPrefix(token, fallbackPrefix)
+	return locale, ok && len(locale) > 0 && !strings.ContainsAny(locale, " \t\n")
+}
+
 // Split will split the file into (fuzzy, context, id, string) tuples.
 //
 // TODO count processed runes so we can point to a location when there is an error.
 func Split() bufio.SplitFunc {
+	return split(new(bool))
+}
+
+// split is Split, it sets marker to whether the last token returned is a
+// Fuzzy or Fallback marker rather than a context, id or string.
+func split(marker *bool) bufio.SplitFunc {
 
 	type LexFunc func(data []byte, atEOF bool) (advance int, token []byte, err error)
 
@@ -41,7 +61,7 @@ func Split() bufio.SplitFunc {
 			dataP := data[p:]
 
 			// r may contain the value utf8.RuneError
-			r, size = utf8.DecodeRune(dataP)
+			r, size = decodeRune(dataP)
 			if unicode.IsSpace(r) {
 				continue
 			}
@@ -57,7 +77,7 @@ func Split() bufio.SplitFunc {
 			}
 
 			// check for sep and advance to first location after the sep
-			if strings.HasPrefix(string(dataP), sep) {
+			if bytes.HasPrefix(dataP, []byte(sep)) {
 				advance = p + seplen
 				return
 			}
@@ -79,7 +99,7 @@ func Split() bufio.SplitFunc {
 
 		seplen := len(sep)
 
-		p := strings.Index(string(data), sep)
+		p := bytes.Index(data, []byte(sep))
 
 		if p == -1 {
 			if !atEOF {
@@ -98,26 +118,15 @@ func Split() bufio.SplitFunc {
 	var collectString = func(data []byte, atEOF bool) (advance int, token []byte, err error) {
 
 		datalen := len(data)
-		skipNextRune := false
-
-		var r rune
-		var size int
-		for p := 0; p < datalen; p += size {
-			// r may contain the value utf8.RuneError
-			r, size = utf8.DecodeRune(data[p:])
-
-			if skipNextRune {
-				skipNextRune = false
-				continue
-			}
-
-			if r == '\\' {
-				skipNextRune = true
-				continue
-			}
 
-			if r == '"' {
-				advance = p + size
+		// Backslash and quote are ASCII and never part of a multibyte UTF-8
+		// sequence, so the string can be scanned byte by byte.
+		for p := 0; p < datalen; p++ {
+			switch data[p] {
+			case '\\':
+				p++ // skip the character it escapes
+			case '"':
+				advance = p + 1
 				token = data[:p]
 				return
 			}
@@ -153,10 +162,24 @@ func Split() bufio.SplitFunc {
 
 		advance = offset
 		if IsFuzzyToken(string(token)) {
+			*marker = true
 			return // Remain in lexContext when we are returning a Fuzzy token.
 		}
+		if _, ok := FallbackToken(string(token)); ok {
+			// A Fallback token followed by another comment is a marker, so
+			// remain in lexContext for it too.
+			next, e := skipTo(data[offset:], atEOF, "/* ")
+			if next == 0 && e == nil {
+				return 0, nil, nil // request more data
+			}
+			if e == nil {
+				*marker = true
+				return
+			}
+		}
 
 		// Switch to ID lexer and return Context token.
+		*marker = false
 		lexer = lexID
 		return
 	}
@@ -177,6 +200,7 @@ func Split() bufio.SplitFunc {
 		offset += advance
 
 		advance = offset
+		*marker = false
 		lexer = lexString
 		return
 	}
@@ -222,3 +246,12 @@ func Split() bufio.SplitFunc {
 		return lexer(data, atEOF)
 	}
 }
+
+// decodeRune is utf8.DecodeRune with a fast path for ASCII, which is what
+// the syntax of a .strings file consists of.
+func decodeRune(p []byte) (rune, int) {
+	if c := p[0]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+	return utf8.DecodeRune(p)
+}
@@ -0,0 +1,128 @@
+// Package extract finds the translatable strings in source files and
+// collects them into a source catalog, written as .strings file or as PO
+// template.
+package extract
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/po"
+)
+
+// Ref is the place a string was found.
+type Ref struct {
+	File string
+	Line int
+}
+
+// String returns the reference as file:line.
+func (r Ref) String() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// String is a translatable string with the comments for the translator and
+// the places it was found.
+type String struct {
+	ID       string
+	Comments []string
+	Refs     []Ref
+}
+
+// Catalog collects the strings extracted from source files. A string found
+// more than once is collected once, with all its comments and references.
+type Catalog struct {
+	strings map[string]*String
+}
+
+// NewCatalog returns an empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{strings: map[string]*String{}}
+}
+
+// Add adds the string id found at ref with the comment for the translator,
+// which may be empty.
+func (c *Catalog) Add(id, comment string, ref Ref) {
+	s, ok := c.strings[id]
+	if !ok {
+		s = &String{ID: id}
+		c.strings[id] = s
+	}
+	if len(comment) > 0 && !contains(s.Comments, comment) {
+		s.Comments = append(s.Comments, comment)
+	}
+	s.Refs = append(s.Refs, ref)
+}
+
+// Len returns the number of strings in c.
+func (c *Catalog) Len() int {
+	return len(c.strings)
+}
+
+// Strings returns the strings of c sorted by ID.
+func (c *Catalog) Strings() []*String {
+	list := make([]*String, 0, len(c.strings))
+	for _, s := range c.strings {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Messages returns the strings of c as the messages of a source .strings
+// file. The Ctx of a message holds the comments and references of its string,
+// its Str is the text of the message with the same ID in existing, or the ID
+// itself for new strings. Strings that are no longer found are dropped from
+// existing, the same way genstrings regenerates a file.
+func (c *Catalog) Messages(existing map[string]dotstrings.Message) (messages []dotstrings.Message) {
+	for _, s := range c.Strings() {
+		m := dotstrings.Message{ID: dotstrings.StringsEscape(s.ID), Str: dotstrings.StringsEscape(s.ID)}
+		if old, ok := existing[m.ID]; ok {
+			m.Str = old.Str
+		}
+		refs := make([]string, len(s.Refs))
+		for i, r := range s.Refs {
+			refs[i] = r.String()
+		}
+		ctx := strings.Join(refs, " ")
+		if len(s.Comments) > 0 {
+			ctx = strings.Join(s.Comments, "; ") + " (" + ctx + ")"
+		}
+		m.Ctx = strings.ReplaceAll(dotstrings.StringsEscape(ctx), "*/", "* /")
+		messages = append(messages, m)
+	}
+	return
+}
+
+// WritePO writes the strings of c to w as PO template, with the comments as
+// extracted comments and the references as reference comments.
+func (c *Catalog) WritePO(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	for _, s := range c.Strings() {
+		b.WriteString("\n")
+		for _, comment := range s.Comments {
+			for _, line := range strings.Split(comment, "\n") {
+				fmt.Fprintf(b, "#. %s\n", line)
+			}
+		}
+		for _, r := range s.Refs {
+			fmt.Fprintf(b, "#: %s\n", r)
+		}
+		fmt.Fprintf(b, "msgid %s\nmsgstr \"\"\n", po.Quote(s.ID))
+	}
+	return b.Flush()
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
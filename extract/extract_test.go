@@ -0,0 +1,190 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+const mail = `{{define "footer"}}{{T "bye" "Closing line"}}{{end}}
+<h1>{{T "hello" "Greeting" "name" .name}}</h1>
+{{if .unread}}<p>{{TN "unread" .unread "Number of unread mails"}}</p>{{else}}{{T "empty"}}{{end}}
+{{range .items}}{{printf "%s: %s" .name (T "price" "Label of a price")}}{{end}}
+{{T .dynamic}} {{T "hello" "name" .other}} {{template "footer" .}}`
+
+func TestTemplateCalls(t *testing.T) {
+	calls, err := TemplateCalls("mail.tpl", mail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range calls {
+		got = append(got, c.Func+" "+c.ID+" "+c.Comment+" "+Ref{"", c.Line}.String())
+		if quoted := mail[c.Pos:c.End]; quoted != `"`+c.ID+`"` {
+			t.Errorf("Expected the span of %q got %q", c.ID, quoted)
+		}
+	}
+	expect := []string{
+		"T bye Closing line :1",
+		"T hello Greeting :2",
+		"TN unread Number of unread mails :3",
+		"T empty  :3",
+		"T price Label of a price :4",
+		"T hello  :5",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+
+	if _, err := TemplateCalls("bad.tpl", `{{T "x"`); err == nil {
+		t.Errorf("Expected an error for an unterminated action")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog()
+	if err := c.ExtractTemplate("mail.tpl", mail); err != nil {
+		t.Fatal(err)
+	}
+	c.Add(`say "hi"`, "Uses */ in a comment", Ref{"main.tpl", 7})
+	if c.Len() != 6 {
+		t.Errorf("Expected 6 strings got %d", c.Len())
+	}
+
+	existing := map[string]dotstrings.Message{
+		"hello": {ID: "hello", Str: "Hello {name}"},
+		"gone":  {ID: "gone", Str: "Gone"},
+	}
+	buf := &bytes.Buffer{}
+	w := dotstrings.NewWriter(buf)
+	for _, m := range c.Messages(existing) {
+		w.Write(m)
+	}
+	expect := `/* Closing line (mail.tpl:1) */
+"bye" = "bye";
+
+/* mail.tpl:3 */
+"empty" = "empty";
+
+/* Greeting (mail.tpl:2 mail.tpl:5) */
+"hello" = "Hello {name}";
+
+/* Label of a price (mail.tpl:4) */
+"price" = "price";
+
+/* Uses * / in a comment (main.tpl:7) */
+"say \"hi\"" = "say \"hi\"";
+
+/* Number of unread mails (mail.tpl:3) */
+"unread" = "unread";
+
+`
+	if buf.String() != expect {
+		t.Errorf("Expected\n%s\ngot\n%s", expect, buf.String())
+	}
+
+	buf.Reset()
+	if err := c.WritePO(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"#. Greeting\n#: mail.tpl:2\n#: mail.tpl:5\nmsgid \"hello\"\nmsgstr \"\"\n", "msgid \"say \\\"hi\\\"\"\n"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected the PO file to contain %q got\n%s", s, buf.String())
+		}
+	}
+}
+
+const swiftSource = `import SwiftUI
+
+/* NSLocalizedString("commented", comment: "out") */
+func NSLocalizedString(_ key: String, comment: String) -> String { key }
+
+let title = NSLocalizedString("title", comment: "Title of the window")
+let long = NSLocalizedString("long", tableName: "Help",
+                             bundle: .main,
+                             value: "A long " + "text",
+                             comment: "Help text")
+let greeting = String(localized: "greeting \"friend\"", defaultValue: #"Hello "friend""#, comment: "Greeting")
+let button = Text(LocalizedStringKey("OK"))
+let dynamic = NSLocalizedString(key, comment: "")
+let interpolated = String(localized: "Hello \(name)")
+let format = String(format: MyLocalized("count", comment: "Count"), n)
+let body = """
+    Line one
+    Line two
+    """
+let multi = NSLocalizedString(body, comment: "")
+let title2 = NSLocalizedString("title", comment: "Window title")
+`
+
+const objcSource = `#define LOCALIZE(key) NSLocalizedString(key, nil)
+// NSLocalizedString(@"ignored", nil)
+- (void)load {
+	self.title = NSLocalizedString(@"Loading...", @"");
+	label.text = NSLocalizedStringFromTable(@"error_" @"network", @"Errors", @"Network error message");
+	other = NSLocalizedStringWithDefaultValue(@"retry", nil, [NSBundle mainBundle], @"Try again", @"Retry button");
+	char c = '"';
+	x = NSLocalizedString(@"tab\tstop", nil);
+}
+`
+
+func TestSourceStrings(t *testing.T) {
+	found, bad, err := SourceStrings("View.swift", swiftSource, []string{"MyLocalized"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range found {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%s|%d", l.Table, l.Key, l.Value, l.Comment, l.Ref.Line))
+	}
+	expect := []string{
+		"Localizable|title||Title of the window|6",
+		"Help|long|A long text|Help text|7",
+		`Localizable|greeting "friend"|Hello "friend"|Greeting|11`,
+		"Localizable|OK|||12",
+		"Localizable|count||Count|15",
+		"Localizable|title||Window title|21",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+	if len(bad) != 3 || bad[0].Line != 13 || bad[1].Line != 14 || bad[2].Line != 20 {
+		t.Errorf("Expected the calls on lines 13, 14 and 20 to be bad got %v", bad)
+	}
+
+	tables := Tables(found)
+	if m := tables["Localizable"]; len(m) != 4 || m[3].ID != "title" || m[3].Ctx != "Title of the window\n   Window title" || m[0].Ctx != dotstrings.NoComment {
+		t.Errorf("Expected 4 sorted Localizable messages got %+v", m)
+	}
+	if m := tables["Localizable"][2]; m.ID != `greeting \"friend\"` || m.Str != `Hello \"friend\"` {
+		t.Errorf("Expected escaped messages got %+v", m)
+	}
+
+	found, bad, err = SourceStrings("Loader.m", objcSource, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, l := range found {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%s|%d", l.Table, l.Key, l.Value, l.Comment, l.Ref.Line))
+	}
+	expect = []string{
+		"Localizable|Loading...|||4",
+		"Errors|error_network||Network error message|5",
+		"Localizable|retry|Try again|Retry button|6",
+		"Localizable|tab\tstop|||8",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") || len(bad) != 0 {
+		t.Errorf("Expected\n%s\ngot\n%s\nbad %v", strings.Join(expect, "\n"), strings.Join(got, "\n"), bad)
+	}
+
+	if _, _, err := SourceStrings("Bad.swift", `let s = NSLocalizedString("open`, nil); err == nil {
+		t.Errorf("Expected an error for an unterminated string")
+	}
+	if found, _, _ := SourceStrings("Multi.swift", "let s = String(localized: \"\"\"\n    One\n      Two\n    \"\"\")", nil); len(found) != 1 || found[0].Key != "One\n  Two" {
+		t.Errorf("Expected the multiline key got %+v", found)
+	}
+}
@@ -0,0 +1,554 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+// DefaultTable is the table of strings that don't name one.
+const DefaultTable = "Localizable"
+
+// Localized is a string found in a Swift or Objective-C source file.
+type Localized struct {
+	Table   string
+	Key     string
+	Value   string // empty when the key is the text
+	Comment string
+	Ref     Ref
+}
+
+// IsSource reports whether name is a Swift or Objective-C source file.
+func IsSource(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".swift", ".m", ".mm", ".h":
+		return true
+	}
+	return false
+}
+
+// routineArgs lists the positional arguments of the variants of a routine
+// like NSLocalizedString, by the suffix of their name.
+var routineArgs = map[string][]string{
+	"":                  {"key", "comment"},
+	"FromTable":         {"key", "table", "comment"},
+	"FromTableInBundle": {"key", "table", "bundle", "comment"},
+	"WithDefaultValue":  {"key", "table", "bundle", "value", "comment"},
+}
+
+// argLabels maps the Swift argument labels to the arguments they name.
+var argLabels = map[string]string{
+	"localized":    "key",
+	"tableName":    "table",
+	"table":        "table",
+	"bundle":       "bundle",
+	"value":        "value",
+	"defaultValue": "value",
+	"comment":      "comment",
+	"locale":       "locale",
+}
+
+// SourceStrings returns the strings the Swift or Objective-C source text of
+// the file name localizes, the way genstrings finds them: the calls of
+// NSLocalizedString, NSLocalizedStringFromTable,
+// NSLocalizedStringFromTableInBundle and NSLocalizedStringWithDefaultValue,
+// of the same variants of every routine in routines, of String(localized:)
+// and of LocalizedStringKey. Adjacent string literals and literals joined by
+// + are concatenated. The calls whose key isn't a literal string, or whose
+// table, value or comment isn't a literal string or nil, are returned as bad,
+// they can't be extracted.
+func SourceStrings(name, text string, routines []string) (found []Localized, bad []Ref, err error) {
+	tokens, err := lexSource(text, strings.EqualFold(filepath.Ext(name), ".swift"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s:%v", name, err)
+	}
+
+	variants := map[string][]string{}
+	for _, routine := range append([]string{"NSLocalizedString"}, routines...) {
+		for suffix, args := range routineArgs {
+			variants[routine+suffix] = args
+		}
+	}
+	variants["LocalizedStringKey"] = []string{"key"}
+
+	for i := 0; i+1 < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind != tIdent || !tokens[i+1].is("(") {
+			continue
+		}
+		if i > 0 && tokens[i-1].is("func") {
+			continue
+		}
+		positional, isRoutine := variants[t.text]
+		if !isRoutine && t.text != "String" {
+			continue
+		}
+		args, end := callArgs(tokens, i+2)
+		if t.text == "String" && (len(args) == 0 || args[0].label != "localized") {
+			continue
+		}
+		i = end
+
+		ref := Ref{File: name, Line: t.line}
+		l, ok := localized(args, positional)
+		if !ok {
+			bad = append(bad, ref)
+			continue
+		}
+		l.Ref = ref
+		found = append(found, l)
+	}
+	return
+}
+
+// localized returns the string localized by a call with args.
+func localized(args []arg, positional []string) (l Localized, ok bool) {
+	l.Table = DefaultTable
+	hasKey := false
+	for i, a := range args {
+		field := argLabels[a.label]
+		if len(a.label) == 0 && i < len(positional) {
+			field = positional[i]
+		}
+		if field == "" || field == "bundle" || field == "locale" {
+			continue
+		}
+		value, isLiteral := a.literal()
+		if !isLiteral {
+			if field != "key" && len(a.tokens) == 1 && a.tokens[0].is("nil") {
+				continue
+			}
+			return l, false
+		}
+		switch field {
+		case "key":
+			l.Key, hasKey = value, true
+		case "table":
+			if len(value) > 0 {
+				l.Table = value
+			}
+		case "value":
+			l.Value = value
+		case "comment":
+			l.Comment = value
+		}
+	}
+	return l, hasKey && len(l.Key) > 0
+}
+
+// Tables returns the strings found by table, as the messages genstrings
+// writes: sorted by key, with the value or else the key as text and the
+// comments, or NoComment, as context. Run them through FormatMessages to get
+// source strings files.
+func Tables(found []Localized) map[string][]dotstrings.Message {
+	type entry struct {
+		value    string
+		comments []string
+	}
+	tables := map[string]map[string]*entry{}
+	for _, l := range found {
+		table, ok := tables[l.Table]
+		if !ok {
+			table = map[string]*entry{}
+			tables[l.Table] = table
+		}
+		e, ok := table[l.Key]
+		if !ok {
+			e = &entry{}
+			table[l.Key] = e
+		}
+		if len(e.value) == 0 {
+			e.value = l.Value
+		}
+		if len(l.Comment) > 0 && !contains(e.comments, l.Comment) {
+			e.comments = append(e.comments, l.Comment)
+		}
+	}
+
+	messages := map[string][]dotstrings.Message{}
+	for name, table := range tables {
+		for key, e := range table {
+			m := dotstrings.Message{ID: dotstrings.StringsEscape(key), Str: dotstrings.StringsEscape(key), Ctx: dotstrings.NoComment}
+			if len(e.value) > 0 {
+				m.Str = dotstrings.StringsEscape(e.value)
+			}
+			if len(e.comments) > 0 {
+				comments := make([]string, len(e.comments))
+				for i, c := range e.comments {
+					comments[i] = strings.ReplaceAll(dotstrings.StringsEscape(c), "*/", "* /")
+				}
+				m.Ctx = strings.Join(comments, "\n   ")
+			}
+			messages[name] = append(messages[name], m)
+		}
+		list := messages[name]
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	}
+	return messages
+}
+
+// arg is an argument of a call, with its Swift label if it has one.
+type arg struct {
+	label  string
+	tokens []token
+}
+
+// literal returns the value of a when it is a literal string, or literals
+// joined by + or written next to each other.
+func (a arg) literal() (s string, ok bool) {
+	if len(a.tokens) == 0 {
+		return "", false
+	}
+	var b strings.Builder
+	for i, t := range a.tokens {
+		switch {
+		case t.kind == tString:
+			b.WriteString(t.text)
+		case t.is("+") && i > 0 && i+1 < len(a.tokens) && a.tokens[i-1].kind == tString:
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// callArgs returns the arguments of the call whose arguments start at
+// tokens[i], and the index of its closing parenthesis.
+func callArgs(tokens []token, i int) (args []arg, end int) {
+	depth := 0
+	var current arg
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == tPunct {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					if len(current.tokens) > 0 || len(current.label) > 0 {
+						args = append(args, current)
+					}
+					return args, i
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					args = append(args, current)
+					current = arg{}
+					continue
+				}
+			case ":":
+				if depth == 0 && len(current.label) == 0 && len(current.tokens) == 1 && current.tokens[0].kind == tIdent {
+					current.label = current.tokens[0].text
+					current.tokens = nil
+					continue
+				}
+			}
+		}
+		current.tokens = append(current.tokens, t)
+	}
+	return args, i
+}
+
+const (
+	tIdent = iota
+	tString
+	tPunct
+	tOther
+)
+
+type token struct {
+	kind int
+	text string
+	line int
+}
+
+func (t token) is(s string) bool {
+	return t.kind != tString && t.text == s
+}
+
+// lexer splits Swift or Objective-C source text into tokens, dropping the
+// comments and the preprocessor lines.
+type lexer struct {
+	text   string
+	pos    int
+	line   int
+	swift  bool
+	tokens []token
+}
+
+func lexSource(text string, swift bool) ([]token, error) {
+	lx := &lexer{text: text, line: 1, swift: swift}
+	lineStart := true
+	for lx.pos < len(lx.text) {
+		c := lx.text[lx.pos]
+		switch {
+		case c == '\n':
+			lx.line++
+			lx.pos++
+			lineStart = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			lx.pos++
+			continue
+		case strings.HasPrefix(lx.text[lx.pos:], "//"):
+			lx.skipLine()
+		case strings.HasPrefix(lx.text[lx.pos:], "/*"):
+			if err := lx.skipComment(); err != nil {
+				return nil, err
+			}
+		case c == '#' && !swift && lineStart:
+			lx.skipLine()
+		case c == '"' || (c == '@' && !swift && strings.HasPrefix(lx.text[lx.pos+1:], `"`)):
+			if err := lx.lexString(0); err != nil {
+				return nil, err
+			}
+		case c == '#' && swift && lx.rawString():
+			hashes := len(lx.text[lx.pos:]) - len(strings.TrimLeft(lx.text[lx.pos:], "#"))
+			lx.pos += hashes
+			if err := lx.lexString(hashes); err != nil {
+				return nil, err
+			}
+		case c == '\'' && !swift:
+			lx.skipChar()
+		case c == '_' || c == '$' || c >= 0x80 || isLetter(c):
+			start := lx.pos
+			for lx.pos < len(lx.text) && (isLetter(lx.text[lx.pos]) || isDigit(lx.text[lx.pos]) || lx.text[lx.pos] == '_' || lx.text[lx.pos] == '$' || lx.text[lx.pos] >= 0x80) {
+				lx.pos++
+			}
+			lx.tokens = append(lx.tokens, token{tIdent, lx.text[start:lx.pos], lx.line})
+		case isDigit(c):
+			start := lx.pos
+			for lx.pos < len(lx.text) && (isLetter(lx.text[lx.pos]) || isDigit(lx.text[lx.pos]) || lx.text[lx.pos] == '.' || lx.text[lx.pos] == '_') {
+				lx.pos++
+			}
+			lx.tokens = append(lx.tokens, token{tOther, lx.text[start:lx.pos], lx.line})
+		default:
+			lx.tokens = append(lx.tokens, token{tPunct, string(c), lx.line})
+			lx.pos++
+		}
+		lineStart = false
+	}
+	return lx.tokens, nil
+}
+
+// skipLine skips to the end of the line, including the lines a preprocessor
+// line continues on with a backslash.
+func (lx *lexer) skipLine() {
+	for lx.pos < len(lx.text) && lx.text[lx.pos] != '\n' {
+		if lx.text[lx.pos] == '\\' && strings.HasPrefix(lx.text[lx.pos+1:], "\n") {
+			lx.pos++
+			lx.line++
+		}
+		lx.pos++
+	}
+}
+
+// skipComment skips a block comment, which nests in Swift.
+func (lx *lexer) skipComment() error {
+	line, depth := lx.line, 0
+	for lx.pos < len(lx.text) {
+		switch {
+		case strings.HasPrefix(lx.text[lx.pos:], "/*"):
+			if depth > 0 && !lx.swift {
+				lx.pos++
+				continue
+			}
+			depth++
+			lx.pos += 2
+		case strings.HasPrefix(lx.text[lx.pos:], "*/"):
+			depth--
+			lx.pos += 2
+			if depth == 0 {
+				return nil
+			}
+		default:
+			if lx.text[lx.pos] == '\n' {
+				lx.line++
+			}
+			lx.pos++
+		}
+	}
+	return fmt.Errorf("%d: Unterminated comment", line)
+}
+
+// skipChar skips a C character literal.
+func (lx *lexer) skipChar() {
+	for lx.pos++; lx.pos < len(lx.text) && lx.text[lx.pos] != '\'' && lx.text[lx.pos] != '\n'; lx.pos++ {
+		if lx.text[lx.pos] == '\\' {
+			lx.pos++
+		}
+	}
+	lx.pos++
+}
+
+// rawString reports whether a Swift raw string like #"text"# starts at pos.
+func (lx *lexer) rawString() bool {
+	rest := strings.TrimLeft(lx.text[lx.pos:], "#")
+	return strings.HasPrefix(rest, `"`)
+}
+
+// lexString adds the string literal starting at pos as token, with hashes
+// the number of # delimiting a Swift raw string. A Swift string with an
+// interpolation isn't a literal and is added as other token.
+func (lx *lexer) lexString(hashes int) error {
+	line := lx.line
+	if lx.text[lx.pos] == '@' {
+		lx.pos++
+	}
+	delim := `"`
+	if lx.swift && strings.HasPrefix(lx.text[lx.pos:], `"""`) {
+		delim = `"""`
+	}
+	closing := delim + strings.Repeat("#", hashes)
+	escape := `\` + strings.Repeat("#", hashes)
+	lx.pos += len(delim)
+
+	var b strings.Builder
+	interpolated := false
+	for {
+		if lx.pos >= len(lx.text) || (delim == `"` && lx.text[lx.pos] == '\n') {
+			return fmt.Errorf("%d: Unterminated string literal", line)
+		}
+		rest := lx.text[lx.pos:]
+		switch {
+		case strings.HasPrefix(rest, closing):
+			lx.pos += len(closing)
+			s := b.String()
+			if delim == `"""` {
+				s = multiline(s)
+			}
+			kind := tString
+			if interpolated {
+				kind = tOther
+			}
+			lx.tokens = append(lx.tokens, token{kind, s, line})
+			return nil
+		case strings.HasPrefix(rest, escape):
+			lx.pos += len(escape)
+			if lx.swift && strings.HasPrefix(lx.text[lx.pos:], "(") {
+				interpolated = true
+				lx.skipParens()
+				continue
+			}
+			if err := lx.unescape(&b); err != nil {
+				return fmt.Errorf("%d: %v", lx.line, err)
+			}
+		default:
+			if rest[0] == '\n' {
+				lx.line++
+			}
+			b.WriteByte(rest[0])
+			lx.pos++
+		}
+	}
+}
+
+// skipParens skips the parenthesized expression of an interpolation.
+func (lx *lexer) skipParens() {
+	depth := 0
+	for ; lx.pos < len(lx.text); lx.pos++ {
+		switch lx.text[lx.pos] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				lx.pos++
+				return
+			}
+		case '\n':
+			lx.line++
+		}
+	}
+}
+
+// unescape writes the character of the escape sequence at pos to b.
+func (lx *lexer) unescape(b *strings.Builder) error {
+	if lx.pos >= len(lx.text) {
+		return fmt.Errorf("Unterminated escape sequence")
+	}
+	c := lx.text[lx.pos]
+	lx.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case '0':
+		b.WriteByte(0)
+	case 'a':
+		b.WriteByte('\a')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '\\', '"', '\'', '?':
+		b.WriteByte(c)
+	case '\n':
+		// A backslash at the end of a line continues the line.
+		lx.line++
+	case 'u', 'U', 'x':
+		var digits string
+		if lx.swift && strings.HasPrefix(lx.text[lx.pos:], "{") {
+			end := strings.IndexByte(lx.text[lx.pos:], '}')
+			if end < 0 {
+				return fmt.Errorf("Invalid unicode escape sequence")
+			}
+			digits = lx.text[lx.pos+1 : lx.pos+end]
+			lx.pos += end + 1
+		} else {
+			n := map[byte]int{'u': 4, 'U': 8, 'x': 2}[c]
+			if lx.pos+n > len(lx.text) {
+				return fmt.Errorf("Invalid escape sequence \\%c", c)
+			}
+			digits = lx.text[lx.pos : lx.pos+n]
+			lx.pos += n
+		}
+		r, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || (c != 'x' && !utf8.ValidRune(rune(r))) {
+			return fmt.Errorf("Invalid escape sequence \\%c%s", c, digits)
+		}
+		if c == 'x' {
+			b.WriteByte(byte(r))
+		} else {
+			b.WriteRune(rune(r))
+		}
+	default:
+		return fmt.Errorf("Invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+// multiline returns the text of a Swift multiline string literal: the lines
+// between the delimiters, without the indentation of the closing delimiter.
+func multiline(s string) string {
+	s = strings.TrimPrefix(s, "\n")
+	indent := s[strings.LastIndexByte(s, '\n')+1:]
+	if strings.TrimLeft(indent, " \t") != "" {
+		indent = ""
+	}
+	s = strings.TrimSuffix(s[:len(s)-len(indent)], "\n")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
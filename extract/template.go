@@ -0,0 +1,150 @@
+package extract
+
+import (
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Call is a call of the template function T or TN with a literal ID, like
+//
+//	{{T "welcome" "Title of the welcome mail"}}
+//	{{TN "files" .count "Number of attached files" "folder" .name}}
+//
+// A literal string before the name value pairs of the call is the comment
+// for the translator.
+type Call struct {
+	Func    string
+	ID      string
+	Comment string
+	Line    int
+	// Pos and End are the byte offsets of the quoted ID in the template.
+	Pos, End int
+}
+
+// translateFuncs maps the template functions that translate their first
+// argument to the number of arguments before their name value pairs.
+var translateFuncs = map[string]int{"T": 1, "TN": 2}
+
+// TemplateCalls parses the Go template text named name and returns the calls
+// of T and TN with a literal ID it contains, including those in the templates
+// it defines, in the order they appear in text. Calls with an ID that isn't
+// a literal string can't be extracted and are skipped.
+func TemplateCalls(name, text string) (calls []Call, err error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := map[string]*parse.Tree{}
+	if tree, err = tree.Parse(text, "", "", treeSet); err != nil {
+		return
+	}
+
+	seen := map[*parse.Tree]bool{}
+	for _, t := range append([]*parse.Tree{tree}, treesOf(treeSet)...) {
+		if t == nil || t.Root == nil || seen[t] {
+			continue
+		}
+		seen[t] = true
+		walk(t.Root, func(cmd *parse.CommandNode) {
+			if call, ok := templateCall(cmd); ok {
+				call.Line = 1 + strings.Count(text[:call.Pos], "\n")
+				calls = append(calls, call)
+			}
+		})
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Pos < calls[j].Pos })
+	return
+}
+
+// ExtractTemplate adds the IDs of the T and TN calls in the Go template text
+// of the file name to c.
+func (c *Catalog) ExtractTemplate(name, text string) error {
+	calls, err := TemplateCalls(name, text)
+	if err != nil {
+		return err
+	}
+	for _, call := range calls {
+		c.Add(call.ID, call.Comment, Ref{File: name, Line: call.Line})
+	}
+	return nil
+}
+
+// templateCall returns the call of cmd when it calls T or TN with a literal
+// ID.
+func templateCall(cmd *parse.CommandNode) (call Call, ok bool) {
+	if len(cmd.Args) < 2 {
+		return
+	}
+	ident, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+	if !isIdent {
+		return
+	}
+	fixed, isFunc := translateFuncs[ident.Ident]
+	if !isFunc || len(cmd.Args) < 1+fixed {
+		return
+	}
+	id, isString := cmd.Args[1].(*parse.StringNode)
+	if !isString {
+		return
+	}
+	call = Call{Func: ident.Ident, ID: id.Text, Pos: int(id.Pos), End: int(id.Pos) + len(id.Quoted)}
+	if extra := cmd.Args[1+fixed:]; len(extra)%2 == 1 {
+		if comment, isString := extra[0].(*parse.StringNode); isString {
+			call.Comment = comment.Text
+		}
+	}
+	return call, true
+}
+
+// walk calls f for every command in the tree of n.
+func walk(n parse.Node, f func(cmd *parse.CommandNode)) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walk(child, f)
+		}
+	case *parse.ActionNode:
+		walk(n.Pipe, f)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			f(cmd)
+			for _, arg := range cmd.Args {
+				walk(arg, f)
+			}
+		}
+	case *parse.ChainNode:
+		walk(n.Node, f)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, f)
+	case *parse.TemplateNode:
+		walk(n.Pipe, f)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, f func(cmd *parse.CommandNode)) {
+	walk(n.Pipe, f)
+	walk(n.List, f)
+	walk(n.ElseList, f)
+}
+
+// treesOf returns the trees of treeSet sorted by name.
+func treesOf(treeSet map[string]*parse.Tree) (trees []*parse.Tree) {
+	names := make([]string, 0, len(treeSet))
+	for name := range treeSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		trees = append(trees, treeSet[name])
+	}
+	return
+}
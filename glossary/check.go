@@ -0,0 +1,128 @@
+package glossary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+// Violation describes a translation that does not use the approved rendering
+// of a glossary term found in its source text.
+type Violation struct {
+	ID       string
+	Term     *Term
+	Expected string
+	Source   string
+	Target   string
+}
+
+func (v Violation) String() string {
+	if v.Term.DoNotTranslate {
+		return fmt.Sprintf("%q: term %q must not be translated", v.ID, v.Term.Source)
+	}
+	return fmt.Sprintf("%q: term %q should be translated as %q", v.ID, v.Term.Source, v.Expected)
+}
+
+// Check compares the source text against the target text for every term in
+// the glossary and returns the violations found. The texts are expected to be
+// unescaped.
+func (g *Glossary) Check(id, source, target, lang string) (violations []Violation) {
+	for i := range g.Terms {
+		term := &g.Terms[i]
+		expected, ok := term.Rendering(lang)
+		if !ok {
+			continue
+		}
+		if !contains(source, term.Source, term.CaseSensitive) {
+			continue
+		}
+		if contains(target, expected, term.CaseSensitive) {
+			continue
+		}
+		violations = append(violations, Violation{ID: id, Term: term, Expected: expected, Source: source, Target: target})
+	}
+	return
+}
+
+// CheckMessages takes the messages emitted by TranslateMessages, where Ctx
+// holds the source text and Str holds the translation, and checks them against
+// the glossary for language lang. Missing messages are skipped as they have
+// not been translated yet. The violations are written to the returned channel.
+func CheckMessages(ctx context.Context, srcChan <-chan dotstrings.Message, g *Glossary, lang string) <-chan Violation {
+	dstChan := make(chan Violation, 3)
+
+	checker := func(srcChan <-chan dotstrings.Message, dstChan chan<- Violation) {
+		defer close(dstChan)
+		for m := range srcChan {
+			if m.Missing {
+				continue
+			}
+			// Fall back to the escaped text when unescaping fails, the term
+			// check is still meaningful in that case.
+			source, err := dotstrings.StringsUnescape(m.Ctx)
+			if err != nil {
+				source = m.Ctx
+			}
+			target, err := dotstrings.StringsUnescape(m.Str)
+			if err != nil {
+				target = m.Str
+			}
+			for _, v := range g.Check(m.ID, source, target, lang) {
+				select {
+				case dstChan <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+
+	go checker(srcChan, dstChan)
+	return dstChan
+}
+
+// contains reports whether text contains term as a whole word. Scripts that
+// don't separate words with spaces (e.g. Chinese or Japanese) match anywhere.
+func contains(text, term string, caseSensitive bool) bool {
+	if !caseSensitive {
+		text = strings.ToLower(text)
+		term = strings.ToLower(term)
+	}
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	for offset := 0; offset <= len(text); {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !joined(before, first) && !joined(last, after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+// joined reports whether runes a and b are part of the same word.
+func joined(a, b rune) bool {
+	return word(a) && word(b) && !unspaced(a) && !unspaced(b)
+}
+
+func word(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// unspaced reports whether r belongs to a script that is written without
+// spaces between words.
+func unspaced(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar)
+}
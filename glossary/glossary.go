@@ -0,0 +1,83 @@
+package glossary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Glossary contains the terms that must be translated consistently. It is
+// stored as a JSON file, e.g.
+//
+//	{
+//	  "terms": [
+//	    {"source": "Mind Map", "caseSensitive": true, "translations": {"fr": "carte mentale", "es": "Mapa Mental"}},
+//	    {"source": "Workspace", "doNotTranslate": true}
+//	  ]
+//	}
+type Glossary struct {
+	Terms []Term `json:"terms"`
+}
+
+// Term is a single glossary entry. Translations maps a language (as used in
+// the file names, e.g. "fr" or "pt-BR") to the approved rendering of the
+// Source term in that language. A term that is marked DoNotTranslate has to
+// appear unchanged in every translation.
+type Term struct {
+	Source         string            `json:"source"`
+	CaseSensitive  bool              `json:"caseSensitive,omitempty"`
+	DoNotTranslate bool              `json:"doNotTranslate,omitempty"`
+	Translations   map[string]string `json:"translations,omitempty"`
+	Note           string            `json:"note,omitempty"`
+}
+
+// LoadFile reads the glossary from the JSON file filename.
+func LoadFile(filename string) (g *Glossary, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Load reads a glossary in JSON format from r and validates its terms.
+func Load(r io.Reader) (g *Glossary, err error) {
+	g = &Glossary{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(g); err != nil {
+		return nil, fmt.Errorf("Failed to decode glossary (%v)", err)
+	}
+	for i, term := range g.Terms {
+		if len(strings.TrimSpace(term.Source)) == 0 {
+			return nil, fmt.Errorf("Glossary term %d has an empty source", i+1)
+		}
+		if term.DoNotTranslate && len(term.Translations) > 0 {
+			return nil, fmt.Errorf("Glossary term %q is marked doNotTranslate but has translations", term.Source)
+		}
+	}
+	return
+}
+
+// Rendering returns the approved rendering of the term in language lang. For
+// a regional language like "fr-CA" the rendering of "fr" is used when there
+// is none for "fr-CA" itself. The ok result is false when the glossary has no
+// opinion on how the term is translated in lang.
+func (t *Term) Rendering(lang string) (rendering string, ok bool) {
+	if t.DoNotTranslate {
+		return t.Source, true
+	}
+	for {
+		if rendering, ok = t.Translations[lang]; ok {
+			return
+		}
+		i := strings.LastIndexAny(lang, "-_")
+		if i < 0 {
+			return
+		}
+		lang = lang[:i]
+	}
+}
@@ -0,0 +1,56 @@
+package glossary
+
+import (
+	"strings"
+	"testing"
+)
+
+const tvGlossary = `{
+  "terms": [
+    {"source": "Mind Map", "caseSensitive": true, "translations": {"fr": "carte mentale", "es": "Mapa Mental"}},
+    {"source": "Workspace", "doNotTranslate": true},
+    {"source": "map", "translations": {"ja": "マップ"}}
+  ]
+}`
+
+func TestCheck(t *testing.T) {
+	g, err := Load(strings.NewReader(tvGlossary))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source, target, lang string
+		expect               int
+	}{
+		{"New Mind Map", "Nouvelle carte mentale", "fr", 0},
+		{"New Mind Map", "Nouvelle carte heuristique", "fr", 1},
+		{"New Mind Map", "Nouvelle carte heuristique", "fr-CA", 1},
+		{"New mind map", "Nouvelle carte heuristique", "fr", 0},
+		{"New Mind Map", "Nuevo Mapa Mental", "es", 0},
+		{"New Mind Map", "Neue Mind-Map", "de", 0},
+		{"Open Workspace", "Ouvrir le Workspace", "fr", 0},
+		{"Open Workspace", "Ouvrir l'espace de travail", "fr", 1},
+		{"Open Workspaces", "Ouvrir les espaces", "fr", 0},
+		{"Share map", "マップを共有", "ja", 0},
+		{"Share map", "地図を共有", "ja", 1},
+	}
+	for i, tv := range tests {
+		v := g.Check("id", tv.source, tv.target, tv.lang)
+		if len(v) != tv.expect {
+			t.Errorf("Expected %d violations for test %d got %v", tv.expect, i, v)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(strings.NewReader(`{"terms": [{"source": ""}]}`)); err == nil {
+		t.Error("Expected an error for an empty term")
+	}
+	if _, err := Load(strings.NewReader(`{"terms": [{"source": "A", "doNotTranslate": true, "translations": {"fr": "B"}}]}`)); err == nil {
+		t.Error("Expected an error for a doNotTranslate term with translations")
+	}
+	if _, err := Load(strings.NewReader(`{"words": []}`)); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
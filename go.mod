@@ -1,5 +1,5 @@
 module github.com/simpleapps-eu/translate
 
-go 1.18
+go 1.23
 
 require golang.org/x/text v0.3.7
@@ -0,0 +1,302 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simpleapps-eu/translate/icu"
+	"github.com/simpleapps-eu/translate/plural"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/number"
+)
+
+// Format formats the ICU message m with the argument values by name. Plural
+// arguments select their branch with the plural rules of the locale, numbers
+// with decimals select other.
+func (z *Localizer) Format(m icu.Message, values map[string]interface{}) (string, error) {
+	b := &strings.Builder{}
+	if err := z.formatMessage(b, m, values, nil); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// formatMessage writes m to b, pound is the number of the enclosing plural
+// branch.
+func (z *Localizer) formatMessage(b *strings.Builder, m icu.Message, values map[string]interface{}, pound *float64) error {
+	for _, p := range m {
+		switch p := p.(type) {
+		case icu.Text:
+			b.WriteString(string(p))
+		case icu.Pound:
+			if pound == nil {
+				b.WriteByte('#')
+				continue
+			}
+			b.WriteString(z.number(*pound))
+		case *icu.Arg:
+			v, ok := values[p.Name]
+			if !ok {
+				return fmt.Errorf("Missing value of argument {%s}", p.Name)
+			}
+			if err := z.formatArg(b, p, v, values, pound); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatArg writes the argument a with value v to b.
+func (z *Localizer) formatArg(b *strings.Builder, a *icu.Arg, v interface{}, values map[string]interface{}, pound *float64) (err error) {
+	var s string
+	switch a.Type {
+	case "":
+		switch v := v.(type) {
+		case string:
+			s = v
+		case time.Time:
+			s, err = z.Date(v)
+		default:
+			if s, err = z.Number(v); err != nil {
+				s, err = fmt.Sprint(v), nil
+			}
+		}
+	case "number":
+		s, err = z.numberStyle(v, a.Style)
+	case "date":
+		s, err = z.Date(v)
+	case "time":
+		s, err = z.Time(v)
+	case icu.Plural, icu.SelectOrdinal:
+		return z.formatPlural(b, a, v, values)
+	case icu.Select:
+		return z.formatMessage(b, branch(a, fmt.Sprint(v)), values, pound)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid value of argument {%s} (%v)", a.Name, err)
+	}
+	b.WriteString(s)
+	return nil
+}
+
+// formatPlural writes the branch of the plural or selectordinal argument a
+// that the number v selects to b.
+func (z *Localizer) formatPlural(b *strings.Builder, a *icu.Arg, v interface{}, values map[string]interface{}) error {
+	f, err := toNumber(v)
+	if err != nil {
+		return fmt.Errorf("Invalid value of argument {%s} (%v)", a.Name, err)
+	}
+	n := f - float64(a.Offset)
+	for _, br := range a.Branches {
+		if !strings.HasPrefix(br.Key, "=") {
+			continue
+		}
+		if exact, err := strconv.ParseFloat(br.Key[1:], 64); err == nil && exact == f {
+			return z.formatMessage(b, br.Message, values, &n)
+		}
+	}
+
+	c := plural.Other
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		if a.Type == icu.SelectOrdinal {
+			c = plural.SelectOrdinal(z.Locale, int(n))
+		} else {
+			c = plural.Select(z.Locale, int(n))
+		}
+	}
+	return z.formatMessage(b, branch(a, string(c)), values, &n)
+}
+
+// branch returns the message of the branch key of a, or of its other branch.
+func branch(a *icu.Arg, key string) icu.Message {
+	var other icu.Message
+	for _, br := range a.Branches {
+		switch br.Key {
+		case key:
+			return br.Message
+		case "other":
+			other = br.Message
+		}
+	}
+	return other
+}
+
+// Number formats the number v with the decimal and grouping separators of
+// the locale.
+func (z *Localizer) Number(v interface{}) (string, error) {
+	f, err := toNumber(v)
+	if err != nil {
+		return "", err
+	}
+	return z.number(f), nil
+}
+
+func (z *Localizer) number(f float64, opts ...number.Option) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return z.printer.Sprint(number.Decimal(int64(f), opts...))
+	}
+	return z.printer.Sprint(number.Decimal(f, opts...))
+}
+
+// numberStyle formats v for the ICU number styles integer and percent,
+// other styles format v as Number does.
+func (z *Localizer) numberStyle(v interface{}, style string) (string, error) {
+	f, err := toNumber(v)
+	if err != nil {
+		return "", err
+	}
+	switch style {
+	case "integer":
+		return z.number(math.Round(f)), nil
+	case "percent":
+		return z.printer.Sprint(number.Percent(f)), nil
+	}
+	return z.number(f), nil
+}
+
+// suffixCurrency lists the languages that write the currency symbol after the
+// amount, like 12,50 € in French.
+var suffixCurrency = map[string]bool{
+	"bg": true, "cs": true, "da": true, "de": true, "el": true, "es": true, "et": true, "fi": true,
+	"fr": true, "hr": true, "hu": true, "is": true, "it": true, "lt": true, "lv": true, "nb": true,
+	"no": true, "pl": true, "pt-PT": true, "ro": true, "ru": true, "sk": true, "sl": true, "sr": true,
+	"sv": true, "uk": true, "vi": true,
+}
+
+// Currency formats the amount v in the currency with the ISO 4217 code, like
+// EUR, with the digits the currency uses and its symbol in the locale.
+func (z *Localizer) Currency(code string, v interface{}) (string, error) {
+	cur, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("Invalid currency %q (%v)", code, err)
+	}
+	f, err := toNumber(v)
+	if err != nil {
+		return "", err
+	}
+	scale, _ := currency.Standard.Rounding(cur)
+	amount := z.printer.Sprint(number.Decimal(f, number.MinFractionDigits(scale), number.MaxFractionDigits(scale)))
+	symbol := z.printer.Sprint(currency.Symbol(cur))
+	if suffixCurrency[z.Locale.String()] || suffixCurrency[z.Locale.Language()] {
+		return amount + "\u00a0" + symbol, nil
+	}
+	if len([]rune(symbol)) > 1 {
+		return symbol + "\u00a0" + amount, nil
+	}
+	return symbol + amount, nil
+}
+
+// dateLayouts holds the numeric date layouts by language, languages not
+// listed use ISO 8601.
+var dateLayouts = map[string]string{
+	"bg": "2.01.2006", "cs": "2. 1. 2006", "da": "02.01.2006", "de": "02.01.2006",
+	"el": "2/1/2006", "es": "2/1/2006", "fi": "2.1.2006", "fr": "02/01/2006",
+	"hu": "2006. 01. 02.", "it": "02/01/2006", "ja": "2006/01/02", "ko": "2006. 1. 2.",
+	"lt": "2006-01-02", "nb": "02.01.2006", "nl": "02-01-2006", "pl": "02.01.2006",
+	"pt": "02/01/2006", "ru": "02.01.2006", "sv": "2006-01-02", "tr": "02.01.2006",
+	"uk": "02.01.2006", "zh": "2006/1/2",
+}
+
+// Date formats the date v as a numeric date in the order and with the
+// separators of the locale. The date is a time.Time, a string in RFC 3339
+// or 2006-01-02 form, or a number of seconds since the Unix epoch.
+func (z *Localizer) Date(v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	layout, ok := dateLayouts[z.Locale.Language()]
+	switch {
+	case z.american():
+		layout = "1/2/2006"
+	case z.Locale.Language() == "en":
+		layout = "02/01/2006"
+	case !ok:
+		layout = "2006-01-02"
+	}
+	return t.Format(layout), nil
+}
+
+// Time formats the time of day of v, given like Date's, with the 12 hour
+// clock in American English and the 24 hour clock otherwise.
+func (z *Localizer) Time(v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	if z.american() {
+		return t.Format("3:04 PM"), nil
+	}
+	return t.Format("15:04"), nil
+}
+
+// american reports whether the locale is American English, which is also
+// the English without a region.
+func (z *Localizer) american() bool {
+	r, _ := z.Locale.Tag().Region()
+	return z.Locale.Language() == "en" && r.String() == "US"
+}
+
+// toNumber converts the Go or JSON number v, or a string holding one, to a
+// float64.
+func toNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int8:
+		return float64(n), nil
+	case int16:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint8:
+		return float64(n), nil
+	case uint16:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+// toTime converts v to a time, see Date.
+func toTime(v interface{}) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	if s, ok := v.(string); ok {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a date", s)
+	}
+	f, err := toNumber(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v is not a date", v)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+}
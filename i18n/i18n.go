@@ -0,0 +1,158 @@
+// Package i18n provides the functions templates use to render localized
+// text: T and TN look up translations in a translation chain, number,
+// currency and date format values the way the locale writes them.
+//
+//	{{T "welcome" "name" .firstName}}
+//	{{TN "files" .count "Number of attached files"}}
+//	{{currency "EUR" .total}} on {{date .due}}
+//
+// Translations with arguments are ICU messages, like
+// "{n, plural, one {# file} other {# files}}".
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/icu"
+	"github.com/simpleapps-eu/translate/locale"
+	"golang.org/x/text/message"
+)
+
+// Localizer translates and formats for a single locale.
+type Localizer struct {
+	Locale locale.Locale
+	Chain  translate.Chain
+
+	printer *message.Printer
+
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+// New returns a localizer for the locale l that translates using chain. The
+// zero locale formats values without regional conventions.
+func New(l locale.Locale, chain translate.Chain) *Localizer {
+	return &Localizer{Locale: l, Chain: chain, printer: message.NewPrinter(l.Tag()), missing: map[string]bool{}}
+}
+
+// Missing returns the IDs T and TN found no translation for so far, sorted.
+func (z *Localizer) Missing() (ids []string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for id := range z.missing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return
+}
+
+// Funcs returns the template functions of z: T, TN, number, currency and
+// date.
+func (z *Localizer) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"T":        z.T,
+		"TN":       z.TN,
+		"number":   z.Number,
+		"currency": z.Currency,
+		"date":     z.Date,
+	}
+}
+
+// T returns the translation of id, looked up the same way TranslateIDs
+// does: through the fallbacks of the chain, and id itself when no memory
+// has a translation. The optional args are pairs of ICU argument names and
+// values the translation is formatted with, optionally preceded by a comment
+// for the translator that extract collects and T ignores:
+//
+//	{{T "welcome" "Greeting at the top of the mail" "name" .firstName}}
+func (z *Localizer) T(id string, args ...interface{}) (string, error) {
+	text, err := z.lookup(id)
+	if err != nil || len(args) == 0 {
+		return text, err
+	}
+	values, err := pairs(id, args)
+	if err != nil {
+		return "", err
+	}
+	return z.format(id, text, values, nil)
+}
+
+// TN returns the translation of id like T, formatted for count. The count is
+// the value of every plural and selectordinal argument of the translation
+// that isn't given in args.
+func (z *Localizer) TN(id string, count interface{}, args ...interface{}) (string, error) {
+	text, err := z.lookup(id)
+	if err != nil {
+		return "", err
+	}
+	values, err := pairs(id, args)
+	if err != nil {
+		return "", err
+	}
+	return z.format(id, text, values, count)
+}
+
+// lookup returns the unescaped translation of id, or id itself when it is
+// missing.
+func (z *Localizer) lookup(id string) (string, error) {
+	m, _, ok := z.Chain.Lookup(dotstrings.StringsEscape(id))
+	if !ok || len(m.Str) == 0 {
+		z.mu.Lock()
+		z.missing[id] = true
+		z.mu.Unlock()
+		return id, nil
+	}
+	text, err := dotstrings.StringsUnescape(m.Str)
+	if err != nil {
+		return "", fmt.Errorf("Failed to unescape the translation of %q (%v)", id, err)
+	}
+	return text, nil
+}
+
+// format formats the translation text of id as an ICU message. A non-nil
+// count is the value of the plural arguments missing from values.
+func (z *Localizer) format(id, text string, values map[string]interface{}, count interface{}) (string, error) {
+	m, err := icu.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Invalid translation of %q (%v)", id, err)
+	}
+	if count != nil {
+		for name, typ := range m.Args() {
+			if _, ok := values[name]; !ok && (typ == icu.Plural || typ == icu.SelectOrdinal) {
+				values[name] = count
+			}
+		}
+	}
+	s, err := z.Format(m, values)
+	if err != nil {
+		return "", fmt.Errorf("Failed to format the translation of %q (%v)", id, err)
+	}
+	return s, nil
+}
+
+// pairs returns the name value pairs of args by name. With an odd number of
+// args the first is the comment for the translator, which is skipped.
+func pairs(id string, args []interface{}) (map[string]interface{}, error) {
+	if len(args)%2 != 0 {
+		if _, ok := args[0].(string); ok {
+			args = args[1:]
+		}
+	}
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("Odd number of arguments for %q, name value pairs expected", id)
+	}
+	values := map[string]interface{}{}
+	for i := 0; i < len(args); i += 2 {
+		name, ok := args[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid argument name %v for %q, a string expected", args[i], id)
+		}
+		values[name] = args[i+1]
+	}
+	return values, nil
+}
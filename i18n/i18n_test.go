@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+func testChain() translate.Chain {
+	return translate.Chain{
+		{Locale: "fr-CA", Translations: map[string]dotstrings.Message{
+			"hello": {ID: "hello", Str: `Allô {name}`},
+		}},
+		{Locale: "fr", Translations: map[string]dotstrings.Message{
+			"hello": {ID: "hello", Str: `Bonjour {name}`},
+			"files": {ID: "files", Str: `{n, plural, =0 {Aucun fichier} one {# fichier} other {# fichiers}} dans \"{folder}\"`},
+			"rank":  {ID: "rank", Str: `{n, selectordinal, one {#er} other {#e}}`},
+			"quit":  {ID: "quit", Str: `Quitter`},
+		}},
+	}
+}
+
+func TestT(t *testing.T) {
+	z := New(locale.MustParse("fr-CA"), testChain())
+	for _, tv := range []struct {
+		got    func() (string, error)
+		expect string
+	}{
+		{func() (string, error) { return z.T("quit") }, "Quitter"},
+		{func() (string, error) { return z.T("new") }, "new"},
+		{func() (string, error) { return z.T("hello", "name", "Marie") }, "Allô Marie"},
+		{func() (string, error) { return z.TN("files", 0, "folder", "Documents") }, `Aucun fichier dans "Documents"`},
+		{func() (string, error) { return z.TN("files", 1.0, "folder", "Documents") }, `1 fichier dans "Documents"`},
+		{func() (string, error) { return z.TN("files", 12345, "folder", "Documents") }, "12 345 fichiers dans \"Documents\""},
+		{func() (string, error) { return z.TN("rank", 1) }, "1er"},
+	} {
+		s, err := tv.got()
+		if err != nil {
+			t.Error(err)
+		} else if s != tv.expect {
+			t.Errorf("Expected %q got %q", tv.expect, s)
+		}
+	}
+
+	if missing := z.Missing(); len(missing) != 1 || missing[0] != "new" {
+		t.Errorf("Expected new to be missing got %q", missing)
+	}
+	if s, err := z.T("hello", "Greeting", "name", "Marie"); err != nil || s != "Allô Marie" {
+		t.Errorf("Expected the comment to be skipped got %q (%v)", s, err)
+	}
+	if _, err := z.T("hello", 1); err == nil {
+		t.Errorf("Expected an error for an odd number of arguments")
+	}
+	if _, err := z.TN("files", 2); err == nil {
+		t.Errorf("Expected an error for the missing folder")
+	}
+}
+
+func TestFormatValues(t *testing.T) {
+	due := time.Date(2024, 3, 7, 14, 5, 0, 0, time.UTC)
+	for _, tv := range []struct {
+		locale                 string
+		number, currency, date string
+	}{
+		{"en", "1,234.5", "€1,234.50", "3/7/2024"},
+		{"en-GB", "1,234.5", "€1,234.50", "07/03/2024"},
+		{"de", "1.234,5", "1.234,50\u00a0€", "07.03.2024"},
+		{"ja", "1,234.5", "€1,234.50", "2024/03/07"},
+	} {
+		z := New(locale.MustParse(tv.locale), nil)
+		if s, err := z.Number(1234.5); err != nil || s != tv.number {
+			t.Errorf("%s: expected number %q got %q (%v)", tv.locale, tv.number, s, err)
+		}
+		if s, err := z.Currency("EUR", 1234.5); err != nil || s != tv.currency {
+			t.Errorf("%s: expected currency %q got %q (%v)", tv.locale, tv.currency, s, err)
+		}
+		if s, err := z.Date(due.Format(time.RFC3339)); err != nil || s != tv.date {
+			t.Errorf("%s: expected date %q got %q (%v)", tv.locale, tv.date, s, err)
+		}
+	}
+	if _, err := New(locale.MustParse("en"), nil).Currency("XYZW", 1); err == nil {
+		t.Errorf("Expected an error for an invalid currency")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	z := New(locale.MustParse("fr"), testChain()[1:])
+	templ := template.Must(template.New("mail").Funcs(z.Funcs()).Parse(`{{T "hello" "name" .name}}, {{TN "files" .count "folder" "Photos"}} ({{currency "EUR" .total}})`))
+	buf := &bytes.Buffer{}
+	if err := templ.Execute(buf, map[string]interface{}{"name": "Luc", "count": 2.0, "total": 9.5}); err != nil {
+		t.Fatal(err)
+	}
+	expect := "Bonjour Luc, 2 fichiers dans \"Photos\" (9,50\u00a0€)"
+	if buf.String() != expect {
+		t.Errorf("Expected %q got %q", expect, buf.String())
+	}
+}
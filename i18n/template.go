@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	htemplate "html/template"
+	"io"
+	"sync"
+	ttemplate "text/template"
+
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+// Template is a text or HTML template that uses the template functions of a
+// Localizer. It is parsed once and can then be executed for many localizers
+// at the same time.
+type Template struct {
+	mu   sync.Mutex
+	text *ttemplate.Template
+	html *htemplate.Template
+}
+
+// Parse parses text as the template name. With html the template is an
+// html/template, which escapes the values it inserts for the context they
+// are inserted in, as HTML emails need.
+func Parse(name, text string, html bool) (t *Template, err error) {
+	funcs := New(locale.Locale{}, nil).Funcs()
+	t = &Template{}
+	if html {
+		t.html, err = htemplate.New(name).Funcs(htemplate.FuncMap(funcs)).Parse(text)
+	} else {
+		t.text, err = ttemplate.New(name).Funcs(funcs).Parse(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseGlob parses the files matching pattern as additional templates, like
+// partials the template includes with {{template "header.tpl" .}}.
+func (t *Template) ParseGlob(pattern string) (err error) {
+	if t.html != nil {
+		_, err = t.html.ParseGlob(pattern)
+	} else {
+		_, err = t.text.ParseGlob(pattern)
+	}
+	return
+}
+
+// Execute executes the template with data for the localizer z and writes the
+// result to w.
+func (t *Template) Execute(w io.Writer, z *Localizer, data interface{}) error {
+	t.mu.Lock()
+	if t.html != nil {
+		clone, err := t.html.Clone()
+		t.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		return clone.Funcs(htemplate.FuncMap(z.Funcs())).Execute(w, data)
+	}
+	clone, err := t.text.Clone()
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return clone.Funcs(z.Funcs()).Execute(w, data)
+}
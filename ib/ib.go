@@ -0,0 +1,257 @@
+// Package ib extracts the localizable strings of Interface Builder files,
+// .storyboard and .xib, the way ibtool --export-strings-file does:
+//
+//	/* Class = "UILabel"; text = "Hello"; ObjectID = "abc-12-xyz"; */
+//	"abc-12-xyz.text" = "Hello";
+//
+// It doesn't need Xcode, the XML of the files is read directly.
+package ib
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// String is a localizable property of an object, like the text of a label
+// or the normal title of a button.
+type String struct {
+	ObjectID string
+	Property string
+	Class    string
+	Text     string
+}
+
+// Key returns the ID of s in a .strings file, ObjectID.Property.
+func (s String) Key() string {
+	return s.ObjectID + "." + s.Property
+}
+
+// Message returns s as message of a source .strings file, with the comment
+// ibtool generates describing the object.
+func (s String) Message() dotstrings.Message {
+	text := dotstrings.StringsEscape(s.Text)
+	ctx := fmt.Sprintf("Class = %q; %s = \"%s\"; ObjectID = %q;", s.Class, s.Property, text, s.ObjectID)
+	return dotstrings.Message{
+		ID:  dotstrings.StringsEscape(s.Key()),
+		Str: text,
+		Ctx: strings.ReplaceAll(ctx, "*/", "* /"),
+	}
+}
+
+// LoadMessages asynchronously reads the strings of the Interface Builder file
+// srcFile and sends them as messages of a source .strings file. Reading stops
+// when ctx is done, ctx.Err() is then sent on the error channel.
+func LoadMessages(ctx context.Context, srcFile io.Reader) (<-chan dotstrings.Message, <-chan error) {
+	strChan, errChan := stage.Load(ctx, NewReader(srcFile).All())
+	return stage.Map(ctx, strChan, String.Message), errChan
+}
+
+// properties are the attributes that hold localizable text.
+var properties = []string{"title", "text", "placeholder", "placeholderString", "prompt", "headerTitle", "footerTitle", "label", "toolTip"}
+
+func isProperty(name string) bool {
+	for _, p := range properties {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// object is an element of the file that is an object, it has an ID.
+type object struct {
+	id, class string
+	segments  int
+}
+
+// Reader reads the localizable strings of an Interface Builder file one at
+// a time, in the order of the file.
+type Reader struct {
+	d       *xml.Decoder
+	prefix  string
+	objects []object // the enclosing objects, innermost last
+	depths  []int    // the depth of every enclosing object
+	depth   int
+	pending []String
+	ids     map[string]bool
+	err     error
+}
+
+// NewReader returns a Reader reading the Interface Builder file provided by
+// r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{d: xml.NewDecoder(r), prefix: "UI", ids: map[string]bool{}}
+}
+
+// ObjectIDs returns the IDs of the objects read so far, all objects of the
+// file once Next returned io.EOF.
+func (p *Reader) ObjectIDs() map[string]bool {
+	return p.ids
+}
+
+// Next returns the next string. It returns io.EOF when there are no more
+// strings.
+func (p *Reader) Next() (s String, err error) {
+	for p.err == nil && len(p.pending) == 0 {
+		p.err = p.next()
+	}
+	if len(p.pending) > 0 {
+		s, p.pending = p.pending[0], p.pending[1:]
+		return s, nil
+	}
+	return s, p.err
+}
+
+// next decodes tokens until the end of the next element, adding the strings
+// it finds to pending. Raw tokens are used as they are a lot cheaper, the
+// files have no name spaces to translate.
+func (p *Reader) next() error {
+	token, err := p.d.RawToken()
+	if err == io.EOF && p.depth > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		p.depth++
+		if p.depth == 1 {
+			if t.Name.Local != "document" {
+				return fmt.Errorf("expected element type <document> but have <%s>", t.Name.Local)
+			}
+			if strings.HasPrefix(attr(t, "targetRuntime"), "MacOSX") {
+				p.prefix = "NS"
+			}
+			return nil
+		}
+		if id := attr(t, "id"); len(id) > 0 {
+			p.ids[id] = true
+			o := object{id: id, class: p.prefix + strings.ToUpper(t.Name.Local[:1]) + t.Name.Local[1:]}
+			p.objects = append(p.objects, o)
+			p.depths = append(p.depths, p.depth)
+			for _, a := range t.Attr {
+				if isProperty(a.Name.Local) && len(a.Value) > 0 {
+					p.add(a.Name.Local, a.Value)
+				}
+			}
+			return nil
+		}
+		if len(p.objects) == 0 {
+			return nil
+		}
+		switch t.Name.Local {
+		case "state":
+			// <state key="normal" title="Button"/>
+			if title := attr(t, "title"); len(title) > 0 {
+				p.add(attr(t, "key")+"Title", title)
+			}
+		case "segment":
+			// <segments><segment title="First"/></segments>
+			o := &p.objects[len(p.objects)-1]
+			if title := attr(t, "title"); len(title) > 0 {
+				p.add(fmt.Sprintf("segmentTitles[%d]", o.segments), title)
+			}
+			o.segments++
+		case "string":
+			// <string key="text">Multi line text</string>
+			if key := attr(t, "key"); isProperty(key) {
+				text, err := p.text()
+				if err != nil {
+					return err
+				}
+				if len(text) > 0 {
+					p.add(key, text)
+				}
+			}
+		}
+	case xml.EndElement:
+		if n := len(p.depths); n > 0 && p.depths[n-1] == p.depth {
+			p.objects, p.depths = p.objects[:n-1], p.depths[:n-1]
+		}
+		p.depth--
+	}
+	return nil
+}
+
+// add adds the property of the innermost object to pending.
+func (p *Reader) add(property, text string) {
+	o := p.objects[len(p.objects)-1]
+	p.pending = append(p.pending, String{ObjectID: o.id, Property: property, Class: o.class, Text: text})
+}
+
+// text returns the character data of the element just started, it consumes
+// the end of the element.
+func (p *Reader) text() (s string, err error) {
+	var b []byte
+	for depth := 1; depth > 0; {
+		token, err := p.d.RawToken()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			if depth == 1 {
+				b = append(b, t...)
+			}
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	p.depth--
+	return string(b), nil
+}
+
+// All returns an iterator over the remaining strings. The iteration stops at
+// the end of the file or after yielding the first error.
+func (p *Reader) All() iter.Seq2[String, error] {
+	return func(yield func(String, error) bool) {
+		for {
+			s, err := p.Next()
+			if err == io.EOF || !yield(s, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Obsolete returns the IDs of messages, sorted, whose object isn't one of
+// the objects ids of an Interface Builder file, because the object was
+// removed since the messages were exported.
+func Obsolete(ids map[string]bool, messages map[string]dotstrings.Message) (obsolete []string) {
+	for id := range messages {
+		key, err := dotstrings.StringsUnescape(id)
+		if err != nil {
+			key = id
+		}
+		objectID, _, _ := strings.Cut(key, ".")
+		if !ids[objectID] {
+			obsolete = append(obsolete, id)
+		}
+	}
+	sort.Strings(obsolete)
+	return
+}
@@ -0,0 +1,128 @@
+package ib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+const storyboard = `<?xml version="1.0" encoding="UTF-8"?>
+<document type="com.apple.InterfaceBuilder3.CocoaTouch.Storyboard.XIB" version="3.0" targetRuntime="iOS.CocoaTouch" initialViewController="BYZ-38-t0r">
+    <scenes>
+        <scene sceneID="tne-QT-ifu">
+            <objects>
+                <viewController id="BYZ-38-t0r" title="Settings" sceneMemberID="viewController">
+                    <view key="view" contentMode="scaleToFill" id="8bC-Xf-vdC">
+                        <subviews>
+                            <label text="Hello &quot;you&quot;" id="dkx-z0-nzr">
+                                <fontDescription key="fontDescription" type="system" pointSize="17"/>
+                            </label>
+                            <button id="Kq2-1c-4aX">
+                                <state key="normal" title="Save"/>
+                                <state key="highlighted"/>
+                            </button>
+                            <textField placeholder="Name" text="" id="tf1-aa-bbb"/>
+                            <textView id="tv1-aa-bbb">
+                                <string key="text">Line one
+Line two</string>
+                            </textView>
+                            <segmentedControl id="seg-aa-bbb">
+                                <segments>
+                                    <segment title="First"/>
+                                    <segment title="Second"/>
+                                </segments>
+                            </segmentedControl>
+                        </subviews>
+                    </view>
+                    <navigationItem key="navigationItem" title="Options" id="nav-aa-bbb"/>
+                </viewController>
+                <placeholder placeholderIdentifier="IBFirstResponder" id="dkx-FR-ifu" sceneMemberID="firstResponder"/>
+            </objects>
+        </scene>
+    </scenes>
+</document>
+`
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader(storyboard))
+	var got []string
+	for s, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s.Class+" "+s.Key()+" "+s.Text)
+	}
+	expect := []string{
+		"UIViewController BYZ-38-t0r.title Settings",
+		`UILabel dkx-z0-nzr.text Hello "you"`,
+		"UIButton Kq2-1c-4aX.normalTitle Save",
+		"UITextField tf1-aa-bbb.placeholder Name",
+		"UITextView tv1-aa-bbb.text Line one\nLine two",
+		"UISegmentedControl seg-aa-bbb.segmentTitles[0] First",
+		"UISegmentedControl seg-aa-bbb.segmentTitles[1] Second",
+		"UINavigationItem nav-aa-bbb.title Options",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+	if ids := r.ObjectIDs(); len(ids) != 9 || !ids["8bC-Xf-vdC"] {
+		t.Errorf("Expected the 9 object IDs got %v", ids)
+	}
+
+	if _, err := NewReader(strings.NewReader(`<plist></plist>`)).Next(); err == nil || err == io.EOF {
+		t.Errorf("Expected an error for a file that isn't an Interface Builder file got %v", err)
+	}
+	if _, err := NewReader(strings.NewReader(`<document><label id="a"`)).Next(); err == nil || err == io.EOF {
+		t.Errorf("Expected an error for a truncated file got %v", err)
+	}
+}
+
+func TestLoadMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgChan, errChan := LoadMessages(ctx, strings.NewReader(storyboard))
+	buf := &bytes.Buffer{}
+	n, _ := dotstrings.SaveMessages(msgChan, buf)
+	if err := stage.Wait(ctx, cancel, errChan); err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 {
+		t.Errorf("Expected 8 messages got %d", n)
+	}
+	for _, s := range []string{
+		"/* Class = \"UILabel\"; text = \"Hello \\\"you\\\"\"; ObjectID = \"dkx-z0-nzr\"; */\n\"dkx-z0-nzr.text\" = \"Hello \\\"you\\\"\";\n",
+		"/* Class = \"UIButton\"; normalTitle = \"Save\"; ObjectID = \"Kq2-1c-4aX\"; */\n\"Kq2-1c-4aX.normalTitle\" = \"Save\";\n",
+		"\"tv1-aa-bbb.text\" = \"Line one\\nLine two\";\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected the strings to contain\n%s\ngot\n%s", s, buf.String())
+		}
+	}
+
+	mac := `<document targetRuntime="MacOSX.Cocoa"><objects><menuItem title="Quit" id="q-1"/></objects></document>`
+	if s, err := NewReader(strings.NewReader(mac)).Next(); err != nil || s.Class != "NSMenuItem" {
+		t.Errorf("Expected an NSMenuItem got %+v (%v)", s, err)
+	}
+}
+
+func TestObsolete(t *testing.T) {
+	r := NewReader(strings.NewReader(storyboard))
+	for _, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	messages := map[string]dotstrings.Message{
+		"dkx-z0-nzr.text":         {ID: "dkx-z0-nzr.text", Str: "Bonjour"},
+		"old-aa-bbb.text":         {ID: "old-aa-bbb.text", Str: "Ancien"},
+		"gone-aa-bbb.normalTitle": {ID: "gone-aa-bbb.normalTitle", Str: "Parti"},
+	}
+	if obsolete := Obsolete(r.ObjectIDs(), messages); strings.Join(obsolete, ",") != "gone-aa-bbb.normalTitle,old-aa-bbb.text" {
+		t.Errorf("Expected the removed objects got %q", obsolete)
+	}
+}
@@ -0,0 +1,181 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/icu"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// FlattenICUUnits asynchronously replaces every translation unit whose source
+// is an ICU message with plural or select arguments by a unit per variant, so
+// translators never see the ICU syntax. The variants are those the target
+// language of the unit needs, the ID of a variant is the ID of the unit
+// followed by # and the key of the variant, like files#count:plural=one.
+// Units without such arguments are passed on unchanged. A target that isn't a
+// valid ICU message with the arguments of the source is an error.
+func FlattenICUUnits(ctx context.Context, srcChan <-chan xliff.TranslationUnit) (<-chan xliff.TranslationUnit, <-chan error) {
+	unitsChan, errChan := stage.MapErr(ctx, srcChan, func(tu xliff.TranslationUnit) ([]xliff.TranslationUnit, error) {
+		source, err := icu.Parse(xliff.XMLUnescape(tu.Source))
+		if err != nil || !source.HasBranches() {
+			return []xliff.TranslationUnit{tu}, nil
+		}
+		l := unitLocale(tu)
+
+		targets := map[string]icu.Variant{}
+		if len(tu.Target) > 0 {
+			target, err := icu.Parse(xliff.XMLUnescape(tu.Target))
+			if err == nil {
+				err = icu.Compare(source, target)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Failed to flatten the target of %q (%v)", xliff.XMLUnescape(tu.ID), err)
+			}
+			for _, v := range icu.ExpandFor(target, l) {
+				targets[v.Key()] = v
+			}
+		}
+
+		var units []xliff.TranslationUnit
+		for _, v := range icu.ExpandFor(source, l) {
+			unit := tu
+			unit.ID = tu.ID + "#" + v.Key()
+			unit.Source = xliff.XMLEscapeLoose(v.String())
+			unit.Target = ""
+			if t, ok := targets[v.Key()]; ok {
+				unit.Target = xliff.XMLEscapeLoose(t.String())
+			}
+			units = append(units, unit)
+		}
+		return units, nil
+	})
+	return stage.FlatMap(ctx, unitsChan, func(units []xliff.TranslationUnit) []xliff.TranslationUnit {
+		return units
+	}), errChan
+}
+
+// RebuildICUUnits asynchronously joins the consecutive units flattened by
+// FlattenICUUnits back into a single unit with an ICU message. The rebuilt
+// target is empty when any of the variants is untranslated. Other units are
+// passed on unchanged.
+func RebuildICUUnits(ctx context.Context, srcChan <-chan xliff.TranslationUnit) (<-chan xliff.TranslationUnit, <-chan error) {
+	dst := make(chan xliff.TranslationUnit, stage.Buffer)
+	errChan := make(chan error, 1)
+
+	rebuilder := func() {
+		defer close(dst)
+		defer close(errChan)
+
+		var (
+			base  string
+			group []xliff.TranslationUnit
+			keys  [][]icu.Selector
+		)
+		flush := func() bool {
+			if len(group) == 0 {
+				return true
+			}
+			tu, err := rebuildUnit(base, group, keys)
+			if err != nil {
+				errChan <- err
+				return false
+			}
+			group, keys = group[:0], keys[:0]
+			return stage.Send(ctx, dst, tu)
+		}
+
+		for tu := range srcChan {
+			id, selectors, ok := splitVariantID(tu.ID)
+			if len(group) > 0 && (!ok || id != base || tu.File != group[0].File) {
+				if !flush() {
+					return
+				}
+			}
+			if !ok {
+				if !stage.Send(ctx, dst, tu) {
+					return
+				}
+				continue
+			}
+			base = id
+			group = append(group, tu)
+			keys = append(keys, selectors)
+		}
+		flush()
+	}
+
+	go rebuilder()
+	return dst, errChan
+}
+
+// rebuildUnit returns the unit with the ID base rebuilt from its variants.
+func rebuildUnit(base string, group []xliff.TranslationUnit, keys [][]icu.Selector) (tu xliff.TranslationUnit, err error) {
+	var sources, targets []icu.Variant
+	for i, unit := range group {
+		var v icu.Variant
+		if v, err = icu.ParseVariant(keys[i], xliff.XMLUnescape(unit.Source)); err != nil {
+			err = fmt.Errorf("Failed to rebuild the source of %q (%v)", xliff.XMLUnescape(unit.ID), err)
+			return
+		}
+		sources = append(sources, v)
+		if len(unit.Target) == 0 {
+			continue
+		}
+		if v, err = icu.ParseVariant(keys[i], xliff.XMLUnescape(unit.Target)); err != nil {
+			err = fmt.Errorf("Failed to rebuild the target of %q (%v)", xliff.XMLUnescape(unit.ID), err)
+			return
+		}
+		targets = append(targets, v)
+	}
+
+	tu = group[0]
+	tu.ID = base
+	source, err := icu.Rebuild(sources)
+	if err != nil {
+		err = fmt.Errorf("Failed to rebuild the source of %q (%v)", xliff.XMLUnescape(base), err)
+		return
+	}
+	tu.Source = xliff.XMLEscapeLoose(source.String())
+	tu.Target = ""
+	if len(targets) == len(sources) {
+		var target icu.Message
+		if target, err = icu.Rebuild(targets); err != nil {
+			err = fmt.Errorf("Failed to rebuild the target of %q (%v)", xliff.XMLUnescape(base), err)
+			return
+		}
+		tu.Target = xliff.XMLEscapeLoose(target.String())
+	}
+	return
+}
+
+// splitVariantID splits the ID of a unit flattened by FlattenICUUnits into
+// the ID of the original unit and the selectors of the variant.
+func splitVariantID(id string) (base string, selectors []icu.Selector, ok bool) {
+	i := strings.LastIndexByte(id, '#')
+	if i < 0 {
+		return
+	}
+	selectors, err := icu.ParseKey(id[i+1:])
+	if err != nil {
+		return
+	}
+	return id[:i], selectors, true
+}
+
+// unitLocale returns the target locale of tu, or its source locale when the
+// file has no valid target language.
+func unitLocale(tu xliff.TranslationUnit) locale.Locale {
+	if tu.File == nil {
+		return locale.Locale{}
+	}
+	for _, lang := range []string{tu.File.TargetLanguage, tu.File.SourceLanguage} {
+		if l, err := locale.Parse(lang); err == nil {
+			return l
+		}
+	}
+	return locale.Locale{}
+}
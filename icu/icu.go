@@ -0,0 +1,467 @@
+// Package icu parses and validates ICU MessageFormat messages like
+//
+//	{count, plural, one {# file} other {# files}} in {folder}
+//
+// and flattens their plural and select arguments into plain variants, one
+// per combination of branches, so translators never see the raw syntax.
+package icu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plural"
+)
+
+// Argument types with branches.
+const (
+	Plural        = "plural"
+	SelectOrdinal = "selectordinal"
+	Select        = "select"
+)
+
+// Message is a parsed ICU message, a sequence of Text, Pound and Arg parts.
+type Message []Part
+
+// Part is a part of a message.
+type Part interface {
+	write(b *strings.Builder, inPlural bool)
+}
+
+// Text is literal text, without ICU quoting.
+type Text string
+
+// Pound is the # in a plural branch, it stands for the number.
+type Pound struct{}
+
+// Arg is an argument like {name}, {n, number, integer} or an argument with
+// branches like {n, plural, one {...} other {...}}. Type is empty for a
+// simple argument.
+type Arg struct {
+	Name     string
+	Type     string
+	Style    string
+	Offset   int
+	Branches []Branch
+}
+
+// Branch is a branch of a plural, selectordinal or select argument. Key is a
+// plural category, an exact value like =0 or a select keyword.
+type Branch struct {
+	Key     string
+	Message Message
+}
+
+// HasBranches reports whether a is a plural, selectordinal or select
+// argument.
+func (a *Arg) HasBranches() bool {
+	return a.Type == Plural || a.Type == SelectOrdinal || a.Type == Select
+}
+
+// Parse parses the ICU message s and validates its syntax.
+func Parse(s string) (Message, error) {
+	p := &parser{s: s}
+	m, err := p.message(false)
+	if err == nil && p.pos < len(p.s) {
+		err = p.errorf("unexpected %q", p.s[p.pos])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// String returns the message in ICU syntax.
+func (m Message) String() string {
+	b := &strings.Builder{}
+	m.write(b, false)
+	return b.String()
+}
+
+func (m Message) write(b *strings.Builder, inPlural bool) {
+	for _, p := range m {
+		p.write(b, inPlural)
+	}
+}
+
+func (t Text) write(b *strings.Builder, inPlural bool) {
+	// Quote from the first special character up to the end of the text.
+	quoted := false
+	for _, r := range string(t) {
+		switch {
+		case r == '\'':
+			b.WriteString("''")
+			continue
+		case !quoted && (r == '{' || r == '}' || (inPlural && r == '#')):
+			b.WriteByte('\'')
+			quoted = true
+		}
+		b.WriteRune(r)
+	}
+	if quoted {
+		b.WriteByte('\'')
+	}
+}
+
+func (Pound) write(b *strings.Builder, inPlural bool) {
+	b.WriteByte('#')
+}
+
+func (a *Arg) write(b *strings.Builder, inPlural bool) {
+	b.WriteByte('{')
+	b.WriteString(a.Name)
+	if len(a.Type) > 0 {
+		b.WriteString(", ")
+		b.WriteString(a.Type)
+	}
+	if len(a.Style) > 0 {
+		b.WriteString(", ")
+		b.WriteString(a.Style)
+	}
+	if a.HasBranches() {
+		b.WriteByte(',')
+		if a.Offset != 0 {
+			fmt.Fprintf(b, " offset:%d", a.Offset)
+		}
+		for _, br := range a.Branches {
+			b.WriteByte(' ')
+			b.WriteString(br.Key)
+			b.WriteString(" {")
+			br.Message.write(b, a.Type != Select)
+			b.WriteByte('}')
+		}
+	}
+	b.WriteByte('}')
+}
+
+// Args returns the types of the arguments of m by name, including those
+// nested in branches. An argument used with several types has them all,
+// sorted and separated by commas.
+func (m Message) Args() map[string]string {
+	types := map[string]map[string]bool{}
+	m.walk(func(a *Arg) {
+		if types[a.Name] == nil {
+			types[a.Name] = map[string]bool{}
+		}
+		types[a.Name][a.Type] = true
+	})
+	args := map[string]string{}
+	for name, set := range types {
+		var list []string
+		for t := range set {
+			list = append(list, t)
+		}
+		sort.Strings(list)
+		args[name] = strings.Join(list, ",")
+	}
+	return args
+}
+
+// HasBranches reports whether m contains plural, selectordinal or select
+// arguments.
+func (m Message) HasBranches() (found bool) {
+	m.walk(func(a *Arg) {
+		found = found || a.HasBranches()
+	})
+	return
+}
+
+// walk calls f for every argument of m, depth first.
+func (m Message) walk(f func(a *Arg)) {
+	for _, p := range m {
+		if a, ok := p.(*Arg); ok {
+			f(a)
+			for _, br := range a.Branches {
+				br.Message.walk(f)
+			}
+		}
+	}
+}
+
+// Compare checks that target uses the same arguments with the same types as
+// source. A nil error is returned when they match.
+func Compare(source, target Message) error {
+	src, tgt := source.Args(), target.Args()
+	for _, name := range sortedKeys(src) {
+		t, ok := tgt[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("Missing argument {%s} of the source", name)
+		case t != src[name]:
+			return fmt.Errorf("Argument {%s} is %s, in the source it is %s", name, describe(t), describe(src[name]))
+		}
+	}
+	for _, name := range sortedKeys(tgt) {
+		if _, ok := src[name]; !ok {
+			return fmt.Errorf("Argument {%s} is not in the source", name)
+		}
+	}
+	return nil
+}
+
+func describe(types string) string {
+	if len(types) == 0 {
+		return "a simple argument"
+	}
+	return "of type " + types
+}
+
+// CheckPlurals checks that every plural argument of m has a branch for
+// exactly the plural categories the locale l needs, besides exact values like
+// =0.
+func CheckPlurals(m Message, l locale.Locale) (err error) {
+	m.walk(func(a *Arg) {
+		if a.Type != Plural || err != nil {
+			return
+		}
+		forms := plural.Forms{}
+		for _, br := range a.Branches {
+			if !strings.HasPrefix(br.Key, "=") {
+				forms[plural.Category(br.Key)] = plural.Form{}
+			}
+		}
+		if e := plural.Validate(l, forms); e != nil {
+			err = fmt.Errorf("Invalid plural argument {%s} (%v)", a.Name, e)
+		}
+	})
+	return
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// parser is a recursive descent parser of ICU messages.
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid ICU message at offset %d (%s)", p.pos, fmt.Sprintf(format, args...))
+}
+
+// message parses a message up to the end of s or the } that closes it.
+func (p *parser) message(inPlural bool) (m Message, err error) {
+	text := &strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			m = append(m, Text(text.String()))
+			text.Reset()
+		}
+	}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '}':
+			flush()
+			return
+		case c == '{':
+			flush()
+			var a *Arg
+			if a, err = p.arg(); err != nil {
+				return
+			}
+			m = append(m, a)
+		case c == '#' && inPlural:
+			flush()
+			m = append(m, Pound{})
+			p.pos++
+		case c == '\'':
+			p.quoted(text, inPlural)
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+	flush()
+	return
+}
+
+// quoted handles an apostrophe: two apostrophes are a literal one, an apostrophe
+// before a special character starts quoted literal text that runs up to the
+// next single apostrophe, any other apostrophe is literal.
+func (p *parser) quoted(text *strings.Builder, inPlural bool) {
+	p.pos++
+	if p.pos >= len(p.s) {
+		text.WriteByte('\'')
+		return
+	}
+	switch c := p.s[p.pos]; {
+	case c == '\'':
+		text.WriteByte('\'')
+		p.pos++
+		return
+	case c == '{' || c == '}' || c == '|' || (c == '#' && inPlural):
+	default:
+		text.WriteByte('\'')
+		return
+	}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c != '\'' {
+			text.WriteByte(c)
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '\'' {
+			text.WriteByte('\'')
+			p.pos++
+			continue
+		}
+		return
+	}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// ident parses a name, a type or a select keyword.
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return p.errorf("expected %q, found the end of the message", c)
+	}
+	if p.s[p.pos] != c {
+		return p.errorf("expected %q, found %q", c, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// arg parses an argument starting at its {.
+func (p *parser) arg() (a *Arg, err error) {
+	p.pos++
+	p.skipSpace()
+	a = &Arg{Name: p.ident()}
+	if len(a.Name) == 0 {
+		return nil, p.errorf("missing argument name")
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == ',' {
+		p.pos++
+		p.skipSpace()
+		if a.Type = p.ident(); len(a.Type) == 0 {
+			return nil, p.errorf("missing type of argument {%s}", a.Name)
+		}
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			if a.HasBranches() {
+				err = p.branches(a)
+			} else {
+				err = p.style(a)
+			}
+			if err != nil {
+				return
+			}
+		} else if a.HasBranches() {
+			return nil, p.errorf("missing branches of argument {%s}", a.Name)
+		}
+	}
+	if err = p.expect('}'); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// style parses the style of a simple argument, up to its closing }.
+func (p *parser) style(a *Arg) error {
+	p.skipSpace()
+	start, depth := p.pos, 0
+	for ; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				a.Style = strings.TrimSpace(p.s[start:p.pos])
+				return nil
+			}
+			depth--
+		}
+	}
+	return p.errorf("unterminated argument {%s}", a.Name)
+}
+
+// branches parses the optional offset and the branches of a plural,
+// selectordinal or select argument.
+func (p *parser) branches(a *Arg) error {
+	p.skipSpace()
+	if a.Type != Select && strings.HasPrefix(p.s[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			a.Offset = 10*a.Offset + int(p.s[p.pos]-'0')
+			p.pos++
+		}
+		if p.pos == start {
+			return p.errorf("missing offset of argument {%s}", a.Name)
+		}
+	}
+	keys := map[string]bool{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] == '}' {
+			break
+		}
+		start := p.pos
+		if p.s[p.pos] == '=' && a.Type != Select {
+			p.pos++
+		}
+		key := p.s[start:p.pos] + p.ident()
+		if len(key) == 0 || key == "=" {
+			return p.errorf("missing branch key of argument {%s}", a.Name)
+		}
+		if a.Type != Select && !strings.HasPrefix(key, "=") {
+			if _, err := plural.ParseCategory(key); err != nil {
+				p.pos = start
+				return p.errorf("unknown plural category %q in argument {%s}", key, a.Name)
+			}
+		}
+		if keys[key] {
+			p.pos = start
+			return p.errorf("duplicate branch %q in argument {%s}", key, a.Name)
+		}
+		keys[key] = true
+		if err := p.expect('{'); err != nil {
+			return err
+		}
+		m, err := p.message(a.Type != Select)
+		if err != nil {
+			return err
+		}
+		if err = p.expect('}'); err != nil {
+			return err
+		}
+		a.Branches = append(a.Branches, Branch{Key: key, Message: m})
+	}
+	if !keys["other"] {
+		return p.errorf("missing other branch in argument {%s}", a.Name)
+	}
+	return nil
+}
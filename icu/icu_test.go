@@ -0,0 +1,157 @@
+package icu
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+var tvParse = []struct {
+	in, out string
+}{
+	{"Hello", "Hello"},
+	{"Hello {name}", "Hello {name}"},
+	{"{ n ,number,integer }", "{n, number, integer}"},
+	{"{n, plural, one {# file} other {# files}} in {folder}", "{n, plural, one {# file} other {# files}} in {folder}"},
+	{"{n,plural,offset:1 =0{nobody} one{{who}} other{{who} and # others}}", "{n, plural, offset:1 =0 {nobody} one {{who}} other {{who} and # others}}"},
+	{"{g, select, female {her} other {their}} {n, selectordinal, one {#st} other {#th}}", "{g, select, female {her} other {their}} {n, selectordinal, one {#st} other {#th}}"},
+	{"It's '{name}'", "It''s '{name}'"},
+	{"Use '' and '{''}'", "Use '' and '{''}'"},
+	{"{n, plural, other {'#' is #}}", "{n, plural, other {'# is '#}}"},
+	{"# {g, select, other {#}}", "# {g, select, other {#}}"},
+}
+
+func TestParse(t *testing.T) {
+	for _, tv := range tvParse {
+		m, err := Parse(tv.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tv.in, err)
+			continue
+		}
+		if s := m.String(); s != tv.out {
+			t.Errorf("Parse(%q): expected %q got %q", tv.in, tv.out, s)
+		}
+		again, err := Parse(m.String())
+		if err != nil || !reflect.DeepEqual(again, m) {
+			t.Errorf("Parse(%q): %q doesn't parse back (%v)", tv.in, m, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"{",
+		"{}",
+		"Hello {name",
+		"Hello }",
+		"{n, plural}",
+		"{n, plural, one {#}}",
+		"{n, plural, few {#} other {#} few {#}}",
+		"{n, plural, lots {#} other {#}}",
+		"{n, plural, one # other {#}}",
+		"{n, number, {x}",
+	} {
+		if m, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q): expected an error got %q", in, m)
+		} else if !strings.HasPrefix(err.Error(), "Invalid ICU message at offset") {
+			t.Errorf("Parse(%q): unexpected error %v", in, err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	src, _ := Parse("{n, plural, one {# file} other {# files}} in {folder}")
+	for _, tv := range []struct {
+		target string
+		ok     bool
+	}{
+		{"{folder} : {n, plural, one {# fichier} other {# fichiers}}", true},
+		{"{n, plural, one {# fichier} other {# fichiers}}", false},
+		{"{n, plural, other {# fichiers}} dans {folder} {x}", false},
+		{"{n, number} fichiers dans {folder}", false},
+	} {
+		tgt, err := Parse(tv.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Compare(src, tgt); (err == nil) != tv.ok {
+			t.Errorf("Compare(%q): expected ok %v got %v", tv.target, tv.ok, err)
+		}
+	}
+}
+
+func TestCheckPlurals(t *testing.T) {
+	ru := locale.MustParse("ru")
+	m, _ := Parse("{n, plural, =0 {нет} one {# файл} few {# файла} many {# файлов} other {# файла}}")
+	if err := CheckPlurals(m, ru); err != nil {
+		t.Error(err)
+	}
+	m, _ = Parse("{g, select, other {{n, plural, one {# файл} other {# файла}}}}")
+	if err := CheckPlurals(m, ru); err == nil {
+		t.Errorf("Expected an error for the missing few and many branches")
+	}
+}
+
+func TestExpandRebuild(t *testing.T) {
+	m, _ := Parse("{g, select, female {She has} other {They have}} {n, plural, one {# file} other {# files}}.")
+	variants := Expand(m)
+	var keys, texts []string
+	for _, v := range variants {
+		keys = append(keys, v.Key())
+		texts = append(texts, v.String())
+	}
+	expectKeys := []string{
+		"g:select=female,n:plural=one",
+		"g:select=female,n:plural=other",
+		"g:select=other,n:plural=one",
+		"g:select=other,n:plural=other",
+	}
+	expectTexts := []string{"She has # file.", "She has # files.", "They have # file.", "They have # files."}
+	if !reflect.DeepEqual(keys, expectKeys) || !reflect.DeepEqual(texts, expectTexts) {
+		t.Fatalf("Expected %q %q got %q %q", expectKeys, expectTexts, keys, texts)
+	}
+
+	var parsed []Variant
+	for i := range variants {
+		selectors, err := ParseKey(keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ParseVariant(selectors, texts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, v)
+	}
+	rebuilt, err := Rebuild(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "{g, select, female {{n, plural, one {She has # file.} other {She has # files.}}} other {{n, plural, one {They have # file.} other {They have # files.}}}}"
+	if s := rebuilt.String(); s != expect {
+		t.Errorf("Expected %q got %q", expect, s)
+	}
+	if err = Compare(m, rebuilt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExpandFor(t *testing.T) {
+	m, _ := Parse("{n, plural, offset:1 =0 {none} one {# file} other {# files}}")
+	var keys []string
+	for _, v := range ExpandFor(m, locale.MustParse("ru")) {
+		keys = append(keys, v.Key())
+	}
+	expect := []string{"n:plural:1==0", "n:plural:1=one", "n:plural:1=few", "n:plural:1=many", "n:plural:1=other"}
+	if !reflect.DeepEqual(keys, expect) {
+		t.Errorf("Expected %q got %q", expect, keys)
+	}
+	if _, err := ParseKey(keys[0]); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseKey("n:number=one"); err == nil {
+		t.Errorf("Expected an error for a number selector")
+	}
+}
@@ -0,0 +1,208 @@
+package icu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plural"
+)
+
+// Selector picks a branch of a plural, selectordinal or select argument.
+type Selector struct {
+	Name   string
+	Type   string
+	Offset int
+	Key    string
+}
+
+// String returns the selector as name:type=key, or name:type:offset=key
+// when the argument has an offset.
+func (s Selector) String() string {
+	if s.Offset != 0 {
+		return fmt.Sprintf("%s:%s:%d=%s", s.Name, s.Type, s.Offset, s.Key)
+	}
+	return fmt.Sprintf("%s:%s=%s", s.Name, s.Type, s.Key)
+}
+
+// sameArg reports whether s and o select a branch of the same argument.
+func (s Selector) sameArg(o Selector) bool {
+	return s.Name == o.Name && s.Type == o.Type && s.Offset == o.Offset
+}
+
+// Variant is a message with all its plural, selectordinal and select
+// arguments resolved by its selectors. Its message only holds text, simple
+// arguments and the # of plural branches.
+type Variant struct {
+	Selectors []Selector
+	Message   Message
+}
+
+// Key returns the selectors of v separated by commas, like
+// count:plural=one,gender:select=female.
+func (v Variant) Key() string {
+	list := make([]string, len(v.Selectors))
+	for i, s := range v.Selectors {
+		list[i] = s.String()
+	}
+	return strings.Join(list, ",")
+}
+
+// String returns the message of v in ICU syntax. A literal # is quoted, as
+// the message is always parsed as a plural branch by ParseVariant.
+func (v Variant) String() string {
+	b := &strings.Builder{}
+	v.Message.write(b, true)
+	return b.String()
+}
+
+// ParseKey parses the selectors of a key returned by Variant.Key.
+func ParseKey(key string) (selectors []Selector, err error) {
+	for _, s := range strings.Split(key, ",") {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid variant key %q (missing =)", key)
+		}
+		sel := Selector{Key: s[i+1:]}
+		fields := strings.Split(s[:i], ":")
+		switch len(fields) {
+		case 3:
+			if sel.Offset, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("Invalid variant key %q (%v)", key, err)
+			}
+			fallthrough
+		case 2:
+			sel.Name, sel.Type = fields[0], fields[1]
+		default:
+			return nil, fmt.Errorf("Invalid variant key %q (name:type expected)", key)
+		}
+		a := &Arg{Type: sel.Type}
+		if len(sel.Name) == 0 || len(sel.Key) == 0 || !a.HasBranches() {
+			return nil, fmt.Errorf("Invalid variant key %q", key)
+		}
+		selectors = append(selectors, sel)
+	}
+	return
+}
+
+// ParseVariant parses the message of a variant, which can't contain plural,
+// selectordinal or select arguments.
+func ParseVariant(selectors []Selector, s string) (v Variant, err error) {
+	p := &parser{s: s}
+	m, err := p.message(true)
+	if err == nil && p.pos < len(p.s) {
+		err = p.errorf("unexpected %q", p.s[p.pos])
+	}
+	if err == nil && m.HasBranches() {
+		err = fmt.Errorf("Invalid ICU variant (plural and select arguments are not allowed)")
+	}
+	if err != nil {
+		return
+	}
+	return Variant{Selectors: selectors, Message: m}, nil
+}
+
+// Expand returns the variants of m, one per combination of branches. Text
+// around an argument with branches is repeated in every variant, so each
+// variant is a complete sentence. A message without branches has a single
+// variant without selectors.
+func Expand(m Message) []Variant {
+	return ExpandFor(m, locale.Locale{})
+}
+
+// ExpandFor returns the variants of m like Expand, but the plural arguments
+// have exactly the branches the locale l needs: their =n branches and one per
+// plural category of l. A category m lacks gets the other branch, so an
+// English source expands to the four variants a Russian translation needs.
+// The zero locale keeps the branches of m.
+func ExpandFor(m Message, l locale.Locale) []Variant {
+	variants := []Variant{{}}
+	for _, p := range m {
+		a, ok := p.(*Arg)
+		if !ok || !a.HasBranches() {
+			for i := range variants {
+				variants[i].Message = append(variants[i].Message, p)
+			}
+			continue
+		}
+
+		var expanded []Variant
+		for _, v := range variants {
+			for _, br := range branchesFor(a, l) {
+				sel := Selector{Name: a.Name, Type: a.Type, Offset: a.Offset, Key: br.Key}
+				for _, sub := range ExpandFor(br.Message, l) {
+					selectors := append(append(append([]Selector{}, v.Selectors...), sel), sub.Selectors...)
+					message := append(append(Message{}, v.Message...), sub.Message...)
+					expanded = append(expanded, Variant{Selectors: selectors, Message: message})
+				}
+			}
+		}
+		variants = expanded
+	}
+	return variants
+}
+
+// branchesFor returns the branches of a the locale l needs.
+func branchesFor(a *Arg, l locale.Locale) []Branch {
+	if a.Type != Plural || l.IsZero() {
+		return a.Branches
+	}
+	var branches []Branch
+	byKey := map[string]Message{}
+	for _, br := range a.Branches {
+		byKey[br.Key] = br.Message
+		if strings.HasPrefix(br.Key, "=") {
+			branches = append(branches, br)
+		}
+	}
+	for _, c := range plural.Categories(l) {
+		m, ok := byKey[string(c)]
+		if !ok {
+			m = byKey[string(plural.Other)]
+		}
+		branches = append(branches, Branch{Key: string(c), Message: m})
+	}
+	return branches
+}
+
+// Rebuild returns the message of variants, the reverse of Expand. The
+// selectors of the variants must all start with the same argument and are
+// nested in order. Text that Expand repeated in every variant stays inside the
+// branches, which is equivalent.
+func Rebuild(variants []Variant) (Message, error) {
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("Failed to rebuild ICU message (no variants)")
+	}
+	if len(variants[0].Selectors) == 0 {
+		if len(variants) > 1 {
+			return nil, fmt.Errorf("Failed to rebuild ICU message (%d variants without selectors)", len(variants))
+		}
+		return variants[0].Message, nil
+	}
+
+	first := variants[0].Selectors[0]
+	a := &Arg{Name: first.Name, Type: first.Type, Offset: first.Offset}
+	groups := map[string][]Variant{}
+	for _, v := range variants {
+		if len(v.Selectors) == 0 || !v.Selectors[0].sameArg(first) {
+			return nil, fmt.Errorf("Failed to rebuild ICU message (variant %q doesn't select argument {%s})", v.Key(), first.Name)
+		}
+		key := v.Selectors[0].Key
+		if _, ok := groups[key]; !ok {
+			a.Branches = append(a.Branches, Branch{Key: key})
+		}
+		groups[key] = append(groups[key], Variant{Selectors: v.Selectors[1:], Message: v.Message})
+	}
+	for i := range a.Branches {
+		m, err := Rebuild(groups[a.Branches[i].Key])
+		if err != nil {
+			return nil, err
+		}
+		a.Branches[i].Message = m
+	}
+	if _, ok := groups["other"]; !ok {
+		return nil, fmt.Errorf("Failed to rebuild ICU message (missing other branch in argument {%s})", a.Name)
+	}
+	return Message{a}, nil
+}
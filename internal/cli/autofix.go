@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/autofix"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var autofixCommand = &Command{
+	Name:    "autofix",
+	Summary: "fix mechanical issues in translations",
+	Help: `Fixes mechanical issues in the translations of the -target .strings file, like a missing
+trailing ellipsis or French punctuation spacing. The Ctx of every entry is expected to hold
+the source string, as in the files written by translate. Format specifiers are never changed.
+Without -w or -out the changes are only reported (dry-run).`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		tgtName := fs.String("target", "", ".strings file in target language to fix")
+		outName := fs.String("out", "", "file to write the fixed strings to")
+		lang := fs.String("lang", "", "language of the -target file (default: the locale of the -target name)")
+		disable := fs.String("disable", "", "comma separated list of fixes to disable")
+		doWrite := fs.Bool("w", false, "write the fixed strings back to the -target file")
+		doList := fs.Bool("list", false, "list the available fixes")
+		return func(env *Env, args []string) (err error) {
+			if *doList {
+				for _, f := range autofix.DefaultFixes() {
+					languages := "all"
+					if len(f.Languages) > 0 {
+						languages = strings.Join(f.Languages, ",")
+					}
+					fmt.Fprintf(env.Stdout, "%-12s %-6s %s\n", f.Name, languages, f.Description)
+				}
+				return
+			}
+
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if len(*tgtName) == 0 {
+				return usagef("-target is required")
+			}
+			if *doWrite && (len(*outName) > 0 || *tgtName == "-") {
+				return usagef("-w can't be combined with -out or a -target read from standard input")
+			}
+			if err = stringsFlag(*tgtName, "-target", true); err != nil {
+				return
+			}
+			if *lang, err = flagLang("-lang", *lang, *tgtName); err != nil {
+				return
+			}
+
+			fixes, err := selectFixes(*disable)
+			if err != nil {
+				return
+			}
+			fixer := autofix.New(*lang, fixes...)
+
+			// Perform the fixes into an in memory bytes.Buffer
+			resultBuf := &bytes.Buffer{}
+			n, err := fixTo(env, fixer, *tgtName, resultBuf)
+			if err != nil {
+				return
+			}
+
+			// The changes go to standard output, unless the fixed strings do.
+			changes := fixer.Changes()
+			w := env.Stdout
+			if *outName == "-" {
+				w = env.Stderr
+			}
+			for _, c := range changes {
+				fmt.Fprintf(w, "%s: %v\n", *tgtName, c)
+			}
+
+			if *doWrite {
+				*outName = *tgtName
+			}
+			if len(*outName) == 0 {
+				env.Logf("%d\tStrings would be changed in %q\n", len(changes), *tgtName)
+				return
+			}
+
+			outFile, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer outFile.Close()
+
+			if _, err = resultBuf.WriteTo(dotstrings.NewWriterUTF16(outFile)); err != nil {
+				return
+			}
+			if err = outFile.Commit(); err != nil {
+				return
+			}
+			env.Logf("%d\tStrings changed, %d strings written to %q\n", len(changes), n, *outName)
+			return
+		}
+	},
+}
+
+// selectFixes returns the default fixes without the comma separated list of
+// disabled fixes, every name in it must be one of the default fixes.
+func selectFixes(disable string) (fixes []*autofix.Fix, err error) {
+	disabled := make(map[string]bool)
+	if len(disable) > 0 {
+		for _, name := range strings.Split(disable, ",") {
+			disabled[name] = false
+		}
+	}
+	for _, f := range autofix.DefaultFixes() {
+		if _, ok := disabled[f.Name]; ok {
+			disabled[f.Name] = true
+			continue
+		}
+		fixes = append(fixes, f)
+	}
+	for name, found := range disabled {
+		if !found {
+			return nil, usagef("unknown fix %q in -disable, see -list for the available fixes", name)
+		}
+	}
+	return
+}
+
+func fixTo(env *Env, fixer *autofix.Fixer, tgtName string, buf *bytes.Buffer) (n int, err error) {
+	tgtFile, err := env.Open(tgtName)
+	if err != nil {
+		return
+	}
+	defer tgtFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Asynchronously load the messages and fix them
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(tgtFile))
+	msgChan = fixer.FixMessages(ctx, msgChan)
+
+	// Synchronously save the fixed messages to the buffer
+	n, err = dotstrings.SaveMessages(msgChan, buf)
+
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/simpleapps-eu/translate/check"
+	"github.com/simpleapps-eu/translate/config"
+	"github.com/simpleapps-eu/translate/project"
+)
+
+var checkCommand = &Command{
+	Name:    "check",
+	Summary: "fail when translations are missing, fuzzy, obsolete or have invalid placeholders",
+	Help: `Checks the translations of every target locale without writing any file, for use in CI. The
+locales are those of the project described by the -config file, or of the -root resource
+directory when given. The check of a locale fails when the percentage of its entries that are
+missing, fuzzy, obsolete or have placeholders not matching the source exceeds its limit. The
+limits default to 0 and are read from the "thresholds" of the configuration or the -thresholds
+file, e.g.
+
+  {"*": {"fuzzy": 10}, "ja": {"missing": 5}, "pt": {"missing": 2}}
+
+The report is written as text, json, github (GitHub Actions annotations) or junit (JUnit XML).
+
+  e.g. check -format github
+       check -root MyApp/Resources -thresholds thresholds.json -format junit -out check.xml`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		configName := fs.String("config", "translate.json", "project configuration file")
+		rootName := fs.String("root", "", "directory containing the .lproj directories, used instead of -config")
+		base := fs.String("base", "", "base locale holding the source strings for -root (default: en, en-US or Base)")
+		thresholdsName := fs.String("thresholds", "", "JSON file with the limits per locale, overrides those of the configuration")
+		format := fs.String("format", "text", "format of the report: text, json, github or junit")
+		outName := fs.String("out", "-", "file to write the report to")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			write, ok := checkFormats[*format]
+			if !ok {
+				return usagef("unsupported format %q, expected text, json, github or junit", *format)
+			}
+
+			var files map[string][]check.File
+			var locales []string
+			var thresholds check.Thresholds
+			if len(*rootName) > 0 {
+				locales, files, err = projectFiles(*rootName, *base)
+			} else {
+				var c *config.Config
+				if c, err = config.LoadFile(*configName); err != nil {
+					return
+				}
+				locales, thresholds = c.TargetLocales, c.Thresholds
+				files, err = configFiles(c)
+			}
+			if err != nil {
+				return
+			}
+			if len(*thresholdsName) > 0 {
+				if thresholds, err = loadThresholds(*thresholdsName); err != nil {
+					return
+				}
+			}
+
+			report := &check.Report{}
+			for _, locale := range locales {
+				var l *check.Locale
+				if l, err = check.CheckLocale(env.Context(), locale, files[locale], thresholds); err != nil {
+					return
+				}
+				report.Locales = append(report.Locales, l)
+			}
+
+			out, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer out.Close()
+			if err = write(report, out); err != nil {
+				return
+			}
+			if err = out.Commit(); err != nil {
+				return
+			}
+
+			failed := 0
+			for _, l := range report.Locales {
+				if l.Failed() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				err = IssuesError(fmt.Sprintf("%d of %d locales failed the check", failed, len(report.Locales)))
+			}
+			return
+		}
+	},
+}
+
+var checkFormats = map[string]func(r *check.Report, w io.Writer) error{
+	"text":   (*check.Report).WriteText,
+	"json":   (*check.Report).WriteJSON,
+	"github": (*check.Report).WriteGitHub,
+	"junit":  (*check.Report).WriteJUnit,
+}
+
+// projectFiles returns the target locales of the resource directory root and
+// the files of every locale.
+func projectFiles(root, base string) (locales []string, files map[string][]check.File, err error) {
+	p, err := project.Discover(root, base)
+	if err != nil {
+		return
+	}
+	files = make(map[string][]check.File)
+	for _, locale := range p.Locales {
+		for _, table := range p.Tables {
+			files[locale] = append(files[locale], check.File{Source: p.Path(p.Base, table), Target: p.Path(locale, table)})
+		}
+	}
+	return p.Locales, files, nil
+}
+
+// configFiles returns the files of every target locale of c.
+func configFiles(c *config.Config) (files map[string][]check.File, err error) {
+	files = make(map[string][]check.File)
+	for _, locale := range c.TargetLocales {
+		for _, f := range c.Files {
+			var pairs []config.Pair
+			if pairs, err = c.Pairs(f, locale); err != nil {
+				return
+			}
+			for _, pair := range pairs {
+				files[locale] = append(files[locale], check.File{Source: pair.Source, Target: pair.Target})
+			}
+		}
+	}
+	return
+}
+
+func loadThresholds(name string) (t check.Thresholds, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	if err = t.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid thresholds in %q (%v)", name, err)
+	}
+	return
+}
@@ -0,0 +1,298 @@
+// Package cli implements the translate command and its subcommands. The
+// standalone commands (xliff, xlate, fuzzy, stringsfmt, tplex, lint, report,
+// autofix and lproj) are thin aliases of these subcommands, see Legacy.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/atomicfile"
+)
+
+// Exit codes returned by Main and Legacy.
+const (
+	// ExitOK is returned when the command succeeded.
+	ExitOK = 0
+	// ExitIssues is returned when the command ran but found issues, e.g.
+	// lint findings or glossary violations.
+	ExitIssues = 1
+	// ExitError is returned for invalid flags or arguments and when the
+	// command failed, e.g. because a file could not be read.
+	ExitError = 2
+)
+
+// Env is the environment a command runs in.
+type Env struct {
+	// Ctx is done when the command should stop, context.Background() is
+	// used when it is nil.
+	Ctx    context.Context
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Quiet suppresses the progress messages written by Logf.
+	Quiet bool
+	// Backup keeps the previous version of every file replaced as
+	// name.bak.
+	Backup bool
+	// legacyExit makes a failed command exit with 1 instead of ExitError,
+	// like the standalone commands did before they became aliases.
+	legacyExit bool
+}
+
+// NewEnv returns an environment using the standard input and output of the
+// process. Its context is done when the process is interrupted, so commands
+// stop without leaving partially written files behind.
+func NewEnv() *Env {
+	// The process exits when the command returns, so the signal handler
+	// doesn't need to be stopped.
+	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
+	return &Env{Ctx: ctx, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// Context returns the context of the command.
+func (env *Env) Context() context.Context {
+	if env.Ctx == nil {
+		return context.Background()
+	}
+	return env.Ctx
+}
+
+// Logf writes a progress message to Stderr, unless Quiet is set. Progress
+// messages never go to Stdout so it can be used for data.
+func (env *Env) Logf(format string, args ...interface{}) {
+	if !env.Quiet {
+		fmt.Fprintf(env.Stderr, format, args...)
+	}
+}
+
+// Open opens the file name for reading, the name "-" is Stdin.
+func (env *Env) Open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(env.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// Output is a file being written by a command.
+type Output interface {
+	io.WriteCloser
+	// Commit replaces the file by what was written. Closing an Output that
+	// isn't committed leaves the file untouched.
+	Commit() error
+}
+
+// Create creates the file name for writing, the name "-" is Stdout. The file
+// is written atomically, see atomicfile, and backed up when Backup is set.
+func (env *Env) Create(name string) (Output, error) {
+	if name == "-" {
+		return stdout{env.Stdout}, nil
+	}
+	f, err := atomicfile.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Backup = env.Backup
+	return f, nil
+}
+
+// stdout is standard output as Output, it can't be undone and is written
+// directly.
+type stdout struct {
+	io.Writer
+}
+
+func (stdout) Close() error  { return nil }
+func (stdout) Commit() error { return nil }
+
+// Command is a subcommand of translate.
+type Command struct {
+	Name string
+	// Args describes the arguments following the flags, if any.
+	Args    string
+	Summary string
+	// Help is shown above the flags by -h.
+	Help string
+	// Setup defines the flags of the command on fs and returns the function
+	// that runs the command with the remaining arguments. Commands that group
+	// subcommands have no Setup.
+	Setup    func(fs *flag.FlagSet) func(env *Env, args []string) error
+	Commands []*Command
+}
+
+// UsageError is returned by a command for invalid flags or arguments, the
+// usage of the command is shown after its message.
+type UsageError string
+
+func (e UsageError) Error() string { return string(e) }
+
+func usagef(format string, args ...interface{}) error {
+	return UsageError(fmt.Sprintf(format, args...))
+}
+
+// IssuesError is returned by a command that ran but found issues.
+type IssuesError string
+
+func (e IssuesError) Error() string { return string(e) }
+
+// Commands lists the subcommands of translate.
+var Commands = []*Command{
+	convertCommand,
+	translateCommand,
+	fuzzyCommand,
+	formatCommand,
+	templateCommand,
+	extractCommand,
+	ibCommand,
+	xcstringsCommand,
+	lintCommand,
+	autofixCommand,
+	pluralsCommand,
+	reportCommand,
+	lprojCommand,
+	syncCommand,
+	checkCommand,
+}
+
+const name = "translate"
+
+// Main runs the translate command with args, the arguments following the
+// program name, and returns the exit code.
+func Main(env *Env, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	dir := fs.String("C", "", "change to `dir` before running the command")
+	fs.BoolVar(&env.Quiet, "q", false, "don't write progress messages")
+	fs.BoolVar(&env.Backup, "backup", false, "keep the previous version of every file written as <file>.bak")
+	fs.Usage = func() {
+		fmt.Fprintf(env.Stderr, "Usage: %s [flags] <command> [arguments]\n\n", name)
+		fmt.Fprintln(env.Stderr, "The commands are:")
+		fmt.Fprintln(env.Stderr, "")
+		listCommands(env.Stderr, Commands)
+		fmt.Fprintln(env.Stderr, "")
+		fmt.Fprintln(env.Stderr, "The flags are:")
+		fmt.Fprintln(env.Stderr, "")
+		fs.PrintDefaults()
+		fmt.Fprintln(env.Stderr, "")
+		fmt.Fprintf(env.Stderr, "Use \"%s <command> -h\" for more information about a command.\n", name)
+		fmt.Fprintf(env.Stderr, "Exit codes: %d success, %d issues found, %d error.\n", ExitOK, ExitIssues, ExitError)
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitError
+	}
+	if len(*dir) > 0 {
+		if err := os.Chdir(*dir); err != nil {
+			fmt.Fprintf(env.Stderr, "%s: %v\n", name, err)
+			return ExitError
+		}
+	}
+	return dispatch(env, name, Commands, fs.Args(), fs.Usage)
+}
+
+// dispatch runs the command in commands named by args[0].
+func dispatch(env *Env, path string, commands []*Command, args []string, usage func()) int {
+	if len(args) == 0 {
+		usage()
+		return ExitError
+	}
+	if args[0] == "help" {
+		if len(args) > 1 {
+			return dispatch(env, path, commands, []string{args[1], "-h"}, usage)
+		}
+		usage()
+		return ExitOK
+	}
+	for _, c := range commands {
+		if c.Name == args[0] {
+			return run(env, path+" "+c.Name, c, args[1:])
+		}
+	}
+	fmt.Fprintf(env.Stderr, "%s: unknown command %q\n", path, args[0])
+	usage()
+	return ExitError
+}
+
+// run parses the flags of c and runs it.
+func run(env *Env, path string, c *Command, args []string) int {
+	if len(c.Commands) > 0 {
+		usage := func() {
+			fmt.Fprintf(env.Stderr, "Usage: %s <command> [arguments]\n\n", path)
+			if len(c.Help) > 0 {
+				fmt.Fprintln(env.Stderr, c.Help)
+				fmt.Fprintln(env.Stderr, "")
+			}
+			fmt.Fprintln(env.Stderr, "The commands are:")
+			fmt.Fprintln(env.Stderr, "")
+			listCommands(env.Stderr, c.Commands)
+		}
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			usage()
+			return ExitOK
+		}
+		return dispatch(env, path, c.Commands, args, usage)
+	}
+
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	runner := c.Setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(env.Stderr, "Usage: %s [flags] %s\n\n", path, c.Args)
+		if len(c.Help) > 0 {
+			fmt.Fprintln(env.Stderr, c.Help)
+			fmt.Fprintln(env.Stderr, "")
+		}
+		fmt.Fprintln(env.Stderr, "The flags are:")
+		fmt.Fprintln(env.Stderr, "")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitError
+	}
+
+	err := runner(env, fs.Args())
+	var usageErr UsageError
+	var issuesErr IssuesError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(env.Stderr, "%s: %v\n", path, err)
+		fs.Usage()
+		return ExitError
+	case errors.As(err, &issuesErr):
+		fmt.Fprintf(env.Stderr, "%s: %v\n", path, err)
+		return ExitIssues
+	default:
+		fmt.Fprintf(env.Stderr, "%s: %v\n", path, err)
+		if env.legacyExit {
+			return 1
+		}
+		return ExitError
+	}
+}
+
+func listCommands(w io.Writer, commands []*Command) {
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.Name, c.Summary)
+	}
+}
+
+// wantNoArgs returns a usage error when args is not empty.
+func wantNoArgs(args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", strings.Join(args, " "))
+	}
+	return nil
+}
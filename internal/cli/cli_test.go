@@ -0,0 +1,643 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/internal/testutil"
+)
+
+func runMain(stdin []byte, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	env := &Env{Stdin: bytes.NewReader(stdin), Stdout: &out, Stderr: &errOut}
+	code = Main(env, args)
+	return code, out.String(), errOut.String()
+}
+
+var tvExitCodes = []struct {
+	args []string
+	code int
+}{
+	{nil, ExitError},
+	{[]string{"-h"}, ExitOK},
+	{[]string{"help"}, ExitOK},
+	{[]string{"help", "lint"}, ExitOK},
+	{[]string{"lint", "--help"}, ExitOK},
+	{[]string{"fuzzy", "-h"}, ExitOK},
+	{[]string{"fuzzy"}, ExitError},
+	{[]string{"unknown"}, ExitError},
+	{[]string{"lint", "-unknown"}, ExitError},
+	{[]string{"lint"}, ExitError},
+	{[]string{"lproj", "-h"}, ExitOK},
+	{[]string{"lproj", "-translate"}, ExitError},
+	{[]string{"fuzzy", "count", "-source", "missing.strings", "-tm", "missing.strings"}, ExitError},
+}
+
+func TestExitCodes(t *testing.T) {
+	for i, tv := range tvExitCodes {
+		if code, _, _ := runMain(nil, tv.args...); code != tv.code {
+			t.Errorf("Expected exit code %d for tvExitCodes[%d] %q got %d", tv.code, i, tv.args, code)
+		}
+	}
+}
+
+func TestStdinStdout(t *testing.T) {
+	genstrings := "/* No comment provided by engineer. */\n\"Loading...\" = \"Loading...\";\n"
+	code, stdout, stderr := runMain(testutil.UTF16(t, genstrings), "format", "-")
+	if code != ExitOK {
+		t.Fatalf("Expected format to succeed got %d: %s", code, stderr)
+	}
+	msgChan, errChan := dotstrings.LoadMessages(context.Background(), dotstrings.NewReaderUTF16(strings.NewReader(stdout)))
+	m := <-msgChan
+	for range msgChan {
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if m.Ctx != "Loading..." {
+		t.Errorf("Expected formatted message on standard output got %+v", m)
+	}
+}
+
+func TestLintIssues(t *testing.T) {
+	dir := t.TempDir()
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open file */\n\"open\" = \"Open file\";\n")
+	tgt := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Open file */\n\"open\" = \"Ouvrir  le fichier\";\n")
+
+	code, stdout, _ := runMain(nil, "-q", "lint", "-source", src, "-target", tgt, "-fail", "warning")
+	if code != ExitIssues {
+		t.Errorf("Expected exit code %d got %d", ExitIssues, code)
+	}
+	if !strings.Contains(stdout, "double-space") {
+		t.Errorf("Expected double-space issue in report got %q", stdout)
+	}
+	if code, _, _ = runMain(nil, "lint", "-source", src, "-target", tgt); code != ExitOK {
+		t.Errorf("Expected warnings not to fail by default got %d", code)
+	}
+	if code, _, _ = runMain(nil, "-q", "lint", "-source", src, "-target", tgt, "-fail", "warning", "-level", "double-space=info"); code != ExitOK {
+		t.Errorf("Expected -level to lower the severity got %d", code)
+	}
+	code, _, stderr := runMain(nil, "lint", "-source", src, "-target", tgt, "-level", "double-spaces=error")
+	if code != ExitError || !strings.Contains(stderr, `unknown rule "double-spaces"`) {
+		t.Errorf("Expected usage error for unknown rule in -level got %d: %q", code, stderr)
+	}
+}
+
+// writeFile writes the UTF-8 contents s to the file name and returns name.
+func writeFile(t *testing.T, name, s string) string {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// stringsdict returns a .stringsdict file with a files variable with the
+// texts by category.
+func stringsdict(forms ...string) string {
+	var b strings.Builder
+	b.WriteString("<plist version=\"1.0\"><dict><key>%d files</key><dict>")
+	b.WriteString("<key>NSStringLocalizedFormatKey</key><string>%#@files@</string><key>files</key><dict>")
+	b.WriteString("<key>NSStringFormatSpecTypeKey</key><string>NSStringPluralRuleType</string>")
+	for i := 0; i < len(forms); i += 2 {
+		b.WriteString("<key>" + forms[i] + "</key><string>" + forms[i+1] + "</string>")
+	}
+	b.WriteString("</dict></dict></dict></plist>\n")
+	return b.String()
+}
+
+func TestLintPlurals(t *testing.T) {
+	dir := t.TempDir()
+	src := writeFile(t, filepath.Join(dir, "en.lproj", "Localizable.stringsdict"), stringsdict("one", "%d file", "other", "%d files"))
+	tgt := writeFile(t, filepath.Join(dir, "ru.lproj", "Localizable.stringsdict"), stringsdict("one", "%d файл", "other", "%d файла"))
+	code, stdout, stderr := runMain(nil, "-q", "lint", "-source", src, "-target", tgt)
+	if code != ExitIssues || !strings.Contains(stdout, `"%d files:files"`) || !strings.Contains(stdout, "[plurals]") {
+		t.Errorf("Expected a plurals issue for the ru .stringsdict got %d: %q %q", code, stdout, stderr)
+	}
+	tgt = writeFile(t, filepath.Join(dir, "ru.lproj", "Localizable.stringsdict"), stringsdict("one", "%d файл", "few", "%d файла", "many", "%d файлов", "other", "%d файла"))
+	if code, stdout, stderr = runMain(nil, "-q", "lint", "-source", src, "-target", tgt); code != ExitOK {
+		t.Errorf("Expected the complete ru .stringsdict to pass got %d: %q %q", code, stdout, stderr)
+	}
+
+	res := `<resources><plurals name="files"><item quantity="one">%d plik</item><item quantity="other">%d pliki</item></plurals></resources>`
+	src = writeFile(t, filepath.Join(dir, "values", "strings.xml"), `<resources><plurals name="files"><item quantity="one">%d file</item><item quantity="other">%d files</item></plurals></resources>`)
+	tgt = writeFile(t, filepath.Join(dir, "values-pl", "strings.xml"), res)
+	if code, stdout, _ = runMain(nil, "-q", "lint", "-source", src, "-target", tgt); code != ExitIssues || !strings.Contains(stdout, `["few" "many"]`) {
+		t.Errorf("Expected few and many to be missing in pl got %d: %q", code, stdout)
+	}
+
+	tgt = writeFile(t, filepath.Join(dir, "ru.po"), "msgid \"\"\nmsgstr \"\"\n\"Language: ru\\n\"\n\n"+
+		"msgid \"%d file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"%d файл\"\nmsgstr[1] \"\"\nmsgstr[2] \"%d файлов\"\n")
+	if code, stdout, _ = runMain(nil, "-q", "lint", "-target", tgt); code != ExitIssues || !strings.Contains(stdout, `["few"]`) {
+		t.Errorf("Expected few to be missing in the PO file got %d: %q", code, stdout)
+	}
+	if code, _, _ = runMain(nil, "-q", "lint", "-source", src, "-target", tgt); code != ExitError {
+		t.Errorf("Expected -source with a .po -target to fail got %d", code)
+	}
+
+	if code, _, stderr = runMain(nil, "-q", "plurals", "-target", tgt, "-w"); code != ExitOK {
+		t.Fatalf("Expected plurals to succeed got %d: %q", code, stderr)
+	}
+	data, err := os.ReadFile(tgt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "#, fuzzy\nmsgid \"%d file\"") || !strings.Contains(string(data), "msgstr[1] \"%d файлов\"") {
+		t.Errorf("Expected a fuzzy scaffolded few form got %q", data)
+	}
+	if code, stdout, _ = runMain(nil, "-q", "lint", "-target", tgt); code != ExitOK {
+		t.Errorf("Expected the scaffolded PO file to pass got %d: %q", code, stdout)
+	}
+	if code, _, _ = runMain(nil, "-q", "plurals", "-target", src); code != ExitError {
+		t.Errorf("Expected plurals to reject an Android resources file got %d", code)
+	}
+}
+
+func TestAutofixDisable(t *testing.T) {
+	tgt := testutil.WriteStrings(t, filepath.Join(t.TempDir(), "fr.strings"), "/* Open… */\n\"open\" = \"Ouvrir\";\n")
+
+	code, _, stderr := runMain(nil, "-q", "autofix", "-target", tgt, "-disable", "ellipsis,unknown")
+	if code != ExitError || !strings.Contains(stderr, `unknown fix "unknown"`) {
+		t.Errorf("Expected usage error for unknown fix got %d: %q", code, stderr)
+	}
+	code, stdout, _ := runMain(nil, "-q", "autofix", "-target", tgt, "-disable", "ellipsis")
+	if code != ExitOK || strings.Contains(stdout, "ellipsis") {
+		t.Errorf("Expected disabled ellipsis fix not to be applied got %d: %q", code, stdout)
+	}
+}
+
+func TestLintTranslated(t *testing.T) {
+	dir := t.TempDir()
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open file */\n\"open\" = \"Open file\";\n\n/* Save file */\n\"save\" = \"Save file\";\n\n/* Close file */\n\"close\" = \"Close file\";\n")
+	tm := testutil.WriteStrings(t, filepath.Join(dir, "tm.strings"), "/* Open file */\n\"open\" = \"Ouvrir le fichier\";\n\n/* Save */\n\"save\" = \"Enregistrer\";\n")
+	tgt := filepath.Join(dir, "fr.strings")
+
+	// The target has a translation, a fuzzy one for the changed source and
+	// a missing one.
+	if code, _, stderr := runMain(nil, "-q", "translate", "-source", src, "-tm", tm, "-target", tgt); code != ExitOK {
+		t.Fatalf("Expected translate to succeed got %d: %s", code, stderr)
+	}
+	code, stdout, stderr := runMain(nil, "-q", "lint", "-source", src, "-target", tgt)
+	if code != ExitOK {
+		t.Fatalf("Expected lint of a translated target to succeed got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "close") || !strings.Contains(stdout, "untranslated") {
+		t.Errorf("Expected the missing entry to be reported as untranslated got %q", stdout)
+	}
+}
+
+func TestLproj(t *testing.T) {
+	root := t.TempDir()
+	for _, locale := range []string{"en", "fr"} {
+		if err := os.Mkdir(filepath.Join(root, locale+".lproj"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.WriteStrings(t, filepath.Join(root, "en.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "fr.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n")
+
+	if code, _, stderr := runMain(nil, "-q", "lproj", "translate", "-root", root); code != ExitOK {
+		t.Fatalf("Expected lproj translate to succeed got %d: %s", code, stderr)
+	}
+	exportDir := filepath.Join(t.TempDir(), "ToTranslate")
+	if code, _, stderr := runMain(nil, "-q", "lproj", "export", "-root", root, exportDir); code != ExitOK {
+		t.Fatalf("Expected lproj export to succeed got %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, "fr.lproj", "Localizable.strings")); err != nil {
+		t.Errorf("Expected the fuzzy strings of fr to be exported (%v)", err)
+	}
+	code, stdout, _ := runMain(nil, "-q", "lproj", "count", "-root", root)
+	if code != ExitOK || !strings.Contains(stdout, "fr") {
+		t.Errorf("Expected the count of fr got %d: %q", code, stdout)
+	}
+	if code, _, _ = runMain(nil, "lproj", "export", "-root", root); code != ExitError {
+		t.Errorf("Expected lproj export without a directory to fail got %d", code)
+	}
+}
+
+func TestReportConfigFallbacks(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(root, "en.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "fr.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "de.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Öffnen\";\n\n/* Save */\n\"save\" = \"Speichern\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "fr-CA.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n")
+	configName := filepath.Join(root, "translate.json")
+	config := `{
+		"sourceLocale": "en",
+		"targetLocales": ["de", "fr", "fr-CA"],
+		"files": [{"format": "strings", "path": "{locale}.lproj/*.strings"}],
+		"fallbacks": {"fr-CA": ["de"]}
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fallback := func(args ...string) int {
+		code, stdout, stderr := runMain(nil, append([]string{"report", "-root", root, "-format", "json"}, args...)...)
+		if code != ExitOK {
+			t.Fatalf("Expected report to succeed got %d: %s", code, stderr)
+		}
+		var r struct {
+			Locales []struct {
+				Locale   string
+				Fallback int
+			}
+		}
+		if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range r.Locales {
+			if l.Locale == "fr-CA" {
+				return l.Fallback
+			}
+		}
+		t.Fatalf("Expected fr-CA in the report got %s", stdout)
+		return 0
+	}
+	if n := fallback(); n != 0 {
+		t.Errorf("Expected no fallback to fr got %d", n)
+	}
+	if n := fallback("-config", configName); n != 1 {
+		t.Errorf("Expected 1 fallback to de got %d", n)
+	}
+	if n := fallback("-config", configName, "-fallback", "fr-CA=fr"); n != 0 {
+		t.Errorf("Expected -fallback to override the config got %d", n)
+	}
+
+	if code, _, stderr := runMain(nil, "-q", "lproj", "translate", "-root", root, "-config", configName); code != ExitOK {
+		t.Fatalf("Expected lproj translate to succeed got %d: %s", code, stderr)
+	}
+	ca, err := dotstrings.LoadTargetMessagesMapFromFile(filepath.Join(root, "fr-CA.lproj", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := ca["save"]; m.Str != "Speichern" || m.Locale != "de" {
+		t.Errorf("Expected save to fall back to de got %+v", m)
+	}
+}
+
+var tvLegacy = []struct {
+	name   string
+	args   []string
+	expect string
+}{
+	{"xlate", []string{"-tm", "fr.strings"}, "translate -tm fr.strings"},
+	{"xliff", []string{"-source", "en.strings", "-xliff", "fr.xlf"}, "convert -source en.strings -xliff fr.xlf"},
+	{"xliff", []string{"-source", "en.strings", "-xliff", "fr.xlf", "-out", "fr.strings"}, "translate -source en.strings -xliff fr.xlf -target fr.strings"},
+	{"fuzzy", []string{"-export", "-normal", "-source", "en.strings", "-tm", "fr.strings", "-target", "out.strings"}, "fuzzy export -normal=true -source=en.strings -target=out.strings -tm=fr.strings"},
+	{"fuzzy", []string{"-import", "-tm", "fr.strings", "-target", "in.strings"}, "fuzzy import -target=in.strings -tm=fr.strings"},
+	{"fuzzy", []string{"-glossary", "g.json", "-source", "en.strings", "-tm", "fr.strings"}, "fuzzy terms -glossary=g.json -source=en.strings -tm=fr.strings"},
+	{"fuzzy", []string{"-source", "en.strings", "-tm", "fr.strings"}, "fuzzy count -source=en.strings -tm=fr.strings"},
+	{"stringsfmt", []string{"en.strings"}, "format en.strings"},
+	{"lproj", []string{"-root", "Resources"}, "lproj count -root=Resources"},
+	{"lproj", []string{"-root", "Resources", "-translate", "-j", "2"}, "lproj translate -j=2 -root=Resources"},
+	{"lproj", []string{"-root", "Resources", "-export", "out", "-normal"}, "lproj export -normal=true -root=Resources out"},
+	{"lproj", []string{"-import", "in"}, "lproj import in"},
+}
+
+func TestLegacyExitCodes(t *testing.T) {
+	var out, errOut bytes.Buffer
+	env := &Env{Stdout: &out, Stderr: &errOut}
+	if code := Legacy(env, "xlate", []string{"-source", "missing.strings", "-tm", "missing.strings", "-target", "-"}); code != 1 {
+		t.Errorf("Expected xlate to exit with 1 on errors got %d", code)
+	}
+	if code := Legacy(env, "stringsfmt", []string{"-unknown"}); code != ExitError {
+		t.Errorf("Expected stringsfmt to exit with %d on invalid flags got %d", ExitError, code)
+	}
+	if code := Legacy(env, "lint", []string{"-source", "missing.strings", "-target", "missing.strings"}); code != ExitError {
+		t.Errorf("Expected lint to exit with %d on errors got %d", ExitError, code)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	for i, tv := range tvLegacy {
+		if args := strings.Join(legacy[tv.name](tv.args), " "); args != tv.expect {
+			t.Errorf("Expected tvLegacy[%d] to map to %q got %q", i, tv.expect, args)
+		}
+	}
+}
+
+func TestFailedWriteKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open */\n\"open\" = \"Open\";\n\n\"broken\" = \"Broken\";\n")
+	tm := testutil.WriteStrings(t, filepath.Join(dir, "tm.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n")
+	tgt := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n")
+
+	if code, _, _ := runMain(nil, "-q", "translate", "-source", src, "-tm", tm, "-target", tgt); code != ExitError {
+		t.Errorf("Expected exit code %d for invalid source got %d", ExitError, code)
+	}
+	data, err := os.ReadFile(tgt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testutil.UTF16(t, "/* Open */\n\"open\" = \"Ouvrir\";\n")) {
+		t.Errorf("Expected target to be left untouched got %q", data)
+	}
+}
+
+func TestConvertLanguages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "de.lproj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := testutil.WriteStrings(t, filepath.Join(dir, "de.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Öffnen\";\n")
+	xlf := filepath.Join(dir, "es_419.xlf")
+
+	if code, _, stderr := runMain(nil, "-q", "convert", "-source", src, "-xliff", xlf); code != ExitOK {
+		t.Fatalf("Expected convert to succeed got %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(xlf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`source-language="de" target-language="es-419"`)) {
+		t.Errorf("Expected de to es-419 XLIFF file got %s", data)
+	}
+
+	if code, _, _ := runMain(nil, "-q", "convert", "-source", src, "-xliff", xlf, "-lang", "español"); code != ExitError {
+		t.Errorf("Expected exit code %d for an invalid -lang got %d", ExitError, code)
+	}
+	tgt := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Open */\n\"open\" = \"Open\";\n")
+	if code, _, _ := runMain(nil, "-q", "convert", "-target", tgt, "-xliff", filepath.Join(dir, "en-US.xlf")); code != ExitError {
+		t.Errorf("Expected exit code %d for a target in the source language got %d", ExitError, code)
+	}
+}
+
+func TestConvertICU(t *testing.T) {
+	dir := t.TempDir()
+	tgt := testutil.WriteStrings(t, filepath.Join(dir, "ru.strings"), "/* {n, plural, one {# file} other {# files}} */\n\"files\" = \"{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}\";\n")
+	xlf := filepath.Join(dir, "ru.xlf")
+	if code, _, stderr := runMain(nil, "-q", "convert", "-icu", "-target", tgt, "-xliff", xlf); code != ExitOK {
+		t.Fatalf("Expected convert to succeed got %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(xlf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`id="files#n:plural=many"`)) || bytes.Contains(data, []byte("plural,")) {
+		t.Errorf("Expected a unit per plural category got %s", data)
+	}
+
+	out := filepath.Join(dir, "out", "ru.strings")
+	if err = os.Mkdir(filepath.Dir(out), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runMain(nil, "-q", "convert", "-icu", "-xliff", xlf, "-out", out); code != ExitOK {
+		t.Fatalf("Expected convert to succeed got %d: %s", code, stderr)
+	}
+	msgs, err := dotstrings.LoadTargetMessagesMapFromFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := msgs["files"]; m.Str != "{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}" {
+		t.Errorf("Expected the rebuilt plural got %+v", m)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	dir := t.TempDir()
+	tm := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Hello */\n\"hello\" = \"Bonjour {name}\";\n\n/* Unread */\n\"unread\" = \"{n, plural, one {# message non lu} other {# messages non lus}}\";\n")
+	tpl := filepath.Join(dir, "mail.tpl")
+	if err := os.WriteFile(tpl, []byte(`{{T "hello" "name" .name}}, {{TN "unread" .unread}} ({{number .total}})`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := runMain([]byte(`{"name": "Luc", "unread": 3, "total": 1234.5}`), "-q", "template", "-template", tpl, "-data", "-", "-tm", tm)
+	if code != ExitOK {
+		t.Fatalf("Expected template to succeed got %d: %s", code, stderr)
+	}
+	if expect := "Bonjour Luc, 3 messages non lus (1 234,5)"; stdout != expect {
+		t.Errorf("Expected %q got %q", expect, stdout)
+	}
+}
+
+func TestTemplateLocales(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Hello */\n\"hello\" = \"Bonjour <{name}>\";\n\n/* Bye */\n\"bye\" = \"Au revoir\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "fr-CA.strings"), "/* Hello */\n\"hello\" = \"Allô <{name}>\";\n")
+	testutil.WriteStrings(t, filepath.Join(dir, "de.strings"), "/* Hello */\n\"hello\" = \"Hallo <{name}>\";\n")
+	if err := os.Mkdir(filepath.Join(dir, "partials"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "partials", "footer.html"), []byte(`{{define "footer"}}<p>{{T "bye"}}</p>{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tpl := filepath.Join(dir, "welcome.html")
+	if err := os.WriteFile(tpl, []byte(`<h1>{{T "hello" "name" .name}}</h1>{{template "footer"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := runMain([]byte(`{"name": "Luc"}`), "-q", "template", "-html", "-template", tpl, "-data", "-",
+		"-partials", filepath.Join(dir, "partials", "*.html"),
+		"-locales", "fr,fr-CA,de", "-tm", filepath.Join(dir, "{locale}.strings"), "-out", filepath.Join(dir, "out", "{locale}", "welcome.html"))
+	if code != ExitIssues || !strings.Contains(stderr, "de: 1 missing translations: bye") {
+		t.Errorf("Expected the missing de translation to be reported got %d: %s", code, stderr)
+	}
+	for lang, expect := range map[string]string{
+		"fr":    "<h1>Bonjour &lt;Luc&gt;</h1><p>Au revoir</p>",
+		"fr-CA": "<h1>Allô &lt;Luc&gt;</h1><p>Au revoir</p>",
+		"de":    "<h1>Hallo &lt;Luc&gt;</h1><p>bye</p>",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, "out", lang, "welcome.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Errorf("%s: expected %q got %q", lang, expect, data)
+		}
+	}
+
+	if code, _, _ := runMain([]byte(`{}`), "-q", "template", "-template", tpl, "-data", "-", "-locales", "fr,de", "-out", filepath.Join(dir, "welcome.html")); code != ExitError {
+		t.Errorf("Expected exit code %d for an -out without {locale} got %d", ExitError, code)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	tpl := filepath.Join(dir, "mail.tpl")
+	if err := os.WriteFile(tpl, []byte("{{T \"hello\" \"Greeting\" \"name\" .name}}\n{{TN \"unread\" .unread}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := testutil.WriteStrings(t, filepath.Join(dir, "en.strings"), "/* Old */\n\"hello\" = \"Hello {name}\";\n\n/* Old */\n\"gone\" = \"Gone\";\n")
+	if code, _, stderr := runMain(nil, "-q", "extract", "-out", src, tpl); code != ExitOK {
+		t.Fatalf("Expected extract to succeed got %d: %s", code, stderr)
+	}
+	messages, err := dotstrings.LoadMessagesMapFromFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages["hello"].Str != "Hello {name}" || messages["hello"].Ctx != "Greeting ("+tpl+":1)" || messages["unread"].Str != "unread" {
+		t.Errorf("Expected the updated source strings got %+v", messages)
+	}
+
+	code, stdout, _ := runMain(nil, "-q", "extract", "-out", "-", tpl)
+	if code != ExitOK || len(stdout) == 0 {
+		t.Errorf("Expected the strings on standard output got %d", code)
+	}
+	pot := filepath.Join(dir, "mail.pot")
+	if code, _, stderr := runMain(nil, "-q", "extract", "-out", pot, tpl); code != ExitOK {
+		t.Fatalf("Expected extract to succeed got %d: %s", code, stderr)
+	}
+	if data, err := os.ReadFile(pot); err != nil || !strings.Contains(string(data), "#. Greeting\n#: "+tpl+":1\nmsgid \"hello\"\n") {
+		t.Errorf("Expected the PO template got %q (%v)", data, err)
+	}
+	if code, _, _ := runMain(nil, "-q", "extract", "-out", filepath.Join(dir, "en.txt"), tpl); code != ExitError {
+		t.Errorf("Expected an unsupported -out to fail got %d", code)
+	}
+
+	tm := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* Hello */\n\"hello\" = \"Bonjour {name}\";\n")
+	code, stdout, stderr := runMain(nil, "-q", "translate", "-source", tpl, "-tm", tm, "-target", "-")
+	if expect := "{{T \"Bonjour {name}\" \"Greeting\" \"name\" .name}}\n{{TN \"unread\" .unread}}"; code != ExitOK || stdout != expect {
+		t.Errorf("Expected %q got %d %q: %s", expect, code, stdout, stderr)
+	}
+}
+
+func TestExtractSources(t *testing.T) {
+	dir := t.TempDir()
+	swift := filepath.Join(dir, "View.swift")
+	if err := os.WriteFile(swift, []byte(`let a = NSLocalizedString("loading", comment: "")
+let b = AppLocalized("window_title", comment: "Title of the window")
+let c = NSLocalizedString("net", tableName: "Errors", comment: "Network " + "error")
+let d = NSLocalizedString(key, comment: "")
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	objc := filepath.Join(dir, "Loader.m")
+	if err := os.WriteFile(objc, []byte(`label.text = NSLocalizedString(@"Loading...", @"");`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "en.lproj")
+	code, _, stderr := runMain(nil, "-q", "extract", "-dir", out, "-s", "AppLocalized", swift, objc)
+	if code != ExitOK {
+		t.Fatalf("Expected extract to succeed got %d: %s", code, stderr)
+	}
+	if expect := swift + ":4: Skipped a call without literal string arguments\n"; stderr != expect {
+		t.Errorf("Expected %q got %q", expect, stderr)
+	}
+	messages, err := dotstrings.LoadMessagesMapFromFile(filepath.Join(out, "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages["Loading..."].Ctx != "Loading..." || messages["window_title"].Str != "Title of the window" || messages["loading"].Ctx != "loading" {
+		t.Errorf("Expected the formatted Localizable table got %+v", messages)
+	}
+	if messages, err = dotstrings.LoadMessagesMapFromFile(filepath.Join(out, "Errors.strings")); err != nil || messages["net"].Str != "Network error" {
+		t.Errorf("Expected the Errors table got %+v (%v)", messages, err)
+	}
+	if code, _, _ := runMain(nil, "-q", "extract", swift); code != ExitError {
+		t.Errorf("Expected extract without -dir to fail got %d", code)
+	}
+}
+
+func TestIB(t *testing.T) {
+	dir := t.TempDir()
+	sb := filepath.Join(dir, "Main.storyboard")
+	if err := os.WriteFile(sb, []byte(`<document targetRuntime="iOS.CocoaTouch"><scenes><scene sceneID="s"><objects>
+<viewController id="vc-1"><view key="view" id="v-1"><subviews>
+<label text="Hello" id="lb-1"/><button id="bt-1"><state key="normal" title="Save"/></button>
+</subviews></view></viewController></objects></scene></scenes></document>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "Main.strings")
+	if code, _, stderr := runMain(nil, "-q", "ib", "export", "-out", out, sb); code != ExitOK {
+		t.Fatalf("Expected export to succeed got %d: %s", code, stderr)
+	}
+	messages, err := dotstrings.LoadMessagesMapFromFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages["bt-1.normalTitle"].Str != "Save" || messages["lb-1.text"].Ctx != `Class = "UILabel"; text = "Hello"; ObjectID = "lb-1";` {
+		t.Errorf("Expected the exported strings got %+v", messages)
+	}
+
+	fr := testutil.WriteStrings(t, filepath.Join(dir, "fr.strings"), "/* c */\n\"lb-1.text\" = \"Bonjour\";\n\n/* c */\n\"old-1.text\" = \"Ancien\";\n")
+	code, stdout, _ := runMain(nil, "-q", "ib", "obsolete", "-strings", fr, sb)
+	if code != ExitIssues || stdout != "old-1.text\n" {
+		t.Errorf("Expected old-1.text to be obsolete got %d %q", code, stdout)
+	}
+	if code, _, _ := runMain(nil, "-q", "ib", "export", filepath.Join(dir, "fr.strings")); code != ExitError {
+		t.Errorf("Expected export of a .strings file to fail got %d", code)
+	}
+}
+
+func TestXCStrings(t *testing.T) {
+	dir := t.TempDir()
+	catalog := filepath.Join(dir, "Localizable.xcstrings")
+	if err := os.WriteFile(catalog, []byte(`{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "Hello" : {
+      "comment" : "Greeting",
+      "localizations" : {
+        "fr" : {
+          "stringUnit" : {
+            "state" : "needs_review",
+            "value" : "Salut"
+          }
+        }
+      }
+    },
+    "Save" : {
+
+    }
+  },
+  "version" : "1.0"
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fr := filepath.Join(dir, "fr.strings")
+	if code, _, stderr := runMain(nil, "-q", "xcstrings", "export", "-locale", "fr", "-out", fr, catalog); code != ExitOK {
+		t.Fatalf("Expected export to succeed got %d: %s", code, stderr)
+	}
+	messages, err := dotstrings.LoadTargetMessagesMapFromFile(fr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := messages["Hello"]; !m.Fuzzy || m.Str != "Salut" || m.Ctx != "Hello" {
+		t.Errorf("Expected the fuzzy translation got %+v", m)
+	}
+
+	xlf := filepath.Join(dir, "fr.xlf")
+	if code, _, stderr := runMain(nil, "-q", "xcstrings", "export", "-locale", "fr", "-out", xlf, catalog); code != ExitOK {
+		t.Fatalf("Expected export to succeed got %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(xlf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated := strings.Replace(string(data), "<target>Salut</target>", "<target>Bonjour</target>", 1)
+	translated = strings.Replace(translated, "<source>Save</source>", "<source>Save</source><target>Enregistrer</target>", 1)
+	if translated == string(data) {
+		t.Fatalf("Expected the exported targets got\n%s", data)
+	}
+	if err := os.WriteFile(xlf, []byte(translated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runMain(nil, "-q", "xcstrings", "import", "-in", xlf, catalog); code != ExitOK {
+		t.Fatalf("Expected import to succeed got %d: %s", code, stderr)
+	}
+	data, err = os.ReadFile(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"state" : "translated",
+            "value" : "Bonjour"`, `"Save" : {
+      "localizations" : {
+        "fr" : {`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Expected the catalog to contain\n%s\ngot\n%s", s, data)
+		}
+	}
+	if code, _, _ := runMain(nil, "-q", "xcstrings", "import", "-in", fr, catalog); code != ExitError {
+		t.Errorf("Expected import of a .strings file without -locale to fail got %d", code)
+	}
+}
@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+var convertCommand = &Command{
+	Name:    "convert",
+	Summary: "convert between .strings and XLIFF files, or normalize a .strings file",
+	Help: `  -source en.strings -xliff fr.xlf    write the source strings as a fresh XLIFF file
+  -target fr.strings -xliff fr.xlf    write the translated strings as an XLIFF file
+  -xliff fr.xlf -out fr.strings       write the XLIFF file as a target .strings file
+  -source en.strings -out en.strings  normalize a .strings file, reporting errors
+
+The languages are taken from the locale the file names start with, like pt-BR.strings,
+or from their directory, like fr.lproj/Localizable.strings, unless -lang is given.
+The source language is en-US unless -sourcelang is given or the -source file names one.
+Any file can be - to use standard input or output.
+
+With -icu, strings that are ICU messages with plural or select arguments, like
+{count, plural, one {# file} other {# files}}, are written to XLIFF as a unit per
+variant with the plural categories of the target language, and joined again when
+converting the XLIFF file back.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		srcName := fs.String("source", "", ".strings file in source language")
+		tgtName := fs.String("target", "", ".strings file in target language")
+		xlfName := fs.String("xliff", "", ".xlf file to read, or to write when -source or -target is given")
+		outName := fs.String("out", "", ".strings file to write")
+		lang := fs.String("lang", "", "target language (default: the locale of the -xliff or -out name)")
+		srcLang := fs.String("sourcelang", "", "source language (default: the locale of the -source name, or "+defaultSourceLang+")")
+		flatten := fs.Bool("icu", false, "flatten ICU plural and select arguments into a unit per variant")
+		return func(env *Env, args []string) error {
+			if err := wantNoArgs(args); err != nil {
+				return err
+			}
+			switch {
+			case len(*outName) > 0 && len(*srcName) > 0 && len(*xlfName) > 0:
+				return usagef("use translate -source %s -xliff %s -target %s to translate using an XLIFF file", *srcName, *xlfName, *outName)
+			case len(*outName) > 0 && len(*srcName) > 0:
+				return normalize(env, *srcName, *outName)
+			case len(*outName) > 0 && len(*tgtName) > 0:
+				return normalize(env, *tgtName, *outName)
+			case len(*outName) > 0 && len(*xlfName) > 0:
+				return convertXliff(env, *xlfName, *outName, *lang, *srcLang, *flatten)
+			case len(*srcName) > 0 && len(*tgtName) > 0 && len(*xlfName) > 0:
+				return fmt.Errorf("Converting -source and -target into one -xliff file is not implemented yet")
+			case len(*srcName) > 0 && len(*xlfName) > 0:
+				return convertSource(env, *srcName, *xlfName, *lang, *srcLang, *flatten)
+			case len(*tgtName) > 0 && len(*xlfName) > 0:
+				return convertTarget(env, *tgtName, *xlfName, *lang, *srcLang, *flatten)
+			}
+			return usagef("missing flags")
+		}
+	},
+}
+
+// defaultSourceLang is the source language of files that don't name one.
+const defaultSourceLang = "en-US"
+
+// fileLang returns the language of the file name in its canonical form, as
+// found by locale.FromPath. Standard input and output, and files not named
+// after a locale, have no language.
+func fileLang(name string) string {
+	if name == "-" {
+		return ""
+	}
+	return locale.FromPath(name).String()
+}
+
+// flagLang returns the language given by a flag in its canonical form, or
+// when it is empty the language of the file name.
+func flagLang(flag, lang, name string) (string, error) {
+	if len(lang) == 0 {
+		return fileLang(name), nil
+	}
+	l, err := locale.Parse(lang)
+	if err != nil {
+		return "", usagef("invalid %s (%v)", flag, err)
+	}
+	return l.String(), nil
+}
+
+// sameLang reports whether the languages a and b are the same locale, so en
+// is the same as en-US. No language is only the same as no language.
+func sameLang(a, b string) bool {
+	la, _ := locale.Parse(a)
+	lb, _ := locale.Parse(b)
+	return la.Same(lb)
+}
+
+// normalize reads the strings from the inName .strings file and then writes
+// them out again to the outName .strings file. This detects any errors in the
+// .strings file, normalizes the strings and cleans up any formatting issues
+// while writing them back out.
+func normalize(env *Env, inName, outName string) (err error) {
+	inFile, err := env.Open(inName)
+	if err != nil {
+		return fmt.Errorf("Failed to open %q (%v)", inName, err)
+	}
+	defer inFile.Close()
+
+	outFile, err := env.Create(outName)
+	if err != nil {
+		return fmt.Errorf("Failed to create %q (%v)", outName, err)
+	}
+	defer outFile.Close()
+
+	env.Logf("Normalizing %q writing result to %q\n", inName, outName)
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(inFile))
+	n, err := dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(outFile))
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if err = outFile.Commit(); err != nil {
+		return
+	}
+	env.Logf("Normalized %d strings\n", n)
+	return
+}
+
+// convertXliff converts the xlfName XLIFF file into the outName .strings file.
+// The file is written as a source .strings file when both files are named
+// after the source language slang, otherwise as a target file. With rebuild,
+// the units of flattened ICU messages are joined again.
+func convertXliff(env *Env, xlfName, outName, lang, slang string, rebuild bool) (err error) {
+	olang, err := flagLang("-lang", lang, outName)
+	if err != nil {
+		return
+	}
+	if slang, err = flagLang("-sourcelang", slang, ""); err != nil {
+		return
+	}
+	if len(slang) == 0 {
+		slang = defaultSourceLang
+	}
+	convertSource := len(olang) > 0 && sameLang(fileLang(xlfName), olang) && sameLang(olang, slang)
+
+	xlfFile, err := env.Open(xlfName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -xliff %q (%v)", xlfName, err)
+	}
+	defer xlfFile.Close()
+
+	outFile, err := env.Create(outName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -out %q (%v)", outName, err)
+	}
+	defer outFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	xlfChan, errChan := xliff.LoadTranslationUnits(ctx, xlfFile)
+	errChans := []<-chan error{errChan}
+	if rebuild {
+		xlfChan, errChan = translate.RebuildICUUnits(ctx, xlfChan)
+		errChans = append(errChans, errChan)
+	}
+
+	var msgChan <-chan dotstrings.Message
+	if convertSource {
+		msgChan = translate.ConvertTranslationUnitsToSourceMessages(ctx, xlfChan)
+	} else {
+		msgChan = translate.ConvertTranslationUnitsToTargetMessages(ctx, xlfChan)
+	}
+
+	n, err := dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(outFile))
+	if e := stage.Wait(ctx, cancel, errChans...); e != nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if err = outFile.Commit(); err != nil {
+		return
+	}
+	env.Logf("Converted %d strings\n", n)
+	return
+}
+
+// convertSource reads the source .strings file and writes out a fresh .xlf
+// file to be sent on to translators. The source language is taken from slang
+// or the name of the source file, it defaults to en-US. With flatten, ICU
+// messages are written as a unit per variant.
+func convertSource(env *Env, srcName, xlfName, lang, slang string, flatten bool) (err error) {
+	if slang, err = flagLang("-sourcelang", slang, srcName); err != nil {
+		return
+	}
+	if len(slang) == 0 {
+		slang = defaultSourceLang
+	}
+
+	// Deduce translation file metadata
+	tlang, err := flagLang("-lang", lang, xlfName)
+	if err != nil {
+		return
+	}
+	if len(tlang) > 0 && !sameLang(tlang, slang) {
+		env.Logf("Converting to Target Language %q\n", tlang)
+	} else {
+		tlang = ""
+	}
+	tf := &xliff.TranslationFile{Original: "Localizable.strings", SourceLanguage: slang, Datatype: "x-strings", TargetLanguage: tlang}
+
+	inFile, err := env.Open(srcName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -source %q (%v)", srcName, err)
+	}
+	defer inFile.Close()
+
+	xlfFile, err := env.Create(xlfName)
+	if err != nil {
+		return fmt.Errorf("Failed to create -xliff %q (%v)", xlfName, err)
+	}
+	defer xlfFile.Close()
+
+	env.Logf("Converting strings file %q to xliff file %q\n", srcName, xlfName)
+	n, err := convertUnits(env.Context(), translate.ConvertSourceMessagesToTranslationUnits, inFile, tf, xlfFile, flatten)
+	if err != nil {
+		return
+	}
+	if err = xlfFile.Commit(); err != nil {
+		return
+	}
+	env.Logf("Converted %d strings\n", n)
+	return
+}
+
+// convertTarget reads the xx.strings and writes out a fresh xx.xlf file to be
+// sent on to translators. The target language is taken from the locale the
+// target and xliff file names start with, or are in the directory of. With
+// flatten, ICU messages are written as a unit per variant.
+func convertTarget(env *Env, tgtName, xlfName, lang, slang string, flatten bool) (err error) {
+	tlang, err := flagLang("-lang", lang, "")
+	if err != nil {
+		return
+	}
+	if len(tlang) == 0 {
+		// Check language of tgtName is the same as the language of the xliff file.
+		fromtlang, xlftlang := fileLang(tgtName), fileLang(xlfName)
+		if len(fromtlang) > 0 && len(xlftlang) > 0 && !sameLang(fromtlang, xlftlang) {
+			return fmt.Errorf("Mismatching target languages %q and %q", fromtlang, xlftlang)
+		}
+		tlang = fromtlang
+		if len(tlang) == 0 {
+			tlang = xlftlang
+		}
+	}
+
+	if slang, err = flagLang("-sourcelang", slang, ""); err != nil {
+		return
+	}
+	if len(slang) == 0 {
+		slang = defaultSourceLang
+	}
+	if sameLang(tlang, slang) {
+		return fmt.Errorf("Invalid language for -target %q (%q is the source language)", tgtName, tlang)
+	}
+
+	if len(tlang) > 0 {
+		env.Logf("Converting to Target Language %q\n", tlang)
+	}
+	tf := &xliff.TranslationFile{Original: "Localizable.strings", SourceLanguage: slang, Datatype: "x-strings", TargetLanguage: tlang}
+
+	tgtFile, err := env.Open(tgtName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -target %q (%v)", tgtName, err)
+	}
+	defer tgtFile.Close()
+
+	xlfFile, err := env.Create(xlfName)
+	if err != nil {
+		return fmt.Errorf("Failed to create -xliff %q (%v)", xlfName, err)
+	}
+	defer xlfFile.Close()
+
+	env.Logf("Converting strings file %q to xliff file %q\n", tgtName, xlfName)
+	n, err := convertUnits(env.Context(), translate.ConvertTargetMessagesToTranslationUnits, tgtFile, tf, xlfFile, flatten)
+	if err != nil {
+		return
+	}
+	if err = xlfFile.Commit(); err != nil {
+		return
+	}
+	env.Logf("Converted %d strings\n", n)
+	return
+}
+
+// convertUnits reads the messages from the UTF-16 encoded .strings file
+// inFile and writes them as translation units of tf, made by convert, to the
+// XLIFF file xlfFile. With flatten, ICU messages are written as a unit per
+// variant. It returns the number of translation units written.
+func convertUnits(ctx context.Context, convert func(context.Context, <-chan dotstrings.Message, *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error), inFile io.Reader, tf *xliff.TranslationFile, xlfFile io.Writer, flatten bool) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgChan, errChan1 := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(inFile))
+	unitChan, errChan2 := convert(ctx, msgChan, tf)
+	errChans := []<-chan error{errChan1, errChan2}
+	if flatten {
+		var errChan3 <-chan error
+		unitChan, errChan3 = translate.FlattenICUUnits(ctx, unitChan)
+		errChans = append(errChans, errChan3)
+	}
+	n, err = xliff.SaveTranslationUnits(unitChan, xlfFile)
+	if e := stage.Wait(ctx, cancel, errChans...); e != nil {
+		err = e
+	}
+	return
+}
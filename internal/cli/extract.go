@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/extract"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var extractCommand = &Command{
+	Name:    "extract",
+	Args:    "file...",
+	Summary: "collect the translatable strings of templates and sources into source strings or PO files",
+	Help: `Extracts the IDs of the T and TN calls in the Go template files and writes them to the -out
+source file, with the comments for the translator and the places the IDs are used:
+
+  e.g. extract -out en.strings mail.tpl welcome.html
+
+  with mail.tpl: {{T "greeting" "Opening line of the mail" "name" .firstName}}
+
+A string right after the id of T, or after the count of TN, is the comment for the translator.
+An -out .strings file that exists is updated: the source text of the IDs it has is kept, new IDs
+are added with the ID as text and IDs no longer used are removed. An -out .po or .pot file is
+written as PO template.
+
+Swift and Objective-C files (.swift, .m, .mm and .h) are scanned the way genstrings scans them,
+for NSLocalizedString and its FromTable, FromTableInBundle and WithDefaultValue variants,
+String(localized:) and LocalizedStringKey. Every table is written to <table>.strings in -dir,
+formatted the way format does, replacing the file:
+
+  e.g. extract -dir en.lproj -s AppLocalizedString Sources/*.swift Sources/*.m
+
+Calls whose key isn't a literal string can't be extracted and are reported.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		outName := fs.String("out", "", ".strings, .po or .pot file to write the strings of the templates to")
+		dirName := fs.String("dir", "", "directory to write a .strings file per table of the Swift and Objective-C files to")
+		var routines listFlag
+		fs.Var(&routines, "s", "`routine` used like NSLocalizedString in the sources, can be given more than once")
+		return func(env *Env, args []string) (err error) {
+			if len(args) == 0 {
+				return usagef("at least one file is required")
+			}
+			var templates, sources []string
+			for _, name := range args {
+				if extract.IsSource(name) {
+					sources = append(sources, name)
+				} else {
+					templates = append(templates, name)
+				}
+			}
+			if len(templates) > 0 && len(*outName) == 0 {
+				return usagef("-out is required for templates")
+			}
+			if len(sources) > 0 && len(*dirName) == 0 {
+				return usagef("-dir is required for Swift and Objective-C files")
+			}
+
+			if len(sources) > 0 {
+				if err = extractSources(env, sources, routines, *dirName); err != nil {
+					return
+				}
+			}
+			if len(templates) > 0 {
+				err = extractTemplates(env, templates, *outName)
+			}
+			return
+		}
+	},
+}
+
+// extractTemplates writes the strings of the templates to the .strings, .po
+// or .pot file outName.
+func extractTemplates(env *Env, templates []string, outName string) (err error) {
+	po := false
+	switch ext := strings.ToLower(filepath.Ext(outName)); {
+	case ext == ".po" || ext == ".pot":
+		po = true
+	case ext == ".strings" || outName == "-":
+	default:
+		return fmt.Errorf("Error: Unsupported -out file type %q", ext)
+	}
+
+	c := extract.NewCatalog()
+	for _, name := range templates {
+		if err = extractFile(env, c, name); err != nil {
+			return
+		}
+	}
+	env.Logf("Extracted %d strings from %d files\n", c.Len(), len(templates))
+
+	var existing map[string]dotstrings.Message
+	if !po && outName != "-" {
+		existing, err = dotstrings.LoadMessagesMapFromFile(outName)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Failed to load -out %q (%v)", outName, err)
+		}
+	}
+
+	outFile, err := env.Create(outName)
+	if err != nil {
+		return
+	}
+	defer outFile.Close()
+	if po {
+		err = c.WritePO(outFile)
+	} else {
+		err = writeMessages(c.Messages(existing), dotstrings.NewWriterUTF16(outFile))
+	}
+	if err != nil {
+		return
+	}
+	return outFile.Commit()
+}
+
+// extractSources writes the strings of the Swift and Objective-C sources to
+// a .strings file per table in dirName.
+func extractSources(env *Env, sources, routines []string, dirName string) (err error) {
+	var found []extract.Localized
+	for _, name := range sources {
+		var text []byte
+		if text, err = os.ReadFile(name); err != nil {
+			return
+		}
+		l, bad, e := extract.SourceStrings(name, string(text), routines)
+		if e != nil {
+			return fmt.Errorf("Failed to extract the strings of %q (%v)", name, e)
+		}
+		for _, ref := range bad {
+			fmt.Fprintf(env.Stderr, "%s: Skipped a call without literal string arguments\n", ref)
+		}
+		found = append(found, l...)
+	}
+
+	if err = os.MkdirAll(dirName, 0755); err != nil {
+		return
+	}
+	tables := extract.Tables(found)
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		if err = writeTable(env, filepath.Join(dirName, table+".strings"), tables[table]); err != nil {
+			return
+		}
+	}
+	env.Logf("Extracted %d strings into %d tables from %d files\n", len(found), len(tables), len(sources))
+	return
+}
+
+// writeTable formats the messages of a table using FormatMessages and writes
+// them to the .strings file name.
+func writeTable(env *Env, name string, messages []dotstrings.Message) (err error) {
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	file, err := env.Create(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	msgChan, errChan := stage.Load(ctx, func(yield func(dotstrings.Message, error) bool) {
+		for _, m := range messages {
+			if !yield(m, nil) {
+				return
+			}
+		}
+	})
+	msgChan = dotstrings.FormatMessages(ctx, msgChan)
+	_, err = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(file))
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	return file.Commit()
+}
+
+// extractFile adds the strings of the template file name to c.
+func extractFile(env *Env, c *extract.Catalog, name string) (err error) {
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	text, err := io.ReadAll(file)
+	if err != nil {
+		return
+	}
+	if err = c.ExtractTemplate(name, string(text)); err != nil {
+		return fmt.Errorf("Failed to extract the strings of %q (%v)", name, err)
+	}
+	return
+}
+
+// writeMessages writes messages to w as .strings file.
+func writeMessages(messages []dotstrings.Message, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	sw := dotstrings.NewWriter(bw)
+	for _, m := range messages {
+		if err := sw.Write(m); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
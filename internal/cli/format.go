@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var formatCommand = &Command{
+	Name:    "format",
+	Args:    "[file]",
+	Summary: "make a strings file generated by genstrings suitable as source strings file",
+	Help: `Formats the strings in the -from file and writes them out to the -to file, or formats the
+file given as argument in place. Use - to read standard input and write standard output.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		doMove := fs.Bool("move", false, "remove -from file when finished writing to -to file")
+		fromName := fs.String("from", "", "name of file to read strings from")
+		toName := fs.String("to", "", "name of file to write strings to")
+		return func(env *Env, args []string) (err error) {
+			switch {
+			case len(args) == 1 && len(*fromName) == 0 && len(*toName) == 0 && !*doMove:
+				*fromName, *toName = args[0], args[0]
+			case len(args) == 0 && len(*fromName) > 0 && len(*toName) > 0:
+			default:
+				return usagef("expected -from and -to, or a single file")
+			}
+			if *doMove && (*fromName == "-" || *fromName == *toName) {
+				return usagef("-move needs -from and -to to be different files")
+			}
+
+			// Format into memory, so files can be changed in-place.
+			buf := &bytes.Buffer{}
+			if err = formatFile(env, *fromName, buf); err != nil {
+				return
+			}
+
+			toFile, err := env.Create(*toName)
+			if err != nil {
+				return
+			}
+			defer toFile.Close()
+			if _, err = buf.WriteTo(toFile); err != nil {
+				return
+			}
+			if err = toFile.Commit(); err != nil {
+				return
+			}
+
+			if *doMove {
+				err = os.Remove(*fromName)
+			}
+			return
+		}
+	},
+}
+
+// formatFile formats the strings in the fromName file using FormatMessages and
+// writes them UTF-16 encoded to buf.
+func formatFile(env *Env, fromName string, buf *bytes.Buffer) (err error) {
+	fromFile, err := env.Open(fromName)
+	if err != nil {
+		return
+	}
+	defer fromFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(fromFile))
+	msgChan = dotstrings.FormatMessages(ctx, msgChan)
+	_, err = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(buf))
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	return
+}
@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/glossary"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var fuzzyCommand = &Command{
+	Name:    "fuzzy",
+	Summary: "count, export and import the fuzzy strings of a translation",
+	Help: `Fuzzy strings are the entries of a translation that are missing or whose source string changed
+since they were translated. They can be exported for a translator and imported back once translated.`,
+	Commands: []*Command{
+		fuzzyCountCommand,
+		fuzzyExportCommand,
+		fuzzyImportCommand,
+		fuzzyTermsCommand,
+	},
+}
+
+// selectFlags defines the flags selecting which strings to operate on.
+func selectFlags(fs *flag.FlagSet) (normal, present *bool) {
+	normal = fs.Bool("normal", false, "operate on normal instead of fuzzy strings")
+	present = fs.Bool("present", false, "operate on fuzzy strings not missing from the -tm file")
+	return
+}
+
+// stringsFlag checks that the file name given by flag is a .strings file.
+func stringsFlag(name, flag string, required bool) error {
+	if len(name) == 0 {
+		if required {
+			return usagef("%s is required", flag)
+		}
+		return nil
+	}
+	if ext := filepath.Ext(name); name != "-" && !strings.EqualFold(ext, ".strings") {
+		return fmt.Errorf("Error: Unsupported %s file type %q", flag, ext)
+	}
+	return nil
+}
+
+var fuzzyCountCommand = &Command{
+	Name:    "count",
+	Summary: "count the (non)fuzzy strings",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		srcName := fs.String("source", "", "file to read source strings from")
+		tmName := fs.String("tm", "", "translation file used to translate source strings into target strings")
+		normal, present := selectFlags(fs)
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if err = stringsFlag(*srcName, "-source", true); err != nil {
+				return
+			}
+			if err = stringsFlag(*tmName, "-tm", true); err != nil {
+				return
+			}
+			return fuzzyCount(env, !*normal, !*present, *srcName, *tmName)
+		}
+	},
+}
+
+var fuzzyExportCommand = &Command{
+	Name:    "export",
+	Summary: "export the (non)fuzzy strings to a file",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		srcName := fs.String("source", "", "file to read source strings from")
+		tmName := fs.String("tm", "", "translation file used to translate source strings into target strings")
+		tgtName := fs.String("target", "-", "file to write the exported strings to")
+		normal, present := selectFlags(fs)
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			for _, f := range []struct{ name, flag string }{{*srcName, "-source"}, {*tmName, "-tm"}, {*tgtName, "-target"}} {
+				if err = stringsFlag(f.name, f.flag, true); err != nil {
+					return
+				}
+			}
+			return fuzzyExport(env, !*normal, !*present, *srcName, *tmName, *tgtName)
+		}
+	},
+}
+
+var fuzzyImportCommand = &Command{
+	Name:    "import",
+	Summary: "merge translated strings into the translation file",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		tmName := fs.String("tm", "", "translation file to merge the translated strings into")
+		tgtName := fs.String("target", "", "file to read the translated strings from")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if err = stringsFlag(*tmName, "-tm", true); err != nil {
+				return
+			}
+			if err = stringsFlag(*tgtName, "-target", true); err != nil {
+				return
+			}
+			if *tmName == "-" || *tgtName == "-" {
+				return usagef("-tm and -target must be files")
+			}
+			return fuzzyImport(env, *tmName, *tgtName)
+		}
+	},
+}
+
+var fuzzyTermsCommand = &Command{
+	Name:    "terms",
+	Summary: "check the translations against a glossary",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		srcName := fs.String("source", "", "file to read source strings from")
+		tmName := fs.String("tm", "", "translation file to check")
+		glossaryName := fs.String("glossary", "", "JSON glossary file with the approved terminology")
+		lang := fs.String("lang", "", "language of the -tm file (default: the locale of the -tm name)")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if err = stringsFlag(*srcName, "-source", true); err != nil {
+				return
+			}
+			if err = stringsFlag(*tmName, "-tm", true); err != nil {
+				return
+			}
+			if len(*glossaryName) == 0 {
+				return usagef("-glossary is required")
+			}
+			if *lang, err = flagLang("-lang", *lang, *tmName); err != nil {
+				return
+			}
+			return fuzzyTerms(env, *srcName, *tmName, *glossaryName, *lang)
+		}
+	},
+}
+
+func fuzzyCount(env *Env, fuzzy bool, missing bool, srcName string, tmName string) (err error) {
+	// Load map of translations from tm file
+	translations, err := loadMessagesMap(env, tmName, "-tm")
+	if err != nil {
+		return
+	}
+
+	// Open source file
+	srcFile, err := env.Open(srcName)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Start loading the messages asynchronously
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+
+	// Translate messages asynchronously
+	msgChan = translate.TranslateMessages(ctx, msgChan, translate.NewChain(translations))
+
+	// Synchronously receive all messages from the msgChan
+	var n uint32
+	for m := range msgChan {
+		if fuzzy == m.Fuzzy && (missing || !m.Missing) {
+			n++
+		}
+	}
+
+	err = stage.Wait(ctx, cancel, errChan)
+	if err != nil {
+		return
+	}
+
+	if fuzzy {
+		fmt.Fprintf(env.Stdout, "%d\tFuzzy strings\n", n)
+	} else {
+		fmt.Fprintf(env.Stdout, "%d\tNormal strings\n", n)
+	}
+	return
+}
+
+func fuzzyExport(env *Env, fuzzy bool, missing bool, srcName string, tmName string, tgtName string) (err error) {
+	// Load map of translations from the tm file.
+	translations, err := loadMessagesMap(env, tmName, "-tm")
+	if err != nil {
+		return
+	}
+
+	// Open source file
+	srcFile, err := env.Open(srcName)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	// Open target file
+	tgtFile, err := env.Create(tgtName)
+	if err != nil {
+		return
+	}
+	defer tgtFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Start loading the messages asynchronously from the srcFile
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+
+	// Start translating messages asynchronously
+	msgChan = translate.TranslateMessages(ctx, msgChan, translate.NewChain(translations))
+
+	// Filter out any (non)fuzzy messages asynchronously
+	msgChan = translate.FilterMessages(ctx, fuzzy, missing, msgChan)
+
+	// Finally write the fuzzy messages to a file synchronously.
+	n, err := dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(tgtFile))
+
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if err = tgtFile.Commit(); err != nil {
+		return
+	}
+
+	if fuzzy {
+		env.Logf("%d\tFuzzy strings written to %q\n", n, tgtName)
+	} else {
+		env.Logf("%d\tNormal strings written to %q\n", n, tgtName)
+	}
+	return
+}
+
+func fuzzyImport(env *Env, tmName, tgtName string) (err error) {
+	// Perform the merge into an in memory bytes.Buffer
+	resultBuf := &bytes.Buffer{}
+	n, err := translate.MergeMessagesFile(env.Context(), tmName, tgtName, resultBuf)
+	if err != nil {
+		return
+	}
+
+	// Open existing translation file for writing
+	tmFile, err := env.Create(tmName)
+	if err != nil {
+		return
+	}
+	defer tmFile.Close()
+
+	// Write the resultBuf to the tmFile.
+	if _, err = resultBuf.WriteTo(dotstrings.NewWriterUTF16(tmFile)); err != nil {
+		return
+	}
+	if err = tmFile.Commit(); err != nil {
+		return
+	}
+
+	env.Logf("%d\tStrings written to %q\n", n, tmName)
+	return
+}
+
+func fuzzyTerms(env *Env, srcName string, tmName string, glossaryName string, lang string) (err error) {
+	// Load the glossary
+	g, err := glossary.LoadFile(glossaryName)
+	if err != nil {
+		return
+	}
+
+	// Load map of translations from tm file
+	translations, err := loadMessagesMap(env, tmName, "-tm")
+	if err != nil {
+		return
+	}
+
+	// Open source file
+	srcFile, err := env.Open(srcName)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Start loading the messages asynchronously
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+
+	// Translate messages asynchronously
+	msgChan = translate.TranslateMessages(ctx, msgChan, translate.NewChain(translations))
+
+	// Check the translated messages against the glossary asynchronously
+	violationChan := glossary.CheckMessages(ctx, msgChan, g, lang)
+
+	// Synchronously report all violations
+	var n uint32
+	for v := range violationChan {
+		fmt.Fprintf(env.Stdout, "%s: %v\n", tmName, v)
+		n++
+	}
+
+	err = stage.Wait(ctx, cancel, errChan)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(env.Stdout, "%d\tGlossary violations\n", n)
+	if n > 0 {
+		err = IssuesError(fmt.Sprintf("%d strings in %q do not follow the glossary", n, tmName))
+	}
+	return
+}
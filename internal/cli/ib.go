@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/ib"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var ibCommand = &Command{
+	Name:    "ib",
+	Summary: "export the strings of storyboard and xib files, or find obsolete translations of them",
+	Help: `Reads Interface Builder .storyboard and .xib files without Xcode. The localizable properties of
+their objects, like the text of labels, the titles of buttons, segments and navigation items and
+placeholders, are exported the way ibtool --export-strings-file exports them.`,
+	Commands: []*Command{
+		ibExportCommand,
+		ibObsoleteCommand,
+	},
+}
+
+var ibExportCommand = &Command{
+	Name:    "export",
+	Args:    "file",
+	Summary: "write the localizable strings of a storyboard or xib file to a .strings file",
+	Help: `Writes the localizable strings of the storyboard or xib file to the -out .strings file, with a
+comment describing every object:
+
+  e.g. ib export -out en.lproj/Main.strings Base.lproj/Main.storyboard`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		outName := fs.String("out", "-", "file to write the strings to")
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single storyboard or xib file")
+			}
+			if err = ibFlag(args[0]); err != nil {
+				return
+			}
+			if err = stringsFlag(*outName, "-out", true); err != nil {
+				return
+			}
+			inFile, err := env.Open(args[0])
+			if err != nil {
+				return
+			}
+			defer inFile.Close()
+			outFile, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer outFile.Close()
+
+			ctx, cancel := context.WithCancel(env.Context())
+			defer cancel()
+			msgChan, errChan := ib.LoadMessages(ctx, inFile)
+			n, err := dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(outFile))
+			if e := stage.Wait(ctx, cancel, errChan); e != nil {
+				return fmt.Errorf("Failed to read %q (%v)", args[0], e)
+			}
+			if err != nil {
+				return
+			}
+			if err = outFile.Commit(); err != nil {
+				return
+			}
+			env.Logf("Exported %d strings from %q\n", n, args[0])
+			return
+		}
+	},
+}
+
+var ibObsoleteCommand = &Command{
+	Name:    "obsolete",
+	Args:    "file",
+	Summary: "list the translations of objects that are no longer in a storyboard or xib file",
+	Help: `Lists the IDs of the -strings file whose ObjectID is no longer in the storyboard or xib file,
+the objects were removed since the strings were exported. The command exits with 1 when there are
+any.
+
+  e.g. ib obsolete -strings fr.lproj/Main.strings Base.lproj/Main.storyboard`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		stringsName := fs.String("strings", "", "translated .strings file exported from the storyboard or xib file")
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single storyboard or xib file")
+			}
+			if err = ibFlag(args[0]); err != nil {
+				return
+			}
+			if err = stringsFlag(*stringsName, "-strings", true); err != nil {
+				return
+			}
+			messages, err := loadTargetMessages(env, *stringsName)
+			if err != nil {
+				return
+			}
+
+			inFile, err := env.Open(args[0])
+			if err != nil {
+				return
+			}
+			defer inFile.Close()
+			r := ib.NewReader(inFile)
+			for _, e := range r.All() {
+				if e != nil {
+					return fmt.Errorf("Failed to read %q (%v)", args[0], e)
+				}
+			}
+
+			obsolete := ib.Obsolete(r.ObjectIDs(), messages)
+			for _, id := range obsolete {
+				fmt.Fprintln(env.Stdout, id)
+			}
+			if len(obsolete) > 0 {
+				return IssuesError(fmt.Sprintf("%d obsolete strings in %q", len(obsolete), *stringsName))
+			}
+			return
+		}
+	},
+}
+
+// ibFlag checks that name is an Interface Builder file.
+func ibFlag(name string) error {
+	switch ext := filepath.Ext(name); {
+	case name == "-", strings.EqualFold(ext, ".storyboard"), strings.EqualFold(ext, ".xib"):
+		return nil
+	default:
+		return fmt.Errorf("Error: Unsupported file type %q", ext)
+	}
+}
+
+// loadTargetMessages loads the translated .strings file name, fuzzy entries
+// included.
+func loadTargetMessages(env *Env, name string) (map[string]dotstrings.Message, error) {
+	file, err := env.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return dotstrings.LoadTargetMessagesMap(dotstrings.NewReaderUTF16(file))
+}
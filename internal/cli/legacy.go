@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"flag"
+	"io"
+)
+
+// legacy maps the arguments of a standalone command to the arguments of the
+// equivalent translate subcommand.
+var legacy = map[string]func(args []string) []string{
+	"xliff":      legacyXliff,
+	"xlate":      prefix("translate"),
+	"fuzzy":      legacyFuzzy,
+	"stringsfmt": prefix("format"),
+	"tplex":      prefix("template"),
+	"lint":       prefix("lint"),
+	"report":     prefix("report"),
+	"autofix":    prefix("autofix"),
+	"lproj":      legacyLproj,
+}
+
+// legacyExit lists the standalone commands that exited with 1 when they
+// failed, which their aliases keep doing for existing scripts. Invalid flags
+// or arguments exit with ExitError, as they did.
+var legacyExit = map[string]bool{
+	"xliff":      true,
+	"xlate":      true,
+	"fuzzy":      true,
+	"stringsfmt": true,
+	"tplex":      true,
+}
+
+// Legacy runs the standalone command name, e.g. xlate, as the translate
+// subcommand it is an alias of and returns the exit code.
+func Legacy(env *Env, name string, args []string) int {
+	convert, ok := legacy[name]
+	if !ok {
+		panic("unknown legacy command " + name)
+	}
+	env.legacyExit = legacyExit[name]
+	return Main(env, convert(args))
+}
+
+func prefix(command string) func(args []string) []string {
+	return func(args []string) []string {
+		return append([]string{command}, args...)
+	}
+}
+
+// legacyXliff maps xliff to convert, except translating with an XLIFF file
+// (-source, -xliff and -out) which maps to translate.
+func legacyXliff(args []string) []string {
+	fs := flag.NewFlagSet("xliff", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outName := fs.String("out", "", "")
+	srcName := fs.String("source", "", "")
+	fs.String("target", "", "")
+	xlfName := fs.String("xliff", "", "")
+	if err := fs.Parse(args); err != nil {
+		// Let convert report the error.
+		return append([]string{"convert"}, args...)
+	}
+	if len(*outName) > 0 && len(*srcName) > 0 && len(*xlfName) > 0 {
+		return []string{"translate", "-source", *srcName, "-xliff", *xlfName, "-target", *outName}
+	}
+	return append([]string{"convert"}, args...)
+}
+
+// legacyFuzzy maps the -count, -export and -import flags and -glossary of
+// fuzzy to the fuzzy subcommands.
+func legacyFuzzy(args []string) []string {
+	fs := flag.NewFlagSet("fuzzy", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, name := range []string{"normal", "present", "count", "export", "import"} {
+		fs.Bool(name, false, "")
+	}
+	for _, name := range []string{"tm", "source", "target", "glossary", "lang"} {
+		fs.String(name, "", "")
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return []string{"fuzzy", "-h"}
+		}
+		return append([]string{"fuzzy", "count"}, args...)
+	}
+
+	command, allowed := "count", []string{"normal", "present", "tm", "source"}
+	switch {
+	case fs.Lookup("export").Value.String() == "true":
+		command, allowed = "export", []string{"normal", "present", "tm", "source", "target"}
+	case fs.Lookup("import").Value.String() == "true":
+		command, allowed = "import", []string{"tm", "target"}
+	case len(fs.Lookup("glossary").Value.String()) > 0:
+		command, allowed = "terms", []string{"tm", "source", "glossary", "lang"}
+	}
+
+	out := []string{"fuzzy", command}
+	fs.Visit(func(f *flag.Flag) {
+		for _, a := range allowed {
+			if a == f.Name {
+				out = append(out, "-"+f.Name+"="+f.Value.String())
+			}
+		}
+	})
+	return append(out, fs.Args()...)
+}
+
+// legacyLproj maps the -count, -translate, -export and -import flags of lproj
+// to the lproj subcommands.
+func legacyLproj(args []string) []string {
+	fs := flag.NewFlagSet("lproj", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, name := range []string{"normal", "present", "count", "translate"} {
+		fs.Bool(name, false, "")
+	}
+	for _, name := range []string{"root", "base", "j", "export", "import"} {
+		fs.String(name, "", "")
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return []string{"lproj", "-h"}
+		}
+		return append([]string{"lproj", "count"}, args...)
+	}
+
+	var command, dir string
+	allowed := []string{"root", "base", "j"}
+	switch {
+	case fs.Lookup("translate").Value.String() == "true":
+		command = "translate"
+	case len(fs.Lookup("export").Value.String()) > 0:
+		command, dir = "export", fs.Lookup("export").Value.String()
+		allowed = append(allowed, "normal", "present")
+	case len(fs.Lookup("import").Value.String()) > 0:
+		command, dir = "import", fs.Lookup("import").Value.String()
+	default:
+		command = "count"
+	}
+
+	out := []string{"lproj", command}
+	fs.Visit(func(f *flag.Flag) {
+		for _, a := range allowed {
+			if a == f.Name {
+				out = append(out, "-"+f.Name+"="+f.Value.String())
+			}
+		}
+	})
+	if len(dir) > 0 {
+		out = append(out, dir)
+	}
+	return append(out, fs.Args()...)
+}
@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/glossary"
+	"github.com/simpleapps-eu/translate/lint"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plural"
+	"github.com/simpleapps-eu/translate/po"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+type lintFlags struct {
+	srcName, tgtName, xlfName, glossaryName, lang string
+	format, outName, disable, levels, min, fail   string
+	list                                          bool
+}
+
+var lintCommand = &Command{
+	Name:    "lint",
+	Summary: "check the quality of translations",
+	Help: `Checks the quality of the translations in the -target .strings file of the -source .strings
+file, or in the -xliff file. Issues can be suppressed per entry by adding lint:ignore or
+lint:ignore=rule,... to the comment of the entry in the source file (or the note in XLIFF).
+
+The -target can also be a plural catalog: a .stringsdict file or an Android resources .xml
+file, paired with the same kind of -source file, or a .po file, whose msgid_plural are the
+source texts. The plurals rule checks that their plural variants have exactly the plural
+categories of the language.
+
+  e.g. lint -source en.strings -target fr.strings -format junit -out lint.xml
+       lint -source en.lproj/Localizable.stringsdict -target ru.lproj/Localizable.stringsdict
+       lint -target ru.po`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		var f lintFlags
+		fs.StringVar(&f.srcName, "source", "", ".strings file in source language")
+		fs.StringVar(&f.tgtName, "target", "", ".strings file in target language")
+		fs.StringVar(&f.xlfName, "xliff", "", ".xlf file with source and target to check instead of -source and -target")
+		fs.StringVar(&f.glossaryName, "glossary", "", "JSON glossary file with the approved terminology")
+		fs.StringVar(&f.lang, "lang", "", "language of the translations (default: XLIFF target-language or the locale of the -target name)")
+		fs.StringVar(&f.format, "format", "text", "report format: text, json or junit")
+		fs.StringVar(&f.outName, "out", "-", "file to write the report to")
+		fs.StringVar(&f.disable, "disable", "", "comma separated list of rules to disable")
+		fs.StringVar(&f.levels, "level", "", "comma separated list of rule=severity pairs overriding the default severities")
+		fs.StringVar(&f.min, "min", "info", "minimum severity to report: info, warning or error")
+		fs.StringVar(&f.fail, "fail", "error", "minimum severity of the issues that make lint exit with 1")
+		fs.BoolVar(&f.list, "list", false, "list the available rules and their default severity")
+		return func(env *Env, args []string) error {
+			if err := wantNoArgs(args); err != nil {
+				return err
+			}
+			return runLint(env, &f)
+		}
+	},
+}
+
+func runLint(env *Env, f *lintFlags) (err error) {
+	if f.list {
+		for _, r := range lint.DefaultRules() {
+			fmt.Fprintf(env.Stdout, "%-14s %s\n", r.Name(), r.Severity())
+		}
+		fmt.Fprintf(env.Stdout, "%-14s %s\n", "glossary", lint.Error)
+		return
+	}
+
+	if len(f.xlfName) == 0 && (len(f.tgtName) == 0 || len(f.srcName) == 0 && !isPO(f.tgtName)) {
+		return usagef("-source and -target, or -xliff are required")
+	}
+	if len(f.srcName) > 0 && isPO(f.tgtName) {
+		return usagef("-source can't be used with a .po -target, its msgid_plural are the source texts")
+	}
+
+	if f.lang, err = flagLang("-lang", f.lang, ""); err != nil {
+		return
+	}
+
+	linter, err := newLinter(f)
+	if err != nil {
+		return
+	}
+
+	min, err := lint.ParseSeverity(f.min)
+	if err != nil {
+		return
+	}
+	fail, err := lint.ParseSeverity(f.fail)
+	if err != nil {
+		return
+	}
+
+	report := &lint.Report{Min: min}
+	switch {
+	case len(f.xlfName) > 0:
+		err = lintXLIFF(env, linter, report, f.xlfName)
+	case isPluralCatalog(f.tgtName):
+		err = lintPlurals(env, linter, report, f)
+	default:
+		err = lintStrings(env, linter, report, f)
+	}
+	if err != nil {
+		return
+	}
+
+	out, err := env.Create(f.outName)
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	if err = writeLintReport(report, f.format, out); err != nil {
+		return
+	}
+	if err = out.Commit(); err != nil {
+		return
+	}
+
+	if n := report.Count(fail); n > 0 {
+		err = IssuesError(fmt.Sprintf("%d issues of severity %s or higher", n, fail))
+	}
+	return
+}
+
+func newLinter(f *lintFlags) (linter *lint.Linter, err error) {
+	linter = lint.New(lint.DefaultRules()...)
+
+	if len(f.glossaryName) > 0 {
+		var g *glossary.Glossary
+		g, err = glossary.LoadFile(f.glossaryName)
+		if err != nil {
+			return
+		}
+		linter.Rules = append(linter.Rules, lint.Glossary(g, f.lang))
+	}
+
+	// The names of all rules, including the disabled ones, for checking -level
+	known := make(map[string]bool)
+	for _, r := range linter.Rules {
+		known[r.Name()] = true
+	}
+
+	if len(f.disable) > 0 {
+		if err = linter.Disable(strings.Split(f.disable, ",")...); err != nil {
+			return
+		}
+	}
+
+	if len(f.levels) > 0 {
+		for _, pair := range strings.Split(f.levels, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				err = usagef("expected rule=severity in -level, found %q", pair)
+				return
+			}
+			if !known[kv[0]] {
+				err = usagef("unknown rule %q in -level", kv[0])
+				return
+			}
+			linter.Levels[kv[0]], err = lint.ParseSeverity(kv[1])
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func lintStrings(env *Env, linter *lint.Linter, report *lint.Report, f *lintFlags) (err error) {
+	for _, name := range []string{f.srcName, f.tgtName} {
+		if ext := filepath.Ext(name); name != "-" && !strings.EqualFold(ext, ".strings") {
+			return fmt.Errorf("Error: Unsupported file type %q", ext)
+		}
+	}
+
+	tgtLang := f.lang
+	if len(tgtLang) == 0 {
+		tgtLang = fileLang(f.tgtName)
+	}
+
+	translations, err := loadTargetMessages(env, f.tgtName)
+	if err != nil {
+		return
+	}
+
+	srcFile, err := env.Open(f.srcName)
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Pair the source and target messages and check them asynchronously
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	entryChan := lint.PairMessages(ctx, msgChan, translations, f.tgtName, tgtLang)
+
+	// Synchronously collect the results
+	for res := range linter.CheckEntries(ctx, entryChan) {
+		report.Add(res)
+	}
+
+	err = stage.Wait(ctx, cancel, errChan)
+	return
+}
+
+func lintXLIFF(env *Env, linter *lint.Linter, report *lint.Report, xlfName string) (err error) {
+	xlfFile, err := env.Open(xlfName)
+	if err != nil {
+		return
+	}
+	defer xlfFile.Close()
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Load the translation units and check them asynchronously
+	tuChan, errChan := xliff.LoadTranslationUnits(ctx, xlfFile)
+	entryChan := lint.ConvertTranslationUnits(ctx, tuChan, xlfName)
+
+	// Synchronously collect the results
+	for res := range linter.CheckEntries(ctx, entryChan) {
+		report.Add(res)
+	}
+
+	err = stage.Wait(ctx, cancel, errChan)
+	return
+}
+
+// isPluralCatalog reports whether name is a plural catalog lint can check: a
+// .stringsdict, .po or Android resources .xml file.
+func isPluralCatalog(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".stringsdict", ".po", ".xml":
+		return true
+	}
+	return false
+}
+
+func isPO(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".po")
+}
+
+// lintPlurals checks the plural messages of the -target plural catalog. The
+// messages are paired by ID with those of the -source catalog, which supplies
+// their source texts, a PO file has them itself.
+func lintPlurals(env *Env, linter *lint.Linter, report *lint.Report, f *lintFlags) (err error) {
+	if len(f.srcName) > 0 && !strings.EqualFold(filepath.Ext(f.srcName), filepath.Ext(f.tgtName)) {
+		return fmt.Errorf("Error: Unsupported -source file type %q for -target %q", filepath.Ext(f.srcName), f.tgtName)
+	}
+
+	tgtLang := f.lang
+	if len(tgtLang) == 0 {
+		tgtLang = fileLang(f.tgtName)
+	}
+	msgs, tgtLang, err := loadPlurals(env, f.tgtName, tgtLang)
+	if err != nil {
+		return
+	}
+	if len(tgtLang) == 0 {
+		return usagef("-lang is required, the language of %q is unknown", f.tgtName)
+	}
+
+	if len(f.srcName) > 0 {
+		var sources []plural.Message
+		if sources, _, err = loadPlurals(env, f.srcName, ""); err != nil {
+			return
+		}
+		byID := make(map[string]plural.Message, len(msgs))
+		for _, m := range msgs {
+			byID[m.ID] = m
+		}
+		msgs = msgs[:0]
+		for _, src := range sources {
+			if m, ok := byID[src.ID]; ok {
+				m.Source = src.Forms.Other().Text
+				msgs = append(msgs, m)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	// Synchronously collect the results
+	for res := range linter.CheckEntries(ctx, lint.PluralMessages(ctx, msgs, f.tgtName, tgtLang)) {
+		report.Add(res)
+	}
+	return
+}
+
+// loadPlurals loads the plural messages of the plural catalog name in the
+// language lang. The language of a PO file defaults to its Language header,
+// the language found is returned.
+func loadPlurals(env *Env, name, lang string) (msgs []plural.Message, _ string, err error) {
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".stringsdict":
+		var data []byte
+		if data, err = io.ReadAll(file); err != nil {
+			return
+		}
+		msgs, err = plural.LoadStringsDict(data)
+	case ".xml":
+		msgs, err = plural.LoadAndroid(file)
+	default:
+		msgs, lang, err = loadPOPlurals(file, lang)
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	return msgs, lang, err
+}
+
+// loadPOPlurals loads the plural messages of the PO file r in the language
+// lang, by default the Language of its header. Without a language no
+// messages are loaded and the language returned is empty.
+func loadPOPlurals(r io.Reader, lang string) (msgs []plural.Message, _ string, err error) {
+	entries, err := po.Load(r)
+	if err != nil {
+		return
+	}
+	if len(lang) == 0 {
+		lang = po.Header(entries, "Language")
+	}
+	l, err := locale.ParseGettext(lang)
+	if err != nil {
+		return nil, "", nil
+	}
+	msgs, err = plural.FromPO(l, entries)
+	return msgs, l.String(), err
+}
+
+// writeLintReport writes report to w in the text, json or junit format.
+func writeLintReport(report *lint.Report, format string, w io.Writer) error {
+	switch format {
+	case "text":
+		return report.WriteText(w)
+	case "json":
+		return report.WriteJSON(w)
+	case "junit":
+		return report.WriteJUnit(w)
+	}
+	return usagef("unsupported -format %q", format)
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/simpleapps-eu/translate/project"
+)
+
+var lprojCommand = &Command{
+	Name:    "lproj",
+	Summary: "process every table of every locale in a resource directory",
+	Help: `Processes every table of every locale in the -root resource directory at once. Every locale has
+a <locale>.lproj directory containing its .strings tables, the tables of the -base locale are
+the source strings. Tables are paired by file name. Entries a locale doesn't translate are
+taken from its fallback locales, by default a locale like de-AT falls back to de, the
+"fallbacks" of the -config project configuration override it.
+
+  e.g. lproj translate -root MyApp/Resources
+       lproj export -root MyApp/Resources ToTranslate
+       lproj import -root MyApp/Resources Translated
+       lproj count -root MyApp/Resources -config translate.json`,
+	Commands: []*Command{
+		lprojCountCommand,
+		lprojTranslateCommand,
+		lprojExportCommand,
+		lprojImportCommand,
+	},
+}
+
+// lprojFlags defines the flags shared by the lproj subcommands.
+type lprojFlags struct {
+	rootName, base, configName string
+	workers                    int
+}
+
+func (f *lprojFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&f.rootName, "root", ".", "directory containing the .lproj directories")
+	fs.StringVar(&f.base, "base", "", "base locale holding the source strings (default: en, en-US or Base)")
+	fs.StringVar(&f.configName, "config", "", "project configuration file whose fallback chains are used")
+	fs.IntVar(&f.workers, "j", 0, "number of locales processed concurrently (default: number of CPUs)")
+}
+
+var lprojCountCommand = &Command{
+	Name:    "count",
+	Summary: "count the strings of every locale",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		f := &lprojFlags{}
+		f.define(fs)
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			return runLproj(env, f, project.Count)
+		}
+	},
+}
+
+var lprojTranslateCommand = &Command{
+	Name:    "translate",
+	Summary: "translate the base tables into every locale, marking new and changed strings fuzzy",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		f := &lprojFlags{}
+		f.define(fs)
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			return runLproj(env, f, project.Translate)
+		}
+	},
+}
+
+var lprojExportCommand = &Command{
+	Name:    "export",
+	Args:    "dir",
+	Summary: "export the (non)fuzzy strings of every locale into <locale>.lproj directories in dir",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		f := &lprojFlags{}
+		f.define(fs)
+		normal := fs.Bool("normal", false, "export normal instead of fuzzy strings")
+		present := fs.Bool("present", false, "export fuzzy strings not missing from the locale")
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single directory to export to")
+			}
+			return runLproj(env, f, project.Export(args[0], !*normal, !*present))
+		}
+	},
+}
+
+var lprojImportCommand = &Command{
+	Name:    "import",
+	Args:    "dir",
+	Summary: "import translated strings from <locale>.lproj directories in dir",
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		f := &lprojFlags{}
+		f.define(fs)
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single directory to import from")
+			}
+			return runLproj(env, f, project.Import(args[0]))
+		}
+	},
+}
+
+// runLproj runs op for every locale of the project in the -root directory
+// and writes a summary per locale.
+func runLproj(env *Env, f *lprojFlags, op project.Operation) (err error) {
+	p, err := project.Discover(f.rootName, f.base)
+	if err != nil {
+		return
+	}
+	p.Backup = env.Backup
+	if len(f.configName) > 0 {
+		if p.Fallbacks, err = configFallbacks(f.configName, nil); err != nil {
+			return
+		}
+	}
+
+	summaries := p.Run(env.Context(), op, f.workers)
+
+	tw := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Locale\tTables\tTotal\tTranslated\tFuzzy\tMissing\tObsolete\tWritten\t\n")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", s.Locale, len(s.Tables), s.Total, s.Translated, s.Fuzzy, s.Missing, s.Obsolete, s.Written)
+	}
+	if err = tw.Flush(); err != nil {
+		return
+	}
+
+	failed := 0
+	for _, s := range summaries {
+		if len(s.Extra) > 0 {
+			env.Logf("Warning: %s has tables not in %s: %s\n", s.Locale, p.Base, strings.Join(s.Extra, ", "))
+		}
+		if s.Err != nil {
+			fmt.Fprintf(env.Stderr, "Error: %s: %v\n", s.Locale, s.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d locales failed", failed)
+	}
+	return
+}
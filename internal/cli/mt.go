@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/mt"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// translateMessagesMT translates the source .strings file like
+// translate.TranslateMessagesFile does, but sends the missing entries to the
+// -mt endpoint and writes the machine translations as fuzzy drafts.
+func translateMessagesMT(ctx context.Context, srcFile io.Reader, chain translate.Chain, tgtFile io.Writer, mtf mtFlags) (n int, err error) {
+	schema, ok := mt.Schemas[strings.ToLower(mtf.api)]
+	if !ok {
+		err = fmt.Errorf("Error: Unsupported -mtapi %q", mtf.api)
+		return
+	}
+	provider := mt.NewHTTPProvider(mtf.url, schema)
+	if len(mtf.auth) > 0 {
+		provider.Header.Set("Authorization", mtf.auth)
+	}
+	opts := mt.DefaultOptions(mtf.source, mtf.target)
+
+	var cache *mt.MemoryCache
+	if len(mtf.cache) > 0 {
+		cache = mt.NewMemoryCache()
+		if err = cache.LoadFile(mtf.cache); err != nil {
+			return
+		}
+		opts.Cache = cache
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Load the messages to be translated asynchronously.
+	msgChan, errChan1 := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+
+	// Start translation asynchronously
+	msgChan = translate.TranslateMessages(ctx, msgChan, chain)
+
+	// Draft the missing translations asynchronously
+	msgChan, errChan2 := mt.TranslateMissing(ctx, msgChan, provider, opts)
+
+	// Save the translated messages synchronously
+	n, err = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(tgtFile))
+
+	if e := stage.Wait(ctx, cancel, errChan1, errChan2); e != nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+
+	if cache != nil {
+		err = cache.SaveFile(mtf.cache)
+	}
+	return
+}
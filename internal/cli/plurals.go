@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plural"
+	"github.com/simpleapps-eu/translate/po"
+)
+
+var pluralsCommand = &Command{
+	Name:    "plurals",
+	Summary: "add the plural forms a PO file lacks for its language, marked fuzzy",
+	Help: `Completes the msgstr[n] of the plural entries in the -target .po file with the plural forms the
+language needs, so a Russian entry translated as one and other also gets few and many. The
+new forms get the text of the other form (or the last one) and the entry is marked fuzzy for
+a translator to check. Untranslated entries are left alone. The number of msgstr[n] must
+match the language, fix the Plural-Forms header first when it doesn't. Without -w or -out
+the entries are only counted (dry-run).
+
+Use lint to check the plurals of .stringsdict and Android resources files, which can't mark
+a form fuzzy.
+
+  e.g. plurals -target ru.po -w`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		tgtName := fs.String("target", "", ".po file in target language to complete")
+		outName := fs.String("out", "", "file to write the completed .po file to")
+		lang := fs.String("lang", "", "language of the -target file (default: the Language header or the locale of the -target name)")
+		doWrite := fs.Bool("w", false, "write the completed entries back to the -target file")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if len(*tgtName) == 0 {
+				return usagef("-target is required")
+			}
+			if *tgtName != "-" && !isPO(*tgtName) {
+				return usagef("-target must be a .po file")
+			}
+			if *doWrite && (len(*outName) > 0 || *tgtName == "-") {
+				return usagef("-w can't be combined with -out or a -target read from standard input")
+			}
+			if *lang, err = flagLang("-lang", *lang, *tgtName); err != nil {
+				return
+			}
+
+			entries, err := loadPO(env, *tgtName)
+			if err != nil {
+				return
+			}
+			if len(*lang) == 0 {
+				*lang = po.Header(entries, "Language")
+			}
+			l, err := locale.ParseGettext(*lang)
+			if err != nil {
+				return usagef("-lang is required, the language of %q is unknown", *tgtName)
+			}
+
+			n, err := plural.ScaffoldPO(l, entries)
+			if err != nil {
+				return fmt.Errorf("Failed to complete %q (%v)", *tgtName, err)
+			}
+
+			if *doWrite {
+				*outName = *tgtName
+			}
+			if len(*outName) == 0 {
+				env.Logf("%d\tPlural entries would be completed in %q\n", n, *tgtName)
+				return
+			}
+
+			out, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer out.Close()
+			if err = po.Save(out, entries); err != nil {
+				return
+			}
+			if err = out.Commit(); err != nil {
+				return
+			}
+			env.Logf("%d\tPlural entries completed, written to %q\n", n, *outName)
+			return
+		}
+	},
+}
+
+// loadPO loads the entries of the PO file name.
+func loadPO(env *Env, name string) (entries []po.Entry, err error) {
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if entries, err = po.Load(file); err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	return
+}
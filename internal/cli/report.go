@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/config"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/project"
+	"github.com/simpleapps-eu/translate/report"
+)
+
+var reportCommand = &Command{
+	Name:    "report",
+	Summary: "report the translation progress of every locale",
+	Help: `Reports the translation progress of every locale in the -root resource directory. Every locale
+has a <locale>.lproj directory containing its .strings tables, the tables of the -base locale
+are the source strings.
+
+Entries a locale doesn't translate count as translated by its fallback locales, the Fallback
+column shows how many. By default a locale like de-AT falls back to de, the "fallbacks" of the
+-config project configuration override it and -fallback overrides both:
+
+  e.g. report -root MyApp/Resources -format html -out progress.html
+       report -config translate.json -fallback de-CH=de
+       report -fallback pt-PT=pt-BR,pt -fallback de-CH=de`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		rootName := fs.String("root", ".", "directory containing the .lproj directories")
+		base := fs.String("base", "", "base locale holding the source strings (default: en, en-US or Base)")
+		format := fs.String("format", "text", "report format: text, json or html")
+		outName := fs.String("out", "-", "file to write the report to")
+		configName := fs.String("config", "", "project configuration file whose fallback chains are used")
+		fallbacks := locale.Fallbacks{}
+		fs.Var(fallbackFlag(fallbacks), "fallback", "locale=fallback,... chain of fallback locales, can be given more than once")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+
+			p, err := project.Discover(*rootName, *base)
+			if err != nil {
+				return
+			}
+			p.Fallbacks = fallbacks
+			if len(*configName) > 0 {
+				if p.Fallbacks, err = configFallbacks(*configName, fallbacks); err != nil {
+					return
+				}
+			}
+
+			r, err := report.Build(env.Context(), p)
+			if err != nil {
+				return
+			}
+
+			out, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer out.Close()
+
+			switch *format {
+			case "text":
+				err = r.WriteText(out)
+			case "json":
+				err = r.WriteJSON(out)
+			case "html":
+				err = r.WriteHTML(out)
+			default:
+				err = usagef("unsupported -format %q", *format)
+			}
+			if err != nil {
+				return
+			}
+			return out.Commit()
+		}
+	},
+}
+
+// fallbackFlag is a flag that adds a fallback chain given as
+// locale=fallback,... to the fallbacks.
+type fallbackFlag locale.Fallbacks
+
+func (f fallbackFlag) String() string { return "" }
+
+func (f fallbackFlag) Set(s string) error {
+	code, list, ok := strings.Cut(s, "=")
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("expected locale=fallback,... got %q", s)
+	}
+	chain := locale.Fallbacks{code: strings.Split(list, ",")}
+	if err := chain.Validate(); err != nil {
+		return err
+	}
+	f[code] = chain[code]
+	return nil
+}
+
+// configFallbacks returns the fallback chains of the project configuration
+// file name, overridden by those in overrides.
+func configFallbacks(name string, overrides locale.Fallbacks) (fallbacks locale.Fallbacks, err error) {
+	c, err := config.LoadFile(name)
+	if err != nil {
+		return
+	}
+	fallbacks = locale.Fallbacks{}
+	for code, chain := range c.Fallbacks {
+		fallbacks[code] = chain
+	}
+	for code, chain := range overrides {
+		fallbacks[code] = chain
+	}
+	return
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/simpleapps-eu/translate/config"
+)
+
+var syncCommand = &Command{
+	Name:    "sync",
+	Summary: "update all files of the project described by a configuration file",
+	Help: `Brings the project described by the -config file up to date: runs the extract commands,
+normalizes the source files, updates the target files of every target locale, exports them as
+XLIFF and runs the checks. Entries a target locale doesn't translate are taken from the target
+files of its fallback locales, as given by "fallbacks" in the configuration.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		configName := fs.String("config", "translate.json", "project configuration file")
+		noExtract := fs.Bool("no-extract", false, "don't run the extract commands")
+		format := fs.String("format", "text", "format of the check report: text, json or junit")
+		outName := fs.String("out", "-", "file to write the check report to")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+
+			c, err := config.LoadFile(*configName)
+			if err != nil {
+				return
+			}
+
+			results, report, err := c.Sync(env.Context(), config.SyncOptions{NoExtract: *noExtract, Output: env.Stderr, Backup: env.Backup})
+			if err != nil {
+				return
+			}
+
+			tw := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintf(tw, "Locale\tFiles\tTotal\tTranslated\tFuzzy\tMissing\tObsolete\tXLIFF\t\n")
+			for _, r := range results {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", r.Locale, r.Files, r.Total, r.Translated, r.Fuzzy, r.Missing, r.Obsolete, r.XLIFF)
+			}
+			if err = tw.Flush(); err != nil {
+				return
+			}
+
+			if report == nil {
+				return
+			}
+
+			out, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer out.Close()
+			if err = writeLintReport(report, *format, out); err != nil {
+				return
+			}
+			if err = out.Commit(); err != nil {
+				return
+			}
+
+			fail := c.Checks.FailSeverity()
+			if n := report.Count(fail); n > 0 {
+				err = IssuesError(fmt.Sprintf("%d issues of severity %s or higher", n, fail))
+			}
+			return
+		}
+	},
+}
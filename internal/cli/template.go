@@ -0,0 +1,378 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/config"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/i18n"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+var templateCommand = &Command{
+	Name:    "template",
+	Summary: "execute a text template with JSON data",
+	Help: `Executes the -template file with the JSON value in the -data file and writes the result to -out.
+Either file can be - to use standard input or output.
+
+  e.g. template -template greeting.tpl -data john.json
+
+  with greeting.tpl: This is {{.firstName}} {{.surName}}, {{.age}} years old.
+
+The template can use these functions to render the text for the -locale:
+
+  T "id" [name value]...      the translation of id in -tm, or in the -tmfb files when -tm lacks it,
+                              formatted as ICU message with the name value pairs
+  TN "id" count [name value]  T with count as the value of the plural arguments of the translation
+
+A string right after the id of T, or after the count of TN, is a comment for the translator. It
+is ignored here and collected by extract.
+
+  number value                the number formatted for the locale
+  currency "EUR" value        the amount formatted for the locale
+  date value                  the date formatted for the locale
+
+  e.g. template -template mail.tpl -data john.json -tm fr.strings -locale fr
+
+  with mail.tpl: {{T "greeting" "name" .firstName}} {{TN "unread" .unread}}
+
+With -locales or -config the template is rendered for every locale at once. The -tm and -out
+names then contain {locale}, which is replaced by every locale in turn. Translations missing
+from the memory of a locale are taken from the memories of its parent locales, like fr for
+fr-CA. With -config the locales and their memories are those of the project.
+
+  e.g. template -template welcome.html -partials 'partials/*.html' -html -data user.json
+                -locales fr,de,fr-CA -tm 'strings/{locale}.strings' -out 'out/{locale}/welcome.html'
+
+Translations that are missing are reported per locale, the command then exits with 1.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		templName := fs.String("template", "", "template file to execute")
+		dataName := fs.String("data", "", "file with json data to execute the template with")
+		outName := fs.String("out", "-", "file to write the template execution result to, contains {locale} with -locales or -config")
+		tmName := fs.String("tm", "", ".strings file used as translation memory by T and TN, contains {locale} with -locales")
+		var tmfbNames listFlag
+		fs.Var(&tmfbNames, "tmfb", "file used as fallback translation memory, can be given more than once")
+		lang := fs.String("locale", "", "locale to render the template for (default: the locale of the -tm name)")
+		langs := fs.String("locales", "", "comma separated locales to render the template for")
+		configName := fs.String("config", "", "project configuration file whose locales the template is rendered for")
+		var partials listFlag
+		fs.Var(&partials, "partials", "glob pattern of templates the template can include, can be given more than once")
+		html := fs.Bool("html", false, "execute the template as HTML template, escaping the inserted values")
+		workers := fs.Int("j", 0, "number of locales rendered concurrently (default: number of CPUs)")
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if len(*templName) == 0 || len(*dataName) == 0 {
+				return usagef("-template and -data are required")
+			}
+			if *templName == "-" && *dataName == "-" {
+				return usagef("-template and -data can't both be read from standard input")
+			}
+
+			var targets []renderTarget
+			switch {
+			case len(*configName) > 0 && (len(*langs) > 0 || len(*lang) > 0 || len(*tmName) > 0 || len(tmfbNames) > 0):
+				return usagef("-config can't be combined with -locale, -locales, -tm or -tmfb")
+			case len(*langs) > 0 && len(*lang) > 0:
+				return usagef("use either -locale or -locales")
+			case len(*configName) > 0:
+				targets, err = configTargets(*configName)
+			case len(*langs) > 0:
+				targets, err = patternTargets(env, strings.Split(*langs, ","), *tmName, tmfbNames)
+			default:
+				var z *i18n.Localizer
+				if z, err = localizer(env, *tmName, tmfbNames, *lang); err == nil {
+					targets = []renderTarget{{code: z.Locale.String(), z: z}}
+				}
+			}
+			if err != nil {
+				return
+			}
+			batch := len(*configName) > 0 || len(*langs) > 0
+			if batch && !strings.Contains(*outName, localeVar) {
+				return usagef("-out must contain %s to render several locales", localeVar)
+			}
+
+			templ, err := parseTemplate(env, *templName, *html, partials)
+			if err != nil {
+				return
+			}
+
+			dataFile, err := env.Open(*dataName)
+			if err != nil {
+				return
+			}
+			defer dataFile.Close()
+
+			// decode any JSON value
+			var v interface{}
+			if err = json.NewDecoder(dataFile).Decode(&v); err != nil {
+				return
+			}
+
+			return renderTargets(env, templ, v, targets, *outName, batch, *workers)
+		}
+	},
+}
+
+// localeVar is replaced by the locale in the -tm and -out names.
+const localeVar = "{locale}"
+
+// renderTarget is a locale to render the template for.
+type renderTarget struct {
+	// code replaces {locale} in the -out name.
+	code string
+	z    *i18n.Localizer
+}
+
+// renderResult is the outcome of rendering the template for a target.
+type renderResult struct {
+	target  renderTarget
+	out     string
+	missing []string
+	err     error
+}
+
+// renderTargets renders templ with data for every target concurrently. The
+// -out name of a batch is created with its directories. Missing translations
+// are reported per locale and fail the command with IssuesError.
+func renderTargets(env *Env, templ *i18n.Template, data interface{}, targets []renderTarget, outName string, batch bool, workers int) (err error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(env.Context())
+	defer cancel()
+
+	targetChan := make(chan renderTarget, len(targets))
+	for _, t := range targets {
+		targetChan <- t
+	}
+	close(targetChan)
+
+	resultChan := stage.ParallelMap(ctx, targetChan, workers, func(t renderTarget) (res renderResult) {
+		res.target = t
+		res.out = strings.ReplaceAll(outName, localeVar, t.code)
+		if batch {
+			if res.err = os.MkdirAll(filepath.Dir(res.out), 0755); res.err != nil {
+				return
+			}
+		}
+		res.err = renderFile(env, templ, data, t.z, res.out)
+		res.missing = t.z.Missing()
+		return
+	})
+
+	incomplete := 0
+	for res := range resultChan {
+		switch {
+		case res.err != nil:
+			env.Logf("Failed to render %q (%v)\n", res.out, res.err)
+			if err == nil {
+				err = fmt.Errorf("Failed to render %q (%v)", res.out, res.err)
+			}
+		case len(res.missing) > 0 && len(res.target.z.Chain) > 0:
+			incomplete++
+			label := res.target.code
+			if len(label) == 0 {
+				label = res.out
+			}
+			fmt.Fprintf(env.Stderr, "%s: %d missing translations: %s\n", label, len(res.missing), strings.Join(res.missing, ", "))
+		case batch:
+			env.Logf("Rendered %q\n", res.out)
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil && incomplete > 0 {
+		err = IssuesError(fmt.Sprintf("%d of %d locales have missing translations", incomplete, len(targets)))
+	}
+	return
+}
+
+// renderFile executes templ with data for z and writes the result to the file
+// name.
+func renderFile(env *Env, templ *i18n.Template, data interface{}, z *i18n.Localizer, name string) (err error) {
+	outFile, err := env.Create(name)
+	if err != nil {
+		return
+	}
+	defer outFile.Close()
+	if err = templ.Execute(outFile, z, data); err != nil {
+		return
+	}
+	return outFile.Commit()
+}
+
+// localizer returns the localizer of the template functions for the locale
+// lang, which defaults to the locale of the -tm name. It translates using the
+// -tm file followed by the -tmfb files, without -tm T and TN return the IDs.
+func localizer(env *Env, tmName string, tmfbNames []string, lang string) (z *i18n.Localizer, err error) {
+	if lang, err = flagLang("-locale", lang, tmName); err != nil {
+		return
+	}
+	var l locale.Locale
+	if len(lang) > 0 {
+		l = locale.MustParse(lang)
+	}
+
+	var chain translate.Chain
+	switch {
+	case len(tmName) > 0:
+		if chain, err = loadChain(env, tmName, tmfbNames); err != nil {
+			return
+		}
+	case len(tmfbNames) > 0:
+		return nil, usagef("-tmfb requires -tm")
+	}
+	return i18n.New(l, chain), nil
+}
+
+// patternTargets returns the targets of the -locales langs. The memory of a
+// locale is the -tm pattern for the locale, followed by the -tm pattern of its
+// parent locales that exist and the -tmfb files.
+func patternTargets(env *Env, langs []string, tmPattern string, tmfbNames []string) (targets []renderTarget, err error) {
+	if len(tmPattern) > 0 && !strings.Contains(tmPattern, localeVar) {
+		return nil, usagef("-tm must contain %s with -locales", localeVar)
+	}
+	if len(tmPattern) == 0 && len(tmfbNames) > 0 {
+		return nil, usagef("-tmfb requires -tm")
+	}
+	for _, code := range langs {
+		code = strings.TrimSpace(code)
+		l, e := locale.Parse(code)
+		if e != nil {
+			return nil, usagef("invalid -locales (%v)", e)
+		}
+
+		var chain translate.Chain
+		if len(tmPattern) > 0 {
+			for _, fallback := range locale.Fallbacks(nil).Chain(code) {
+				name := strings.ReplaceAll(tmPattern, localeVar, fallback)
+				if len(chain) > 0 {
+					if _, e := os.Stat(name); e != nil {
+						continue
+					}
+				}
+				var translations map[string]dotstrings.Message
+				if translations, err = loadMessagesMap(env, name, "-tm"); err != nil {
+					return nil, fmt.Errorf("Failed to load %q (%v)", name, err)
+				}
+				chain = append(chain, translate.Memory{Locale: fallback, Translations: translations})
+			}
+			for _, name := range tmfbNames {
+				var fallback map[string]dotstrings.Message
+				if fallback, err = loadMessagesMap(env, name, "-tmfb"); err != nil {
+					return
+				}
+				chain = append(chain, translate.Memory{Locale: memoryLocale(name), Translations: fallback})
+			}
+		}
+		targets = append(targets, renderTarget{code: code, z: i18n.New(l, chain)})
+	}
+	return
+}
+
+// configTargets returns a target for the source and every target locale of
+// the project configuration in the file name. The memory of a locale holds the
+// non-fuzzy entries of its .strings files and its memory file, followed by
+// those of its parent locales in the project.
+func configTargets(name string) (targets []renderTarget, err error) {
+	c, err := config.LoadFile(name)
+	if err != nil {
+		return
+	}
+	locales := append([]string{c.SourceLocale}, c.TargetLocales...)
+	memories := map[string]map[string]dotstrings.Message{}
+	for _, code := range locales {
+		if memories[code], err = configMemory(c, code); err != nil {
+			return
+		}
+	}
+	for _, code := range locales {
+		var chain translate.Chain
+		for _, fallback := range locale.Fallbacks(nil).Chain(code) {
+			if translations, ok := memories[fallback]; ok {
+				chain = append(chain, translate.Memory{Locale: fallback, Translations: translations})
+			}
+		}
+		targets = append(targets, renderTarget{code: c.Code(code), z: i18n.New(locale.MustParse(code), chain)})
+	}
+	return
+}
+
+// configMemory loads the translations of the locale code in the project c.
+func configMemory(c *config.Config, code string) (memory map[string]dotstrings.Message, err error) {
+	var names []string
+	for _, f := range c.Files {
+		if code == c.SourceLocale {
+			var sources []string
+			if sources, err = c.Sources(f); err != nil {
+				return
+			}
+			names = append(names, sources...)
+			continue
+		}
+		var pairs []config.Pair
+		if pairs, err = c.Pairs(f, code); err != nil {
+			return
+		}
+		for _, p := range pairs {
+			names = append(names, p.Target)
+		}
+	}
+	if len(c.Memory) > 0 {
+		names = append(names, c.Expand(c.Memory, code, nil))
+	}
+
+	memory = map[string]dotstrings.Message{}
+	for _, name := range names {
+		messages, e := dotstrings.LoadTargetMessagesMapFromFile(name)
+		if os.IsNotExist(e) {
+			continue
+		}
+		if e != nil {
+			return nil, fmt.Errorf("Failed to load %q (%v)", name, e)
+		}
+		for id, m := range messages {
+			if _, ok := memory[id]; !ok && !m.Fuzzy {
+				memory[id] = m
+			}
+		}
+	}
+	return
+}
+
+// parseTemplate parses the template file name and the templates matching the
+// partials glob patterns.
+func parseTemplate(env *Env, name string, html bool, partials []string) (templ *i18n.Template, err error) {
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	text, err := io.ReadAll(file)
+	if err != nil {
+		return
+	}
+	if templ, err = i18n.Parse(filepath.Base(name), string(text), html); err != nil {
+		return
+	}
+	for _, pattern := range partials {
+		if err = templ.ParseGlob(pattern); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// fileTypes maps the file extensions translate accepts for -source to the
+// type of the file.
+var fileTypes = map[string]string{
+	".strings": "strings",
+	".tpl":     "tpl",
+	".txt":     "txt",
+	".plist":   "plist",
+}
+
+type mtFlags struct {
+	url, api, auth, cache, source, target string
+}
+
+var translateCommand = &Command{
+	Name:    "translate",
+	Summary: "translate a source file using a translation memory or XLIFF file",
+	Help: `Translates the -source file into the -target file using the translations in the -tm .strings
+file, or in the -xliff file. The -source file type is taken from its extension unless -type is given:
+
+  strings  .strings file, untranslated entries are written as fuzzy
+  tpl      Go template, the IDs of its T and TN calls are translated wherever they are
+  ids      file with a translation ID on every line, every line is translated as a whole
+  txt      text file, every line is translated as a whole
+  plist    property list in XML, binary or OpenStep format, its string values are translated
+           by key path: the key for the top level dict, Items/0/Title for nested values
+
+Translations missing from -tm are taken from the -tmfb files, in the order they are given. The
+fallback chain de-AT → de → en is -tm de-AT.strings -tmfb de.strings -tmfb en.strings. In a
+.strings -target a translation taken from a fallback is marked by a /* Fallback: de */ comment,
+which stays until it is removed from the translation.
+
+Any file can be - to use standard input or output.`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		tmName := fs.String("tm", "", "file used as translation memory")
+		var tmfbNames listFlag
+		fs.Var(&tmfbNames, "tmfb", "file used as fallback translation memory, can be given more than once")
+		xlfName := fs.String("xliff", "", "XLIFF file used as translation memory instead of -tm, for .strings files")
+		srcName := fs.String("source", "", "file for reading source strings")
+		tgtName := fs.String("target", "", "file to write the translated target strings to")
+		fileType := fs.String("type", "", "type of the -source file: strings, tpl, ids, txt or plist (default: -source extension)")
+		forcePLIST := fs.Bool("plist", false, "interpret .strings -source and -target as property list files, same as -type plist")
+
+		var mtf mtFlags
+		fs.StringVar(&mtf.url, "mt", "", "machine translation endpoint used to draft missing .strings translations")
+		fs.StringVar(&mtf.api, "mtapi", "generic", "machine translation API style: generic, deepl, google or libretranslate")
+		fs.StringVar(&mtf.auth, "mtauth", "", "value of the Authorization header sent to the -mt endpoint")
+		fs.StringVar(&mtf.cache, "mtcache", "", ".strings file used to cache machine translations")
+		fs.StringVar(&mtf.source, "mtsource", "en", "source language passed to the -mt endpoint")
+		fs.StringVar(&mtf.target, "mttarget", "", "target language passed to the -mt endpoint (default: the locale of the -target name)")
+
+		return func(env *Env, args []string) (err error) {
+			if err = wantNoArgs(args); err != nil {
+				return
+			}
+			if len(*srcName) == 0 || len(*tgtName) == 0 {
+				return usagef("-source and -target are required")
+			}
+			if (len(*tmName) == 0) == (len(*xlfName) == 0) {
+				return usagef("one of -tm or -xliff is required")
+			}
+			if *srcName == *tgtName && *srcName != "-" {
+				return fmt.Errorf("Error: -source and -target file cannot be the same")
+			}
+
+			typ := *fileType
+			if *forcePLIST {
+				typ = "plist"
+			}
+			if len(typ) == 0 {
+				ext := filepath.Ext(*srcName)
+				var ok bool
+				if typ, ok = fileTypes[strings.ToLower(ext)]; !ok {
+					return fmt.Errorf("Error: Unsupported -source file type %q", ext)
+				}
+			}
+			if len(*xlfName) > 0 && typ != "strings" {
+				return usagef("-xliff can only translate .strings files")
+			}
+			if len(mtf.url) > 0 && typ != "strings" {
+				return usagef("-mt can only translate .strings files")
+			}
+			if len(mtf.target) == 0 {
+				mtf.target = fileLang(*tgtName)
+			}
+
+			if len(*xlfName) > 0 {
+				return translateXLIFF(env, *srcName, *xlfName, *tgtName)
+			}
+
+			// Read translations from the translation memory and its fallbacks
+			chain, err := loadChain(env, *tmName, tmfbNames)
+			if err != nil {
+				return
+			}
+			translations := chain.Translations()
+
+			srcFile, err := env.Open(*srcName)
+			if err != nil {
+				return
+			}
+			defer srcFile.Close()
+
+			tgtFile, err := env.Create(*tgtName)
+			if err != nil {
+				return
+			}
+			defer tgtFile.Close()
+
+			var n int
+			switch typ {
+			case "plist":
+				n, err = translate.TranslatePlistFile(env.Context(), srcFile, chain, tgtFile)
+				env.Logf("Translated %d Plist Entries\n", n)
+			case "strings":
+				if len(mtf.url) > 0 {
+					n, err = translateMessagesMT(env.Context(), srcFile, chain, tgtFile, mtf)
+				} else {
+					n, err = translate.TranslateMessagesFile(env.Context(), srcFile, chain, tgtFile)
+				}
+				env.Logf("Translated %d Strings Entries\n", n)
+			case "tpl":
+				n, err = translate.TranslateTemplateFile(env.Context(), srcFile, chain, tgtFile)
+				env.Logf("Translated %d IDs\n", n)
+			case "ids":
+				n, err = translate.TranslateIDsFile(env.Context(), srcFile, chain, tgtFile)
+				env.Logf("Translated %d IDs\n", n)
+			case "txt":
+				n, err = translate.TranslateTextFile(env.Context(), srcFile, translations, tgtFile)
+				env.Logf("Translated %d Text Strings\n", n)
+			default:
+				err = usagef("unsupported -type %q", typ)
+			}
+			if err != nil {
+				return
+			}
+			return tgtFile.Commit()
+		}
+	},
+}
+
+// listFlag is a flag that can be given more than once, it collects all values.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// memoryLocale returns the locale of the translation memory name, or the name
+// itself when it doesn't name a locale.
+func memoryLocale(name string) string {
+	if lang := fileLang(name); len(lang) > 0 {
+		return lang
+	}
+	return name
+}
+
+// loadChain loads the -tm translation memory tmName followed by the fallback
+// -tmfb memories, which complete its translations in order.
+func loadChain(env *Env, tmName string, tmfbNames []string) (chain translate.Chain, err error) {
+	translations, err := loadMessagesMap(env, tmName, "-tm")
+	if err != nil {
+		return
+	}
+	chain = translate.Chain{{Locale: memoryLocale(tmName), Translations: translations}}
+	for _, name := range tmfbNames {
+		var fallback map[string]dotstrings.Message
+		if fallback, err = loadMessagesMap(env, name, "-tmfb"); err != nil {
+			return
+		}
+		chain = append(chain, translate.Memory{Locale: memoryLocale(name), Translations: fallback})
+	}
+	return
+}
+
+// loadMessagesMap loads the .strings translation memory name given by flag.
+func loadMessagesMap(env *Env, name, flag string) (translations map[string]dotstrings.Message, err error) {
+	if ext := filepath.Ext(name); name != "-" && !strings.EqualFold(ext, ".strings") {
+		return nil, fmt.Errorf("Error: Unsupported %s file type %q", flag, ext)
+	}
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return dotstrings.LoadMessagesMap(dotstrings.NewReaderUTF16(file))
+}
+
+// translateXLIFF reads the strings from the inName .strings file, then
+// translates them using the translations from the xlfName XLIFF file and
+// writes them out to the outName .strings file.
+func translateXLIFF(env *Env, inName, xlfName, outName string) (err error) {
+	inFile, err := env.Open(inName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -source %q (%v)", inName, err)
+	}
+	defer inFile.Close()
+
+	xlfFile, err := env.Open(xlfName)
+	if err != nil {
+		return fmt.Errorf("Failed to open -xliff %q (%v)", xlfName, err)
+	}
+	defer xlfFile.Close()
+
+	// Read in the translation from the xlf file and store it based on Resname in a map
+	tf, translation, err := xliff.LoadTranslationMap(xlfFile)
+	if err != nil {
+		return fmt.Errorf("Error processing -xliff %q (%v)", xlfName, err)
+	}
+
+	outFile, err := env.Create(outName)
+	if err != nil {
+		return fmt.Errorf("Failed to create -target %q (%v)", outName, err)
+	}
+	defer outFile.Close()
+
+	env.Logf("Translating %q to %q using %q\n", inName, outName, xlfName)
+	n, err := translateMessagesXLIFFFile(env.Context(), inFile, translation, outFile)
+	if err != nil {
+		return fmt.Errorf("Failure while translating -source %q using -xliff %q (%v)", inName, xlfName, err)
+	}
+	if err = outFile.Commit(); err != nil {
+		return
+	}
+	env.Logf("Translated %d strings from %q to %q\n", n, tf.SourceLanguage, tf.TargetLanguage)
+	return
+}
+
+func translateMessagesXLIFFFile(ctx context.Context, srcFile io.Reader, translation map[string]string, tgtFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgChan, errChan1 := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	msgChan, errChan2 := translate.TranslateMessagesXLIFF(ctx, msgChan, translation)
+	n, err = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(tgtFile))
+	if e := stage.Wait(ctx, cancel, errChan1, errChan2); e != nil {
+		err = e
+	}
+	return
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xcstrings"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+var xcstringsCommand = &Command{
+	Name:    "xcstrings",
+	Summary: "export and import the strings of a locale of an Xcode String Catalog",
+	Help: `Xcode String Catalogs (.xcstrings) hold the strings of every locale of an app. The strings of a
+locale are exported to a .strings or XLIFF file and imported back once translated, so catalogs
+work with fuzzy and with the XLIFF files sent to translators.
+
+Plural and device variations and the variations of substitutions are exported as a string each,
+with the path of the variation appended to the key: "files#plural=one", "tap#device=mac" or
+"users#count:plural=other". The plural cases are those the locale needs.
+
+Texts that are new, need review or are stale are exported as fuzzy, missing texts as fuzzy and
+missing. Imported fuzzy texts need review, the others are translated.`,
+	Commands: []*Command{
+		xcstringsExportCommand,
+		xcstringsImportCommand,
+	},
+}
+
+var xcstringsExportCommand = &Command{
+	Name:    "export",
+	Args:    "catalog",
+	Summary: "write the strings of a locale to a .strings or XLIFF file",
+	Help: `Writes the strings of the -locale of the catalog to the -out .strings or XLIFF file. The strings
+of the source language are written as source strings.
+
+  e.g. xcstrings export -locale fr -out fr.xlf Localizable.xcstrings`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		lang := fs.String("locale", "", "locale to export (default: the source language of the catalog)")
+		outName := fs.String("out", "-", ".strings, .xlf or .xliff file to write the strings to")
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single .xcstrings file")
+			}
+			isXliff, err := catalogFileType(*outName, "-out")
+			if err != nil {
+				return
+			}
+			c, err := loadCatalog(env, args[0])
+			if err != nil {
+				return
+			}
+			code := *lang
+			if len(code) == 0 {
+				code = c.SourceLanguage
+			}
+
+			outFile, err := env.Create(*outName)
+			if err != nil {
+				return
+			}
+			defer outFile.Close()
+
+			ctx, cancel := context.WithCancel(env.Context())
+			defer cancel()
+			var n int
+			if isXliff {
+				tf := &xliff.TranslationFile{Original: filepath.Base(args[0]), SourceLanguage: c.SourceLanguage, Datatype: "x-xcstrings"}
+				if code != c.SourceLanguage {
+					tf.TargetLanguage = code
+				}
+				unitChan, errChan := translate.ConvertCatalogToTranslationUnits(ctx, c, tf)
+				n, err = xliff.SaveTranslationUnits(unitChan, outFile)
+				if e := stage.Wait(ctx, cancel, errChan); e != nil {
+					err = e
+				}
+			} else {
+				n, err = dotstrings.SaveMessages(c.Messages(ctx, code), dotstrings.NewWriterUTF16(outFile))
+			}
+			if err != nil {
+				return
+			}
+			if err = outFile.Commit(); err != nil {
+				return
+			}
+			env.Logf("Exported %d strings of %q\n", n, code)
+			return
+		}
+	},
+}
+
+var xcstringsImportCommand = &Command{
+	Name:    "import",
+	Args:    "catalog",
+	Summary: "update the strings of a locale from a .strings or XLIFF file",
+	Help: `Updates the strings of the -locale of the catalog with the translations in the -in .strings or
+XLIFF file, and writes the catalog in place. Strings missing from -in and units without target
+are left as they are.
+
+  e.g. xcstrings import -locale fr -in fr.xlf Localizable.xcstrings`,
+	Setup: func(fs *flag.FlagSet) func(env *Env, args []string) error {
+		lang := fs.String("locale", "", "locale to import (default: the target language of an XLIFF -in file)")
+		inName := fs.String("in", "", ".strings, .xlf or .xliff file to read the translated strings from")
+		return func(env *Env, args []string) (err error) {
+			if len(args) != 1 {
+				return usagef("expected a single .xcstrings file")
+			}
+			if len(*inName) == 0 {
+				return usagef("-in is required")
+			}
+			isXliff, err := catalogFileType(*inName, "-in")
+			if err != nil {
+				return
+			}
+			if !isXliff && len(*lang) == 0 {
+				return usagef("-locale is required for .strings files")
+			}
+			c, err := loadCatalog(env, args[0])
+			if err != nil {
+				return
+			}
+
+			inFile, err := env.Open(*inName)
+			if err != nil {
+				return
+			}
+			defer inFile.Close()
+
+			ctx, cancel := context.WithCancel(env.Context())
+			defer cancel()
+			code := *lang
+			var n int
+			var errChan <-chan error
+			if isXliff {
+				// The catalog is held in memory anyway, the units tell the
+				// target language once they are read.
+				var units []xliff.TranslationUnit
+				for tu, e := range xliff.NewReader(inFile).All() {
+					if e != nil {
+						return fmt.Errorf("Failed to load %q (%v)", *inName, e)
+					}
+					units = append(units, tu)
+				}
+				if len(code) == 0 && len(units) > 0 {
+					code = units[0].File.TargetLanguage
+				}
+				if len(code) == 0 {
+					return usagef("-locale is required, -in has no target language")
+				}
+				var unitChan <-chan xliff.TranslationUnit
+				unitChan, errChan = stage.Load(ctx, func(yield func(xliff.TranslationUnit, error) bool) {
+					for _, tu := range units {
+						if !yield(tu, nil) {
+							return
+						}
+					}
+				})
+				n, err = translate.SetCatalogTranslationUnits(ctx, c, code, unitChan)
+			} else {
+				var msgChan <-chan dotstrings.Message
+				msgChan, errChan = dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(inFile))
+				n, err = c.SetMessages(code, msgChan)
+			}
+			if e := stage.Wait(ctx, cancel, errChan); err == nil {
+				err = e
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to import %q (%v)", *inName, err)
+			}
+
+			buf := &bytes.Buffer{}
+			if err = c.Save(buf); err != nil {
+				return
+			}
+			outFile, err := env.Create(args[0])
+			if err != nil {
+				return
+			}
+			defer outFile.Close()
+			if _, err = buf.WriteTo(outFile); err != nil {
+				return
+			}
+			if err = outFile.Commit(); err != nil {
+				return
+			}
+			env.Logf("Imported %d strings of %q\n", n, code)
+			return
+		}
+	},
+}
+
+// catalogFileType checks that the file name given by flag is a .strings or
+// XLIFF file and reports whether it is XLIFF.
+func catalogFileType(name, flag string) (isXliff bool, err error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".xlf" || ext == ".xliff":
+		return true, nil
+	case ext == ".strings" || name == "-":
+		return false, nil
+	default:
+		return false, fmt.Errorf("Error: Unsupported %s file type %q", flag, ext)
+	}
+}
+
+// loadCatalog loads the String Catalog name.
+func loadCatalog(env *Env, name string) (c *xcstrings.Catalog, err error) {
+	if ext := filepath.Ext(name); !strings.EqualFold(ext, ".xcstrings") {
+		return nil, fmt.Errorf("Error: Unsupported catalog file type %q", ext)
+	}
+	file, err := env.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if c, err = xcstrings.Load(file); err != nil {
+		return nil, fmt.Errorf("Failed to load %q (%v)", name, err)
+	}
+	return
+}
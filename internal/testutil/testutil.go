@@ -0,0 +1,34 @@
+// Package testutil holds the helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+// UTF16 returns s encoded as UTF-16, the way .strings files are written.
+func UTF16(t testing.TB, s string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if _, err := dotstrings.NewWriterUTF16(buf).Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// WriteStrings writes s as the UTF-16 .strings file name, creating its
+// directory when needed, and returns name.
+func WriteStrings(t testing.TB, name, s string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, UTF16(t, s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
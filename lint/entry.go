@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"context"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/plural"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// PairMessages will asynchronously pair the messages from a source .strings
+// file with their translation from the translations map, the same way
+// TranslateMessages does, and write an Entry for every pair to the returned
+// channel. Source messages without a translation are skipped, they are not a
+// quality issue but simply missing. The Ctx of the source message is used as
+// the Note of the entry.
+func PairMessages(ctx context.Context, srcChan <-chan dotstrings.Message, translations map[string]dotstrings.Message, file, lang string) <-chan Entry {
+	dstChan := make(chan Entry, 3)
+
+	pairer := func(srcChan <-chan dotstrings.Message, dstChan chan<- Entry) {
+		defer close(dstChan)
+		for src := range srcChan {
+			tm, ok := translations[src.ID]
+			if !ok {
+				continue
+			}
+			e := Entry{
+				File:   file,
+				Lang:   lang,
+				ID:     src.ID,
+				Source: unescape(src.Str),
+				Target: unescape(tm.Str),
+				Note:   unescape(src.Ctx),
+				Fuzzy:  tm.Fuzzy || tm.Ctx != src.Str,
+			}
+			select {
+			case dstChan <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go pairer(srcChan, dstChan)
+	return dstChan
+}
+
+// PluralMessages will asynchronously convert the plural messages of a target
+// catalog into entries, with the Other form as Target. Messages without any
+// translated form are skipped. The entry is fuzzy when any of its forms is.
+func PluralMessages(ctx context.Context, msgs []plural.Message, file, lang string) <-chan Entry {
+	dstChan := make(chan Entry, 3)
+
+	converter := func(msgs []plural.Message, dstChan chan<- Entry) {
+		defer close(dstChan)
+		for i := range msgs {
+			m := &msgs[i]
+			if len(m.Forms) == 0 {
+				continue
+			}
+			e := Entry{
+				File:   file,
+				Lang:   lang,
+				ID:     m.ID,
+				Source: m.Source,
+				Target: m.Forms.Other().Text,
+				Plural: m,
+			}
+			for _, f := range m.Forms {
+				e.Fuzzy = e.Fuzzy || f.Fuzzy
+			}
+			select {
+			case dstChan <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go converter(msgs, dstChan)
+	return dstChan
+}
+
+// ConvertTranslationUnits will asynchronously convert XLIFF translation units
+// into entries. Units without a target are skipped.
+func ConvertTranslationUnits(ctx context.Context, tuChan <-chan xliff.TranslationUnit, file string) <-chan Entry {
+	dstChan := make(chan Entry, 3)
+
+	converter := func(tuChan <-chan xliff.TranslationUnit, dstChan chan<- Entry) {
+		defer close(dstChan)
+		for tu := range tuChan {
+			if len(tu.Target) == 0 {
+				continue
+			}
+			e := Entry{
+				File:   file,
+				ID:     xliff.XMLUnescape(tu.ID),
+				Source: xliff.XMLUnescape(tu.Source),
+				Target: xliff.XMLUnescape(tu.Target),
+				Note:   xliff.XMLUnescape(tu.Note),
+			}
+			if tu.File != nil {
+				e.Lang = tu.File.TargetLanguage
+			}
+			select {
+			case dstChan <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go converter(tuChan, dstChan)
+	return dstChan
+}
+
+// unescape returns the unescaped .strings text s, or s itself when it isn't
+// properly escaped so the rules can still look at it.
+func unescape(s string) string {
+	t, err := dotstrings.StringsUnescape(s)
+	if err != nil {
+		return s
+	}
+	return t
+}
@@ -0,0 +1,191 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/plural"
+)
+
+// Severity indicates how serious an issue found by a rule is.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the severity for the names "info", "warning" and
+// "error".
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "info":
+		return Info, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	}
+	return Info, fmt.Errorf("unknown severity %q", name)
+}
+
+// Entry is a pair of source and target text to be checked by the rules. The
+// texts are unescaped, i.e. they contain real newlines and quotes and not the
+// escape sequences used in the files they were read from.
+type Entry struct {
+	File   string
+	Lang   string
+	ID     string
+	Source string
+	Target string
+	// Note is the comment for the translator. It may contain a lint:ignore
+	// annotation to suppress issues for this entry.
+	Note  string
+	Fuzzy bool
+	// Plural holds the plural variants of the target when it was read from a
+	// plural catalog, Target is then the Other form.
+	Plural *plural.Message
+}
+
+// Issue is a problem a rule found in an entry.
+type Issue struct {
+	File     string
+	ID       string
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %q: %s: %s [%s]", i.File, i.ID, i.Severity, i.Message, i.Rule)
+}
+
+// Rule is implemented by every check the Linter can run. Check returns a
+// message for every problem found in the entry, or nil when the entry is fine.
+type Rule interface {
+	Name() string
+	Severity() Severity
+	Check(e *Entry) []string
+}
+
+// Linter runs a set of rules over entries.
+type Linter struct {
+	Rules []Rule
+	// Levels overrides the default severity of rules by rule name.
+	Levels map[string]Severity
+}
+
+// New returns a Linter that runs the given rules.
+func New(rules ...Rule) *Linter {
+	return &Linter{Rules: rules, Levels: make(map[string]Severity)}
+}
+
+// Disable removes the named rules from the linter. An error is returned when
+// a name does not match any rule.
+func (l *Linter) Disable(names ...string) error {
+	disabled := make(map[string]bool)
+	for _, name := range names {
+		disabled[name] = false
+	}
+	rules := make([]Rule, 0, len(l.Rules))
+	for _, r := range l.Rules {
+		if _, ok := disabled[r.Name()]; ok {
+			disabled[r.Name()] = true
+			continue
+		}
+		rules = append(rules, r)
+	}
+	for name, found := range disabled {
+		if !found {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	l.Rules = rules
+	return nil
+}
+
+// Check runs all rules over e and returns the issues found. Rules suppressed
+// with a lint:ignore annotation in the entry Note are skipped.
+func (l *Linter) Check(e *Entry) (issues []Issue) {
+	ignored := suppressed(e.Note)
+	for _, r := range l.Rules {
+		if ignored["*"] || ignored[r.Name()] {
+			continue
+		}
+		severity, ok := l.Levels[r.Name()]
+		if !ok {
+			severity = r.Severity()
+		}
+		for _, msg := range r.Check(e) {
+			issues = append(issues, Issue{File: e.File, ID: e.ID, Rule: r.Name(), Severity: severity, Message: msg})
+		}
+	}
+	return
+}
+
+// CheckEntries will asynchronously check the entries from srcChan and write a
+// Result for every entry to the returned channel. Entries without issues are
+// reported too so reports can count the passing entries.
+func (l *Linter) CheckEntries(ctx context.Context, srcChan <-chan Entry) <-chan Result {
+	dstChan := make(chan Result, 3)
+
+	checker := func(srcChan <-chan Entry, dstChan chan<- Result) {
+		defer close(dstChan)
+		for e := range srcChan {
+			select {
+			case dstChan <- Result{Entry: e, Issues: l.Check(&e)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go checker(srcChan, dstChan)
+	return dstChan
+}
+
+// Result holds the issues found for a single entry.
+type Result struct {
+	Entry  Entry
+	Issues []Issue
+}
+
+var ignoreAnnotation = regexp.MustCompile(`lint:ignore(?:=([\w,-]+))?`)
+
+// suppressed parses the lint:ignore annotations in note. A bare lint:ignore
+// suppresses all rules, lint:ignore=untranslated,length suppresses only the
+// listed rules. The returned map uses "*" for all rules.
+func suppressed(note string) map[string]bool {
+	matches := ignoreAnnotation.FindAllStringSubmatch(note, -1)
+	if matches == nil {
+		return nil
+	}
+	ignored := make(map[string]bool)
+	for _, m := range matches {
+		if len(m[1]) == 0 {
+			ignored["*"] = true
+			continue
+		}
+		for _, name := range strings.Split(m[1], ",") {
+			if len(name) > 0 {
+				ignored[name] = true
+			}
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,135 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/plural"
+)
+
+var tvRules = []struct {
+	source, target string
+	expect         []string
+}{
+	{"Open", "Ouvrir", nil},
+	{"Open", "Open", []string{"untranslated"}},
+	{"%d", "%d", nil},
+	{"%@ Mind Map", "Carte mentale", []string{"placeholders"}},
+	{" Open", "Ouvrir", []string{"whitespace"}},
+	{"Open ", "Ouvrir", []string{"whitespace"}},
+	{"Open file", "Ouvrir  le fichier", []string{"double-space"}},
+	{"Loading...", "Chargement…", nil},
+	{"Loading...", "Chargement", []string{"punctuation"}},
+	{"Are you sure?", "本当ですか？", nil},
+	{"Line 1\nLine 2", "Ligne 1 Ligne 2", []string{"newlines"}},
+	{"Open (new)", "Ouvrir (nouveau", []string{"brackets"}},
+	{`Say "hi"`, `Dites "salut`, []string{"brackets"}},
+	{`Say "hello"`, "Sag „Hallo“", nil},
+	{`Say "hello"`, "Powiedz „cześć”", nil},
+	{`Say "hello"`, "Sag „Hallo", []string{"brackets"}},
+	{`Say "hello"`, "Say “hello“", []string{"brackets"}},
+	{"Open the file in a new window", "Ouvrir", []string{"length"}},
+	{"Open", "Ouv�rir", []string{"forbidden"}},
+	{"{n, plural, one {# file} other {# files}}", "{n, plural, one {# fichier} other {# fichiers}}", nil},
+	{"{n, plural, one {# file} other {# files}}", "{n, plural, one {# fichier} other {# fichiers}", []string{"brackets", "icu"}},
+	{"{n, plural, one {# file} other {# files}}", "{count, plural, one {# fichier} other {# fichiers}}", []string{"icu"}},
+}
+
+func TestRules(t *testing.T) {
+	l := New(DefaultRules()...)
+	for i, tv := range tvRules {
+		issues := l.Check(&Entry{ID: "id", Source: tv.source, Target: tv.target})
+		var rules []string
+		for _, issue := range issues {
+			rules = append(rules, issue.Rule)
+		}
+		if strings.Join(rules, ",") != strings.Join(tv.expect, ",") {
+			t.Errorf("Expected rules %q for tvRules[%d] got %q", tv.expect, i, rules)
+		}
+	}
+}
+
+func TestPlurals(t *testing.T) {
+	l := New(Plurals)
+	m := &plural.Message{ID: "files", Forms: plural.Forms{plural.One: {Text: "%d файл"}, plural.Other: {Text: "%d файла"}}}
+	e := Entry{ID: "files", Lang: "ru", Source: "%d files", Target: "%d файла", Plural: m}
+	if issues := l.Check(&e); len(issues) != 1 || !strings.Contains(issues[0].Message, `["few" "many"]`) {
+		t.Errorf("Expected few and many to be missing got %v", issues)
+	}
+	e.Lang = "en"
+	if issues := l.Check(&e); len(issues) != 0 {
+		t.Errorf("Expected no issues for en got %v", issues)
+	}
+	m.Forms[plural.Few] = plural.Form{Text: "%d файла"}
+	if issues := l.Check(&e); len(issues) != 1 || !strings.Contains(issues[0].Message, "not used by en") {
+		t.Errorf("Expected few to be extra in en got %v", issues)
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	l := New(DefaultRules()...)
+	e := Entry{ID: "id", Source: "OK", Target: "OK ", Note: "Button title lint:ignore=untranslated"}
+	issues := l.Check(&e)
+	if len(issues) != 1 || issues[0].Rule != "whitespace" {
+		t.Errorf("Expected only the whitespace issue got %v", issues)
+	}
+	e.Note = "lint:ignore"
+	if issues := l.Check(&e); len(issues) != 0 {
+		t.Errorf("Expected all issues to be suppressed got %v", issues)
+	}
+}
+
+func TestLevelsAndDisable(t *testing.T) {
+	l := New(DefaultRules()...)
+	l.Levels["untranslated"] = Error
+	issues := l.Check(&Entry{ID: "id", Source: "Open", Target: "Open"})
+	if len(issues) != 1 || issues[0].Severity != Error {
+		t.Errorf("Expected an untranslated error got %v", issues)
+	}
+	if err := l.Disable("untranslated"); err != nil {
+		t.Fatal(err)
+	}
+	if issues := l.Check(&Entry{ID: "id", Source: "Open", Target: "Open"}); len(issues) != 0 {
+		t.Errorf("Expected disabled rule to be skipped got %v", issues)
+	}
+	if err := l.Disable("nonexistent"); err == nil {
+		t.Error("Expected an error disabling an unknown rule")
+	}
+}
+
+func TestReports(t *testing.T) {
+	l := New(DefaultRules()...)
+	r := &Report{Min: Warning}
+	r.Add(Result{Entry: Entry{File: "fr.strings", ID: "a"}, Issues: l.Check(&Entry{File: "fr.strings", ID: "a", Source: "Open", Target: "Open"})})
+	r.Add(Result{Entry: Entry{File: "fr.strings", ID: "b"}, Issues: l.Check(&Entry{File: "fr.strings", ID: "b", Source: "Open the file in a new window", Target: "Ouvrir"})})
+
+	if n := r.Count(Info); n != 1 {
+		t.Errorf("Expected the info issue to be left out of the report, got %d issues", n)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Entries  int
+		Warnings int
+		Issues   []struct{ Severity string }
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Entries != 2 || doc.Warnings != 1 || doc.Issues[0].Severity != "warning" {
+		t.Errorf("Unexpected JSON report %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.WriteJUnit(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<testsuite name="fr.strings" tests="2" failures="1">`) {
+		t.Errorf("Unexpected JUnit report %s", buf.String())
+	}
+}
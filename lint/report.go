@@ -0,0 +1,164 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// MarshalText implements encoding.TextMarshaler so severities appear by name
+// in JSON reports.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) (err error) {
+	*s, err = ParseSeverity(string(text))
+	return
+}
+
+// Report collects the results of a lint run. Issues below the Min severity
+// are left out of the report.
+type Report struct {
+	Min     Severity
+	Results []Result
+}
+
+// Add adds a result to the report.
+func (r *Report) Add(res Result) {
+	issues := res.Issues[:0:0]
+	for _, i := range res.Issues {
+		if i.Severity >= r.Min {
+			issues = append(issues, i)
+		}
+	}
+	res.Issues = issues
+	r.Results = append(r.Results, res)
+}
+
+// Issues returns all issues in the report.
+func (r *Report) Issues() (issues []Issue) {
+	for _, res := range r.Results {
+		issues = append(issues, res.Issues...)
+	}
+	return
+}
+
+// Count returns the number of issues with a severity of at least min.
+func (r *Report) Count(min Severity) (n int) {
+	for _, i := range r.Issues() {
+		if i.Severity >= min {
+			n++
+		}
+	}
+	return
+}
+
+// WriteText writes the issues in the report as lines of text to w, followed
+// by a summary line.
+func (r *Report) WriteText(w io.Writer) (err error) {
+	for _, i := range r.Issues() {
+		if _, err = fmt.Fprintln(w, i); err != nil {
+			return
+		}
+	}
+	_, err = fmt.Fprintf(w, "%d entries checked, %d errors, %d warnings, %d infos\n", len(r.Results), r.Count(Error), r.Count(Warning)-r.Count(Error), r.Count(Info)-r.Count(Warning))
+	return
+}
+
+// WriteJSON writes the report as a JSON document to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	type jsonIssue struct {
+		File     string   `json:"file"`
+		ID       string   `json:"id"`
+		Rule     string   `json:"rule"`
+		Severity Severity `json:"severity"`
+		Message  string   `json:"message"`
+	}
+	doc := struct {
+		Entries  int         `json:"entries"`
+		Errors   int         `json:"errors"`
+		Warnings int         `json:"warnings"`
+		Infos    int         `json:"infos"`
+		Issues   []jsonIssue `json:"issues"`
+	}{
+		Entries:  len(r.Results),
+		Errors:   r.Count(Error),
+		Warnings: r.Count(Warning) - r.Count(Error),
+		Infos:    r.Count(Info) - r.Count(Warning),
+		Issues:   []jsonIssue{},
+	}
+	for _, i := range r.Issues() {
+		doc.Issues = append(doc.Issues, jsonIssue(i))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&doc)
+}
+
+// JUnit XML elements, see https://llg.cubic.org/docs/junit/
+type (
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Name     string       `xml:"name,attr"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	}
+	junitCase struct {
+		ClassName string         `xml:"classname,attr"`
+		Name      string         `xml:"name,attr"`
+		Failures  []junitFailure `xml:"failure"`
+	}
+	junitFailure struct {
+		Type    string `xml:"type,attr"`
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// WriteJUnit writes the report as JUnit XML to w so CI systems can display
+// it. Every file becomes a test suite and every entry a test case that fails
+// when it has issues.
+func (r *Report) WriteJUnit(w io.Writer) (err error) {
+	doc := junitSuites{Name: "lint"}
+	suites := make(map[string]int)
+	for _, res := range r.Results {
+		idx, ok := suites[res.Entry.File]
+		if !ok {
+			idx = len(doc.Suites)
+			suites[res.Entry.File] = idx
+			doc.Suites = append(doc.Suites, junitSuite{Name: res.Entry.File})
+		}
+		suite := &doc.Suites[idx]
+		tc := junitCase{ClassName: res.Entry.File, Name: res.Entry.ID}
+		for _, i := range res.Issues {
+			tc.Failures = append(tc.Failures, junitFailure{Type: i.Rule, Message: i.Message, Text: i.Severity.String() + ": " + i.Message})
+		}
+		suite.Tests++
+		doc.Tests++
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+			doc.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(&doc); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "\n")
+	return
+}
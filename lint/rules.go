@@ -0,0 +1,301 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/simpleapps-eu/translate/glossary"
+	"github.com/simpleapps-eu/translate/icu"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/placeholder"
+)
+
+// RuleFunc adapts a function returning a single message to the Rule
+// interface. An empty message means the entry passed the check.
+type RuleFunc struct {
+	name     string
+	severity Severity
+	check    func(e *Entry) string
+}
+
+// NewRule returns a Rule named name with default severity that runs check.
+func NewRule(name string, severity Severity, check func(e *Entry) string) Rule {
+	return &RuleFunc{name: name, severity: severity, check: check}
+}
+
+func (r *RuleFunc) Name() string       { return r.name }
+func (r *RuleFunc) Severity() Severity { return r.severity }
+
+func (r *RuleFunc) Check(e *Entry) []string {
+	if msg := r.check(e); len(msg) > 0 {
+		return []string{msg}
+	}
+	return nil
+}
+
+// DefaultRules returns a new list of all built-in rules with their default
+// settings.
+func DefaultRules() []Rule {
+	return []Rule{
+		Untranslated,
+		Placeholders,
+		Whitespace,
+		DoubleSpace,
+		Punctuation,
+		Newlines,
+		Brackets,
+		ICU,
+		Plurals,
+		LengthRatio(0.3, 3.0),
+		ForbiddenChars("\uFFFD\u200B"),
+	}
+}
+
+var (
+	// Untranslated reports targets that are identical to their source.
+	Untranslated = NewRule("untranslated", Warning, func(e *Entry) string {
+		if e.Target == e.Source && strings.IndexFunc(literal(e.Source), unicode.IsLetter) >= 0 {
+			return "target is identical to the source"
+		}
+		return ""
+	})
+
+	// Placeholders reports targets whose format specifiers don't match the source.
+	Placeholders = NewRule("placeholders", Error, func(e *Entry) string {
+		if err := placeholder.Compare(e.Source, e.Target); err != nil {
+			return err.Error()
+		}
+		return ""
+	})
+
+	// Whitespace reports leading or trailing whitespace that differs between
+	// source and target.
+	Whitespace = NewRule("whitespace", Error, func(e *Entry) string {
+		if leading(e.Source) != leading(e.Target) {
+			return fmt.Sprintf("leading whitespace %q does not match source %q", leading(e.Target), leading(e.Source))
+		}
+		if trailing(e.Source) != trailing(e.Target) {
+			return fmt.Sprintf("trailing whitespace %q does not match source %q", trailing(e.Target), trailing(e.Source))
+		}
+		return ""
+	})
+
+	// DoubleSpace reports doubled spaces in the target that aren't in the source.
+	DoubleSpace = NewRule("double-space", Warning, func(e *Entry) string {
+		if strings.Contains(strings.TrimSpace(e.Target), "  ") && !strings.Contains(e.Source, "  ") {
+			return "target contains doubled spaces"
+		}
+		return ""
+	})
+
+	// Punctuation reports targets that end in different punctuation than the source.
+	Punctuation = NewRule("punctuation", Warning, func(e *Entry) string {
+		s := ending(e.Source)
+		t := ending(e.Target)
+		if s != t {
+			return fmt.Sprintf("target ends with %q, source ends with %q", t, s)
+		}
+		return ""
+	})
+
+	// Newlines reports targets with a different number of newlines than the source.
+	Newlines = NewRule("newlines", Error, func(e *Entry) string {
+		s := strings.Count(e.Source, "\n")
+		t := strings.Count(e.Target, "\n")
+		if s != t {
+			return fmt.Sprintf("target contains %d newlines, source contains %d", t, s)
+		}
+		return ""
+	})
+
+	// Brackets reports brackets and quotes that are balanced in the source
+	// but not in the target.
+	Brackets = NewRule("brackets", Warning, func(e *Entry) string {
+		if unbalanced(e.Source) != "" {
+			return ""
+		}
+		if pair := unbalanced(e.Target); pair != "" {
+			return fmt.Sprintf("target contains unbalanced %s", pair)
+		}
+		return ""
+	})
+
+	// ICU reports targets of ICU messages that aren't valid ICU, use other
+	// arguments than the source or lack plural categories of the language.
+	// Sources that don't parse as ICU messages with arguments are not checked.
+	ICU = NewRule("icu", Error, func(e *Entry) string {
+		src, err := icu.Parse(e.Source)
+		if err != nil || len(src.Args()) == 0 {
+			return ""
+		}
+		tgt, err := icu.Parse(e.Target)
+		if err != nil {
+			return fmt.Sprintf("target is not a valid ICU message (%v)", err)
+		}
+		if err = icu.Compare(src, tgt); err != nil {
+			return fmt.Sprintf("target arguments don't match the source (%v)", err)
+		}
+		if l, err := locale.Parse(e.Lang); err == nil {
+			if err = icu.CheckPlurals(tgt, l); err != nil {
+				return fmt.Sprintf("target plurals don't match the language (%v)", err)
+			}
+		}
+		return ""
+	})
+
+	// Plurals reports targets read from plural catalogs that lack plural
+	// categories of the language or have categories it doesn't use.
+	Plurals = NewRule("plurals", Error, func(e *Entry) string {
+		if e.Plural == nil {
+			return ""
+		}
+		l, err := locale.Parse(e.Lang)
+		if err != nil {
+			return ""
+		}
+		switch missing, extra := e.Plural.Check(l); {
+		case len(missing) > 0:
+			return fmt.Sprintf("target lacks the plural categories %q of %s", missing, l)
+		case len(extra) > 0:
+			return fmt.Sprintf("target has plural categories %q not used by %s", extra, l)
+		}
+		return ""
+	})
+)
+
+// LengthRatio returns a rule that reports targets that are much shorter or
+// longer than the source. The ratio is the number of characters in the target
+// divided by the number of characters in the source. Short sources are not
+// checked as their ratio varies too much between languages.
+func LengthRatio(min, max float64) Rule {
+	return NewRule("length", Info, func(e *Entry) string {
+		s := utf8.RuneCountInString(e.Source)
+		t := utf8.RuneCountInString(e.Target)
+		if s < 10 {
+			return ""
+		}
+		ratio := float64(t) / float64(s)
+		if ratio < min || ratio > max {
+			return fmt.Sprintf("target is %.1f times the length of the source", ratio)
+		}
+		return ""
+	})
+}
+
+// ForbiddenChars returns a rule that reports targets containing any of the
+// characters in chars or any control character other than tab and newline.
+func ForbiddenChars(chars string) Rule {
+	return NewRule("forbidden", Error, func(e *Entry) string {
+		for _, r := range e.Target {
+			if strings.ContainsRune(chars, r) || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+				return fmt.Sprintf("target contains forbidden character %U", r)
+			}
+		}
+		return ""
+	})
+}
+
+// Glossary returns a rule that reports targets not following the glossary
+// for language lang.
+func Glossary(g *glossary.Glossary, lang string) Rule {
+	return &glossaryRule{g: g, lang: lang}
+}
+
+type glossaryRule struct {
+	g    *glossary.Glossary
+	lang string
+}
+
+func (r *glossaryRule) Name() string       { return "glossary" }
+func (r *glossaryRule) Severity() Severity { return Error }
+
+func (r *glossaryRule) Check(e *Entry) (msgs []string) {
+	lang := r.lang
+	if len(lang) == 0 {
+		lang = e.Lang
+	}
+	for _, v := range r.g.Check(e.ID, e.Source, e.Target, lang) {
+		if v.Term.DoNotTranslate {
+			msgs = append(msgs, fmt.Sprintf("term %q must not be translated", v.Term.Source))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("term %q should be translated as %q", v.Term.Source, v.Expected))
+		}
+	}
+	return
+}
+
+// literal returns s with all format specifiers removed.
+func literal(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholder.Find(s) {
+		b.WriteString(s[last:loc[0]])
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func leading(s string) string {
+	return s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+}
+
+func trailing(s string) string {
+	return s[len(strings.TrimRightFunc(s, unicode.IsSpace)):]
+}
+
+// fullwidth maps the punctuation used by CJK languages to its western
+// equivalent so a Japanese "。" matches an English ".".
+var fullwidth = strings.NewReplacer("。", ".", "．", ".", "！", "!", "？", "?", "：", ":", "；", ";", "؟", "?", "…", "...", "⋯", "...")
+
+// ending returns the sentence ending punctuation of s, if any.
+func ending(s string) string {
+	s = fullwidth.Replace(strings.TrimRightFunc(s, unicode.IsSpace))
+	i := len(strings.TrimRight(s, ".!?:;"))
+	return s[i:]
+}
+
+// bracketPair is a pair checked by the Brackets rule, any of the runes of
+// open opens it and any of close closes it.
+type bracketPair struct {
+	open, close string
+}
+
+// bracketPairs lists the pairs checked by the Brackets rule. Single quotes are
+// left out as the closing single quote doubles as an apostrophe.
+var bracketPairs = []bracketPair{{"(", ")"}, {"[", "]"}, {"{", "}"}, {"«", "»"}, {"“", "”"}, {"「", "」"}, {"（", "）"}}
+
+// lowQuotePair takes the place of the “” pair in text with the low quote „,
+// which is closed by “ in German and by ” in Polish.
+var lowQuotePair = bracketPair{"„", "“”"}
+
+// unbalanced returns a description of the first bracket pair that is not
+// balanced in s, or an empty string when all are balanced.
+func unbalanced(s string) string {
+	for _, p := range bracketPairs {
+		if p.open == "“" && strings.ContainsRune(s, '„') {
+			p = lowQuotePair
+		}
+		depth := 0
+		for _, r := range s {
+			switch {
+			case strings.ContainsRune(p.open, r):
+				depth++
+			case strings.ContainsRune(p.close, r):
+				depth--
+			}
+			if depth < 0 {
+				break
+			}
+		}
+		if depth != 0 {
+			return p.open + p.close
+		}
+	}
+	if strings.Count(s, `"`)%2 != 0 {
+		return `quotes "`
+	}
+	return ""
+}
@@ -2,29 +2,34 @@ package translate
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"iter"
+
+	"github.com/simpleapps-eu/translate/stage"
 )
 
 // LoadLines reads the text stream from io.Reader and outputs lines on
 // a channel it returns. This function will run asynchronously and return
-// before the whole text stream has been processed.
-func LoadLines(srcFile io.Reader) (<-chan string, <-chan error) {
-
-	lineChan := make(chan string, 3)
-	errChan := make(chan error, 1)
+// before the whole text stream has been processed. Reading stops when ctx is
+// done, ctx.Err() is then sent on the error channel.
+func LoadLines(ctx context.Context, srcFile io.Reader) (<-chan string, <-chan error) {
+	return stage.Load(ctx, Lines(srcFile))
+}
 
-	reader := func(srcFile io.Reader, lineChan chan<- string, errChan chan<- error) {
-		defer close(lineChan)
-		defer close(errChan)
+// Lines returns an iterator over the lines of the text stream from srcFile.
+// The iteration stops at the end of the stream or after yielding the first
+// error.
+func Lines(srcFile io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
 		s := bufio.NewScanner(srcFile)
 		for s.Scan() {
-			lineChan <- s.Text()
+			if !yield(s.Text(), nil) {
+				return
+			}
 		}
 		if err := s.Err(); err != nil {
-			errChan <- err
+			yield("", err)
 		}
 	}
-
-	go reader(srcFile, lineChan, errChan)
-	return lineChan, errChan
 }
@@ -0,0 +1,362 @@
+// Package locale models the locale codes used by the translation files. A
+// Locale is a BCP 47 language tag, or the Base locale of Xcode projects, and
+// maps to and from the codes used by Apple, Android, gettext and XLIFF.
+package locale
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Locale is a validated locale. The zero Locale is no locale at all, as found
+// for a file name that doesn't name a locale.
+type Locale struct {
+	tag  language.Tag
+	base bool
+	set  bool
+}
+
+// Base is the Base locale of Xcode projects, it holds the strings of the
+// development language that are not localized.
+var Base = Locale{base: true, set: true}
+
+// Parse parses a BCP 47 language tag like en, pt-BR, zh-Hans or es-419, or
+// Base. Underscores are accepted as separator so pt_BR parses too. The tag is
+// validated and canonicalized, iw becomes he.
+func Parse(s string) (Locale, error) {
+	if len(s) == 0 {
+		return Locale{}, fmt.Errorf("Empty locale")
+	}
+	if strings.EqualFold(s, "Base") {
+		return Base, nil
+	}
+	tag, err := language.Parse(s)
+	if err != nil {
+		return Locale{}, fmt.Errorf("Invalid locale %q (%v)", s, err)
+	}
+	if tag.IsRoot() {
+		return Locale{}, fmt.Errorf("Invalid locale %q (undetermined language)", s)
+	}
+	return Locale{tag: tag, set: true}, nil
+}
+
+// MustParse is Parse for locales known to be valid, it panics on an error.
+func MustParse(s string) Locale {
+	l, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// ParseAndroid parses the Android resource qualifier of a locale, like fr,
+// zh-rCN or b+sr+Latn, optionally as the values directory name like
+// values-zh-rCN. The directory values without qualifier is Base.
+func ParseAndroid(s string) (Locale, error) {
+	q := s
+	if q == "values" {
+		return Base, nil
+	}
+	q = strings.TrimPrefix(q, "values-")
+	if strings.HasPrefix(q, "b+") {
+		return Parse(strings.ReplaceAll(q[2:], "+", "-"))
+	}
+	lang, region, ok := strings.Cut(q, "-r")
+	if ok && len(region) == 0 || strings.ContainsAny(lang, "-_+") {
+		return Locale{}, fmt.Errorf("Invalid Android locale %q", s)
+	}
+	if ok {
+		lang += "-" + region
+	}
+	return Parse(lang)
+}
+
+// gettextScripts maps the script modifiers of gettext locales to scripts.
+var gettextScripts = map[string]string{
+	"latin":      "Latn",
+	"cyrillic":   "Cyrl",
+	"devanagari": "Deva",
+	"arabic":     "Arab",
+}
+
+// ParseGettext parses a gettext locale like pt_BR or sr_RS@latin. A codeset
+// like in de_DE.UTF-8 is ignored.
+func ParseGettext(s string) (Locale, error) {
+	name, modifier, _ := strings.Cut(s, "@")
+	name, _, _ = strings.Cut(name, ".")
+	l, err := Parse(name)
+	if err != nil || len(modifier) == 0 {
+		return l, err
+	}
+	script, ok := gettextScripts[modifier]
+	if !ok {
+		return Locale{}, fmt.Errorf("Invalid gettext locale %q (unknown modifier %q)", s, modifier)
+	}
+	tag, err := language.Compose(l.tag, language.MustParseScript(script))
+	if err != nil {
+		return Locale{}, fmt.Errorf("Invalid gettext locale %q (%v)", s, err)
+	}
+	return Locale{tag: tag, set: true}, nil
+}
+
+// FromPath returns the locale named by the file path name. It tries the
+// file name up to the first dot, as in fr.strings or pt_BR.po, and then the
+// directory, as in fr.lproj/Localizable.strings or values-fr/strings.xml.
+// Languages unknown to CLDR are not accepted, so app.strings doesn't name a
+// locale. The zero Locale is returned when no locale is found.
+func FromPath(name string) Locale {
+	prefix := strings.SplitN(filepath.Base(name), ".", 2)[0]
+	if l, err := ParseGettext(prefix); err == nil && l.known() {
+		return l
+	}
+	dir := filepath.Base(filepath.Dir(name))
+	if ext := filepath.Ext(dir); strings.EqualFold(ext, ".lproj") {
+		if l, err := Parse(strings.TrimSuffix(dir, ext)); err == nil && l.known() {
+			return l
+		}
+	}
+	if dir == "values" || strings.HasPrefix(dir, "values-") {
+		if l, err := ParseAndroid(dir); err == nil && l.known() {
+			return l
+		}
+	}
+	return Locale{}
+}
+
+// known reports whether the language of l is known to CLDR, for which it
+// can infer a script.
+func (l Locale) known() bool {
+	if l.base {
+		return true
+	}
+	_, c := l.tag.Script()
+	return c != language.No
+}
+
+// IsZero reports whether l is no locale at all.
+func (l Locale) IsZero() bool {
+	return !l.set
+}
+
+// IsBase reports whether l is the Base locale.
+func (l Locale) IsBase() bool {
+	return l.base
+}
+
+// Tag returns the language tag of l, it is language.Und for Base and the zero
+// Locale.
+func (l Locale) Tag() language.Tag {
+	return l.tag
+}
+
+// Language returns the language subtag of l, like pt for pt-BR.
+func (l Locale) Language() string {
+	if !l.set || l.base {
+		return ""
+	}
+	b, _, _ := l.tag.Raw()
+	return b.String()
+}
+
+// Script returns the script subtag of l, like Hans for zh-Hans, or the empty
+// string when l has none.
+func (l Locale) Script() string {
+	var none language.Script
+	if _, s, _ := l.tag.Raw(); s != none {
+		return s.String()
+	}
+	return ""
+}
+
+// Region returns the region subtag of l, like BR for pt-BR or 419 for es-419,
+// or the empty string when l has none.
+func (l Locale) Region() string {
+	var none language.Region
+	if _, _, r := l.tag.Raw(); r != none {
+		return r.String()
+	}
+	return ""
+}
+
+// String returns the BCP 47 tag of l, Base for Base and the empty string for
+// the zero Locale.
+func (l Locale) String() string {
+	switch {
+	case !l.set:
+		return ""
+	case l.base:
+		return "Base"
+	}
+	return l.tag.String()
+}
+
+// Apple returns the code of l used by Apple in .lproj directory names, like
+// zh-Hans, pt-BR and Base.
+func (l Locale) Apple() string {
+	return l.String()
+}
+
+// XLIFF returns the code of l used in the source-language and
+// target-language attributes of XLIFF files. Base has no XLIFF code.
+func (l Locale) XLIFF() string {
+	if l.base {
+		return ""
+	}
+	return l.String()
+}
+
+// Android returns the resource qualifier of l, like fr, pt-rBR or b+sr+Latn.
+// Locales with a script, a numeric region or variants use the BCP 47 form.
+// Base has the empty qualifier, it uses the plain values directory.
+func (l Locale) Android() string {
+	if !l.set || l.base {
+		return ""
+	}
+	script, region := l.Script(), l.Region()
+	if len(script) > 0 || len(region) > 2 || len(l.tag.Variants()) > 0 {
+		return "b+" + strings.ReplaceAll(l.tag.String(), "-", "+")
+	}
+	if len(region) > 0 {
+		return l.Language() + "-r" + region
+	}
+	return l.Language()
+}
+
+// Gettext returns the gettext code of l, like pt_BR or sr_RS@latin. The
+// scripts of Chinese are expressed by their main region, zh-Hans is zh_CN and
+// zh-Hant is zh_TW. Base has no gettext code.
+func (l Locale) Gettext() string {
+	if !l.set || l.base {
+		return ""
+	}
+	lang, script, region := l.Language(), l.Script(), l.Region()
+	if lang == "zh" && len(script) > 0 {
+		if len(region) == 0 {
+			r, _ := l.tag.Region()
+			region = r.String()
+		}
+		script = ""
+	}
+	code := lang
+	if len(region) > 0 {
+		code += "_" + region
+	}
+	if len(script) > 0 {
+		modifier := strings.ToLower(script)
+		for m, s := range gettextScripts {
+			if s == script {
+				modifier = m
+			}
+		}
+		code += "@" + modifier
+	}
+	return code
+}
+
+// Format returns the code of l in convention, which is one of Conventions.
+// The empty convention is BCP 47.
+func (l Locale) Format(convention string) (string, error) {
+	switch convention {
+	case "", BCP47, Apple:
+		return l.String(), nil
+	case Android:
+		return l.Android(), nil
+	case Gettext:
+		return l.Gettext(), nil
+	case XLIFF:
+		return l.XLIFF(), nil
+	}
+	return "", fmt.Errorf("Unknown locale convention %q, one of %q expected", convention, Conventions)
+}
+
+// Conventions for locale codes.
+const (
+	BCP47   = "bcp47"
+	Apple   = "apple"
+	Android = "android"
+	Gettext = "gettext"
+	XLIFF   = "xliff"
+)
+
+// Conventions lists the supported conventions for locale codes.
+var Conventions = []string{BCP47, Apple, Android, Gettext, XLIFF}
+
+// Same reports whether l and o are the same locale, after adding the likely
+// script and region. So en is the same as en-US and zh-Hans is the same as
+// zh-CN, but es-419 is not the same as es.
+func (l Locale) Same(o Locale) bool {
+	if !l.set || !o.set || l.base || o.base {
+		return l == o
+	}
+	lb, _ := l.tag.Base()
+	ob, _ := o.tag.Base()
+	ls, _ := l.tag.Script()
+	oc, _ := o.tag.Script()
+	lr, _ := l.tag.Region()
+	or, _ := o.tag.Region()
+	return lb == ob && ls == oc && lr == or && fmt.Sprint(l.tag.Variants()) == fmt.Sprint(o.tag.Variants())
+}
+
+// Parent returns the locale l falls back to according to CLDR, like de for
+// de-AT, zh-Hant for zh-Hant-HK and en-001 for en-AU. Languages without a
+// parent, Base and the zero Locale return the zero Locale.
+func (l Locale) Parent() Locale {
+	if !l.set || l.base {
+		return Locale{}
+	}
+	parent := l.tag.Parent()
+	if parent.IsRoot() {
+		return Locale{}
+	}
+	return Locale{tag: parent, set: true}
+}
+
+// Fallbacks maps a locale to the locales its missing translations are taken
+// from, in order, JSON encoded as
+//
+//	{"de-AT": ["de"], "pt-PT": ["pt-BR", "pt"]}
+//
+// Locales without fallbacks fall back to their CLDR parents.
+type Fallbacks map[string][]string
+
+// Chain returns the fallback chain of the locale code, starting with code
+// itself. The chain ends before the source language, whose strings are
+// used for untranslated entries anyway.
+func (f Fallbacks) Chain(code string) []string {
+	chain := []string{code}
+	if fallbacks, ok := f[code]; ok {
+		return append(chain, fallbacks...)
+	}
+	l, err := Parse(code)
+	if err != nil {
+		return chain
+	}
+	for l = l.Parent(); !l.IsZero(); l = l.Parent() {
+		chain = append(chain, l.String())
+	}
+	return chain
+}
+
+// Validate returns an error for invalid locales and locales that fall back to
+// themselves.
+func (f Fallbacks) Validate() error {
+	for code, fallbacks := range f {
+		l, err := Parse(code)
+		if err != nil {
+			return err
+		}
+		for _, fb := range fallbacks {
+			p, err := Parse(fb)
+			if err != nil {
+				return err
+			}
+			if p.Same(l) {
+				return fmt.Errorf("Locale %q can't fall back to itself", code)
+			}
+		}
+	}
+	return nil
+}
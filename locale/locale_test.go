@@ -0,0 +1,157 @@
+package locale
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		s, expect string
+	}{
+		{"en", "en"},
+		{"en-US", "en-US"},
+		{"pt_BR", "pt-BR"},
+		{"zh-Hans", "zh-Hans"},
+		{"es-419", "es-419"},
+		{"iw", "he"},
+		{"Base", "Base"},
+		{"base", "Base"},
+	} {
+		l, err := Parse(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+		} else if got := l.String(); got != test.expect {
+			t.Errorf("%q: expected %q got %q", test.s, test.expect, got)
+		}
+	}
+	for _, s := range []string{"", "und", "Localizable", "en-", "e"} {
+		if l, err := Parse(s); err == nil {
+			t.Errorf("%q: expected an error got %q", s, l)
+		}
+	}
+}
+
+func TestConventions(t *testing.T) {
+	for _, test := range []struct {
+		s                              string
+		apple, android, gettext, xliff string
+	}{
+		{"fr", "fr", "fr", "fr", "fr"},
+		{"pt-BR", "pt-BR", "pt-rBR", "pt_BR", "pt-BR"},
+		{"zh-Hans", "zh-Hans", "b+zh+Hans", "zh_CN", "zh-Hans"},
+		{"zh-Hant-HK", "zh-Hant-HK", "b+zh+Hant+HK", "zh_HK", "zh-Hant-HK"},
+		{"sr-Latn", "sr-Latn", "b+sr+Latn", "sr@latin", "sr-Latn"},
+		{"es-419", "es-419", "b+es+419", "es_419", "es-419"},
+		{"Base", "Base", "", "", ""},
+	} {
+		l := MustParse(test.s)
+		for _, c := range []struct{ convention, expect string }{
+			{Apple, test.apple}, {Android, test.android}, {Gettext, test.gettext}, {XLIFF, test.xliff},
+		} {
+			if got, err := l.Format(c.convention); err != nil || got != c.expect {
+				t.Errorf("%q %s: expected %q got %q (%v)", test.s, c.convention, c.expect, got, err)
+			}
+		}
+	}
+	if _, err := MustParse("fr").Format("java"); err == nil {
+		t.Errorf("Expected an error for an unknown convention")
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for _, test := range []struct {
+		parse  func(string) (Locale, error)
+		s      string
+		expect string
+	}{
+		{ParseAndroid, "values", "Base"},
+		{ParseAndroid, "values-fr", "fr"},
+		{ParseAndroid, "values-zh-rCN", "zh-CN"},
+		{ParseAndroid, "b+sr+Latn", "sr-Latn"},
+		{ParseAndroid, "values-b+es+419", "es-419"},
+		{ParseGettext, "pt_BR", "pt-BR"},
+		{ParseGettext, "de_DE.UTF-8", "de-DE"},
+		{ParseGettext, "sr_RS@latin", "sr-Latn-RS"},
+	} {
+		l, err := test.parse(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+		} else if got := l.String(); got != test.expect {
+			t.Errorf("%q: expected %q got %q", test.s, test.expect, got)
+		}
+	}
+	for _, s := range []string{"values-zh-r", "values-zh-CN"} {
+		if _, err := ParseAndroid(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+	if _, err := ParseGettext("sr_RS@klingon"); err == nil {
+		t.Errorf("Expected an error for an unknown modifier")
+	}
+}
+
+func TestFromPath(t *testing.T) {
+	for name, expect := range map[string]string{
+		"fr.strings":                           "fr",
+		"xliff/pt-BR.xlf":                      "pt-BR",
+		"po/pt_BR.po":                          "pt-BR",
+		"Resources/de.lproj/Main.strings":      "de",
+		"Resources/Base.lproj/Main.storyboard": "Base",
+		"res/values-es-rMX/strings.xml":        "es-MX",
+		"Localizable.strings":                  "",
+		"app.strings":                          "",
+		"-":                                    "",
+	} {
+		if got := FromPath(name).String(); got != expect {
+			t.Errorf("%q: expected %q got %q", name, expect, got)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		same bool
+	}{
+		{"en", "en-US", true},
+		{"zh-Hans", "zh-CN", true},
+		{"pt", "pt-BR", true},
+		{"es", "es-419", false},
+		{"en", "en-GB", false},
+		{"Base", "Base", true},
+		{"Base", "en", false},
+	} {
+		if got := MustParse(test.a).Same(MustParse(test.b)); got != test.same {
+			t.Errorf("%q %q: expected same %v got %v", test.a, test.b, test.same, got)
+		}
+	}
+	if (Locale{}).Same(MustParse("en")) || !(Locale{}).Same(Locale{}) {
+		t.Errorf("Expected the zero Locale to be only the same as itself")
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	f := Fallbacks{"pt-PT": {"pt-BR", "pt"}}
+	for code, expect := range map[string][]string{
+		"de-AT":      {"de-AT", "de"},
+		"zh-Hant-HK": {"zh-Hant-HK", "zh-Hant"},
+		"en-AU":      {"en-AU", "en-001", "en"},
+		"pt-PT":      {"pt-PT", "pt-BR", "pt"},
+		"fr":         {"fr"},
+		"Base":       {"Base"},
+	} {
+		if chain := f.Chain(code); !reflect.DeepEqual(chain, expect) {
+			t.Errorf("%q: expected chain %q got %q", code, expect, chain)
+		}
+	}
+	if err := f.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Fallbacks{"de-AT": {"de_AT"}}).Validate(); err == nil {
+		t.Errorf("Expected an error for a locale falling back to itself")
+	}
+	if err := (Fallbacks{"de-AT": {"german"}}).Validate(); err == nil {
+		t.Errorf("Expected an error for an invalid fallback")
+	}
+}
@@ -0,0 +1,127 @@
+package translate
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// FilterMessages will take the messages emitted by TranslateMessages and only
+// pass on the ones that are (not) fuzzy. When missing is false the fuzzy
+// messages that are missing from the translation are left out too. The
+// messages passed on are not marked as fuzzy, so they can be handed to a
+// translator as a plain .strings file.
+func FilterMessages(ctx context.Context, fuzzy bool, missing bool, srcChan <-chan dotstrings.Message) <-chan dotstrings.Message {
+	// Process the translated messages, extract fuzzies to file, don't mark them as fuzzy though.
+	return stage.FilterMap(ctx, srcChan, func(m dotstrings.Message) (dotstrings.Message, bool) {
+		keep := fuzzy == m.Fuzzy && (missing || !m.Missing)
+		m.Fuzzy = false
+		return m, keep
+	})
+}
+
+// MergeMessagesFile merges the translations from the .strings file newName
+// into the existing translations of the .strings file tmName and writes the
+// result (not UTF16 encoded) to writer. Existing translations are replaced in
+// place, new translations are appended in the order they appear in newName.
+// When tmName doesn't exist yet all translations from newName are written.
+// The function returns the number of messages written.
+func MergeMessagesFile(ctx context.Context, tmName, newName string, writer io.Writer) (n int, err error) {
+	// Load the fuzzies file with updated translations
+	newTranslations, err := dotstrings.LoadMessagesMapFromFile(newName)
+	if err != nil {
+		return
+	}
+
+	// Open existing translation file that needs to be updated, a translation
+	// file that doesn't exist yet simply gets all new translations.
+	tmFile, err := os.Open(tmName)
+	if err == nil {
+		defer tmFile.Close()
+		n, err = replaceTranslations(ctx, tmFile, newTranslations, writer)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	// Open new translation file again
+	newFile, err := os.Open(newName)
+	if err != nil {
+		return
+	}
+	defer newFile.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Asynchronously load the messages from the new translation
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(newFile))
+
+	// Asynchronously append new translations that were not written during the previous phase.
+	msgChan = appendNewTranslations(ctx, msgChan, newTranslations)
+
+	// Now synchronously append msgChan entries to the writer
+	written, err := dotstrings.SaveMessages(msgChan, writer)
+	n += written
+
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	return
+}
+
+// replaceTranslations writes the messages of the existing translation file
+// tmFile to writer, replacing the translations in newTranslations.
+func replaceTranslations(ctx context.Context, tmFile io.Reader, newTranslations map[string]dotstrings.Message, writer io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Asynchronously load the messages from the existing translation
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(tmFile))
+
+	// Asynchronously replace outdated existing translations with new translations
+	msgChan = replaceOutdatedTranslations(ctx, msgChan, newTranslations)
+
+	// Now synchronously save the msgChan to the writer
+	n, err = dotstrings.SaveMessages(msgChan, writer)
+
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
+	return
+}
+
+func replaceOutdatedTranslations(ctx context.Context, srcChan <-chan dotstrings.Message, newTranslations map[string]dotstrings.Message) <-chan dotstrings.Message {
+	return stage.Map(ctx, srcChan, func(src dotstrings.Message) dotstrings.Message {
+		// Send the entry from the source translation to the output,
+		// unless there is a new translation.
+		tran, isNewTranslation := newTranslations[src.ID]
+		if !isNewTranslation {
+			return src
+		}
+		delete(newTranslations, tran.ID)
+		if len(tran.Ctx) == 0 {
+			// New translation doesn't have context, use context of original.
+			return dotstrings.Message{Ctx: src.Ctx, ID: tran.ID, Str: tran.Str}
+		}
+		// Send the new translation as-is. Don't worry about Fuzzy
+		// flag as loading it as a translation would have croaked.
+		return tran
+	})
+}
+
+func appendNewTranslations(ctx context.Context, srcChan <-chan dotstrings.Message, newTranslations map[string]dotstrings.Message) <-chan dotstrings.Message {
+	return stage.FilterMap(ctx, srcChan, func(src dotstrings.Message) (dotstrings.Message, bool) {
+		// Send new translation that has not been sent yet and remove it from the map.
+		tran, ok := newTranslations[src.ID]
+		if ok {
+			delete(newTranslations, tran.ID)
+		}
+		return tran, ok
+	})
+}
@@ -0,0 +1,126 @@
+package mt
+
+import (
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/simpleapps-eu/translate/atomicfile"
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+// Cache stores the results of previous machine translations so the same
+// text is never sent to a backend twice. A Cache holds translations for a
+// single language pair, keyed by the source text.
+type Cache interface {
+	Get(text string) (translation string, ok bool)
+	Put(text, translation string)
+}
+
+// MemoryCache is a Cache that keeps its translations in memory. It can be
+// loaded from and saved to a .strings file where the ID holds the source
+// text and the Str holds its machine translation.
+type MemoryCache struct {
+	mutex        sync.Mutex
+	translations map[string]string
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{translations: make(map[string]string)}
+}
+
+// Get returns the cached translation for text.
+func (c *MemoryCache) Get(text string) (translation string, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	translation, ok = c.translations[text]
+	return
+}
+
+// Put adds the translation for text to the cache.
+func (c *MemoryCache) Put(text, translation string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.translations[text] = translation
+}
+
+// Len returns the number of cached translations.
+func (c *MemoryCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.translations)
+}
+
+// LoadFile adds the translations from the UTF16 .strings file filename to the
+// cache. A file that does not exist yet is treated as an empty cache.
+func (c *MemoryCache) LoadFile(filename string) (err error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return c.Load(dotstrings.NewReaderUTF16(file))
+}
+
+// Load adds the translations read from the .strings data in r to the cache.
+func (c *MemoryCache) Load(r io.Reader) (err error) {
+	for m, e := range dotstrings.NewReader(r).All() {
+		if e != nil {
+			return e
+		}
+		text, e := dotstrings.StringsUnescape(m.ID)
+		if e != nil {
+			err = e
+			continue
+		}
+		translation, e := dotstrings.StringsUnescape(m.Str)
+		if e != nil {
+			err = e
+			continue
+		}
+		c.Put(text, translation)
+	}
+	return
+}
+
+// SaveFile atomically replaces filename by the cache as a UTF16 .strings
+// file.
+func (c *MemoryCache) SaveFile(filename string) (err error) {
+	file, err := atomicfile.Create(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err = c.Save(dotstrings.NewWriterUTF16(file)); err != nil {
+		return
+	}
+	return file.Commit()
+}
+
+// Save writes the cache to w in .strings format and returns the number of
+// translations written.
+func (c *MemoryCache) Save(w io.Writer) (n int, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// Sort the texts so saving the same cache twice gives the same file.
+	texts := make([]string, 0, len(c.translations))
+	for text := range c.translations {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+	mw := dotstrings.NewWriter(w)
+	for _, text := range texts {
+		id := dotstrings.StringsEscape(text)
+		str := dotstrings.StringsEscape(c.translations[text])
+		if err = mw.Write(dotstrings.Message{Ctx: "Machine translation", ID: id, Str: str}); err != nil {
+			return
+		}
+		n++
+	}
+	return
+}
@@ -0,0 +1,170 @@
+package mt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Schema describes the JSON request and response bodies of a machine
+// translation HTTP endpoint. The request is a JSON object with the batch of
+// texts in the Texts field and the languages in the Source and Target fields.
+// The response is a JSON object containing an array of translations at the
+// dotted path Result. The elements of that array are either strings or, when
+// ResultText is set, objects holding the translation in the ResultText field.
+type Schema struct {
+	Texts      string
+	Source     string
+	Target     string
+	Result     string
+	ResultText string
+	// Upper is true when the backend expects upper case language codes.
+	Upper bool
+	// Extra fields added to every request, e.g. to enable tag handling so
+	// the <x id="N"/> placeholder tokens are left alone.
+	Extra map[string]interface{}
+}
+
+var (
+	// Generic is the schema of the reference endpoint served by package mttest.
+	Generic = Schema{Texts: "texts", Source: "source", Target: "target", Result: "translations"}
+
+	// DeepL is the schema of the DeepL v2 translate endpoint.
+	DeepL = Schema{Texts: "text", Source: "source_lang", Target: "target_lang", Result: "translations", ResultText: "text", Upper: true,
+		Extra: map[string]interface{}{"tag_handling": "xml", "ignore_tags": "x"}}
+
+	// Google is the schema of the Google Cloud Translation v2 endpoint.
+	Google = Schema{Texts: "q", Source: "source", Target: "target", Result: "data.translations", ResultText: "translatedText",
+		Extra: map[string]interface{}{"format": "html"}}
+
+	// LibreTranslate is the schema of the LibreTranslate translate endpoint.
+	LibreTranslate = Schema{Texts: "q", Source: "source", Target: "target", Result: "translatedText",
+		Extra: map[string]interface{}{"format": "html"}}
+)
+
+// Schemas maps the names accepted on the command line to a Schema.
+var Schemas = map[string]Schema{
+	"generic":        Generic,
+	"deepl":          DeepL,
+	"google":         Google,
+	"libretranslate": LibreTranslate,
+}
+
+// HTTPProvider is a Provider that posts batches of texts as JSON to URL and
+// decodes the translations from the JSON response according to Schema.
+type HTTPProvider struct {
+	URL    string
+	Schema Schema
+	// Header is added to every request, use it for e.g. an Authorization key.
+	Header http.Header
+	// Client is used to perform the requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// NewHTTPProvider returns a HTTPProvider for the endpoint at url.
+func NewHTTPProvider(url string, schema Schema) *HTTPProvider {
+	return &HTTPProvider{URL: url, Schema: schema, Header: make(http.Header)}
+}
+
+// Translate implements the Provider interface.
+func (p *HTTPProvider) Translate(ctx context.Context, source, target string, texts []string) (translations []string, err error) {
+	s := p.Schema
+	if s.Upper {
+		source = strings.ToUpper(source)
+		target = strings.ToUpper(target)
+	}
+
+	request := make(map[string]interface{})
+	for k, v := range s.Extra {
+		request[k] = v
+	}
+	request[s.Texts] = texts
+	if len(source) > 0 {
+		request[s.Source] = source
+	}
+	request[s.Target] = target
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.URL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = &StatusError{Code: resp.StatusCode, Status: resp.Status}
+		return
+	}
+
+	var response interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return
+	}
+
+	translations, err = s.results(response)
+	if err != nil {
+		return
+	}
+	if len(translations) != len(texts) {
+		err = fmt.Errorf("expected %d translations, received %d", len(texts), len(translations))
+	}
+	return
+}
+
+// results walks the decoded response along the Result path and collects the
+// translations.
+func (s Schema) results(response interface{}) (translations []string, err error) {
+	v := response
+	for _, field := range strings.Split(s.Result, ".") {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a JSON object containing %q in response", field)
+		}
+		v = object[field]
+	}
+
+	// Some backends return a single string when a single text was sent.
+	if str, ok := v.(string); ok && len(s.ResultText) == 0 {
+		return []string{str}, nil
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON array at %q in response", s.Result)
+	}
+	for _, elem := range list {
+		if len(s.ResultText) > 0 {
+			object, ok := elem.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected JSON objects in %q array", s.Result)
+			}
+			elem = object[s.ResultText]
+		}
+		str, ok := elem.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected JSON strings as translations in %q", s.Result)
+		}
+		translations = append(translations, str)
+	}
+	return
+}
@@ -0,0 +1,139 @@
+package mt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/mt"
+	"github.com/simpleapps-eu/translate/mt/mttest"
+)
+
+var tvMessages = []dotstrings.Message{
+	{ID: "open", Ctx: "Open", Str: "Ouvrir"},
+	{Fuzzy: true, Missing: true, ID: "files", Ctx: "%d files & %@", Str: "%d files & %@"},
+	{Fuzzy: true, ID: "close", Ctx: "Close now", Str: "Fermer"},
+	{Fuzzy: true, Missing: true, ID: "quote", Ctx: `Say \"hi\"`, Str: `Say \"hi\"`},
+}
+
+var tvExpect = []dotstrings.Message{
+	{ID: "open", Ctx: "Open", Str: "Ouvrir"},
+	{Fuzzy: true, ID: "files", Ctx: "%d files & %@", Str: "[fr] %d files & %@"},
+	{Fuzzy: true, ID: "close", Ctx: "Close now", Str: "Fermer"},
+	{Fuzzy: true, ID: "quote", Ctx: `Say \"hi\"`, Str: `[fr] Say \"hi\"`},
+}
+
+func run(p mt.Provider, opts mt.Options) ([]dotstrings.Message, error) {
+	srcChan := make(chan dotstrings.Message)
+	go func() {
+		defer close(srcChan)
+		for _, m := range tvMessages {
+			srcChan <- m
+		}
+	}()
+	var result []dotstrings.Message
+	msgChan, errChan := mt.TranslateMissing(context.Background(), srcChan, p, opts)
+	for m := range msgChan {
+		result = append(result, m)
+	}
+	err, _ := <-errChan
+	return result, err
+}
+
+func TestTranslateMissing(t *testing.T) {
+	s := mttest.NewServer(mttest.Pseudo)
+	defer s.Close()
+
+	opts := mt.DefaultOptions("en", "fr")
+	opts.BatchSize = 1
+	result, err := run(s.Provider, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != len(tvExpect) {
+		t.Fatalf("Expected %d messages got %d", len(tvExpect), len(result))
+	}
+	for i := range tvExpect {
+		if result[i] != tvExpect[i] {
+			t.Errorf("Expected message %d to be %+v got %+v", i, tvExpect[i], result[i])
+		}
+	}
+	if n := s.Handler.Requests(); n != 2 {
+		t.Errorf("Expected 2 requests got %d", n)
+	}
+}
+
+func TestTranslateMissingPlaceholders(t *testing.T) {
+	s := mttest.NewServer(func(source, target, text string) string {
+		// Drop everything, including the placeholder tokens.
+		return "rien"
+	})
+	defer s.Close()
+
+	result, err := run(s.Provider, mt.DefaultOptions("en", "fr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result[1].Missing || result[1].Str != tvMessages[1].Str {
+		t.Errorf("Expected message with dropped placeholders to remain missing, got %+v", result[1])
+	}
+	if result[3].Missing || result[3].Str != "rien" {
+		t.Errorf("Expected message without placeholders to be translated, got %+v", result[3])
+	}
+}
+
+func TestTranslateMissingRetryAndCache(t *testing.T) {
+	s := mttest.NewServer(mttest.Pseudo)
+	defer s.Close()
+	s.Handler.FailFirst = 2
+
+	cache := mt.NewMemoryCache()
+	opts := mt.DefaultOptions("en", "fr")
+	opts.Backoff = time.Millisecond
+	opts.Cache = cache
+
+	if _, err := run(s.Provider, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Handler.Requests(); n != 3 {
+		t.Errorf("Expected 3 requests got %d", n)
+	}
+	if n := cache.Len(); n != 2 {
+		t.Errorf("Expected 2 cached translations got %d", n)
+	}
+	if _, err := cache.Save(failWriter{}); err == nil {
+		t.Error("Expected the write error saving the cache")
+	}
+
+	// A second run should be served from the cache completely.
+	result, err := run(s.Provider, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Handler.Requests(); n != 3 {
+		t.Errorf("Expected no additional requests got %d", n-3)
+	}
+	if result[1] != tvExpect[1] {
+		t.Errorf("Expected cached message %+v got %+v", tvExpect[1], result[1])
+	}
+}
+
+func TestTranslateMissingFailure(t *testing.T) {
+	s := mttest.NewServer(mttest.Pseudo)
+	defer s.Close()
+	s.Handler.FailFirst = 10
+
+	opts := mt.DefaultOptions("en", "fr")
+	opts.Retries = 1
+	opts.Backoff = time.Millisecond
+	if _, err := run(s.Provider, opts); err == nil {
+		t.Error("Expected an error when the provider keeps failing")
+	}
+}
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
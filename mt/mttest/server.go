@@ -0,0 +1,113 @@
+package mttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/simpleapps-eu/translate/mt"
+)
+
+// TranslateFunc produces the translation of a single text.
+type TranslateFunc func(source, target, text string) string
+
+// Pseudo is a TranslateFunc that marks the text with the target language
+// instead of translating it, e.g. "Open" becomes "[fr] Open". This makes it
+// easy to spot machine translated strings while testing.
+func Pseudo(source, target, text string) string {
+	return "[" + target + "] " + text
+}
+
+// Handler is a http.Handler that behaves like a machine translation endpoint
+// using the mt.Generic schema. It is meant for tests and offline development.
+type Handler struct {
+	Translate TranslateFunc
+	// FailFirst makes the first FailFirst requests fail with status 503 so
+	// retry behaviour can be tested.
+	FailFirst int
+
+	mutex    sync.Mutex
+	requests int
+	texts    int
+}
+
+// NewHandler returns a Handler that translates texts using fn.
+func NewHandler(fn TranslateFunc) *Handler {
+	return &Handler{Translate: fn}
+}
+
+// Requests returns the number of requests received so far.
+func (h *Handler) Requests() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.requests
+}
+
+// Texts returns the number of texts translated so far.
+func (h *Handler) Texts() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.texts
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	h.requests++
+	fail := h.requests <= h.FailFirst
+	h.mutex.Unlock()
+
+	if fail {
+		http.Error(w, "stub configured to fail", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Texts  []string `json:"texts"`
+		Source string   `json:"source"`
+		Target string   `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(strings.TrimSpace(request.Target)) == 0 {
+		http.Error(w, "missing target language", http.StatusBadRequest)
+		return
+	}
+
+	var response struct {
+		Translations []string `json:"translations"`
+	}
+	response.Translations = make([]string, len(request.Texts))
+	for i, text := range request.Texts {
+		response.Translations[i] = h.Translate(request.Source, request.Target, text)
+	}
+
+	h.mutex.Lock()
+	h.texts += len(request.Texts)
+	h.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&response)
+}
+
+// Server is a running stub endpoint together with a Provider talking to it.
+type Server struct {
+	*httptest.Server
+	Handler  *Handler
+	Provider *mt.HTTPProvider
+}
+
+// NewServer starts a stub endpoint on a local port that translates using fn.
+// The caller should call Close when finished to shut it down.
+func NewServer(fn TranslateFunc) *Server {
+	h := NewHandler(fn)
+	s := httptest.NewServer(h)
+	return &Server{Server: s, Handler: h, Provider: mt.NewHTTPProvider(s.URL, mt.Generic)}
+}
@@ -0,0 +1,55 @@
+package mt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Provider is implemented by machine translation backends. Translate is
+// called with a batch of texts in the source language and has to return
+// the translations in the target language in the same order. The languages
+// are passed the way they appear in the file names (e.g. "en", "fr"). The
+// request should be abandoned when ctx is done.
+type Provider interface {
+	Translate(ctx context.Context, source, target string, texts []string) ([]string, error)
+}
+
+// ProviderFunc adapts an ordinary function to the Provider interface.
+type ProviderFunc func(ctx context.Context, source, target string, texts []string) ([]string, error)
+
+// Translate calls f(ctx, source, target, texts).
+func (f ProviderFunc) Translate(ctx context.Context, source, target string, texts []string) ([]string, error) {
+	return f(ctx, source, target, texts)
+}
+
+// StatusError is returned by a provider when the backend responded with a
+// HTTP status code other than 200 OK.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("machine translation request failed (%s)", e.Status)
+}
+
+// Temporary reports whether it makes sense to retry the request later. This
+// is the case when the backend is rate limiting or having trouble itself.
+func (e *StatusError) Temporary() bool {
+	return e.Code == 429 || e.Code >= 500
+}
+
+// temporary reports whether err is worth retrying.
+func temporary(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Temporary()
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return false
+}
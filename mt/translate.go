@@ -0,0 +1,217 @@
+package mt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/placeholder"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// Options control how TranslateMissing talks to a Provider.
+type Options struct {
+	// SourceLanguage and TargetLanguage are passed on to the Provider.
+	SourceLanguage string
+	TargetLanguage string
+	// BatchSize is the maximum number of texts sent in a single request.
+	BatchSize int
+	// Interval is the minimum time between the start of two requests.
+	Interval time.Duration
+	// Retries is the number of times a failing request is retried when the
+	// failure is temporary (e.g. rate limiting or a server error).
+	Retries int
+	// Backoff is the time waited before the first retry, it doubles for every
+	// following retry.
+	Backoff time.Duration
+	// Cache is consulted before sending a text to the Provider and is updated
+	// with every translation received. No caching is done when nil.
+	Cache Cache
+}
+
+// DefaultOptions returns the options used when no explicit options are given.
+func DefaultOptions(source, target string) Options {
+	return Options{
+		SourceLanguage: source,
+		TargetLanguage: target,
+		BatchSize:      50,
+		Retries:        3,
+		Backoff:        time.Second,
+	}
+}
+
+// TranslateMissing will take the messages emitted by TranslateMessages and
+// send the text of every Missing message to the Provider. The machine
+// translation is filled in as Str of the message, which is then emitted as
+// Fuzzy (but no longer Missing) so a translator will review it. Messages that
+// are not Missing are passed through unchanged and in their original order.
+//
+// Format specifiers are replaced by <x id="N"/> tokens and the text is XML
+// escaped before it is sent, so the backend should be configured to leave
+// markup alone. When a translation comes back with mangled tokens the
+// message is left Missing. An error is pushed onto the error channel when
+// the Provider keeps failing, after which the function terminates. When ctx
+// is done the pending request is abandoned and ctx.Err() is pushed instead.
+func TranslateMissing(ctx context.Context, srcChan <-chan dotstrings.Message, p Provider, opts Options) (<-chan dotstrings.Message, <-chan error) {
+	dstChan := make(chan dotstrings.Message, 3)
+	errChan := make(chan error, 1)
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+
+	translator := func(srcChan <-chan dotstrings.Message, dstChan chan<- dotstrings.Message, errChan chan<- error) {
+		defer close(dstChan)
+		defer close(errChan)
+
+		b := &batcher{ctx: ctx, provider: p, opts: opts}
+
+		var pending []dotstrings.Message
+		missing := 0
+		flush := func() error {
+			if err := b.translate(pending); err != nil {
+				return err
+			}
+			for _, m := range pending {
+				select {
+				case dstChan <- m:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			pending = pending[:0]
+			missing = 0
+			return nil
+		}
+
+		for m := range srcChan {
+			pending = append(pending, m)
+			if m.Missing {
+				missing++
+			}
+			if missing >= opts.BatchSize {
+				if err := flush(); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			errChan <- err
+		}
+	}
+
+	go translator(srcChan, dstChan, errChan)
+	return dstChan, errChan
+}
+
+// batcher sends batches of texts to a Provider while honoring the rate limit,
+// retry and cache options.
+type batcher struct {
+	ctx      context.Context
+	provider Provider
+	opts     Options
+	last     time.Time
+}
+
+// translate fills in the Str of all Missing messages in msgs.
+func (b *batcher) translate(msgs []dotstrings.Message) error {
+	type job struct {
+		index     int
+		originals []string
+	}
+	var jobs []job
+	var texts []string
+
+	for i, m := range msgs {
+		if !m.Missing {
+			continue
+		}
+		text, err := dotstrings.StringsUnescape(m.Ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to strings unescape Message.Ctx for ID %q (%v)", m.ID, err)
+		}
+		if b.opts.Cache != nil {
+			if translation, ok := b.opts.Cache.Get(text); ok {
+				b.fill(&msgs[i], translation)
+				continue
+			}
+		}
+		masked, originals := placeholder.Protect(xliff.XMLEscapeLoose(text))
+		jobs = append(jobs, job{index: i, originals: originals})
+		texts = append(texts, masked)
+	}
+
+	if len(texts) == 0 {
+		return nil
+	}
+
+	translations, err := b.send(texts)
+	if err != nil {
+		return err
+	}
+
+	for n, j := range jobs {
+		restored, err := placeholder.Restore(translations[n], j.originals)
+		if err != nil {
+			// Leave the message Missing rather than emitting broken format specifiers.
+			continue
+		}
+		translation := xliff.XMLUnescape(restored)
+		if b.opts.Cache != nil {
+			text, _ := dotstrings.StringsUnescape(msgs[j.index].Ctx)
+			b.opts.Cache.Put(text, translation)
+		}
+		b.fill(&msgs[j.index], translation)
+	}
+	return nil
+}
+
+// fill turns a Missing message into a Fuzzy draft using translation.
+func (b *batcher) fill(m *dotstrings.Message, translation string) {
+	m.Str = dotstrings.StringsEscape(translation)
+	m.Missing = false
+	m.Fuzzy = true
+}
+
+// send performs a single (possibly retried) request to the Provider.
+func (b *batcher) send(texts []string) (translations []string, err error) {
+	backoff := b.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		if err = b.sleep(b.opts.Interval - time.Since(b.last)); err != nil {
+			return
+		}
+		b.last = time.Now()
+
+		translations, err = b.provider.Translate(b.ctx, b.opts.SourceLanguage, b.opts.TargetLanguage, texts)
+		if err == nil {
+			if len(translations) != len(texts) {
+				err = fmt.Errorf("expected %d translations, received %d", len(texts), len(translations))
+			}
+			return
+		}
+		if attempt >= b.opts.Retries || !temporary(err) || b.ctx.Err() != nil {
+			return
+		}
+		if err = b.sleep(backoff); err != nil {
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// sleep waits for d, or returns ctx.Err() when the context is done first.
+func (b *batcher) sleep(d time.Duration) error {
+	if d <= 0 {
+		return b.ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
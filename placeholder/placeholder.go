@@ -0,0 +1,137 @@
+package placeholder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// specifier matches the printf style format specifiers found in .strings
+// files. This includes the positional form (%1$@), flags, width, precision,
+// length modifiers (%lld, %lu) and the object specifier %@. The escaped
+// percent sign %% is matched too so it won't be mistaken for the start of
+// another specifier. The space and ' flags are left out, so a percent sign
+// in prose like "50% off" isn't taken for "% o".
+var specifier = regexp.MustCompile(`%(?:[1-9][0-9]*\$)?[-+0#]*(?:[0-9]+|\*)?(?:\.(?:[0-9]+|\*))?(?:hh|h|ll|l|q|L|z|t|j)?[@dDiuUxXoOfFeEgGcCsSpaA%]`)
+
+// Find returns the byte offsets of every format specifier in s. Every
+// element is a pair of start and end offsets like regexp.FindAllStringIndex
+// returns them. When s contains no format specifiers nil is returned.
+func Find(s string) [][]int {
+	return specifier.FindAllStringIndex(s, -1)
+}
+
+// List returns the format specifiers found in s in the order they appear.
+// The escaped percent sign %% is not a placeholder and is not included.
+func List(s string) (list []string) {
+	for _, loc := range Find(s) {
+		if p := s[loc[0]:loc[1]]; p != "%%" {
+			list = append(list, p)
+		}
+	}
+	return
+}
+
+// Compare checks that target uses the same format specifiers as source.
+// Positional specifiers (%1$@) may be reordered in the target, all other
+// specifiers have to appear in the same order. A nil error is returned when
+// the specifiers match.
+func Compare(source, target string) error {
+	src := List(source)
+	tgt := List(target)
+	if len(src) != len(tgt) {
+		return fmt.Errorf("expected %d format specifiers %q, found %d %q", len(src), src, len(tgt), tgt)
+	}
+	srcArgs := arguments(src)
+	tgtArgs := arguments(tgt)
+	for i := range srcArgs {
+		if srcArgs[i] != tgtArgs[i] {
+			return fmt.Errorf("format specifier %q does not match %q", tgtArgs[i], srcArgs[i])
+		}
+	}
+	return nil
+}
+
+// arguments orders the specifiers by the argument they consume and strips
+// the position from positional specifiers so they can be compared.
+func arguments(list []string) []string {
+	args := make([]string, len(list))
+	for i, p := range list {
+		pos, rest := position(p)
+		if pos < 1 || pos > len(list) {
+			pos = i + 1
+		}
+		args[pos-1] = "%" + rest
+	}
+	return args
+}
+
+// position splits a positional specifier like %2$@ into its position 2 and
+// the remaining specifier @. Non positional specifiers return position 0.
+func position(p string) (pos int, rest string) {
+	rest = p[1:]
+	i := strings.IndexByte(rest, '$')
+	if i < 0 {
+		return
+	}
+	pos, err := strconv.Atoi(rest[:i])
+	if err != nil {
+		return 0, rest
+	}
+	rest = rest[i+1:]
+	return
+}
+
+// Protect replaces every format specifier in s with a numbered token of the
+// form <x id="N"/> so the text can be handed to a process (e.g. a machine
+// translation service) that should leave the specifiers alone. The replaced
+// specifiers are returned so Restore can put them back.
+func Protect(s string) (masked string, originals []string) {
+	locs := Find(s)
+	if locs == nil {
+		return s, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(s[last:loc[0]])
+		fmt.Fprintf(&b, `<x id="%d"/>`, len(originals))
+		originals = append(originals, s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), originals
+}
+
+var token = regexp.MustCompile(`<x\s+id\s*=\s*"([0-9]+)"\s*/>`)
+
+// Restore replaces the tokens inserted by Protect with the original format
+// specifiers. An error is returned when a token is missing, duplicated or
+// unknown so a mangled text is never mistaken for a valid one.
+func Restore(masked string, originals []string) (s string, err error) {
+	seen := make([]bool, len(originals))
+	s = token.ReplaceAllStringFunc(masked, func(t string) string {
+		n, _ := strconv.Atoi(token.FindStringSubmatch(t)[1])
+		if n >= len(originals) {
+			err = fmt.Errorf("unknown placeholder token %q", t)
+			return t
+		}
+		if seen[n] {
+			err = fmt.Errorf("duplicated placeholder token %q", t)
+			return t
+		}
+		seen[n] = true
+		return originals[n]
+	})
+	if err != nil {
+		return
+	}
+	for n, ok := range seen {
+		if !ok {
+			err = fmt.Errorf("placeholder %q was dropped", originals[n])
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package placeholder
+
+import "testing"
+
+var tvList = []struct {
+	text   string
+	expect []string
+}{
+	{"Hello", nil},
+	{"%@ Mind Map", []string{"%@"}},
+	{"%d of %lu files", []string{"%d", "%lu"}},
+	{"100%% done in %.1f s", []string{"%.1f"}},
+	{"%1$@ shared %2$lld items", []string{"%1$@", "%2$lld"}},
+	{"100% done, 50% off", nil},
+	{"50 % de remise sur %d articles", []string{"%d"}},
+	{"%-5d%+d", []string{"%-5d", "%+d"}},
+}
+
+func TestList(t *testing.T) {
+	for i, tv := range tvList {
+		list := List(tv.text)
+		if len(list) != len(tv.expect) {
+			t.Errorf("Expected %q for tvList[%d] got %q", tv.expect, i, list)
+			continue
+		}
+		for j := range list {
+			if list[j] != tv.expect[j] {
+				t.Errorf("Expected %q for tvList[%d] got %q", tv.expect, i, list)
+			}
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if err := Compare("%1$@ shared %2$d", "%2$d geteilt von %1$@"); err != nil {
+		t.Errorf("Expected reordered positional specifiers to match (%v)", err)
+	}
+	if err := Compare("%@ and %d", "%d and %@"); err == nil {
+		t.Error("Expected swapped non positional specifiers to mismatch")
+	}
+	if err := Compare("%d files", "fichiers"); err == nil {
+		t.Error("Expected a missing specifier to mismatch")
+	}
+	if err := Compare("100%% of %d", "%d à 100 %%"); err != nil {
+		t.Errorf("Expected %%%% to be ignored (%v)", err)
+	}
+	if err := Compare("50% off", "50 % de remise"); err != nil {
+		t.Errorf("Expected percent signs in prose to be ignored (%v)", err)
+	}
+	if err := Compare("100% done", "100 % erledigt"); err != nil {
+		t.Errorf("Expected percent signs in prose to be ignored (%v)", err)
+	}
+}
+
+func TestProtectRestore(t *testing.T) {
+	masked, originals := Protect("%1$@ has %2$d new messages")
+	if masked != `<x id="0"/> has <x id="1"/> new messages` {
+		t.Errorf("Unexpected masked text %q", masked)
+	}
+	s, err := Restore(`<x id="1"/> nouveaux messages pour <x id = "0" />`, originals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "%2$d nouveaux messages pour %1$@" {
+		t.Errorf("Unexpected restored text %q", s)
+	}
+	if _, err := Restore(`<x id="0"/> messages`, originals); err == nil {
+		t.Error("Expected an error for a dropped token")
+	}
+	if _, err := Restore(`<x id="0"/><x id="0"/><x id="1"/>`, originals); err == nil {
+		t.Error("Expected an error for a duplicated token")
+	}
+}
@@ -0,0 +1,427 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+const binaryMagic = "bplist00"
+
+// binaryEpoch is the Unix time of 2001-01-01, the time dates count from in a
+// binary property list.
+const binaryEpoch = 978307200
+
+var errTruncated = errors.New("object out of range")
+
+// binaryDecoder decodes the objects of a binary property list. The objects
+// are referenced by their index in the offset table.
+type binaryDecoder struct {
+	data    []byte
+	offsets []uint64
+	refSize int
+	// visiting marks the containers being decoded, to detect cycles.
+	visiting []bool
+	// budget is the number of objects left to decode. Every reference takes
+	// at least a byte, so the size of the file limits the objects, unless
+	// containers share references to expand exponentially.
+	budget int
+}
+
+func decodeBinary(data []byte) (Value, error) {
+	if len(data) < len(binaryMagic)+32 {
+		return nil, errors.New("file too short")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize, refSize := int(trailer[6]), int(trailer[7])
+	count := binary.BigEndian.Uint64(trailer[8:])
+	top := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+	end := uint64(len(data) - 32)
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 || count == 0 || top >= count ||
+		tableOffset < uint64(len(binaryMagic)) || tableOffset > end || count > (end-tableOffset)/uint64(offsetSize) {
+		return nil, errors.New("invalid trailer")
+	}
+
+	p := &binaryDecoder{data: data[:tableOffset], offsets: make([]uint64, count), refSize: refSize, visiting: make([]bool, count), budget: len(data)}
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = readUint(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(top)
+}
+
+// readUint returns the big endian unsigned integer b.
+func readUint(b []byte) (u uint64) {
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return
+}
+
+// bytes returns n bytes of the object data at offset.
+func (p *binaryDecoder) bytes(offset, n uint64) ([]byte, error) {
+	if offset > uint64(len(p.data)) || n > uint64(len(p.data))-offset {
+		return nil, errTruncated
+	}
+	return p.data[offset : offset+n], nil
+}
+
+// length returns the length in the marker of the object at offset, and the
+// offset of its contents. A length of 15 or more follows the marker as
+// integer object.
+func (p *binaryDecoder) length(offset uint64) (n, start uint64, err error) {
+	b, err := p.bytes(offset, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]&0xf != 0xf {
+		return uint64(b[0] & 0xf), offset + 1, nil
+	}
+	if b, err = p.bytes(offset+1, 1); err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 || b[0]&0xf > 3 {
+		return 0, 0, errors.New("invalid length")
+	}
+	size := uint64(1) << (b[0] & 0xf)
+	if b, err = p.bytes(offset+2, size); err != nil {
+		return 0, 0, err
+	}
+	return readUint(b), offset + 2 + size, nil
+}
+
+// refs returns the n object references at offset.
+func (p *binaryDecoder) refs(offset, n uint64) ([]uint64, error) {
+	if n > uint64(len(p.data)) {
+		return nil, errTruncated
+	}
+	b, err := p.bytes(offset, n*uint64(p.refSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readUint(b[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
+
+func (p *binaryDecoder) object(ref uint64) (Value, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("invalid object reference %d", ref)
+	}
+	if p.visiting[ref] {
+		return nil, fmt.Errorf("object %d contains itself", ref)
+	}
+	if p.budget--; p.budget < 0 {
+		return nil, errors.New("too many object references")
+	}
+	offset := p.offsets[ref]
+	b, err := p.bytes(offset, 1)
+	if err != nil {
+		return nil, err
+	}
+	marker := b[0]
+
+	switch marker >> 4 {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return Bool(false), nil
+		case 0x09:
+			return Bool(true), nil
+		}
+	case 0x1:
+		size := uint64(1) << (marker & 0xf)
+		if size > 16 {
+			break
+		}
+		b, err := p.bytes(offset+1, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 16 {
+			// 128 bit integers only hold 64 bit values
+			b = b[8:]
+		}
+		return Integer(readUint(b)), nil
+	case 0x2:
+		switch marker & 0xf {
+		case 2:
+			b, err := p.bytes(offset+1, 4)
+			if err != nil {
+				return nil, err
+			}
+			return Real(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 3:
+			b, err := p.bytes(offset+1, 8)
+			if err != nil {
+				return nil, err
+			}
+			return Real(math.Float64frombits(binary.BigEndian.Uint64(b))), nil
+		}
+	case 0x3:
+		if marker != 0x33 {
+			break
+		}
+		b, err := p.bytes(offset+1, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds, fraction := math.Modf(math.Float64frombits(binary.BigEndian.Uint64(b)))
+		return Date(time.Unix(binaryEpoch+int64(seconds), int64(math.Round(fraction*1e9))).UTC()), nil
+	case 0x4, 0x5, 0x6:
+		n, start, err := p.length(offset)
+		if err != nil {
+			return nil, err
+		}
+		if marker>>4 == 0x6 {
+			if n > uint64(len(p.data)) {
+				return nil, errTruncated
+			}
+			n *= 2
+		}
+		b, err := p.bytes(start, n)
+		if err != nil {
+			return nil, err
+		}
+		switch marker >> 4 {
+		case 0x4:
+			return Data(bytes.Clone(b)), nil
+		case 0x5:
+			return String(b), nil
+		}
+		u := make([]uint16, n/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return String(utf16.Decode(u)), nil
+	case 0x8:
+		return nil, errors.New("UID values aren't supported")
+	case 0xa, 0xd:
+		n, start, err := p.length(offset)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(p.data)) {
+			return nil, errTruncated
+		}
+		count := n
+		if marker>>4 == 0xd {
+			count *= 2
+		}
+		refs, err := p.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+		p.visiting[ref] = true
+		defer func() { p.visiting[ref] = false }()
+
+		if marker>>4 == 0xa {
+			a := make(Array, n)
+			for i, r := range refs {
+				if a[i], err = p.object(r); err != nil {
+					return nil, err
+				}
+			}
+			return a, nil
+		}
+		d := NewDict()
+		for i := uint64(0); i < n; i++ {
+			key, err := p.object(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			s, ok := key.(String)
+			if !ok {
+				return nil, fmt.Errorf("dict key is a %s, not a string", typeName(key))
+			}
+			v, err := p.object(refs[n+i])
+			if err != nil {
+				return nil, err
+			}
+			d.Set(string(s), v)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unknown object type 0x%02x", marker)
+}
+
+// binaryEncoder encodes a value as binary property list. The values are
+// flattened to a list of objects first, strings are written once.
+type binaryEncoder struct {
+	objects []Value
+	refs    [][]int // the references of the arrays and dicts by object
+	strings map[String]int
+}
+
+func encodeBinary(v Value) ([]byte, error) {
+	p := &binaryEncoder{strings: map[String]int{}}
+	if _, err := p.flatten(v); err != nil {
+		return nil, err
+	}
+	refSize := uintSize(uint64(len(p.objects) - 1))
+
+	var b bytes.Buffer
+	b.WriteString(binaryMagic)
+	offsets := make([]uint64, len(p.objects))
+	for i, o := range p.objects {
+		offsets[i] = uint64(b.Len())
+		p.write(&b, o, p.refs[i], refSize)
+	}
+	tableOffset := uint64(b.Len())
+	offsetSize := uintSize(tableOffset)
+	for _, offset := range offsets {
+		writeUint(&b, offset, offsetSize)
+	}
+
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = byte(offsetSize), byte(refSize)
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(p.objects)))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	b.Write(trailer)
+	return b.Bytes(), nil
+}
+
+// flatten adds v and the values it contains to the objects, it returns the
+// reference of v.
+func (p *binaryEncoder) flatten(v Value) (int, error) {
+	if s, ok := v.(String); ok {
+		if ref, ok := p.strings[s]; ok {
+			return ref, nil
+		}
+		p.strings[s] = len(p.objects)
+	}
+	ref := len(p.objects)
+	p.objects = append(p.objects, v)
+	p.refs = append(p.refs, nil)
+
+	var refs []int
+	add := func(v Value) error {
+		r, err := p.flatten(v)
+		refs = append(refs, r)
+		return err
+	}
+	switch v := v.(type) {
+	case String, Integer, Real, Bool, Date, Data:
+	case Array:
+		for _, e := range v {
+			if err := add(e); err != nil {
+				return 0, err
+			}
+		}
+	case *Dict:
+		for _, key := range v.keys {
+			if err := add(String(key)); err != nil {
+				return 0, err
+			}
+		}
+		for _, key := range v.keys {
+			if err := add(v.values[key]); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		return 0, fmt.Errorf("Unknown property list value %T", v)
+	}
+	p.refs[ref] = refs
+	return ref, nil
+}
+
+func (p *binaryEncoder) write(b *bytes.Buffer, v Value, refs []int, refSize int) {
+	switch v := v.(type) {
+	case String:
+		if ascii(string(v)) {
+			writeMarker(b, 0x5, len(v))
+			b.WriteString(string(v))
+			return
+		}
+		u := utf16.Encode([]rune(string(v)))
+		writeMarker(b, 0x6, len(u))
+		for _, c := range u {
+			writeUint(b, uint64(c), 2)
+		}
+	case Integer:
+		writeInteger(b, int64(v))
+	case Real:
+		b.WriteByte(0x23)
+		writeUint(b, math.Float64bits(float64(v)), 8)
+	case Bool:
+		if v {
+			b.WriteByte(0x09)
+		} else {
+			b.WriteByte(0x08)
+		}
+	case Date:
+		b.WriteByte(0x33)
+		t := time.Time(v)
+		seconds := float64(t.Unix()-binaryEpoch) + float64(t.Nanosecond())/1e9
+		writeUint(b, math.Float64bits(seconds), 8)
+	case Data:
+		writeMarker(b, 0x4, len(v))
+		b.Write(v)
+	case Array:
+		writeMarker(b, 0xa, len(v))
+	case *Dict:
+		writeMarker(b, 0xd, v.Len())
+	}
+	for _, ref := range refs {
+		writeUint(b, uint64(ref), refSize)
+	}
+}
+
+// writeMarker writes the marker of an object of type kind and length n.
+func writeMarker(b *bytes.Buffer, kind byte, n int) {
+	if n < 15 {
+		b.WriteByte(kind<<4 | byte(n))
+		return
+	}
+	b.WriteByte(kind<<4 | 0xf)
+	writeInteger(b, int64(n))
+}
+
+// writeInteger writes i as integer object of 1, 2, 4 or 8 bytes. Only the
+// 8 byte integers are signed, so negative integers take 8 bytes.
+func writeInteger(b *bytes.Buffer, i int64) {
+	switch {
+	case i < 0 || i > math.MaxUint32:
+		b.WriteByte(0x13)
+		writeUint(b, uint64(i), 8)
+	case i > math.MaxUint16:
+		b.WriteByte(0x12)
+		writeUint(b, uint64(i), 4)
+	case i > math.MaxUint8:
+		b.WriteByte(0x11)
+		writeUint(b, uint64(i), 2)
+	default:
+		b.WriteByte(0x10)
+		writeUint(b, uint64(i), 1)
+	}
+}
+
+// uintSize returns the number of bytes u takes, at least one.
+func uintSize(u uint64) (n int) {
+	for n = 1; n < 8 && u>>(8*n) != 0; n++ {
+	}
+	return
+}
+
+func writeUint(b *bytes.Buffer, u uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		b.WriteByte(byte(u >> (8 * i)))
+	}
+}
+
+func ascii(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
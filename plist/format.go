@@ -0,0 +1,75 @@
+package plist
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Format is the file format of a property list.
+type Format int
+
+const (
+	// XMLFormat is the XML format Xcode writes Info.plist files in.
+	XMLFormat Format = iota
+	// BinaryFormat is the bplist00 format.
+	BinaryFormat
+	// OpenStepFormat is the old ASCII format, like { key = value; }. It only
+	// has strings, data, arrays and dictionaries, the other values are
+	// written as strings.
+	OpenStepFormat
+)
+
+func (f Format) String() string {
+	switch f {
+	case XMLFormat:
+		return "xml"
+	case BinaryFormat:
+		return "binary"
+	case OpenStepFormat:
+		return "openstep"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// Decode decodes the property list in data, in any of the formats, and
+// returns its value and format.
+func Decode(data []byte) (v Value, f Format, err error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch trimmed := bytes.TrimLeft(data, " \t\r\n"); {
+	case bytes.HasPrefix(data, []byte(binaryMagic)):
+		f = BinaryFormat
+		v, err = decodeBinary(data)
+	case bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<!DOCTYPE")) || bytes.HasPrefix(trimmed, []byte("<plist")):
+		f = XMLFormat
+		v, err = decodeXML(data)
+	default:
+		f = OpenStepFormat
+		v, err = decodeOpenStep(data)
+	}
+	if err != nil {
+		return nil, f, fmt.Errorf("Invalid %s property list (%v)", f, err)
+	}
+	return
+}
+
+// Encode writes v to w as property list in format f.
+func Encode(w io.Writer, v Value, f Format) error {
+	var data []byte
+	var err error
+	switch f {
+	case XMLFormat:
+		data, err = encodeXML(v)
+	case BinaryFormat:
+		data, err = encodeBinary(v)
+	case OpenStepFormat:
+		data, err = encodeOpenStep(v)
+	default:
+		err = fmt.Errorf("Unknown property list format %v", f)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
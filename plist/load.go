@@ -1,10 +1,13 @@
 package plist
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"iter"
+
+	"github.com/simpleapps-eu/translate/stage"
 )
 
 // <plist version="1.0">
@@ -16,42 +19,126 @@ import (
 // The contents of the Plist is expected to be an array of key value
 // string entries where the key represents an ID and the value represents the
 // text translation in the target language. Format is expected to be UTF-8.
-func LoadEntries(srcFile io.Reader) (<-chan Entry, <-chan error) {
-	entryChan := make(chan Entry, 3)
-	errChan := make(chan error, 1)
-	reader := func(srcFile io.Reader, entryChan chan<- Entry, errChan chan<- error) {
-		defer close(entryChan)
-		defer close(errChan)
-
-		bytes, err := ioutil.ReadAll(srcFile)
+// Use Decode for property lists holding other values.
+// Reading stops when ctx is done, ctx.Err() is then sent on the error channel.
+func LoadEntries(ctx context.Context, srcFile io.Reader) (<-chan Entry, <-chan error) {
+	return stage.Load(ctx, NewReader(srcFile).All())
+}
+
+// Reader reads the entries of an XML format Property List file one at a
+// time. The file is decoded while reading, it is never held in memory as a
+// whole.
+type Reader struct {
+	d      *xml.Decoder
+	depth  int
+	inDict bool
+	plist  bool
+	err    error
+}
+
+// NewReader returns a Reader reading the entries of the Plist file provided
+// by r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{d: xml.NewDecoder(r)}
+}
+
+// Next returns the next entry. It returns io.EOF when there are no more
+// entries.
+func (p *Reader) Next() (e Entry, err error) {
+	if p.err == nil {
+		e, p.err = p.next()
+	}
+	return e, p.err
+}
+
+// next decodes tokens until the next key and string pair in the top level
+// dict of the plist element. Any other elements are skipped. Raw tokens are
+// used as they are a lot cheaper, a plist has no name spaces to translate.
+func (p *Reader) next() (e Entry, err error) {
+	var key *string
+	for {
+		token, err := p.d.RawToken()
+		if err == io.EOF && (p.depth > 0 || !p.plist) {
+			err = io.ErrUnexpectedEOF
+		}
 		if err != nil {
-			errChan <- err
-			return
+			return e, err
 		}
 
-		type PList struct {
-			XMLName xml.Name `xml:"plist"`
-			Keys    []string `xml:"dict>key"`
-			Strings []string `xml:"dict>string"`
+		switch t := token.(type) {
+		case xml.StartElement:
+			p.depth++
+			switch {
+			case p.depth == 1 && t.Name.Local != "plist":
+				return e, fmt.Errorf("expected element type <plist> but have <%s>", t.Name.Local)
+			case p.depth == 1:
+				p.plist = true
+			case p.depth == 2:
+				p.inDict = t.Name.Local == "dict"
+			case p.depth == 3 && p.inDict && t.Name.Local == "key":
+				if key != nil {
+					return e, fmt.Errorf("Missing string for key %q in PList Strings file", *key)
+				}
+				k, err := p.text()
+				if err != nil {
+					return e, err
+				}
+				key = &k
+			case p.depth == 3 && p.inDict && t.Name.Local == "string":
+				str, err := p.text()
+				if err != nil {
+					return e, err
+				}
+				if key == nil {
+					return e, fmt.Errorf("Missing key for string %q in PList Strings file", str)
+				}
+				return Entry{ID: *key, Str: str}, nil
+			}
+		case xml.EndElement:
+			p.depth--
+			if p.depth == 1 && key != nil {
+				return e, fmt.Errorf("Missing string for key %q in PList Strings file", *key)
+			}
 		}
-		l := PList{}
+	}
+}
 
-		err = xml.Unmarshal(bytes, &l)
+// text returns the character data of the element just started, it consumes
+// the end of the element.
+func (p *Reader) text() (s string, err error) {
+	var b []byte
+	for depth := 1; depth > 0; {
+		token, err := p.d.RawToken()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
 		if err != nil {
-			errChan <- err
-			return
+			return "", err
 		}
-
-		if len(l.Keys) != len(l.Strings) {
-			errChan <- fmt.Errorf("Number of Keys (%d) and Strings (%d) differ in PList Strings file", len(l.Keys), len(l.Strings))
-			return
+		switch t := token.(type) {
+		case xml.CharData:
+			if depth == 1 {
+				b = append(b, t...)
+			}
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
 		}
+	}
+	p.depth--
+	return string(b), nil
+}
 
-		for idx, key := range l.Keys {
-			entryChan <- Entry{ID: key, Str: l.Strings[idx]}
+// All returns an iterator over the remaining entries. The iteration stops at
+// the end of the file or after yielding the first error.
+func (p *Reader) All() iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for {
+			e, err := p.Next()
+			if err == io.EOF || !yield(e, err) || err != nil {
+				return
+			}
 		}
 	}
-
-	go reader(srcFile, entryChan, errChan)
-	return entryChan, errChan
 }
@@ -0,0 +1,382 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// openStepDecoder decodes an OpenStep property list. A file of entries
+// without braces, like a .strings file, is a dict:
+//
+//	"key" = "value";
+type openStepDecoder struct {
+	data []byte
+	pos  int
+}
+
+func decodeOpenStep(data []byte) (Value, error) {
+	p := &openStepDecoder{data: data}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return NewDict(), nil
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.skip(); err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return v, nil
+	}
+	if _, ok := v.(String); ok && (p.data[p.pos] == '=' || p.data[p.pos] == ';') {
+		p.pos = 0
+		return p.dict(false)
+	}
+	return nil, p.errorf("unexpected %q after the value", p.data[p.pos])
+}
+
+func (p *openStepDecoder) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *openStepDecoder) errorf(format string, args ...interface{}) error {
+	line := 1 + bytes.Count(p.data[:min(p.pos, len(p.data))], []byte("\n"))
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip skips white space and comments.
+func (p *openStepDecoder) skip() error {
+	for !p.eof() {
+		switch rest := p.data[p.pos:]; {
+		case bytes.HasPrefix(rest, []byte("//")):
+			if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+				p.pos += i + 1
+			} else {
+				p.pos = len(p.data)
+			}
+		case bytes.HasPrefix(rest, []byte("/*")):
+			i := bytes.Index(rest[2:], []byte("*/"))
+			if i < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += i + 4
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n':
+			p.pos++
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// expect skips to the next character, which must be c.
+func (p *openStepDecoder) expect(c byte) error {
+	if err := p.skip(); err != nil {
+		return err
+	}
+	if p.eof() {
+		return p.errorf("expected %q but have the end of the file", c)
+	}
+	if p.data[p.pos] != c {
+		return p.errorf("expected %q but have %q", c, p.data[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// value decodes the value at the current position.
+func (p *openStepDecoder) value() (Value, error) {
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return nil, p.errorf("missing value at the end of the file")
+	}
+	switch p.data[p.pos] {
+	case '{':
+		p.pos++
+		return p.dict(true)
+	case '(':
+		p.pos++
+		a := Array{}
+		for {
+			if err := p.skip(); err != nil {
+				return nil, err
+			}
+			if !p.eof() && p.data[p.pos] == ')' {
+				p.pos++
+				return a, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+			if err = p.skip(); err != nil {
+				return nil, err
+			}
+			if !p.eof() && p.data[p.pos] == ',' {
+				p.pos++
+				continue
+			}
+			if err = p.expect(')'); err != nil {
+				return nil, err
+			}
+			return a, nil
+		}
+	case '<':
+		end := bytes.IndexByte(p.data[p.pos:], '>')
+		if end < 0 {
+			return nil, p.errorf("unterminated data")
+		}
+		digits := strings.Join(strings.Fields(string(p.data[p.pos+1:p.pos+end])), "")
+		b, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, p.errorf("invalid data (%v)", err)
+		}
+		p.pos += end + 1
+		return Data(b), nil
+	}
+	s, err := p.string()
+	return String(s), err
+}
+
+// dict decodes the entries of a dict, up to the closing brace when braced or
+// else the end of the file. An entry without value, "key";, has its key as
+// value.
+func (p *openStepDecoder) dict(braced bool) (*Dict, error) {
+	d := NewDict()
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			if braced {
+				return nil, p.errorf("missing '}' at the end of the file")
+			}
+			return d, nil
+		}
+		if braced && p.data[p.pos] == '}' {
+			p.pos++
+			return d, nil
+		}
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skip(); err != nil {
+			return nil, err
+		}
+		if !p.eof() && p.data[p.pos] == ';' {
+			p.pos++
+			d.Set(key, String(key))
+			continue
+		}
+		if err = p.expect('='); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(';'); err != nil {
+			return nil, err
+		}
+		d.Set(key, v)
+	}
+}
+
+// string decodes a quoted or unquoted string.
+func (p *openStepDecoder) string() (string, error) {
+	if err := p.skip(); err != nil {
+		return "", err
+	}
+	if p.eof() {
+		return "", p.errorf("missing string at the end of the file")
+	}
+	quote := p.data[p.pos]
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for !p.eof() && isUnquoted(p.data[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", p.errorf("unexpected %q", p.data[p.pos])
+		}
+		return string(p.data[start:p.pos]), nil
+	}
+
+	var b strings.Builder
+	for p.pos++; !p.eof(); {
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+		case p.eof():
+		case p.data[p.pos] == 'U':
+			// \U followed by up to 4 hex digits
+			p.pos++
+			end := p.pos
+			for end < len(p.data) && end-p.pos < 4 && isHex(p.data[end]) {
+				end++
+			}
+			r, err := strconv.ParseUint(string(p.data[p.pos:end]), 16, 32)
+			if err != nil {
+				return "", p.errorf("invalid \\U escape")
+			}
+			p.pos = end
+			b.WriteRune(rune(r))
+		case p.data[p.pos] >= '0' && p.data[p.pos] <= '7':
+			end := p.pos
+			for end < len(p.data) && end-p.pos < 3 && p.data[end] >= '0' && p.data[end] <= '7' {
+				end++
+			}
+			r, _ := strconv.ParseUint(string(p.data[p.pos:end]), 8, 32)
+			p.pos = end
+			b.WriteRune(rune(r))
+		default:
+			e := p.data[p.pos]
+			p.pos++
+			if i := strings.IndexByte("abfnrtv", e); i >= 0 {
+				e = "\a\b\f\n\r\t\v"[i]
+			}
+			b.WriteByte(e)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// isUnquoted reports whether c may be in a string without quotes.
+func isUnquoted(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("_$+/:.-", c) >= 0
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// encodeOpenStep returns v as OpenStep property list, indented by tabs. The
+// format only has strings, so numbers, booleans and dates are written as
+// strings.
+func encodeOpenStep(v Value) ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeOpenStep(&b, v, 0); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func writeOpenStep(b *bytes.Buffer, v Value, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	switch v := v.(type) {
+	case String:
+		writeOpenStepString(b, string(v))
+	case Integer:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case Real:
+		writeOpenStepString(b, formatReal(float64(v)))
+	case Bool:
+		if v {
+			b.WriteString("YES")
+		} else {
+			b.WriteString("NO")
+		}
+	case Date:
+		writeOpenStepString(b, time.Time(v).UTC().Format("2006-01-02 15:04:05 -0700"))
+	case Data:
+		b.WriteByte('<')
+		for i := 0; i < len(v); i += 4 {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(hex.EncodeToString(v[i:min(i+4, len(v))]))
+		}
+		b.WriteByte('>')
+	case Array:
+		if len(v) == 0 {
+			b.WriteString("()")
+			return nil
+		}
+		b.WriteString("(\n")
+		for i, e := range v {
+			b.WriteString(indent + "\t")
+			if err := writeOpenStep(b, e, depth+1); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + ")")
+	case *Dict:
+		if v.Len() == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for _, key := range v.keys {
+			b.WriteString(indent + "\t")
+			writeOpenStepString(b, key)
+			b.WriteString(" = ")
+			if err := writeOpenStep(b, v.values[key], depth+1); err != nil {
+				return err
+			}
+			b.WriteString(";\n")
+		}
+		b.WriteString(indent + "}")
+	default:
+		return fmt.Errorf("Unknown property list value %T", v)
+	}
+	return nil
+}
+
+// writeOpenStepString writes s, quoted unless it only has characters that
+// don't need quotes.
+func writeOpenStepString(b *bytes.Buffer, s string) {
+	quote := len(s) == 0
+	for i := 0; i < len(s); i++ {
+		if !isUnquoted(s[i]) {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		b.WriteString(s)
+		return
+	}
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20:
+			fmt.Fprintf(b, `\%03o`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+}
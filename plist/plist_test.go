@@ -0,0 +1,328 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderWriter(t *testing.T) {
+	const data = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Version</key>
+	<string>Version</string>
+	<key>Name</key>
+	<string>Notes</string>
+</dict>
+</plist>
+`
+	buf := &strings.Builder{}
+	w := NewWriter(buf)
+	for e, err := range NewReader(strings.NewReader(data)).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := strings.TrimSuffix(data, "\n"); strings.TrimSuffix(buf.String(), "\n") != expect {
+		t.Errorf("Expected\n%s\ngot\n%s", expect, buf.String())
+	}
+
+	for _, bad := range []string{
+		``,
+		`<dict><key>a</key><string>b</string></dict>`,
+		`<plist><dict><key>a</key><true/></dict></plist>`,
+		`<plist><dict><key>a</key><key>b</key><string>b</string></dict></plist>`,
+		`<plist><dict><key>a</key><string>b</string>`,
+	} {
+		r := NewReader(strings.NewReader(bad))
+		var err error
+		for err == nil {
+			_, err = r.Next()
+		}
+		if err == io.EOF {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+const infoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleDisplayName</key>
+	<string>Notes &amp; More</string>
+	<key>LSRequiresIPhoneOS</key>
+	<true/>
+	<key>UIApplicationShortcutItems</key>
+	<array>
+		<dict>
+			<key>UIApplicationShortcutItemTitle</key>
+			<string>New Note</string>
+			<key>UIApplicationShortcutItemType</key>
+			<string>new</string>
+		</dict>
+	</array>
+	<key>Build</key>
+	<integer>-42</integer>
+	<key>Scale</key>
+	<real>1.5</real>
+	<key>Released</key>
+	<date>2024-03-01T12:30:00Z</date>
+	<key>Icon</key>
+	<data>
+	AAEC/w==
+	</data>
+	<key>a/b~c</key>
+	<string>Slash</string>
+	<key>Empty</key>
+	<dict/>
+</dict>
+</plist>
+`
+
+func TestDecodeEncode(t *testing.T) {
+	v, f, err := Decode([]byte(infoPlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != XMLFormat {
+		t.Errorf("Expected xml got %v", f)
+	}
+	d := v.(*Dict)
+	if build, _ := d.Get("Build"); build != Integer(-42) {
+		t.Errorf("Expected -42 got %v", build)
+	}
+	if released, _ := d.Get("Released"); !time.Time(released.(Date)).Equal(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2024-03-01 got %v", released)
+	}
+	if icon, _ := d.Get("Icon"); !bytes.Equal(icon.(Data), []byte{0, 1, 2, 255}) {
+		t.Errorf("Expected 00 01 02 ff got %v", icon)
+	}
+	expect := []Entry{
+		{ID: "CFBundleDisplayName", Str: "Notes & More"},
+		{ID: "UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle", Str: "New Note"},
+		{ID: "UIApplicationShortcutItems/0/UIApplicationShortcutItemType", Str: "new"},
+		{ID: "a~1b~0c", Str: "Slash"},
+	}
+	if got := Strings(v); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v got %v", expect, got)
+	}
+
+	// XML is written the way it was read, the other formats decode to the
+	// same value.
+	for _, format := range []Format{XMLFormat, BinaryFormat, OpenStepFormat} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, v, format); err != nil {
+			t.Fatal(err)
+		}
+		v2, f, err := Decode(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%v: %v\n%s", format, err, buf)
+		}
+		if f != format {
+			t.Errorf("Expected %v got %v", format, f)
+		}
+		if format == XMLFormat && buf.String() != infoPlist {
+			t.Errorf("Expected\n%s\ngot\n%s", infoPlist, buf)
+		}
+		if format == OpenStepFormat {
+			// OpenStep only has strings
+			for _, key := range []string{"LSRequiresIPhoneOS", "Build", "Scale", "Released"} {
+				s, _ := v2.(*Dict).Get(key)
+				if _, ok := s.(String); !ok {
+					t.Errorf("Expected a string for %s got %v", key, s)
+				}
+				v2.(*Dict).Set(key, d.values[key])
+			}
+		}
+		if !reflect.DeepEqual(v2, v) {
+			t.Errorf("%v: expected %v got %v", format, v, v2)
+		}
+	}
+}
+
+func TestOpenStep(t *testing.T) {
+	const data = `// Comment
+{
+	Name = "Notes \"Pro\"\n\U00e9";
+	/* block */ Items = (one, "two", <0001 02>);
+	Nested = { key = value; };
+}
+`
+	v, f, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != OpenStepFormat {
+		t.Errorf("Expected openstep got %v", f)
+	}
+	expect := []Entry{
+		{ID: "Name", Str: "Notes \"Pro\"\né"},
+		{ID: "Items/0", Str: "one"},
+		{ID: "Items/1", Str: "two"},
+		{ID: "Nested/key", Str: "value"},
+	}
+	if got := Strings(v); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v got %v", expect, got)
+	}
+
+	// A .strings file is a dict without braces
+	v, _, err = Decode([]byte("/* c */\n\"a\" = \"b\";\n\"c\";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Strings(v); !reflect.DeepEqual(got, []Entry{{ID: "a", Str: "b"}, {ID: "c", Str: "c"}}) {
+		t.Errorf("Expected a=b and c=c got %v", got)
+	}
+
+	for _, bad := range []string{
+		`{ a = b }`,
+		`{ a = b;`,
+		`( a b )`,
+		`"unterminated`,
+		`<0g>`,
+		`/* open`,
+		`a = b; }`,
+		"bplist00",
+		`<plist><dict><string>a</string></dict></plist>`,
+		`<plist><dict><key>a</key></dict></plist>`,
+		`<plist><integer>x</integer></plist>`,
+		`<plist><string>a</string><string>b</string></plist>`,
+	} {
+		if _, _, err := Decode([]byte(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+// binaryPlist returns a binary property list of objs, the first is the top
+// object.
+func binaryPlist(objs ...[]byte) []byte {
+	data := []byte(binaryMagic)
+	var offsets []byte
+	for _, obj := range objs {
+		offsets = append(offsets, byte(len(data)))
+		data = append(data, obj...)
+	}
+	tableOffset := len(data)
+	data = append(data, offsets...)
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 1, 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objs)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	return append(data, trailer...)
+}
+
+func TestDecodeBinaryCrafted(t *testing.T) {
+	v, _, err := Decode(binaryPlist([]byte{0xd1, 1, 2}, []byte{0x51, 'a'}, []byte{0x09}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := v.(*Dict).Get("a"); a != Bool(true) {
+		t.Errorf("Expected a = true got %v", v)
+	}
+	for _, objs := range [][][]byte{
+		// A dict of 2^63+1 entries, twice that overflows to 2
+		{{0xdf, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 1, 1, 1}, {0x51, 'a'}},
+		{{0xaf, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1}, {0x09}},
+		{{0x6f, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 1, 0, 'a'}},
+		{{0x5f, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		// An array containing itself
+		{{0xa1, 0}},
+		{{0xd1, 1, 0}, {0x51, 'a'}},
+		// A key that isn't a string
+		{{0xd1, 1, 1}, {0x09}},
+		// Truncated objects
+		{{0x5f}},
+		{{0x23, 0}},
+	} {
+		if _, _, err := Decode(binaryPlist(objs...)); err == nil {
+			t.Errorf("Expected an error for % x", objs)
+		}
+	}
+
+	// Arrays of twice the next array expand to 2^40 values
+	var objs [][]byte
+	for i := 1; i <= 40; i++ {
+		objs = append(objs, []byte{0xa2, byte(i), byte(i)})
+	}
+	objs = append(objs, []byte{0x09})
+	if _, _, err := Decode(binaryPlist(objs...)); err == nil {
+		t.Error("Expected an error for exponentially expanding arrays")
+	}
+}
+
+func TestMap(t *testing.T) {
+	v, _, err := Decode([]byte(infoPlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = Map(v, func(path string, s String) String {
+		if path == "UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle" {
+			return "Nieuwe notitie"
+		}
+		return s
+	})
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, v, XMLFormat); err != nil {
+		t.Fatal(err)
+	}
+	expect := strings.Replace(infoPlist, "New Note", "Nieuwe notitie", 1)
+	if buf.String() != expect {
+		t.Errorf("Expected\n%s\ngot\n%s", expect, buf)
+	}
+}
+
+func plistEntries(n int) (entries []Entry) {
+	for i := 0; i < n; i++ {
+		entries = append(entries, Entry{ID: fmt.Sprintf("id%d", i), Str: fmt.Sprintf("Text %d", i)})
+	}
+	return
+}
+
+func plistData(entries []Entry) string {
+	b := &strings.Builder{}
+	w := NewWriter(b)
+	for _, e := range entries {
+		w.Write(e)
+	}
+	w.Close()
+	return b.String()
+}
+
+func BenchmarkReader(b *testing.B) {
+	data := plistData(plistEntries(1000))
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		for _, err := range NewReader(strings.NewReader(data)).All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	entries := plistEntries(1000)
+	b.SetBytes(int64(len(plistData(entries))))
+	for i := 0; i < b.N; i++ {
+		w := NewWriter(io.Discard)
+		for _, e := range entries {
+			w.Write(e)
+		}
+		w.Close()
+	}
+}
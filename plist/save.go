@@ -1,6 +1,7 @@
 package plist
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 )
@@ -10,25 +11,73 @@ import (
 // when all entries have been written. The goroutine feeding entryChan should
 // close the channel once it has finished. The closing of the channel indicates
 // to SaveEntries that it can finish too. The function then returns the number
-// of entries it has written.
-func SaveEntries(entryChan <-chan Entry, tgtFile io.Writer) (n int) {
-
-	fmt.Fprintln(tgtFile, plistPrefix)
+// of entries it has written, and the first write error.
+func SaveEntries(entryChan <-chan Entry, tgtFile io.Writer) (n int, err error) {
+	bw := bufio.NewWriter(tgtFile)
+	w := NewWriter(bw)
 	for entry := range entryChan {
-		fmt.Fprintf(tgtFile, plistEntry, entry.ID, entry.Str)
-		n++
+		if err != nil {
+			continue
+		}
+		if err = w.Write(entry); err == nil {
+			n++
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
 	}
-	fmt.Fprintln(tgtFile, plistPostfix)
 	return
 }
 
-var (
-	plistPrefix = `<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>`
+// Writer writes entries to an XML format Property List file.
+type Writer struct {
+	w             io.Writer
+	buf           []byte
+	prefixWritten bool
+}
+
+// NewWriter returns a Writer writing entries to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes entry e.
+func (p *Writer) Write(e Entry) (err error) {
+	if err = p.writePrefix(); err != nil {
+		return
+	}
+	b := append(p.buf[:0], "\t<key>"...)
+	b = append(b, e.ID...)
+	b = append(b, "</key>\n\t<string>"...)
+	b = append(b, e.Str...)
+	b = append(b, "</string>\n"...)
+	p.buf = b
+	_, err = p.w.Write(b)
+	return
+}
+
+// Close ends the Plist file, it doesn't close the underlying writer.
+func (p *Writer) Close() (err error) {
+	if err = p.writePrefix(); err != nil {
+		return
+	}
+	_, err = fmt.Fprintln(p.w, plistPostfix)
+	return
+}
 
-	plistEntry = "\t<key>%s</key>\n\t<string>%s</string>\n"
+func (p *Writer) writePrefix() (err error) {
+	if !p.prefixWritten {
+		p.prefixWritten = true
+		_, err = fmt.Fprintln(p.w, plistPrefix)
+	}
+	return
+}
+
+var (
+	plistPrefix = xmlHeader + "<dict>"
 
 	plistPostfix = "</dict>\n</plist>"
 )
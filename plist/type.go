@@ -3,4 +3,7 @@ package plist
 type Entry struct {
 	ID  string
 	Str string
+	// Locale is the locale of the fallback translation memory that supplied
+	// Str, set by TranslatePlistEntries.
+	Locale string
 }
@@ -0,0 +1,141 @@
+package plist
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is a property list value: a String, Integer, Real, Bool, Date, Data,
+// Array or *Dict.
+type Value interface {
+	plistValue()
+}
+
+type (
+	String  string
+	Integer int64
+	Real    float64
+	Bool    bool
+	Date    time.Time
+	Data    []byte
+	Array   []Value
+)
+
+// Dict is a dictionary of values by key. It keeps the order of its keys, so
+// a property list is written in the order it was read.
+type Dict struct {
+	keys   []string
+	values map[string]Value
+}
+
+func (String) plistValue()  {}
+func (Integer) plistValue() {}
+func (Real) plistValue()    {}
+func (Bool) plistValue()    {}
+func (Date) plistValue()    {}
+func (Data) plistValue()    {}
+func (Array) plistValue()   {}
+func (*Dict) plistValue()   {}
+
+// NewDict returns an empty dictionary.
+func NewDict() *Dict {
+	return &Dict{values: map[string]Value{}}
+}
+
+// Len returns the number of keys of d.
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+// Keys returns the keys of d in order, the slice must not be modified.
+func (d *Dict) Keys() []string {
+	return d.keys
+}
+
+// Get returns the value of key.
+func (d *Dict) Get(key string) (v Value, ok bool) {
+	v, ok = d.values[key]
+	return
+}
+
+// Set sets the value of key, a new key is added after the others.
+func (d *Dict) Set(key string, v Value) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = v
+}
+
+// typeName returns the name of the element of v in an XML property list.
+func typeName(v Value) string {
+	switch v.(type) {
+	case String:
+		return "string"
+	case Integer:
+		return "integer"
+	case Real:
+		return "real"
+	case Bool:
+		return "bool"
+	case Date:
+		return "date"
+	case Data:
+		return "data"
+	case Array:
+		return "array"
+	case *Dict:
+		return "dict"
+	}
+	return "unknown"
+}
+
+// pathEscaper escapes the keys in a key path the way JSON pointers do.
+var pathEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Strings returns the string values of v in order as entries, with the key
+// path of the value as ID. A key path is the path of dictionary keys and
+// array indexes from v to the value joined by /, so the ID of the top level
+// string values of a dictionary is their key:
+//
+//	CFBundleDisplayName
+//	UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle
+//
+// A ~ or / in a key is written as ~0 or ~1.
+func Strings(v Value) (entries []Entry) {
+	Map(v, func(path string, s String) String {
+		entries = append(entries, Entry{ID: path, Str: string(s)})
+		return s
+	})
+	return
+}
+
+// Map replaces every string value of v by the result of f for its key path,
+// see Strings. The dictionaries and arrays of v are changed in place, it
+// returns the new v.
+func Map(v Value, f func(path string, s String) String) Value {
+	return mapValue(v, "", f)
+}
+
+func mapValue(v Value, path string, f func(path string, s String) String) Value {
+	switch v := v.(type) {
+	case String:
+		return f(path, v)
+	case Array:
+		for i, e := range v {
+			v[i] = mapValue(e, join(path, strconv.Itoa(i)), f)
+		}
+	case *Dict:
+		for _, key := range v.keys {
+			v.values[key] = mapValue(v.values[key], join(path, pathEscaper.Replace(key)), f)
+		}
+	}
+	return v
+}
+
+func join(path, elem string) string {
+	if len(path) == 0 {
+		return elem
+	}
+	return path + "/" + elem
+}
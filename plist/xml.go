@@ -0,0 +1,279 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xmlDecoder decodes the value of an XML property list. Raw tokens are used
+// as they are a lot cheaper, a plist has no name spaces to translate.
+type xmlDecoder struct {
+	d *xml.Decoder
+}
+
+func decodeXML(data []byte) (Value, error) {
+	p := &xmlDecoder{d: xml.NewDecoder(bytes.NewReader(data))}
+	start, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	if start.Name.Local != "plist" {
+		return nil, fmt.Errorf("expected element type <plist> but have <%s>", start.Name.Local)
+	}
+	if start, err = p.start(); err != nil {
+		return nil, err
+	}
+	v, err := p.value(start)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.start(); err != io.EOF {
+		return nil, fmt.Errorf("expected the end of <plist>")
+	}
+	return v, nil
+}
+
+// start returns the next start element, it returns io.EOF at the end of the
+// enclosing element.
+func (p *xmlDecoder) start() (xml.StartElement, error) {
+	for {
+		token, err := p.d.RawToken()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, io.EOF
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return xml.StartElement{}, fmt.Errorf("unexpected text %q", t)
+			}
+		}
+	}
+}
+
+// text returns the character data of the element just started, it consumes
+// the end of the element.
+func (p *xmlDecoder) text() (string, error) {
+	var b []byte
+	for {
+		token, err := p.d.RawToken()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b = append(b, t...)
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected element <%s> in text", t.Name.Local)
+		case xml.EndElement:
+			return string(b), nil
+		}
+	}
+}
+
+func (p *xmlDecoder) value(start xml.StartElement) (Value, error) {
+	name := start.Name.Local
+	switch name {
+	case "string":
+		s, err := p.text()
+		return String(s), err
+	case "true", "false":
+		if _, err := p.text(); err != nil {
+			return nil, err
+		}
+		return Bool(name == "true"), nil
+	case "array":
+		a := Array{}
+		for {
+			start, err := p.start()
+			if err == io.EOF {
+				return a, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := p.value(start)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+	case "dict":
+		d := NewDict()
+		for {
+			start, err := p.start()
+			if err == io.EOF {
+				return d, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if start.Name.Local != "key" {
+				return nil, fmt.Errorf("expected element type <key> but have <%s>", start.Name.Local)
+			}
+			key, err := p.text()
+			if err != nil {
+				return nil, err
+			}
+			if start, err = p.start(); err == io.EOF {
+				return nil, fmt.Errorf("missing value for key %q", key)
+			} else if err != nil {
+				return nil, err
+			}
+			v, err := p.value(start)
+			if err != nil {
+				return nil, err
+			}
+			d.Set(key, v)
+		}
+	}
+
+	s, err := p.text()
+	if err != nil {
+		return nil, err
+	}
+	s = strings.TrimSpace(s)
+	switch name {
+	case "integer":
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return Integer(i), nil
+		}
+		// Values above the int64 range are unsigned
+		u, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return Integer(u), nil
+	case "real":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid real %q", s)
+		}
+		return Real(f), nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", s)
+		}
+		return Date(t), nil
+	case "data":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid data (%v)", err)
+		}
+		return Data(b), nil
+	}
+	return nil, fmt.Errorf("unknown element type <%s>", name)
+}
+
+// xmlEscaper escapes text the way Xcode does, only the characters that
+// must be.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// encodeXML returns v as XML property list, indented by tabs like Xcode
+// writes it.
+func encodeXML(v Value) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	if err := writeXML(&b, v, 0); err != nil {
+		return nil, err
+	}
+	b.WriteString("</plist>\n")
+	return b.Bytes(), nil
+}
+
+func writeXML(b *bytes.Buffer, v Value, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	b.WriteString(indent)
+	switch v := v.(type) {
+	case String:
+		b.WriteString("<string>")
+		xmlEscaper.WriteString(b, string(v))
+		b.WriteString("</string>\n")
+	case Integer:
+		fmt.Fprintf(b, "<integer>%d</integer>\n", v)
+	case Real:
+		fmt.Fprintf(b, "<real>%s</real>\n", formatReal(float64(v)))
+	case Bool:
+		if v {
+			b.WriteString("<true/>\n")
+		} else {
+			b.WriteString("<false/>\n")
+		}
+	case Date:
+		fmt.Fprintf(b, "<date>%s</date>\n", time.Time(v).UTC().Format("2006-01-02T15:04:05Z"))
+	case Data:
+		b.WriteString("<data>\n")
+		s := base64.StdEncoding.EncodeToString(v)
+		for len(s) > 0 {
+			n := min(len(s), 68)
+			b.WriteString(indent + s[:n] + "\n")
+			s = s[n:]
+		}
+		b.WriteString(indent + "</data>\n")
+	case Array:
+		if len(v) == 0 {
+			b.WriteString("<array/>\n")
+			return nil
+		}
+		b.WriteString("<array>\n")
+		for _, e := range v {
+			if err := writeXML(b, e, depth+1); err != nil {
+				return err
+			}
+		}
+		b.WriteString(indent + "</array>\n")
+	case *Dict:
+		if v.Len() == 0 {
+			b.WriteString("<dict/>\n")
+			return nil
+		}
+		b.WriteString("<dict>\n")
+		for _, key := range v.keys {
+			b.WriteString(indent + "\t<key>")
+			xmlEscaper.WriteString(b, key)
+			b.WriteString("</key>\n")
+			if err := writeXML(b, v.values[key], depth+1); err != nil {
+				return err
+			}
+		}
+		b.WriteString(indent + "</dict>\n")
+	default:
+		return fmt.Errorf("Unknown property list value %T", v)
+	}
+	return nil
+}
+
+// formatReal formats f the way the XML property list writer does.
+func formatReal(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+infinity"
+	case math.IsInf(f, -1):
+		return "-infinity"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
@@ -0,0 +1,203 @@
+package plural
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plist"
+	"github.com/simpleapps-eu/translate/po"
+)
+
+// Message is a message with plural variants read from a plural catalog.
+type Message struct {
+	ID string
+	// Source is the text of the message in the source language, when the
+	// catalog has it, like the msgid_plural of a PO file.
+	Source string
+	Forms  Forms
+	// Integers is set when the catalog only holds the categories for
+	// integers, like the msgstr[n] of a PO file.
+	Integers bool
+	// ExactZero is set when a Zero form the locale doesn't use is the text
+	// for exactly 0, like in a .stringsdict file.
+	ExactZero bool
+}
+
+// Check compares the categories of m to those the locale l needs, the way
+// the package level Check does.
+func (m Message) Check(l locale.Locale) (missing, extra []Category) {
+	needed := Categories(l)
+	if m.Integers {
+		needed = IntegerCategories(l)
+	}
+	forms := m.Forms
+	if _, ok := forms[Zero]; ok && m.ExactZero && !contains(needed, Zero) {
+		forms = Forms{}
+		for c, f := range m.Forms {
+			if c != Zero {
+				forms[c] = f
+			}
+		}
+	}
+	return check(needed, forms)
+}
+
+// Stringsdict keys that are not plural categories.
+const (
+	formatSpecTypeKey = "NSStringFormatSpecTypeKey"
+	pluralRuleType    = "NSStringPluralRuleType"
+	stringsdictPrefix = "NSString"
+)
+
+// LoadStringsDict reads the plural messages of the .stringsdict property list
+// in data. Every plural variable of a key is a message with key:variable as
+// ID. Variables of other rule types, like NSStringDeviceSpecificRuleType,
+// are skipped.
+func LoadStringsDict(data []byte) (msgs []Message, err error) {
+	v, _, err := plist.Decode(data)
+	if err != nil {
+		return
+	}
+	root, ok := v.(*plist.Dict)
+	if !ok {
+		return nil, fmt.Errorf("Expected a dictionary of keys in the .stringsdict file")
+	}
+	for _, key := range root.Keys() {
+		value, _ := root.Get(key)
+		dict, ok := value.(*plist.Dict)
+		if !ok {
+			return nil, fmt.Errorf("Expected a dictionary for key %q", key)
+		}
+		for _, name := range dict.Keys() {
+			value, _ := dict.Get(name)
+			variable, ok := value.(*plist.Dict)
+			if !ok {
+				continue
+			}
+			if typ, _ := variable.Get(formatSpecTypeKey); typ != plist.String(pluralRuleType) {
+				continue
+			}
+			m := Message{ID: key + ":" + name, Forms: Forms{}, ExactZero: true}
+			for _, c := range variable.Keys() {
+				if strings.HasPrefix(c, stringsdictPrefix) {
+					continue
+				}
+				category, err := ParseCategory(c)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid variable %q of key %q (%v)", name, key, err)
+				}
+				text, _ := variable.Get(c)
+				s, ok := text.(plist.String)
+				if !ok {
+					return nil, fmt.Errorf("Expected a string for %s of variable %q of key %q", c, name, key)
+				}
+				m.Forms[category] = Form{Text: string(s)}
+			}
+			msgs = append(msgs, m)
+		}
+	}
+	return
+}
+
+// androidResources is the part of an Android resources file holding the
+// <plurals> elements.
+type androidResources struct {
+	Plurals []struct {
+		Name         string `xml:"name,attr"`
+		Translatable string `xml:"translatable,attr"`
+		Items        []struct {
+			Quantity string `xml:"quantity,attr"`
+			Text     string `xml:",innerxml"`
+		} `xml:"item"`
+	} `xml:"plurals"`
+}
+
+// LoadAndroid reads the <plurals> elements of the Android resources file in
+// r, like res/values-ru/strings.xml, as messages with their name as ID. The
+// texts are kept as written, including markup and escapes. Plurals marked
+// translatable="false" are skipped.
+func LoadAndroid(r io.Reader) (msgs []Message, err error) {
+	var res androidResources
+	if err = xml.NewDecoder(r).Decode(&res); err != nil {
+		return
+	}
+	for _, p := range res.Plurals {
+		if p.Translatable == "false" {
+			continue
+		}
+		m := Message{ID: p.Name, Forms: Forms{}}
+		for _, item := range p.Items {
+			category, err := ParseCategory(item.Quantity)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid item of plurals %q (%v)", p.Name, err)
+			}
+			m.Forms[category] = Form{Text: item.Text}
+		}
+		msgs = append(msgs, m)
+	}
+	return
+}
+
+// FromPO returns the plural messages of the PO entries with a msgid_plural in
+// the locale l, with the msgid as ID and the msgid_plural as Source. Empty
+// msgstr[n] are untranslated, their categories are missing. The forms of a
+// fuzzy entry are Fuzzy. It returns an error when the number of msgstr[n]
+// doesn't match the IntegerCategories of l, the Plural-Forms header of the
+// file is wrong then.
+func FromPO(l locale.Locale, entries []po.Entry) (msgs []Message, err error) {
+	for _, e := range entries {
+		if len(e.Plural) == 0 {
+			continue
+		}
+		forms, err := poForms(l, e)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, Message{ID: e.ID, Source: e.Plural, Forms: forms, Integers: true})
+	}
+	return
+}
+
+// ScaffoldPO completes the msgstr[n] of the PO entries with a msgid_plural in
+// the locale l using Scaffold. Entries that get new texts are marked fuzzy,
+// entries without any translation are left alone. It returns the number of
+// entries changed.
+func ScaffoldPO(l locale.Locale, entries []po.Entry) (n int, err error) {
+	for i := range entries {
+		e := &entries[i]
+		if len(e.Plural) == 0 {
+			continue
+		}
+		var forms Forms
+		if forms, err = poForms(l, *e); err != nil {
+			return
+		}
+		if missing, _ := check(IntegerCategories(l), forms); len(forms) == 0 || len(missing) == 0 {
+			continue
+		}
+		e.Strs = Indexed(l, Scaffold(l, forms))
+		e.SetFuzzy()
+		n++
+	}
+	return
+}
+
+// poForms returns the translated forms of the PO entry e in the locale l.
+func poForms(l locale.Locale, e po.Entry) (Forms, error) {
+	forms, err := FromIndexed(l, e.Strs)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid msgid %q (%v)", e.ID, err)
+	}
+	for c, f := range forms {
+		if len(f.Text) == 0 {
+			delete(forms, c)
+			continue
+		}
+		f.Fuzzy = e.Fuzzy()
+		forms[c] = f
+	}
+	return forms, nil
+}
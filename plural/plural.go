@@ -0,0 +1,253 @@
+// Package plural answers which CLDR plural categories a locale needs and
+// validates the plural variants of messages against them. The variants can
+// come from any plural catalog: the category keys of a .stringsdict file or
+// an Android <plurals> resource, the msgstr[n] of a PO file or the branches of
+// an ICU {n, plural, ...} argument.
+package plural
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Category is a CLDR plural category, named the way the catalogs name it.
+type Category string
+
+// Plural categories.
+const (
+	Zero  Category = "zero"
+	One   Category = "one"
+	Two   Category = "two"
+	Few   Category = "few"
+	Many  Category = "many"
+	Other Category = "other"
+)
+
+// All lists every plural category in CLDR order, the order Categories returns
+// them in.
+var All = []Category{Zero, One, Two, Few, Many, Other}
+
+// forms maps the forms of x/text to categories.
+var forms = map[plural.Form]Category{
+	plural.Zero:  Zero,
+	plural.One:   One,
+	plural.Two:   Two,
+	plural.Few:   Few,
+	plural.Many:  Many,
+	plural.Other: Other,
+}
+
+// ParseCategory parses the name of a plural category.
+func ParseCategory(s string) (Category, error) {
+	for _, c := range All {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown plural category %q, one of %q expected", s, All)
+}
+
+// rules holds the categories found per language tag.
+type rules struct {
+	all      []Category
+	integers []Category
+}
+
+var (
+	cacheMutex sync.Mutex
+	cache      = map[language.Tag]*rules{}
+)
+
+// probe returns the categories of the cardinal rules of tag. The rules are
+// evaluated for enough integers and decimals to reach every category, the
+// rules only look at the last few digits of a number.
+func probe(tag language.Tag) *rules {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	if r, ok := cache[tag]; ok {
+		return r
+	}
+
+	integers, all := map[Category]bool{}, map[Category]bool{}
+	for i := 0; i <= 1000; i++ {
+		c := match(tag, i, 0, 0)
+		integers[c], all[c] = true, true
+	}
+	for i := 0; i <= 20; i++ {
+		for v, n := 1, 10; v <= 2; v, n = v+1, n*10 {
+			for f := 0; f < n; f++ {
+				all[match(tag, i, v, f)] = true
+			}
+		}
+	}
+
+	r := &rules{}
+	for _, c := range All {
+		if all[c] {
+			r.all = append(r.all, c)
+		}
+		if integers[c] {
+			r.integers = append(r.integers, c)
+		}
+	}
+	cache[tag] = r
+	return r
+}
+
+// match returns the category of the number with integer digits i and the v
+// fraction digits f.
+func match(tag language.Tag, i, v, f int) Category {
+	// w and t are the fraction digits without trailing zeros.
+	w, t := v, f
+	for ; w > 0 && t%10 == 0; w-- {
+		t /= 10
+	}
+	return forms[plural.Cardinal.MatchPlural(tag, i, v, w, f, t)]
+}
+
+// Categories returns the plural categories the locale l needs for numbers
+// with and without decimals, in CLDR order. It always includes Other.
+func Categories(l locale.Locale) []Category {
+	return probe(l.Tag()).all
+}
+
+// IntegerCategories returns the plural categories the locale l needs for
+// integers, in CLDR order. These are the msgstr[n] of a PO file, Russian
+// needs one, few and many but uses other only for decimals.
+func IntegerCategories(l locale.Locale) []Category {
+	return probe(l.Tag()).integers
+}
+
+// Select returns the plural category of the integer n in the locale l.
+func Select(l locale.Locale, n int) Category {
+	if n < 0 {
+		n = -n
+	}
+	return match(l.Tag(), n%10000000, 0, 0)
+}
+
+// SelectOrdinal returns the ordinal plural category of the integer n in the
+// locale l, like few for the 3rd in English.
+func SelectOrdinal(l locale.Locale, n int) Category {
+	if n < 0 {
+		n = -n
+	}
+	return forms[plural.Ordinal.MatchPlural(l.Tag(), n%10000000, 0, 0, 0, 0)]
+}
+
+// Form is the text of a message for a single plural category. A Fuzzy form
+// still needs to be checked by a translator.
+type Form struct {
+	Text  string
+	Fuzzy bool
+}
+
+// Forms holds the plural variants of a message by category.
+type Forms map[Category]Form
+
+// Check compares the categories of forms to those the locale l needs. It
+// returns the needed categories that are missing and the categories that l
+// doesn't use, both in CLDR order.
+func Check(l locale.Locale, forms Forms) (missing, extra []Category) {
+	return check(Categories(l), forms)
+}
+
+// check compares the categories of forms to the needed categories.
+func check(needed []Category, forms Forms) (missing, extra []Category) {
+	for _, c := range All {
+		_, present := forms[c]
+		switch needs := contains(needed, c); {
+		case needs && !present:
+			missing = append(missing, c)
+		case !needs && present:
+			extra = append(extra, c)
+		}
+	}
+	return
+}
+
+// Validate returns an error when forms doesn't provide exactly the
+// categories the locale l needs.
+func Validate(l locale.Locale, forms Forms) error {
+	missing, extra := Check(l, forms)
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("Missing plural categories %q for %s, %q expected", missing, l, Categories(l))
+	case len(extra) > 0:
+		return fmt.Errorf("Plural categories %q are not used by %s, %q expected", extra, l, Categories(l))
+	}
+	return nil
+}
+
+// Other returns the Other form of forms, or the last form in CLDR order when
+// there is no Other form, like many for the msgstr[n] of a Russian PO file.
+func (forms Forms) Other() (f Form) {
+	if f, ok := forms[Other]; ok {
+		return f
+	}
+	for _, c := range All {
+		if form, ok := forms[c]; ok {
+			f = form
+		}
+	}
+	return
+}
+
+// Scaffold completes the forms of a message in the locale l, like a Russian
+// translation that only has one and other. The categories it is missing get
+// the text of forms.Other and are marked Fuzzy, so a translator fills them in
+// instead of them silently falling back to other. Categories l doesn't use
+// are dropped.
+func Scaffold(l locale.Locale, forms Forms) Forms {
+	scaffold := Forms{}
+	for _, c := range Categories(l) {
+		f, ok := forms[c]
+		if !ok {
+			f = Form{Text: forms.Other().Text, Fuzzy: true}
+		}
+		scaffold[c] = f
+	}
+	return scaffold
+}
+
+// FromIndexed returns the forms of the msgstr[n] texts of a PO file, which
+// hold the IntegerCategories of the locale l in order. It returns an error
+// when the number of texts doesn't match.
+func FromIndexed(l locale.Locale, texts []string) (Forms, error) {
+	categories := IntegerCategories(l)
+	if len(texts) != len(categories) {
+		return nil, fmt.Errorf("Found %d plural forms for %s, %d expected (%q)", len(texts), l, len(categories), categories)
+	}
+	forms := Forms{}
+	for i, c := range categories {
+		forms[c] = Form{Text: texts[i]}
+	}
+	return forms, nil
+}
+
+// Indexed returns the texts of the IntegerCategories of the locale l in
+// order, as the msgstr[n] of a PO file. Missing categories have the text of
+// the Other form.
+func Indexed(l locale.Locale, forms Forms) (texts []string) {
+	for _, c := range IntegerCategories(l) {
+		f, ok := forms[c]
+		if !ok {
+			f = forms[Other]
+		}
+		texts = append(texts, f.Text)
+	}
+	return
+}
+
+func contains(list []Category, c Category) bool {
+	for _, l := range list {
+		if l == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package plural
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/po"
+)
+
+func TestCategories(t *testing.T) {
+	for _, test := range []struct {
+		locale        string
+		all, integers []Category
+	}{
+		{"en", []Category{One, Other}, []Category{One, Other}},
+		{"ja", []Category{Other}, []Category{Other}},
+		{"ru", []Category{One, Few, Many, Other}, []Category{One, Few, Many}},
+		{"cs", []Category{One, Few, Many, Other}, []Category{One, Few, Other}},
+		{"ar", All, All},
+	} {
+		l := locale.MustParse(test.locale)
+		if got := Categories(l); !reflect.DeepEqual(got, test.all) {
+			t.Errorf("%s: expected categories %q got %q", test.locale, test.all, got)
+		}
+		if got := IntegerCategories(l); !reflect.DeepEqual(got, test.integers) {
+			t.Errorf("%s: expected integer categories %q got %q", test.locale, test.integers, got)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	ru := locale.MustParse("ru")
+	for n, expect := range map[int]Category{0: Many, 1: One, 2: Few, 5: Many, 11: Many, 21: One, 22: Few, -3: Few} {
+		if c := Select(ru, n); c != expect {
+			t.Errorf("Expected %s for %d got %s", expect, n, c)
+		}
+	}
+	if c := Select(locale.MustParse("ar"), 0); c != Zero {
+		t.Errorf("Expected zero for 0 in ar got %s", c)
+	}
+	en := locale.MustParse("en")
+	for n, expect := range map[int]Category{1: One, 2: Two, 3: Few, 4: Other, 11: Other, 23: Few} {
+		if c := SelectOrdinal(en, n); c != expect {
+			t.Errorf("Expected ordinal %s for %d got %s", expect, n, c)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ru := locale.MustParse("ru")
+	forms := Forms{One: {Text: "%d файл"}, Other: {Text: "%d файла"}, Two: {Text: "%d файла"}}
+	missing, extra := Check(ru, forms)
+	if !reflect.DeepEqual(missing, []Category{Few, Many}) || !reflect.DeepEqual(extra, []Category{Two}) {
+		t.Errorf("Expected few and many missing, two extra got %q %q", missing, extra)
+	}
+	if err := Validate(ru, forms); err == nil {
+		t.Errorf("Expected an error for the missing categories")
+	}
+
+	scaffold := Scaffold(ru, forms)
+	if err := Validate(ru, scaffold); err != nil {
+		t.Error(err)
+	}
+	if f := scaffold[Few]; !f.Fuzzy || f.Text != "%d файла" {
+		t.Errorf("Expected a fuzzy few form with the text of other got %+v", f)
+	}
+	if f := scaffold[One]; f.Fuzzy {
+		t.Errorf("Expected the one form to be kept got %+v", f)
+	}
+	scaffold = Scaffold(ru, Forms{One: {Text: "%d файл"}, Many: {Text: "%d файлов"}})
+	if f := scaffold[Few]; !f.Fuzzy || f.Text != "%d файлов" {
+		t.Errorf("Expected a fuzzy few form with the text of many got %+v", f)
+	}
+
+	if _, err := ParseCategory("several"); err == nil {
+		t.Errorf("Expected an error for an unknown category")
+	}
+}
+
+func TestIndexed(t *testing.T) {
+	ru := locale.MustParse("ru")
+	forms, err := FromIndexed(ru, []string{"файл", "файла", "файлов"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forms[Many].Text != "файлов" {
+		t.Errorf("Expected msgstr[2] to be many got %+v", forms)
+	}
+	if texts := Indexed(ru, forms); !reflect.DeepEqual(texts, []string{"файл", "файла", "файлов"}) {
+		t.Errorf("Expected the msgstr texts back got %q", texts)
+	}
+	if _, err = FromIndexed(ru, []string{"файл", "файлы"}); err == nil {
+		t.Errorf("Expected an error for 2 plural forms in ru")
+	}
+}
+
+const stringsdictData = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>%d files</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>zero</key>
+			<string>Нет файлов</string>
+			<key>one</key>
+			<string>%d файл</string>
+			<key>other</key>
+			<string>%d файла</string>
+		</dict>
+	</dict>
+	<key>Tap</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@device@</string>
+		<key>device</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringDeviceSpecificRuleType</string>
+			<key>iphone</key>
+			<string>Нажмите</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+
+const androidData = `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="app_name">Заметки</string>
+    <plurals name="files">
+        <item quantity="one">%d файл</item>
+        <item quantity="few">%d файла</item>
+        <item quantity="many">%d <b>файлов</b></item>
+        <item quantity="other">%d файла</item>
+    </plurals>
+    <plurals name="internal" translatable="false">
+        <item quantity="other">%d</item>
+    </plurals>
+</resources>
+`
+
+func TestLoadCatalogs(t *testing.T) {
+	ru := locale.MustParse("ru")
+
+	msgs, err := LoadStringsDict([]byte(stringsdictData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "%d files:files" || msgs[0].Forms[Zero].Text != "Нет файлов" {
+		t.Fatalf("Expected the files variable got %+v", msgs)
+	}
+	// zero is the text for 0 in .stringsdict files, it is not an extra category.
+	if missing, extra := msgs[0].Check(ru); !reflect.DeepEqual(missing, []Category{Few, Many}) || len(extra) != 0 {
+		t.Errorf("Expected few and many to be missing got %q %q", missing, extra)
+	}
+	if missing, extra := msgs[0].Check(locale.MustParse("lv")); len(missing) != 0 || len(extra) != 0 {
+		t.Errorf("Expected the zero category of lv to be present got %q %q", missing, extra)
+	}
+
+	msgs, err = LoadAndroid(strings.NewReader(androidData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "files" || msgs[0].Forms[Many].Text != "%d <b>файлов</b>" {
+		t.Fatalf("Expected the files plurals got %+v", msgs)
+	}
+	if missing, extra := msgs[0].Check(ru); len(missing) != 0 || len(extra) != 0 {
+		t.Errorf("Expected all categories of ru got %q %q", missing, extra)
+	}
+	if _, extra := msgs[0].Check(locale.MustParse("en")); !reflect.DeepEqual(extra, []Category{Few, Many}) {
+		t.Errorf("Expected few and many to be extra in en got %q", extra)
+	}
+
+	if _, err = LoadAndroid(strings.NewReader(`<resources><plurals name="x"><item quantity="several">x</item></plurals></resources>`)); err == nil {
+		t.Errorf("Expected an error for an unknown quantity")
+	}
+}
+
+func TestPO(t *testing.T) {
+	ru := locale.MustParse("ru")
+	entries := []po.Entry{
+		{ID: "Open", Str: "Открыть"},
+		{ID: "%d file", Plural: "%d files", Strs: []string{"%d файл", "", "%d файлов"}},
+		{ID: "%d note", Plural: "%d notes", Strs: []string{"", "", ""}},
+		{ID: "%d tag", Plural: "%d tags", Strs: []string{"%d тег", "%d тега", "%d тегов"}, Flags: []string{"c-format"}},
+	}
+
+	msgs, err := FromPO(ru, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || msgs[0].Source != "%d files" || !msgs[0].Integers {
+		t.Fatalf("Expected the plural entries got %+v", msgs)
+	}
+	if missing, _ := msgs[0].Check(ru); !reflect.DeepEqual(missing, []Category{Few}) {
+		t.Errorf("Expected few to be missing got %q", missing)
+	}
+	if missing, extra := msgs[2].Check(ru); len(missing) != 0 || len(extra) != 0 {
+		t.Errorf("Expected the integer categories of ru got %q %q", missing, extra)
+	}
+
+	n, err := ScaffoldPO(ru, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 entry to be completed got %d", n)
+	}
+	if e := entries[1]; !reflect.DeepEqual(e.Strs, []string{"%d файл", "%d файлов", "%d файлов"}) || !e.Fuzzy() {
+		t.Errorf("Expected a fuzzy entry with few scaffolded got %+v", e)
+	}
+	if e := entries[2]; e.Fuzzy() || e.Strs[0] != "" {
+		t.Errorf("Expected the untranslated entry to be left alone got %+v", e)
+	}
+	if e := entries[3]; e.Fuzzy() {
+		t.Errorf("Expected the complete entry to be left alone got %+v", e)
+	}
+
+	if _, err = FromPO(ru, []po.Entry{{ID: "%d file", Plural: "%d files", Strs: []string{"%d файл", "%d файлы"}}}); err == nil {
+		t.Errorf("Expected an error for 2 msgstr[n] in ru")
+	}
+}
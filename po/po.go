@@ -0,0 +1,224 @@
+// Package po reads and writes gettext PO files. It keeps the comments and
+// flags of the entries, so a file can be read, changed and written back.
+package po
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Entry is a single entry of a PO file. The header of the file is the entry
+// with an empty ID.
+type Entry struct {
+	// Comments are the comment lines of the entry without their newline,
+	// like "#. extracted comment" or "#: file.go:12", except for the flags.
+	// Obsolete entries, starting with "#~", are kept as comments too.
+	Comments []string
+	// Flags are the flags of the "#," comment, like fuzzy or c-format.
+	Flags  []string
+	Ctxt   string
+	ID     string
+	Plural string
+	// Str is the translation of an entry without Plural, Strs holds the
+	// msgstr[n] translations of an entry with Plural.
+	Str  string
+	Strs []string
+}
+
+// Fuzzy reports whether e has the fuzzy flag, its translation still needs to
+// be checked by a translator.
+func (e *Entry) Fuzzy() bool {
+	for _, f := range e.Flags {
+		if f == "fuzzy" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFuzzy adds the fuzzy flag to e, when it doesn't have it yet.
+func (e *Entry) SetFuzzy() {
+	if !e.Fuzzy() {
+		e.Flags = append([]string{"fuzzy"}, e.Flags...)
+	}
+}
+
+// Header returns the value of the header field key, like Language or
+// Plural-Forms, of the header entry of entries.
+func Header(entries []Entry, key string) string {
+	for _, e := range entries {
+		if len(e.ID) > 0 || len(e.Ctxt) > 0 {
+			continue
+		}
+		for _, line := range strings.Split(e.Str, "\n") {
+			if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == key {
+				return strings.TrimSpace(v)
+			}
+		}
+	}
+	return ""
+}
+
+// Load reads the entries of the PO file in r. Comments at the end of the
+// file, like obsolete entries, come back as an entry without ID and Str.
+func Load(r io.Reader) (entries []Entry, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var e Entry
+	var str *string // the string continued by a line starting with "
+	keyword, translated := false, false
+	n := 0
+	flush := func() {
+		if keyword || len(e.Comments) > 0 || len(e.Flags) > 0 {
+			entries = append(entries, e)
+		}
+		e, str, keyword, translated = Entry{}, nil, false, false
+	}
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		switch {
+		case len(line) == 0:
+			flush()
+			continue
+		case strings.HasPrefix(line, "#,"):
+			if keyword {
+				flush()
+			}
+			for _, f := range strings.Split(line[2:], ",") {
+				if f = strings.TrimSpace(f); len(f) > 0 {
+					e.Flags = append(e.Flags, f)
+				}
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			if keyword {
+				flush()
+			}
+			e.Comments = append(e.Comments, line)
+			continue
+		case strings.HasPrefix(line, `"`):
+			if str == nil {
+				return nil, fmt.Errorf("Line %d: string without keyword", n)
+			}
+			var s string
+			if s, err = unquote(line); err != nil {
+				return nil, fmt.Errorf("Line %d: %v", n, err)
+			}
+			*str += s
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, " ")
+		if (name == "msgctxt" || name == "msgid") && translated {
+			// A new entry without blank line in between
+			flush()
+		}
+		switch {
+		case name == "msgctxt":
+			str = &e.Ctxt
+		case name == "msgid":
+			str = &e.ID
+		case name == "msgid_plural":
+			str = &e.Plural
+		case name == "msgstr":
+			str, translated = &e.Str, true
+		case strings.HasPrefix(name, "msgstr[") && strings.HasSuffix(name, "]"):
+			i, err := strconv.Atoi(name[len("msgstr[") : len(name)-1])
+			if err != nil || i != len(e.Strs) {
+				return nil, fmt.Errorf("Line %d: unexpected %s", n, name)
+			}
+			e.Strs = append(e.Strs, "")
+			str, translated = &e.Strs[i], true
+		default:
+			return nil, fmt.Errorf("Line %d: unknown keyword %q", n, name)
+		}
+		keyword = true
+		if *str, err = unquote(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("Line %d: %v", n, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return
+}
+
+// unquote returns the text of the quoted PO string s.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string, found %q", s)
+	}
+	t, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return t, nil
+}
+
+// Save writes entries to w as PO file, separated by blank lines. Texts
+// spanning several lines are written as one string per line.
+func Save(w io.Writer, entries []Entry) error {
+	b := bufio.NewWriter(w)
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, c := range e.Comments {
+			fmt.Fprintf(b, "%s\n", c)
+		}
+		if len(e.Flags) > 0 {
+			fmt.Fprintf(b, "#, %s\n", strings.Join(e.Flags, ", "))
+		}
+		if len(e.ID) == 0 && len(e.Ctxt) == 0 && len(e.Str) == 0 && len(e.Strs) == 0 {
+			continue
+		}
+		if len(e.Ctxt) > 0 {
+			writeString(b, "msgctxt", e.Ctxt)
+		}
+		writeString(b, "msgid", e.ID)
+		if len(e.Plural) > 0 {
+			writeString(b, "msgid_plural", e.Plural)
+			for n, s := range e.Strs {
+				writeString(b, fmt.Sprintf("msgstr[%d]", n), s)
+			}
+		} else {
+			writeString(b, "msgstr", e.Str)
+		}
+	}
+	return b.Flush()
+}
+
+// writeString writes the keyword followed by the text s. A text with
+// newlines before its end is written as an empty string followed by one
+// string per line, the way gettext does.
+func writeString(b *bufio.Writer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(b, "%s %s\n", keyword, Quote(s))
+		return
+	}
+	fmt.Fprintf(b, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintf(b, "%s\n", Quote(line))
+	}
+}
+
+// escaper escapes the characters PO strings can't contain.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// Quote returns s as quoted PO string.
+func Quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
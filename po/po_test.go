@@ -0,0 +1,83 @@
+package po
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const poData = `# Russian translation
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+#. Shown in the toolbar
+#: main.go:12
+#, c-format
+msgid "Open %s"
+msgstr "Открыть %s"
+
+msgctxt "menu"
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] ""
+msgstr[2] "%d файлов"
+
+#, fuzzy
+msgid "Say \"hi\"\tnow"
+msgstr "Скажи \"привет\"\tсейчас"
+
+#~ msgid "Gone"
+#~ msgstr "Ушёл"
+`
+
+func TestLoad(t *testing.T) {
+	entries, err := Load(strings.NewReader(poData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Entry{
+		{Comments: []string{"# Russian translation"}, Str: "Language: ru\nPlural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"},
+		{Comments: []string{"#. Shown in the toolbar", "#: main.go:12"}, Flags: []string{"c-format"}, ID: "Open %s", Str: "Открыть %s"},
+		{Ctxt: "menu", ID: "%d file", Plural: "%d files", Strs: []string{"%d файл", "", "%d файлов"}},
+		{Flags: []string{"fuzzy"}, ID: "Say \"hi\"\tnow", Str: "Скажи \"привет\"\tсейчас"},
+		{Comments: []string{`#~ msgid "Gone"`, `#~ msgstr "Ушёл"`}},
+	}
+	if !reflect.DeepEqual(entries, expect) {
+		t.Errorf("Expected %+v got %+v", expect, entries)
+	}
+	if lang := Header(entries, "Language"); lang != "ru" {
+		t.Errorf("Expected Language ru got %q", lang)
+	}
+	if !entries[3].Fuzzy() || entries[1].Fuzzy() {
+		t.Errorf("Expected only the fuzzy entry to be fuzzy")
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Save(buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, expect) {
+		t.Errorf("Expected the entries back got %+v", again)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for i, tv := range []string{
+		"msgid \"a\"\nmsgstr[1] \"b\"\n",
+		"msgid \"a\"\nmsgstr b\n",
+		"\"a\"\n",
+		"msgfoo \"a\"\n",
+	} {
+		if _, err := Load(strings.NewReader(tv)); err == nil {
+			t.Errorf("Expected tv[%d] to fail", i)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/atomicfile"
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// Count is an Operation that counts the entries of a table in locale without
+// writing anything. A table that doesn't exist in the locale yet has all its
+// entries missing, unless its fallback locales have them.
+func Count(ctx context.Context, p *Project, locale, table string) (res TableResult, err error) {
+	chain, err := p.chain(locale, table)
+	if err != nil {
+		return
+	}
+
+	srcFile, err := os.Open(p.Path(p.Base, table))
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	res.Stats, err = translate.StatsFile(ctx, srcFile, chain)
+	if err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", p.Path(p.Base, table), err)
+	}
+	return
+}
+
+// Translate is an Operation that translates a table of the base locale using
+// the existing table in locale as translation memory, completed by the same
+// table in its fallback locales, and writes the result back to the table in
+// locale. Entries without a translation are written as fuzzy, just like
+// TranslateMessages does.
+func Translate(ctx context.Context, p *Project, locale, table string) (res TableResult, err error) {
+	chain, err := p.chain(locale, table)
+	if err != nil {
+		return
+	}
+
+	srcFile, err := os.Open(p.Path(p.Base, table))
+	if err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Load, translate and count the messages asynchronously
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+	msgChan = translate.TranslateMessages(ctx, msgChan, chain)
+	msgChan = translate.CountMessages(ctx, msgChan, &res.Stats, chain.Translations())
+
+	// Save the messages into memory synchronously, only replace the table
+	// once everything has been loaded successfully.
+	buf := &bytes.Buffer{}
+	n, err := dotstrings.SaveMessages(msgChan, buf)
+
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", p.Path(p.Base, table), e)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(p.Dir(locale), 0755); err != nil {
+		return
+	}
+	if err = p.writeUTF16(p.Path(locale, table), buf); err != nil {
+		return
+	}
+	res.Written = n
+	return
+}
+
+// Export returns an Operation that writes the (non)fuzzy entries of a table in
+// locale to the same table in <dir>/<locale>.lproj, the entries are selected
+// the same way FilterMessages does. Tables without entries to export are not
+// written.
+func Export(dir string, fuzzy bool, missing bool) Operation {
+	return func(ctx context.Context, p *Project, locale, table string) (res TableResult, err error) {
+		translations, err := p.translations(locale, table)
+		if err != nil {
+			return
+		}
+
+		srcFile, err := os.Open(p.Path(p.Base, table))
+		if err != nil {
+			return
+		}
+		defer srcFile.Close()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+		msgChan = translate.TranslateMessages(ctx, msgChan, translate.NewChain(translations))
+		msgChan = translate.CountMessages(ctx, msgChan, &res.Stats, translations)
+		msgChan = translate.FilterMessages(ctx, fuzzy, missing, msgChan)
+
+		buf := &bytes.Buffer{}
+		n, err := dotstrings.SaveMessages(msgChan, buf)
+
+		if e := stage.Wait(ctx, cancel, errChan); e != nil {
+			err = fmt.Errorf("Failed to load %q (%v)", p.Path(p.Base, table), e)
+			return
+		}
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			return
+		}
+
+		tgtDir := filepath.Join(dir, locale+lprojExt)
+		if err = os.MkdirAll(tgtDir, 0755); err != nil {
+			return
+		}
+		if err = p.writeUTF16(filepath.Join(tgtDir, table), buf); err != nil {
+			return
+		}
+		res.Written = n
+		return
+	}
+}
+
+// Import returns an Operation that merges the translations found in the same
+// table in <dir>/<locale>.lproj into the table in locale, the way
+// MergeMessagesFile does. Tables that have no counterpart in dir are left
+// alone. The result counts the entries of the table after the import.
+func Import(dir string) Operation {
+	return func(ctx context.Context, p *Project, locale, table string) (res TableResult, err error) {
+		newName := filepath.Join(dir, locale+lprojExt, table)
+		if _, err = os.Stat(newName); err != nil {
+			if os.IsNotExist(err) {
+				return Count(ctx, p, locale, table)
+			}
+			return
+		}
+
+		buf := &bytes.Buffer{}
+		n, err := translate.MergeMessagesFile(ctx, p.Path(locale, table), newName, buf)
+		if err != nil {
+			return
+		}
+
+		if err = os.MkdirAll(p.Dir(locale), 0755); err != nil {
+			return
+		}
+		if err = p.writeUTF16(p.Path(locale, table), buf); err != nil {
+			return
+		}
+
+		res, err = Count(ctx, p, locale, table)
+		res.Written = n
+		return
+	}
+}
+
+// translations loads the table in locale as translation memory, including its
+// fuzzy entries. A table that doesn't exist in the locale yet is empty.
+func (p *Project) translations(locale, table string) (translations map[string]dotstrings.Message, err error) {
+	translations, err = dotstrings.LoadTargetMessagesMapFromFile(p.Path(locale, table))
+	if os.IsNotExist(err) {
+		translations, err = map[string]dotstrings.Message{}, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to load %q (%v)", p.Path(locale, table), err)
+	}
+	return
+}
+
+// chain loads the table in locale followed by the same table in the
+// fallback locales of locale as translation chain. Fallback locales without
+// a directory in the project are skipped, just like the base locale.
+func (p *Project) chain(locale, table string) (chain translate.Chain, err error) {
+	for _, l := range p.Fallbacks.Chain(locale) {
+		if len(chain) > 0 && (l == p.Base || !contains(p.Locales, l)) {
+			continue
+		}
+		var translations map[string]dotstrings.Message
+		if translations, err = p.translations(l, table); err != nil {
+			return
+		}
+		chain = append(chain, translate.Memory{Locale: l, Translations: translations})
+	}
+	return
+}
+
+// writeUTF16 atomically replaces the file name with the UTF-8 contents of r
+// encoded as UTF-16.
+func (p *Project) writeUTF16(name string, r io.Reader) (err error) {
+	file, err := atomicfile.Create(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Backup = p.Backup
+	if _, err = io.Copy(dotstrings.NewWriterUTF16(file), r); err != nil {
+		return
+	}
+	return file.Commit()
+}
@@ -0,0 +1,125 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+// Project describes an Apple resource tree. Every locale has a directory
+// named after it with the .lproj extension (e.g. fr.lproj) that contains the
+// .strings tables for that locale. The tables in the Base locale directory
+// hold the source strings, the other locales hold the translations.
+type Project struct {
+	Root    string
+	Base    string
+	Locales []string
+	Tables  []string
+	// Backup keeps the previous version of every table replaced as a .bak
+	// file.
+	Backup bool
+	// Fallbacks are the fallback chains used by Count and Translate,
+	// entries a locale doesn't translate are taken from its fallbacks.
+	Fallbacks locale.Fallbacks
+}
+
+const lprojExt = ".lproj"
+
+// candidateBases lists the locales tried, in order, when no base locale is
+// specified. Base.lproj only counts when it holds .strings tables, in many
+// projects it contains just storyboards.
+var candidateBases = []string{"en", "en-US", "Base"}
+
+// Discover scans root for .lproj directories. When base is empty the base
+// locale is chosen from the candidates en, en-US and Base, the first one with
+// .strings tables wins. All other locales found become target locales.
+func Discover(root, base string) (p *Project, err error) {
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	var locales []string
+	for _, d := range dirs {
+		if d.IsDir() && strings.EqualFold(filepath.Ext(d.Name()), lprojExt) {
+			locales = append(locales, strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())))
+		}
+	}
+	if len(locales) == 0 {
+		return nil, fmt.Errorf("No %s directories found in %q", lprojExt, root)
+	}
+
+	p = &Project{Root: root}
+	if len(base) == 0 {
+		for _, candidate := range candidateBases {
+			if !contains(locales, candidate) {
+				continue
+			}
+			if tables, _ := p.tables(candidate); len(tables) > 0 {
+				base = candidate
+				break
+			}
+		}
+		if len(base) == 0 {
+			return nil, fmt.Errorf("No base locale with .strings tables found in %q, one of %q expected", root, candidateBases)
+		}
+	} else if !contains(locales, base) {
+		return nil, fmt.Errorf("Base locale %q not found in %q", base, root)
+	}
+	p.Base = base
+
+	for _, l := range locales {
+		if l != base && l != "Base" {
+			p.Locales = append(p.Locales, l)
+		}
+	}
+	sort.Strings(p.Locales)
+
+	p.Tables, err = p.tables(base)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Tables) == 0 {
+		return nil, fmt.Errorf("No .strings tables found in base locale %q", p.Dir(base))
+	}
+	return
+}
+
+// Dir returns the path of the .lproj directory of locale.
+func (p *Project) Dir(locale string) string {
+	return filepath.Join(p.Root, locale+lprojExt)
+}
+
+// Path returns the path of the .strings table in the directory of locale.
+func (p *Project) Path(locale, table string) string {
+	return filepath.Join(p.Dir(locale), table)
+}
+
+// tables returns the sorted names of the .strings files in the directory of
+// locale.
+func (p *Project) tables(locale string) (tables []string, err error) {
+	files, err := os.ReadDir(p.Dir(locale))
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if !f.IsDir() && strings.EqualFold(filepath.Ext(f.Name()), ".strings") {
+			tables = append(tables, f.Name())
+		}
+	}
+	sort.Strings(tables)
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/internal/testutil"
+	"github.com/simpleapps-eu/translate/locale"
+)
+
+func newTree(t *testing.T) string {
+	root := t.TempDir()
+	testutil.WriteStrings(t, filepath.Join(root, "en.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Open\";\n\n/* Save */\n\"save\" = \"Save\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "en.lproj", "InfoPlist.strings"), "/* Name */\n\"CFBundleName\" = \"Notes\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "fr.lproj", "Localizable.strings"), "/* Open */\n\"open\" = \"Ouvrir\";\n\n/* Gone */\n\"gone\" = \"Parti\";\n")
+	testutil.WriteStrings(t, filepath.Join(root, "fr.lproj", "Custom.strings"), "/* X */\n\"x\" = \"y\";\n")
+	if err := os.MkdirAll(filepath.Join(root, "de.lproj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "Base.lproj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestDiscover(t *testing.T) {
+	p, err := Discover(newTree(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Base != "en" {
+		t.Errorf("Expected base locale en got %q", p.Base)
+	}
+	if len(p.Locales) != 2 || p.Locales[0] != "de" || p.Locales[1] != "fr" {
+		t.Errorf("Expected locales [de fr] got %v", p.Locales)
+	}
+	if len(p.Tables) != 2 || p.Tables[0] != "InfoPlist.strings" || p.Tables[1] != "Localizable.strings" {
+		t.Errorf("Expected tables [InfoPlist.strings Localizable.strings] got %v", p.Tables)
+	}
+}
+
+func TestRunTranslate(t *testing.T) {
+	p, err := Discover(newTree(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summaries := p.Run(context.Background(), Translate, 2)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries got %d", len(summaries))
+	}
+	for _, s := range summaries {
+		if s.Err != nil {
+			t.Fatalf("Unexpected error for %s: %v", s.Locale, s.Err)
+		}
+		if s.Total != 3 || s.Written != 3 {
+			t.Errorf("Expected 3 entries written for %s got %+v", s.Locale, s)
+		}
+	}
+	fr := summaries[1]
+	if fr.Translated != 1 || fr.Missing != 2 || fr.Obsolete != 1 {
+		t.Errorf("Unexpected stats for fr %+v", fr.Stats)
+	}
+	if len(fr.Extra) != 1 || fr.Extra[0] != "Custom.strings" {
+		t.Errorf("Expected Custom.strings to be reported as extra table got %v", fr.Extra)
+	}
+
+	translations, err := dotstrings.LoadTargetMessagesMapFromFile(p.Path("de", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := translations["save"]; !m.Fuzzy || m.Str != "Save" {
+		t.Errorf("Expected a fuzzy untranslated entry in de got %+v", m)
+	}
+
+	// Counting afterwards finds the written tables.
+	summaries = p.Run(context.Background(), Count, 0)
+	if fr := summaries[1]; fr.Total != 3 || fr.Translated != 1 || fr.Missing != 2 || fr.Fuzzy != 0 || fr.Written != 0 {
+		t.Errorf("Unexpected count for fr %+v", fr)
+	}
+}
+
+func TestCountFallback(t *testing.T) {
+	root := newTree(t)
+	testutil.WriteStrings(t, filepath.Join(root, "fr-CA.lproj", "Localizable.strings"), "/* Save */\n\"save\" = \"Sauvegarder\";\n")
+	p, err := Discover(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fr-CA falls back to fr for the entries it doesn't translate.
+	summaries := p.Run(context.Background(), Count, 0)
+	if ca := summaries[2]; ca.Locale != "fr-CA" || ca.Translated != 2 || ca.Fallback != 1 || ca.Obsolete != 0 {
+		t.Errorf("Unexpected count for fr-CA %+v", ca)
+	}
+
+	p.Fallbacks = locale.Fallbacks{"fr-CA": {"de"}}
+	summaries = p.Run(context.Background(), Count, 0)
+	if ca := summaries[2]; ca.Translated != 1 || ca.Fallback != 0 {
+		t.Errorf("Unexpected count for fr-CA falling back to de %+v", ca)
+	}
+}
+
+func TestTranslateFallback(t *testing.T) {
+	root := newTree(t)
+	testutil.WriteStrings(t, filepath.Join(root, "fr-CA.lproj", "Localizable.strings"), "/* Save */\n\"save\" = \"Sauvegarder\";\n")
+	p, err := Discover(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Run(context.Background(), Translate, 0)
+
+	ca, err := dotstrings.LoadTargetMessagesMapFromFile(p.Path("fr-CA", "Localizable.strings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := ca["open"]; m.Str != "Ouvrir" || m.Locale != "fr" || m.Fuzzy {
+		t.Errorf("Expected open to fall back to fr got %+v", m)
+	}
+	if m := ca["save"]; m.Str != "Sauvegarder" || len(m.Locale) != 0 {
+		t.Errorf("Expected save to keep its own translation got %+v", m)
+	}
+}
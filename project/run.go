@@ -0,0 +1,105 @@
+package project
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/simpleapps-eu/translate"
+)
+
+// TableResult is the outcome of an Operation on a single table of a locale.
+type TableResult struct {
+	Table string
+	translate.Stats
+	// Written is the number of entries written to a file, if any.
+	Written int
+}
+
+// Summary is the outcome of an Operation on all tables of a locale.
+type Summary struct {
+	Locale string
+	translate.Stats
+	Written int
+	Tables  []TableResult
+	// Extra lists the tables found in the locale without a matching table in
+	// the base locale.
+	Extra []string
+	// Err is the first error encountered, the remaining tables of the locale
+	// are skipped after an error.
+	Err error
+}
+
+// Operation is performed by Run on every table of every target locale.
+type Operation func(ctx context.Context, p *Project, locale, table string) (TableResult, error)
+
+// Run performs op on all tables of all target locales and returns a summary
+// per locale in the order of p.Locales. Locales are processed concurrently by
+// at most workers goroutines, or by runtime.NumCPU goroutines when workers is
+// zero or less. The tables of a single locale are processed in order. Once ctx
+// is done the remaining tables are skipped and ctx.Err() is the Err of their
+// locales.
+func (p *Project) Run(ctx context.Context, op Operation, workers int) []Summary {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	summaries := make([]Summary, len(p.Locales))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				summaries[i] = p.runLocale(ctx, op, p.Locales[i])
+			}
+		}()
+	}
+
+	for i := range p.Locales {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return summaries
+}
+
+func (p *Project) runLocale(ctx context.Context, op Operation, locale string) (s Summary) {
+	s.Locale = locale
+	s.Extra, s.Err = p.extraTables(locale)
+	if s.Err != nil {
+		return
+	}
+	for _, table := range p.Tables {
+		if s.Err = ctx.Err(); s.Err != nil {
+			return
+		}
+		res, err := op(ctx, p, locale, table)
+		res.Table = table
+		s.Tables = append(s.Tables, res)
+		s.Stats.Add(res.Stats)
+		s.Written += res.Written
+		if err != nil {
+			s.Err = err
+			return
+		}
+	}
+	return
+}
+
+// extraTables returns the tables in the directory of locale that don't exist
+// in the base locale. A locale directory without tables has no extra tables.
+func (p *Project) extraTables(locale string) (extra []string, err error) {
+	tables, err := p.tables(locale)
+	if err != nil {
+		return
+	}
+	for _, t := range tables {
+		if !contains(p.Tables, t) {
+			extra = append(extra, t)
+		}
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(n, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(total)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Translation progress</title>
+<style>
+body { font-family: -apple-system, "Helvetica Neue", Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child { text-align: left; }
+.bar { display: flex; width: 12em; height: 0.8em; background: #eee; }
+.translated { background: #3a3; }
+.fuzzy { background: #ea3; }
+.complete td:first-child { font-weight: bold; }
+details td { color: #666; }
+</style>
+</head>
+<body>
+<h1>Translation progress</h1>
+<p>Base locale <b>{{.Base}}</b>, generated {{.Generated.Format "2006-01-02 15:04 MST"}}.</p>
+<table>
+<tr><th>Locale</th><th></th><th>Done</th><th>Total</th><th>Translated</th><th>Fuzzy</th><th>Missing</th><th>Obsolete</th><th>Fallback</th><th>Words</th><th>Words to do</th></tr>
+{{range .Locales}}
+<tr{{if eq .Translated .Total}} class="complete"{{end}}>
+<td>{{.Locale}}</td>
+<td><div class="bar"><div class="translated" style="width: {{percent .Translated .Total}}%"></div><div class="fuzzy" style="width: {{percent .Fuzzy .Total}}%"></div></div></td>
+<td>{{printf "%.1f" .Percent}}%</td>
+<td>{{.Total}}</td><td>{{.Translated}}</td><td>{{.Fuzzy}}</td><td>{{.Missing}}</td><td>{{.Obsolete}}</td><td>{{.Fallback}}</td>
+<td>{{.Words}}</td><td>{{.RemainingWords}}</td>
+</tr>
+{{range .Tables}}
+<tr class="table"><td>&nbsp;&nbsp;{{.Table}}</td><td></td><td></td>
+<td>{{.Total}}</td><td>{{.Translated}}</td><td>{{.Fuzzy}}</td><td>{{.Missing}}</td><td>{{.Obsolete}}</td><td>{{.Fallback}}</td>
+<td>{{.Words}}</td><td>{{.RemainingWords}}</td>
+</tr>
+{{end}}
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a static HTML page to w.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return page.Execute(w, r)
+}
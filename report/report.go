@@ -0,0 +1,69 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/simpleapps-eu/translate"
+	"github.com/simpleapps-eu/translate/project"
+)
+
+// Report contains the translation progress of every locale of a project.
+type Report struct {
+	Root      string    `json:"root"`
+	Base      string    `json:"base"`
+	Generated time.Time `json:"generated"`
+	Locales   []Locale  `json:"locales"`
+}
+
+// Locale contains the translation progress of a single locale, both in total
+// and per table.
+type Locale struct {
+	Locale string `json:"locale"`
+	translate.Stats
+	Percent float64 `json:"percent"`
+	Tables  []Table `json:"tables"`
+}
+
+// Table contains the translation progress of a single table of a locale.
+type Table struct {
+	Table string `json:"table"`
+	translate.Stats
+}
+
+// Build computes the report for all locales of project p.
+func Build(ctx context.Context, p *project.Project) (r *Report, err error) {
+	r = &Report{Root: p.Root, Base: p.Base, Generated: time.Now().UTC()}
+	for _, s := range p.Run(ctx, project.Count, 0) {
+		if s.Err != nil {
+			return nil, s.Err
+		}
+		l := Locale{Locale: s.Locale, Stats: s.Stats, Percent: s.Stats.Percent()}
+		for _, t := range s.Tables {
+			l.Tables = append(l.Tables, Table{Table: t.Table, Stats: t.Stats})
+		}
+		r.Locales = append(r.Locales, l)
+	}
+	return
+}
+
+// WriteText writes the report as a table for the terminal to w.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Locale\tDone\tTotal\tTranslated\tFuzzy\tMissing\tObsolete\tFallback\tWords\tWords to do\t\n")
+	for _, l := range r.Locales {
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", l.Locale, l.Percent, l.Total, l.Translated, l.Fuzzy, l.Missing, l.Obsolete, l.Fallback, l.Words, l.RemainingWords())
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as a JSON document to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -1,7 +1,7 @@
 package translate
 
 import (
-	"fmt"
+	"bufio"
 	"io"
 )
 
@@ -10,20 +10,23 @@ import (
 // The function will return when all lines have been written.
 // The goroutine feeding lineChan should close the channel once it has finished.
 // The closing of the channel indicates to SaveLines that it can finish
-// too. The function then returns the number of lines it has written.
+// too. The function then returns the number of lines it has written, and the
+// first write error.
 // Note that no BOM is being written to the tgtFile to indicate the encoding of
 // the text stream (which is most likely utf-8).
-func SaveLines(lineChan <-chan string, tgtFile io.Writer) (n int) {
+func SaveLines(lineChan <-chan string, tgtFile io.Writer) (n int, err error) {
+	w := bufio.NewWriter(tgtFile)
 	for line := range lineChan {
 		if n > 0 {
-			fmt.Fprintln(tgtFile)
+			w.WriteByte('\n')
 		}
-		fmt.Fprint(tgtFile, line)
+		w.WriteString(line)
 		n++
 	}
 	// Unless the whole text was just a single line, always end with a newline.
 	if n > 1 {
-		fmt.Fprintln(tgtFile)
+		w.WriteByte('\n')
 	}
+	err = w.Flush()
 	return
 }
@@ -0,0 +1,325 @@
+package stage
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Buffer is the capacity of the channels returned by the stages.
+const Buffer = 3
+
+// Send sends v on dst unless ctx is done first. It returns false when ctx is
+// done, the sending stage should then stop.
+func Send[T any](ctx context.Context, dst chan<- T, v T) bool {
+	select {
+	case dst <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Load asynchronously sends the values of seq on the returned channel. The
+// first error of seq, or ctx.Err() when ctx is done first, is sent on the
+// error channel before the value channel is closed.
+func Load[T any](ctx context.Context, seq iter.Seq2[T, error]) (<-chan T, <-chan error) {
+	dst := make(chan T, Buffer)
+	errChan := make(chan error, 1)
+
+	loader := func() {
+		defer close(dst)
+		defer close(errChan)
+		for v, err := range seq {
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if !Send(ctx, dst, v) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}
+
+	go loader()
+	return dst, errChan
+}
+
+// Map asynchronously passes on f(v) for every value v received from src.
+func Map[T, U any](ctx context.Context, src <-chan T, f func(T) U) <-chan U {
+	dst := make(chan U, Buffer)
+
+	mapper := func() {
+		defer close(dst)
+		for v := range src {
+			if !Send(ctx, dst, f(v)) {
+				return
+			}
+		}
+	}
+
+	go mapper()
+	return dst
+}
+
+// MapErr is Map for an f that can fail. The first error returned by f stops
+// the stage, it is sent on the returned error channel.
+func MapErr[T, U any](ctx context.Context, src <-chan T, f func(T) (U, error)) (<-chan U, <-chan error) {
+	dst := make(chan U, Buffer)
+	errChan := make(chan error, 1)
+
+	mapper := func() {
+		defer close(dst)
+		defer close(errChan)
+		for v := range src {
+			u, err := f(v)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if !Send(ctx, dst, u) {
+				return
+			}
+		}
+	}
+
+	go mapper()
+	return dst, errChan
+}
+
+// Filter asynchronously passes on the values received from src for which
+// keep returns true.
+func Filter[T any](ctx context.Context, src <-chan T, keep func(T) bool) <-chan T {
+	return FilterMap(ctx, src, func(v T) (T, bool) {
+		return v, keep(v)
+	})
+}
+
+// FilterMap asynchronously passes on f(v) for every value v received from
+// src, unless f returns false.
+func FilterMap[T, U any](ctx context.Context, src <-chan T, f func(T) (U, bool)) <-chan U {
+	dst := make(chan U, Buffer)
+
+	filter := func() {
+		defer close(dst)
+		for v := range src {
+			u, ok := f(v)
+			if !ok {
+				continue
+			}
+			if !Send(ctx, dst, u) {
+				return
+			}
+		}
+	}
+
+	go filter()
+	return dst
+}
+
+// FlatMap asynchronously passes on all values returned by f(v) for every
+// value v received from src.
+func FlatMap[T, U any](ctx context.Context, src <-chan T, f func(T) []U) <-chan U {
+	dst := make(chan U, Buffer)
+
+	mapper := func() {
+		defer close(dst)
+		for v := range src {
+			for _, u := range f(v) {
+				if !Send(ctx, dst, u) {
+					return
+				}
+			}
+		}
+	}
+
+	go mapper()
+	return dst
+}
+
+// ParallelMap is Map calling f from up to workers goroutines at the same
+// time. The values are passed on in the order they were received from src.
+func ParallelMap[T, U any](ctx context.Context, src <-chan T, workers int, f func(T) U) <-chan U {
+	if workers < 1 {
+		workers = 1
+	}
+	dst := make(chan U, Buffer)
+
+	// Every value gets a future the result is delivered on, the futures are
+	// queued in the order of src so the collector can restore that order.
+	futures := make(chan chan U, workers)
+	sem := make(chan struct{}, workers)
+
+	dispatcher := func() {
+		defer close(futures)
+		for v := range src {
+			if !Send(ctx, sem, struct{}{}) {
+				return
+			}
+			future := make(chan U, 1)
+			if !Send(ctx, futures, future) {
+				<-sem
+				return
+			}
+			go func(v T) {
+				future <- f(v)
+				<-sem
+			}(v)
+		}
+	}
+
+	collector := func() {
+		defer close(dst)
+		for future := range futures {
+			select {
+			case u := <-future:
+				if !Send(ctx, dst, u) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go dispatcher()
+	go collector()
+	return dst
+}
+
+// Tee asynchronously passes on every value received from src on both
+// returned channels. Both channels have to be consumed.
+func Tee[T any](ctx context.Context, src <-chan T) (<-chan T, <-chan T) {
+	dst1 := make(chan T, Buffer)
+	dst2 := make(chan T, Buffer)
+
+	tee := func() {
+		defer close(dst1)
+		defer close(dst2)
+		for v := range src {
+			// A nil channel blocks forever, so set each to nil once sent.
+			c1, c2 := dst1, dst2
+			for c1 != nil || c2 != nil {
+				select {
+				case c1 <- v:
+					c1 = nil
+				case c2 <- v:
+					c2 = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+
+	go tee()
+	return dst1, dst2
+}
+
+// Merge asynchronously passes on the values received from all srcs, in no
+// particular order. The returned channel is closed when all srcs are closed.
+func Merge[T any](ctx context.Context, srcs ...<-chan T) <-chan T {
+	dst := make(chan T, Buffer)
+
+	var wg sync.WaitGroup
+	forwarder := func(src <-chan T) {
+		defer wg.Done()
+		for v := range src {
+			if !Send(ctx, dst, v) {
+				return
+			}
+		}
+	}
+
+	wg.Add(len(srcs))
+	for _, src := range srcs {
+		go forwarder(src)
+	}
+	go func() {
+		wg.Wait()
+		close(dst)
+	}()
+	return dst
+}
+
+// Batch asynchronously groups the values received from src in slices of
+// size values. The last slice holds the remaining values and may be shorter.
+func Batch[T any](ctx context.Context, src <-chan T, size int) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+	dst := make(chan []T, Buffer)
+
+	batcher := func() {
+		defer close(dst)
+		var batch []T
+		for v := range src {
+			batch = append(batch, v)
+			if len(batch) < size {
+				continue
+			}
+			if !Send(ctx, dst, batch) {
+				return
+			}
+			batch = nil
+		}
+		if len(batch) > 0 {
+			Send(ctx, dst, batch)
+		}
+	}
+
+	go batcher()
+	return dst
+}
+
+// JoinErrors returns an error channel that receives the first error sent on
+// any of errChans as soon as it is sent, later errors are dropped. It is
+// closed once all errChans are closed.
+func JoinErrors(errChans ...<-chan error) <-chan error {
+	errChan := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	joiner := func(c <-chan error) {
+		defer wg.Done()
+		for err := range c {
+			if err == nil {
+				continue
+			}
+			select {
+			case errChan <- err:
+			default:
+			}
+		}
+	}
+
+	wg.Add(len(errChans))
+	for _, c := range errChans {
+		go joiner(c)
+	}
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+	return errChan
+}
+
+// Wait waits for the stages of a pipeline to finish and returns the first
+// error they reported. The error channels are given in the order of the
+// stages, they are read starting at the last stage. As soon as a stage reports
+// an error cancel is called, so the stages before it, which are no longer
+// consumed, stop too. When no stage failed Wait returns ctx.Err(), so a
+// pipeline that stopped because ctx is done is never mistaken for a complete
+// one.
+func Wait(ctx context.Context, cancel context.CancelFunc, errChans ...<-chan error) (err error) {
+	for i := len(errChans) - 1; i >= 0; i-- {
+		if e, _ := <-errChans[i]; e != nil && err == nil {
+			err = e
+			cancel()
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return
+}
@@ -0,0 +1,156 @@
+package stage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func source(values ...int) <-chan int {
+	c := make(chan int, len(values))
+	for _, v := range values {
+		c <- v
+	}
+	close(c)
+	return c
+}
+
+func collect[T any](c <-chan T) (values []T) {
+	for v := range c {
+		values = append(values, v)
+	}
+	return
+}
+
+func errChan(err error) <-chan error {
+	c := make(chan error, 1)
+	if err != nil {
+		c <- err
+	}
+	close(c)
+	return c
+}
+
+func TestStages(t *testing.T) {
+	ctx := context.Background()
+	double := func(v int) int { return 2 * v }
+	odd := func(v int) bool { return v%2 == 1 }
+
+	if got, expect := collect(Map(ctx, source(1, 2, 3), double)), []int{2, 4, 6}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Map: expected %v got %v", expect, got)
+	}
+	if got, expect := collect(Filter(ctx, source(1, 2, 3), odd)), []int{1, 3}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Filter: expected %v got %v", expect, got)
+	}
+	repeat := func(v int) []int { return make([]int, v) }
+	if got, expect := collect(FlatMap(ctx, source(1, 0, 2), repeat)), []int{0, 0, 0}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("FlatMap: expected %v got %v", expect, got)
+	}
+	if got, expect := collect(Batch(ctx, source(1, 2, 3, 4, 5), 2)), [][]int{{1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Batch: expected %v got %v", expect, got)
+	}
+
+	got := collect(Merge(ctx, source(1, 2), source(3), source()))
+	sort.Ints(got)
+	if expect := []int{1, 2, 3}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Merge: expected %v got %v", expect, got)
+	}
+
+	c1, c2 := Tee(ctx, source(1, 2, 3))
+	done := make(chan []int)
+	go func() { done <- collect(c2) }()
+	if got, expect := collect(c1), []int{1, 2, 3}; !reflect.DeepEqual(got, expect) || !reflect.DeepEqual(<-done, expect) {
+		t.Errorf("Tee: expected %v on both channels", expect)
+	}
+}
+
+func TestMapErr(t *testing.T) {
+	values, errs := MapErr(context.Background(), source(1, 2, 3), func(v int) (string, error) {
+		if v == 3 {
+			return "", errors.New("three")
+		}
+		return strconv.Itoa(v), nil
+	})
+	if got, expect := collect(values), []string{"1", "2"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v got %v", expect, got)
+	}
+	if err := <-errs; err == nil || err.Error() != "three" {
+		t.Errorf("Expected error three got %v", err)
+	}
+}
+
+func TestParallelMap(t *testing.T) {
+	values := make([]int, 100)
+	for i := range values {
+		values[i] = i
+	}
+	// Later values finish first, the order has to be restored.
+	slow := func(v int) int {
+		time.Sleep(time.Duration(100-v) * time.Microsecond)
+		return v
+	}
+	if got := collect(ParallelMap(context.Background(), source(values...), 8, slow)); !reflect.DeepEqual(got, values) {
+		t.Errorf("Expected %v got %v", values, got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan int)
+	go func() {
+		defer close(src)
+		for i := 0; ; i++ {
+			if !Send(ctx, src, i) {
+				return
+			}
+		}
+	}()
+	c := ParallelMap(ctx, Map(ctx, src, func(v int) int { return v }), 4, func(v int) int { return v })
+	<-c
+	cancel()
+
+	// The stages close their output once they noticed ctx is done.
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-c:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected the stages to stop after cancel")
+		}
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	failed := errors.New("failed")
+	if err := <-JoinErrors(errChan(nil), errChan(failed), errChan(nil)); err != failed {
+		t.Errorf("Expected %v got %v", failed, err)
+	}
+	if err, ok := <-JoinErrors(errChan(nil), errChan(nil)); ok {
+		t.Errorf("Expected no error got %v", err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	failed := errors.New("failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := Wait(ctx, cancel, errChan(nil), errChan(nil)); err != nil {
+		t.Errorf("Expected no error got %v", err)
+	}
+	if err := Wait(ctx, cancel, errChan(nil), errChan(failed)); err != failed {
+		t.Errorf("Expected %v got %v", failed, err)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("Expected the context to be canceled after an error")
+	}
+	if err := Wait(ctx, cancel, errChan(nil)); err != context.Canceled {
+		t.Errorf("Expected %v got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,170 @@
+package translate
+
+import (
+	"context"
+	"io"
+	"unicode"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// Stats contains the number of entries and source words of a translation in
+// each of the states TranslateMessages can put them in. An entry is Translated
+// when it is neither Fuzzy nor Missing. Obsolete entries are translations for
+// IDs that no longer exist in the source, they are not included in Total.
+// Fallback entries are the Translated and Fuzzy entries whose translation
+// was supplied by a fallback locale of the chain.
+type Stats struct {
+	Total      int `json:"total"`
+	Translated int `json:"translated"`
+	Fuzzy      int `json:"fuzzy"`
+	Missing    int `json:"missing"`
+	Obsolete   int `json:"obsolete"`
+	Fallback   int `json:"fallback"`
+
+	Words           int `json:"words"`
+	TranslatedWords int `json:"translatedWords"`
+	FuzzyWords      int `json:"fuzzyWords"`
+	MissingWords    int `json:"missingWords"`
+	FallbackWords   int `json:"fallbackWords"`
+}
+
+// Add adds the counts of o to s.
+func (s *Stats) Add(o Stats) {
+	s.Total += o.Total
+	s.Translated += o.Translated
+	s.Fuzzy += o.Fuzzy
+	s.Missing += o.Missing
+	s.Obsolete += o.Obsolete
+	s.Fallback += o.Fallback
+	s.Words += o.Words
+	s.TranslatedWords += o.TranslatedWords
+	s.FuzzyWords += o.FuzzyWords
+	s.MissingWords += o.MissingWords
+	s.FallbackWords += o.FallbackWords
+}
+
+// Percent returns the percentage of Translated entries, or 100 when there are
+// no entries at all.
+func (s Stats) Percent() float64 {
+	if s.Total == 0 {
+		return 100
+	}
+	return 100 * float64(s.Translated) / float64(s.Total)
+}
+
+// RemainingWords returns the number of source words in entries that still
+// need work from a translator, i.e. Fuzzy and Missing entries.
+func (s Stats) RemainingWords() int {
+	return s.FuzzyWords + s.MissingWords
+}
+
+// StatsFile translates the source .strings file using the chain the same way
+// TranslateMessagesFile does, but instead of saving the result counts the
+// entries and source words in every state. Only the translations of the
+// locale itself count as obsolete.
+func StatsFile(ctx context.Context, srcFile io.Reader, chain Chain) (stats Stats, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Load the messages to be translated asynchronously.
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
+
+	// Start translation asynchronously
+	msgChan = TranslateMessages(ctx, msgChan, chain)
+
+	// Count the translated messages synchronously
+	used := make(map[string]bool)
+	for m := range msgChan {
+		stats.AddMessage(m)
+		used[m.ID] = true
+	}
+	for id := range chain.Translations() {
+		if !used[id] {
+			stats.Obsolete++
+		}
+	}
+
+	err = stage.Wait(ctx, cancel, errChan)
+	return
+}
+
+// AddMessage counts a single message emitted by TranslateMessages.
+func (s *Stats) AddMessage(m dotstrings.Message) {
+	// The Ctx of a translated message always holds the source string.
+	source, err := dotstrings.StringsUnescape(m.Ctx)
+	if err != nil {
+		source = m.Ctx
+	}
+	words := CountWords(source)
+
+	s.Total++
+	s.Words += words
+	switch {
+	case m.Missing:
+		s.Missing++
+		s.MissingWords += words
+	case m.Fuzzy:
+		s.Fuzzy++
+		s.FuzzyWords += words
+	default:
+		s.Translated++
+		s.TranslatedWords += words
+	}
+	if len(m.Locale) > 0 && !m.Missing {
+		s.Fallback++
+		s.FallbackWords += words
+	}
+}
+
+// CountMessages asynchronously adds every message passing through to stats,
+// which must not be read before the returned channel is closed. Once srcChan
+// is closed the translations not used by any message are counted as obsolete.
+func CountMessages(ctx context.Context, srcChan <-chan dotstrings.Message, stats *Stats, translations map[string]dotstrings.Message) <-chan dotstrings.Message {
+	dstChan := make(chan dotstrings.Message, 3)
+
+	counter := func(srcChan <-chan dotstrings.Message, dstChan chan<- dotstrings.Message) {
+		defer close(dstChan)
+		used := make(map[string]bool)
+		for m := range srcChan {
+			stats.AddMessage(m)
+			used[m.ID] = true
+			select {
+			case dstChan <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for id := range translations {
+			if !used[id] {
+				stats.Obsolete++
+			}
+		}
+	}
+
+	go counter(srcChan, dstChan)
+	return dstChan
+}
+
+// CountWords returns the number of words in text. Format specifiers count as
+// words. In scripts that don't separate words by spaces (e.g. Chinese or
+// Japanese) every character is counted as a word.
+func CountWords(text string) (n int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai):
+			n++
+			inWord = false
+		case unicode.IsSpace(r):
+			inWord = false
+		default:
+			if !inWord {
+				n++
+			}
+			inWord = true
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		text   string
+		expect int
+	}{
+		{"", 0},
+		{"Open", 1},
+		{"  Open   the file ", 3},
+		{"%d files in %@", 4},
+		{"ファイルを開く", 7},
+	}
+	for i, tv := range tests {
+		if n := CountWords(tv.text); n != tv.expect {
+			t.Errorf("Expected %d words for test %d got %d", tv.expect, i, n)
+		}
+	}
+}
+
+func TestStatsFile(t *testing.T) {
+	const src = "/* c */\n\"open\" = \"Open file\";\n\n/* c */\n\"save\" = \"Save\";\n\n/* c */\n\"close\" = \"Close\";\n\n"
+	buf := &bytes.Buffer{}
+	w := dotstrings.NewWriterUTF16(buf)
+	w.Write([]byte(src))
+
+	translations := map[string]dotstrings.Message{
+		"open": {Ctx: "Open file", ID: "open", Str: "Ouvrir"},
+		"save": {Ctx: "Save as", ID: "save", Str: "Enregistrer"},
+		"gone": {Ctx: "Gone", ID: "gone", Str: "Parti"},
+	}
+	stats, err := StatsFile(context.Background(), buf, NewChain(translations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Stats{Total: 3, Translated: 1, Fuzzy: 1, Missing: 1, Obsolete: 1, Words: 4, TranslatedWords: 2, FuzzyWords: 1, MissingWords: 1}
+	if stats != expect {
+		t.Errorf("Expected %+v got %+v", expect, stats)
+	}
+}
+
+func TestStatsTranslatedFile(t *testing.T) {
+	const src = "/* c */\n\"open\" = \"Open file\";\n\n/* c */\n\"save\" = \"Save\";\n\n/* c */\n\"close\" = \"Close\";\n\n"
+	srcBuf := &bytes.Buffer{}
+	dotstrings.NewWriterUTF16(srcBuf).Write([]byte(src))
+	srcData := srcBuf.Bytes()
+
+	// Write the target the way translate does, with fuzzy copies of the
+	// source for the missing translations, and count it when read back.
+	translations := map[string]dotstrings.Message{
+		"open": {Ctx: "Open file", ID: "open", Str: "Ouvrir"},
+	}
+	tgtBuf := &bytes.Buffer{}
+	if _, err := TranslateMessagesFile(context.Background(), bytes.NewReader(srcData), NewChain(translations), tgtBuf); err != nil {
+		t.Fatal(err)
+	}
+	translations, err := dotstrings.LoadTargetMessagesMap(dotstrings.NewReaderUTF16(tgtBuf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := StatsFile(context.Background(), bytes.NewReader(srcData), NewChain(translations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Stats{Total: 3, Translated: 1, Missing: 2, Words: 4, TranslatedWords: 2, MissingWords: 2}
+	if stats != expect {
+		t.Errorf("Expected %+v got %+v", expect, stats)
+	}
+}
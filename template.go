@@ -0,0 +1,53 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/extract"
+)
+
+// TranslateTemplateFile translates the Go template in srcFile by replacing
+// the literal ID of every T and TN call by its translation in chain, wherever
+// the call is in the template, and writes the result to tgtFile. Everything
+// else is copied unchanged, so the arguments of a call still format its
+// translation when the template is rendered. IDs without a translation are
+// left as they are. It returns the number of IDs translated.
+func TranslateTemplateFile(ctx context.Context, srcFile io.Reader, chain Chain, tgtFile io.Writer) (n int, err error) {
+	data, err := io.ReadAll(srcFile)
+	if err != nil {
+		return
+	}
+	text := string(data)
+	calls, err := extract.TemplateCalls("template", text)
+	if err != nil {
+		return
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, call := range calls {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		tran, _, present := chain.Lookup(dotstrings.StringsEscape(call.ID))
+		if !present || len(tran.Str) == 0 {
+			continue
+		}
+		str, err := dotstrings.StringsUnescape(tran.Str)
+		if err != nil {
+			return n, fmt.Errorf("Failed to unescape the translation of %q (%v)", call.ID, err)
+		}
+		b.WriteString(text[pos:call.Pos])
+		b.WriteString(strconv.Quote(str))
+		pos = call.End
+		n++
+	}
+	b.WriteString(text[pos:])
+	_, err = io.WriteString(tgtFile, b.String())
+	return
+}
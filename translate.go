@@ -1,147 +1,150 @@
 package translate
 
 import (
+	"context"
+	"fmt"
 	"io"
 
 	"github.com/simpleapps-eu/translate/dotstrings"
 	"github.com/simpleapps-eu/translate/plist"
+	"github.com/simpleapps-eu/translate/stage"
 )
 
-func TranslatePlistFile(srcFile io.Reader, translations map[string]dotstrings.Message, tgtFile io.Writer) (n int, err error) {
+// TranslatePlistFile translates the string values of the property list
+// srcFile, in XML, binary or OpenStep format, and writes it to tgtFile in the
+// same format. A string is translated by its key path, see plist.Strings, so
+// the strings of the top level dict by their key like in an InfoPlist.strings
+// file. The other values are written as they are. It returns the number of
+// strings.
+func TranslatePlistFile(ctx context.Context, srcFile io.Reader, chain Chain, tgtFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	data, err := io.ReadAll(srcFile)
+	if err != nil {
+		return
+	}
+	v, format, err := plist.Decode(data)
+	if err != nil {
+		return
+	}
 
-	// Start loading entries asynchronously
-	entryChan, errChan := plist.LoadEntries(srcFile)
+	// Start sending the strings as escaped entries asynchronously
+	entryChan, errChan := stage.Load(ctx, func(yield func(plist.Entry, error) bool) {
+		for _, entry := range plist.Strings(v) {
+			entry.ID, entry.Str = dotstrings.StringsEscape(entry.ID), dotstrings.StringsEscape(entry.Str)
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	})
 
 	// Start translation entries asynchronously
-	entryChan = TranslatePlistEntries(entryChan, translations)
-
-	// Save the translated entries synchronously
-	n = plist.SaveEntries(entryChan, tgtFile)
+	entryChan = TranslatePlistEntries(ctx, entryChan, chain)
+
+	// Collect the translated strings synchronously
+	strs := make(map[string]plist.String)
+	for entry := range entryChan {
+		str, e := dotstrings.StringsUnescape(entry.Str)
+		if e != nil {
+			err = fmt.Errorf("Failed to unescape the translation of %q (%v)", entry.ID, e)
+			cancel()
+			continue
+		}
+		strs[entry.ID] = plist.String(str)
+		n++
+	}
+	if e := stage.Wait(ctx, cancel, errChan); err == nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
 
-	err, _ = <-errChan
+	v = plist.Map(v, func(path string, s plist.String) plist.String {
+		if str, ok := strs[dotstrings.StringsEscape(path)]; ok {
+			return str
+		}
+		return s
+	})
+	err = plist.Encode(tgtFile, v, format)
 	return
 }
 
-func TranslatePlistEntries(entryChan <-chan plist.Entry, translations map[string]dotstrings.Message) <-chan plist.Entry {
-	dstChan := make(chan plist.Entry, 3)
-
-	translator := func(srcChan <-chan plist.Entry, dstChan chan<- plist.Entry, translations map[string]dotstrings.Message) {
-		defer close(dstChan)
-		for entry := range srcChan {
-			tran, present := translations[entry.ID]
-			if present {
-				// Don't output translations to empty string
-				if len(tran.Str) > 0 {
-					dstChan <- plist.Entry{ID: entry.ID, Str: tran.Str}
-				}
-				continue
+// TranslatePlistEntries translates the entries using the first translation
+// found in the chain, the Locale of an entry records the fallback that
+// supplied it.
+func TranslatePlistEntries(ctx context.Context, entryChan <-chan plist.Entry, chain Chain) <-chan plist.Entry {
+	return stage.FilterMap(ctx, entryChan, func(entry plist.Entry) (plist.Entry, bool) {
+		if tran, locale, present := chain.Lookup(entry.ID); present {
+			// Don't output translations to empty string
+			if len(tran.Str) == 0 {
+				return entry, false
 			}
-			dstChan <- entry
+			entry = plist.Entry{ID: entry.ID, Str: tran.Str, Locale: locale}
 		}
-	}
-
-	go translator(entryChan, dstChan, translations)
-	return dstChan
+		return entry, true
+	})
 }
 
-func TranslateIDsFile(srcFile io.Reader, translations map[string]dotstrings.Message, translationsFallback map[string]dotstrings.Message, tgtFile io.Writer) (n int, err error) {
+func TranslateIDsFile(ctx context.Context, srcFile io.Reader, chain Chain, tgtFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Load IDs to be translated concurrently
-	lineChan, errChan1 := LoadLines(srcFile)
+	lineChan, errChan1 := LoadLines(ctx, srcFile)
 
 	// Start translation concurrently
-	lineChan = TranslateIDs(lineChan, translations, translationsFallback)
+	lineChan = TranslateIDs(ctx, lineChan, chain)
 
 	// Start unescaping the translated lines concurrently
-	lineChan, errChan2 := unescapeLines(lineChan)
+	lineChan, errChan2 := unescapeLines(ctx, lineChan)
 
 	// Synchronously save the lines to the target file
-	n = SaveLines(lineChan, tgtFile)
+	n, err = SaveLines(lineChan, tgtFile)
 
-	err, _ = <-errChan1
-	if err != nil {
-		return
+	if e := stage.Wait(ctx, cancel, errChan1, errChan2); e != nil {
+		err = e
 	}
-
-	err, _ = <-errChan2
 	return
 }
 
-func unescapeLines(srcChan <-chan string) (<-chan string, <-chan error) {
-	dstChan := make(chan string, 3)
-	errChan := make(chan error, 1)
-
-	unescaper := func(srcChan <-chan string, dstChan chan<- string, errChan chan<- error) {
-		defer close(dstChan)
-		defer close(errChan)
-
-		for str := range srcChan {
-			if len(str) == 0 {
-				dstChan <- ""
-			} else {
-				s, err := dotstrings.StringsUnescape(str)
-				if err != nil {
-					s = str
-					errChan <- err
-					return
-				}
-				dstChan <- s
-			}
-		}
-	}
-
-	go unescaper(srcChan, dstChan, errChan)
-	return dstChan, errChan
+func unescapeLines(ctx context.Context, srcChan <-chan string) (<-chan string, <-chan error) {
+	return stage.MapErr(ctx, srcChan, dotstrings.StringsUnescape)
 }
 
 // TranslateIDs will translate a channel of strings where the strings are
-// treated as IDs in a translation map. The translationsFallback map normally
-// contains the original source language and is used to fill in the gaps
-// where translations haven't been provided yet using strings from the source
-// language. The idea being that it is better to show a string instead of an id.
-func TranslateIDs(srcChan <-chan string, translations map[string]dotstrings.Message, translationsFallback map[string]dotstrings.Message) <-chan string {
-	dstChan := make(chan string, 3)
-
-	translator := func(srcChan <-chan string, dstChan chan<- string, translations map[string]dotstrings.Message, translationsFallback map[string]dotstrings.Message) {
-		defer close(dstChan)
-		for id := range srcChan {
-			tran, present := translations[id]
-			if present {
-				// Don't output translations to empty string
-				if len(tran.Str) > 0 {
-					dstChan <- tran.Str
-				}
-				continue
-			}
-			tran, present = translationsFallback[id]
-			if present {
-				// Don't output translations to empty string
-				if len(tran.Str) > 0 {
-					dstChan <- tran.Str
-				}
-				continue
-			}
-			dstChan <- id
+// treated as IDs in the translation memories of chain. The fallbacks of the
+// chain, normally ending with the original source language, fill in the gaps
+// where translations haven't been provided yet. The idea being that it is
+// better to show a string instead of an id.
+func TranslateIDs(ctx context.Context, srcChan <-chan string, chain Chain) <-chan string {
+	return stage.FilterMap(ctx, srcChan, func(id string) (string, bool) {
+		tran, _, present := chain.Lookup(id)
+		if !present {
+			return id, true
 		}
-	}
-
-	go translator(srcChan, dstChan, translations, translationsFallback)
-	return dstChan
+		// Don't output translations to empty string
+		return tran.Str, len(tran.Str) > 0
+	})
 }
 
-func TranslateTextFile(srcFile io.Reader, translations map[string]dotstrings.Message, tgtFile io.Writer) (n int, err error) {
+func TranslateTextFile(ctx context.Context, srcFile io.Reader, translations map[string]dotstrings.Message, tgtFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Load text lines to be translated concurrently
-	lineChan, errChan := LoadLines(srcFile)
+	lineChan, errChan := LoadLines(ctx, srcFile)
 
 	// Start translation concurrently
-	lineChan = TranslateText(lineChan, translations)
+	lineChan = TranslateText(ctx, lineChan, translations)
 
 	// Synchronously save the lines to the target file
-	n = SaveLines(lineChan, tgtFile)
-
-	err, _ = <-errChan
+	n, err = SaveLines(lineChan, tgtFile)
 
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
 	return
 }
 
@@ -152,51 +155,38 @@ func TranslateTextFile(srcFile io.Reader, translations map[string]dotstrings.Mes
 // and shadow the other entries.
 // FIXME: TranslateText does not handle the difference in escaping between lines
 //  of text read from the srcChan and the translations map.
-func TranslateText(srcChan <-chan string, translations map[string]dotstrings.Message) <-chan string {
-	dstChan := make(chan string, 3)
-
-	translator := func(srcChan <-chan string, dstChan chan<- string, translations map[string]dotstrings.Message) {
-		defer close(dstChan)
-
-		tm := make(map[string]string)
-		for _, tran := range translations {
-			tm[tran.Ctx] = tran.Str
-		}
-
-		for ctx := range srcChan {
-			if len(ctx) == 0 {
-				// empty line
-				dstChan <- ""
-			} else {
-				str, present := tm[ctx]
-				if !present {
-					dstChan <- ctx
-				} else {
-					// Don't output translations to empty string
-					if len(str) > 0 {
-						dstChan <- str
-					}
-				}
-			}
-		}
+func TranslateText(ctx context.Context, srcChan <-chan string, translations map[string]dotstrings.Message) <-chan string {
+	tm := make(map[string]string)
+	for _, tran := range translations {
+		tm[tran.Ctx] = tran.Str
 	}
 
-	go translator(srcChan, dstChan, translations)
-	return dstChan
+	return stage.FilterMap(ctx, srcChan, func(line string) (string, bool) {
+		// Empty lines stay empty
+		if str, present := tm[line]; present && len(line) > 0 {
+			// Don't output translations to empty string
+			return str, len(str) > 0
+		}
+		return line, true
+	})
 }
 
-func TranslateMessagesFile(srcFile io.Reader, translations map[string]dotstrings.Message, tgtFile io.Writer) (n int, err error) {
+func TranslateMessagesFile(ctx context.Context, srcFile io.Reader, chain Chain, tgtFile io.Writer) (n int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Load the messages to be translated asynchronously.
-	msgChan, errChan := dotstrings.LoadMessages(dotstrings.NewReaderUTF16(srcFile))
+	msgChan, errChan := dotstrings.LoadMessages(ctx, dotstrings.NewReaderUTF16(srcFile))
 
 	// Start translation asynchronously
-	msgChan = TranslateMessages(msgChan, translations)
+	msgChan = TranslateMessages(ctx, msgChan, chain)
 
 	// Save the translated messages synchronously
-	n = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(tgtFile))
+	n, err = dotstrings.SaveMessages(msgChan, dotstrings.NewWriterUTF16(tgtFile))
 
-	err, _ = <-errChan
+	if e := stage.Wait(ctx, cancel, errChan); e != nil {
+		err = e
+	}
 	return
 }
 
@@ -209,54 +199,56 @@ func TranslateMessagesFile(srcFile io.Reader, translations map[string]dotstrings
 // has changed between the entry found in the source and the entry on which the translation was based.
 // In this case the translation is still written but marked as fuzzy and the context is also changed to
 // the new contetx from the source entry.
-func TranslateMessages(srcChan <-chan dotstrings.Message, translations map[string]dotstrings.Message) <-chan dotstrings.Message {
-	dstChan := make(chan dotstrings.Message, 3)
-
-	translator := func(srcChan <-chan dotstrings.Message, dstChan chan<- dotstrings.Message, translations map[string]dotstrings.Message) {
-		defer close(dstChan)
-		for src := range srcChan {
-			// type Message struc
-			// /* Ctx */
-			// "ID" = "Str"
-			//
-			// src
-			// /* Show Help */
-			// "help_ad_dialog_help_button" = "Show Help";
-			//
-			// tm
-			// /* Show Help */
-			// "help_ad_dialog_help_button" = "Hilfe zeigen";
-			//
-			if tm, ok := translations[src.ID]; ok {
-				// There is a translation available for src.ID
-				// Are we still talking about the same translation?
-				if tm.Ctx == src.Str {
-					// We compare the localized Context (tm.Ctx) to the source String (src.Str)
-					// The source Context might actually contain a comment on the actual
-					// meaning of the source String. For localized .strings files we copy
-					// the source String into the localized Context so translators
-					// can always have the String available that they need to translate.
-					// The actual localized String value always has the last translation.
-					// So when you have a localized entry marked as fuzzy the Context
-					// of that entry provides the latest source String to translate and
-					// the String of that entry provides the previous translation.
-					dstChan <- tm
-				} else {
-					// No, different, so translation is Fuzzy. But do generate entry
-					// with previous translation as basis. We put the src.Str (string to
-					// be translated) into Ctx and we put tm.Str (previous translation)
-					// into Str.
-					dstChan <- dotstrings.Message{Fuzzy: true, ID: src.ID, Ctx: src.Str, Str: tm.Str}
-				}
+// The translation is taken from the first translation memory in the chain that
+// has one, the Locale of a message taken from a fallback records its locale.
+func TranslateMessages(ctx context.Context, srcChan <-chan dotstrings.Message, chain Chain) <-chan dotstrings.Message {
+	return stage.Map(ctx, srcChan, func(src dotstrings.Message) dotstrings.Message {
+		// type Message struc
+		// /* Ctx */
+		// "ID" = "Str"
+		//
+		// src
+		// /* Show Help */
+		// "help_ad_dialog_help_button" = "Show Help";
+		//
+		// tm
+		// /* Show Help */
+		// "help_ad_dialog_help_button" = "Hilfe zeigen";
+		//
+		var dst dotstrings.Message
+		if tm, locale, ok := chain.Lookup(src.ID); ok {
+			if len(locale) == 0 {
+				// The translation memory of the locale itself may hold a
+				// translation that was taken from a fallback before.
+				locale = tm.Locale
+			}
+			// There is a translation available for src.ID
+			// Are we still talking about the same translation?
+			if tm.Ctx == src.Str {
+				// We compare the localized Context (tm.Ctx) to the source String (src.Str)
+				// The source Context might actually contain a comment on the actual
+				// meaning of the source String. For localized .strings files we copy
+				// the source String into the localized Context so translators
+				// can always have the String available that they need to translate.
+				// The actual localized String value always has the last translation.
+				// So when you have a localized entry marked as fuzzy the Context
+				// of that entry provides the latest source String to translate and
+				// the String of that entry provides the previous translation.
+				dst = tm
+				dst.Locale = locale
 			} else {
-				// There is no translation for src.ID so use src as basis but mark it as Missing.
-				dstChan <- dotstrings.Message{Fuzzy: true, Missing: true, ID: src.ID, Ctx: src.Str, Str: src.Str}
+				// No, different, so translation is Fuzzy. But do generate entry
+				// with previous translation as basis. We put the src.Str (string to
+				// be translated) into Ctx and we put tm.Str (previous translation)
+				// into Str.
+				dst = dotstrings.Message{Fuzzy: true, Locale: locale, ID: src.ID, Ctx: src.Str, Str: tm.Str}
 			}
+		} else {
+			// There is no translation for src.ID so use src as basis but mark it as Missing.
+			dst = dotstrings.Message{Fuzzy: true, Missing: true, ID: src.ID, Ctx: src.Str, Str: src.Str}
 		}
-	}
-
-	go translator(srcChan, dstChan, translations)
-	return dstChan
+		return dst
+	})
 }
 
 // TranslateMessagesXLIFF will asynchronously take a channel of dotstrings messages
@@ -265,25 +257,13 @@ func TranslateMessages(srcChan <-chan dotstrings.Message, translations map[strin
 // This function returns 2 channels, a channel that gets the translated messages and a
 // channel of error values that is used by this function to push an error onto before terminating.
 // The error channel is one way of delivering errors from an asynchronously called function.
-func TranslateMessagesXLIFF(srcChan <-chan dotstrings.Message, translations map[string]string) (<-chan dotstrings.Message, <-chan error) {
-	msgChan := make(chan dotstrings.Message, 3)
-	errChan := make(chan error, 1)
-
-	translator := func(translations map[string]string, srcChan <-chan dotstrings.Message, dstChan chan<- dotstrings.Message, errChan chan<- error) {
-		defer close(dstChan)
-		defer close(errChan)
-
-		for m := range srcChan {
-			m.Str = dotstrings.StringsEscape(translations[m.ID])
-			if m.Str != "" {
-				dstChan <- m
-			} else {
-				// There is no translation for m.ID so use m itself as basis but mark it as Missing.
-				dstChan <- dotstrings.Message{Fuzzy: true, Missing: true, ID: m.ID, Ctx: m.Str, Str: m.Str}
-			}
+func TranslateMessagesXLIFF(ctx context.Context, srcChan <-chan dotstrings.Message, translations map[string]string) (<-chan dotstrings.Message, <-chan error) {
+	return stage.MapErr(ctx, srcChan, func(m dotstrings.Message) (dotstrings.Message, error) {
+		m.Str = dotstrings.StringsEscape(translations[m.ID])
+		if m.Str == "" {
+			// There is no translation for m.ID so use m itself as basis but mark it as Missing.
+			m = dotstrings.Message{Fuzzy: true, Missing: true, ID: m.ID, Ctx: m.Str, Str: m.Str}
 		}
-	}
-
-	go translator(translations, srcChan, msgChan, errChan)
-	return msgChan, errChan
+		return m, nil
+	})
 }
@@ -0,0 +1,357 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/plist"
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xcstrings"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+func TestTranslateMessagesFileCanceled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := dotstrings.NewWriterUTF16(buf)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(w, "/* c */\n\"id%d\" = \"Text %d\";\n\n", i, i)
+	}
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := TranslateMessagesFile(ctx, bytes.NewReader(buf.Bytes()), nil, &bytes.Buffer{})
+	if err != context.Canceled {
+		t.Errorf("Expected %v got %v", context.Canceled, err)
+	}
+
+	// The stages are gone once TranslateMessagesFile returns, give the
+	// runtime a moment to reap them.
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected %d goroutines got %d", before, n)
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{
+		{Locale: "de-AT", Translations: map[string]dotstrings.Message{"open": {Ctx: "Open", ID: "open", Str: "Aufmachen"}}},
+		{Locale: "de", Translations: map[string]dotstrings.Message{
+			"open": {Ctx: "Open", ID: "open", Str: "Öffnen"},
+			"save": {Ctx: "Save", ID: "save", Str: "Sichern"},
+			"quit": {Ctx: "Exit", ID: "quit", Str: "Verlassen"},
+		}},
+		{Locale: "en", Translations: map[string]dotstrings.Message{"help": {Ctx: "Help", ID: "help", Str: "Help"}}},
+	}
+	ctx := context.Background()
+
+	src := make(chan dotstrings.Message, 5)
+	for _, id := range []string{"open", "save", "quit", "help", "new"} {
+		str := strings.ToUpper(id[:1]) + id[1:]
+		src <- dotstrings.Message{Ctx: str, ID: id, Str: str}
+	}
+	close(src)
+	expect := []dotstrings.Message{
+		{ID: "open", Ctx: "Open", Str: "Aufmachen"},
+		{Locale: "de", ID: "save", Ctx: "Save", Str: "Sichern"},
+		{Fuzzy: true, Locale: "de", ID: "quit", Ctx: "Quit", Str: "Verlassen"},
+		{Locale: "en", ID: "help", Ctx: "Help", Str: "Help"},
+		{Fuzzy: true, Missing: true, ID: "new", Ctx: "New", Str: "New"},
+	}
+	var stats Stats
+	var i int
+	buf := &bytes.Buffer{}
+	w := dotstrings.NewWriter(buf)
+	for m := range TranslateMessages(ctx, src, chain) {
+		if m != expect[i] {
+			t.Errorf("Expected %+v got %+v", expect[i], m)
+		}
+		stats.AddMessage(m)
+		w.Write(m)
+		i++
+	}
+
+	// The fallback locale is written and read back, a target file used as
+	// translation memory keeps marking the translations of a fallback.
+	if !strings.Contains(buf.String(), "/* Fallback: de */\n/* Save */\n\"save\" = \"Sichern\";") {
+		t.Errorf("Expected the fallback comment of save got\n%s", buf)
+	}
+	loaded, err := dotstrings.LoadTargetMessagesMap(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range expect {
+		if loaded[m.ID].Locale != m.Locale {
+			t.Errorf("Expected locale %q for %q got %+v", m.Locale, m.ID, loaded[m.ID])
+		}
+	}
+	retranslate := make(chan dotstrings.Message, 1)
+	retranslate <- dotstrings.Message{Ctx: "Save", ID: "save", Str: "Save"}
+	close(retranslate)
+	if m := <-TranslateMessages(ctx, retranslate, NewChain(loaded)); m.Locale != "de" {
+		t.Errorf("Expected the fallback locale to be kept got %+v", m)
+	}
+	if stats.Fallback != 3 || stats.Translated != 3 {
+		t.Errorf("Expected 3 translated entries from fallbacks got %+v", stats)
+	}
+
+	ids := make(chan string, 3)
+	ids <- "open"
+	ids <- "help"
+	ids <- "new"
+	close(ids)
+	var lines []string
+	for line := range TranslateIDs(ctx, ids, chain) {
+		lines = append(lines, line)
+	}
+	if got := strings.Join(lines, ","); got != "Aufmachen,Help,new" {
+		t.Errorf("Expected Aufmachen,Help,new got %s", got)
+	}
+
+	entries := make(chan plist.Entry, 1)
+	entries <- plist.Entry{ID: "save", Str: "Save"}
+	close(entries)
+	if e := <-TranslatePlistEntries(ctx, entries, chain); e.Str != "Sichern" || e.Locale != "de" {
+		t.Errorf("Expected the de entry got %+v", e)
+	}
+}
+
+func TestICUUnits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tf := &xliff.TranslationFile{SourceLanguage: "en", TargetLanguage: "ru"}
+	src := make(chan xliff.TranslationUnit, 2)
+	src <- xliff.TranslationUnit{File: tf, ID: "files", Source: "{n, plural, one {# file} other {# files}} &amp; more", Target: "{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}} &amp; ещё"}
+	src <- xliff.TranslationUnit{File: tf, ID: "open", Source: "Open {name}"}
+	close(src)
+
+	unitChan, errChan1 := FlattenICUUnits(ctx, src)
+	var units []xliff.TranslationUnit
+	for tu := range unitChan {
+		units = append(units, tu)
+	}
+	if err := stage.Wait(ctx, cancel, errChan1); err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 5 {
+		t.Fatalf("Expected 4 variants and a plain unit got %+v", units)
+	}
+	if tu := units[1]; tu.ID != "files#n:plural=few" || tu.Source != "# files &amp; more" || tu.Target != "# файла &amp; ещё" {
+		t.Errorf("Expected the few variant got %+v", tu)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	flat := make(chan xliff.TranslationUnit, len(units))
+	for _, tu := range units {
+		flat <- tu
+	}
+	close(flat)
+	unitChan, errChan2 := RebuildICUUnits(ctx, flat)
+	var rebuilt []xliff.TranslationUnit
+	for tu := range unitChan {
+		rebuilt = append(rebuilt, tu)
+	}
+	if err := stage.Wait(ctx, cancel, errChan2); err != nil {
+		t.Fatal(err)
+	}
+	expect := "{n, plural, one {# файл &amp; ещё} few {# файла &amp; ещё} many {# файлов &amp; ещё} other {# файла &amp; ещё}}"
+	if len(rebuilt) != 2 || rebuilt[0].ID != "files" || rebuilt[0].Target != expect || rebuilt[1].ID != "open" {
+		t.Errorf("Expected the rebuilt unit and the plain unit got %+v", rebuilt)
+	}
+
+	src = make(chan xliff.TranslationUnit, 1)
+	src <- xliff.TranslationUnit{File: tf, ID: "files", Source: "{n, plural, one {# file} other {# files}}", Target: "{count, plural, other {# файла}}"}
+	close(src)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	unitChan, errChan1 = FlattenICUUnits(ctx, src)
+	for range unitChan {
+	}
+	if err := stage.Wait(ctx, cancel, errChan1); err == nil {
+		t.Errorf("Expected an error for the mismatching argument")
+	}
+}
+
+// benchmarkCatalog returns a UTF16 .strings file with n messages and the
+// translations of half of them.
+func benchmarkCatalog(n int) (data []byte, translations map[string]dotstrings.Message) {
+	buf := &bytes.Buffer{}
+	w := dotstrings.NewWriterUTF16(buf)
+	translations = make(map[string]dotstrings.Message)
+	for i := 0; i < n; i++ {
+		id, str := fmt.Sprintf("id%d", i), fmt.Sprintf("Text %d", i)
+		fmt.Fprintf(w, "/* Comment %d */\n\"%s\" = \"%s\";\n\n", i, id, str)
+		if i%2 == 0 {
+			translations[id] = dotstrings.Message{Ctx: str, ID: id, Str: fmt.Sprintf("Texte %d", i)}
+		}
+	}
+	return buf.Bytes(), translations
+}
+
+func BenchmarkTranslateMessagesFile(b *testing.B) {
+	data, translations := benchmarkCatalog(10000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := TranslateMessagesFile(context.Background(), bytes.NewReader(data), NewChain(translations), io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvertSourceFile(b *testing.B) {
+	data, _ := benchmarkCatalog(10000)
+	tf := &xliff.TranslationFile{Original: "Localizable.strings", SourceLanguage: "en", Datatype: "plaintext"}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ConvertSourceFile(context.Background(), bytes.NewReader(data), tf, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTranslatePlistFile(b *testing.B) {
+	buf := &bytes.Buffer{}
+	w := plist.NewWriter(buf)
+	translations := make(map[string]dotstrings.Message)
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("id%d", i)
+		w.Write(plist.Entry{ID: id, Str: fmt.Sprintf("Text %d", i)})
+		if i%2 == 0 {
+			translations[id] = dotstrings.Message{ID: id, Str: fmt.Sprintf("Texte %d", i)}
+		}
+	}
+	w.Close()
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := TranslatePlistFile(context.Background(), bytes.NewReader(data), NewChain(translations), io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTranslateTemplateFile(t *testing.T) {
+	chain := Chain{
+		{Locale: "fr", Translations: map[string]dotstrings.Message{
+			"hello":  {ID: "hello", Str: `Bonjour \"{name}\"`},
+			"unread": {ID: "unread", Str: "{n, plural, one {# non lu} other {# non lus}}"},
+			"empty":  {ID: "empty"},
+		}},
+	}
+	src := "Title\n<h1>{{T \"hello\" \"Greeting\" \"name\" .name}}</h1> {{if .n}}{{TN `unread` .n}}{{else}}{{T \"empty\"}}{{end}} {{T \"new\"}}\n"
+	buf := &bytes.Buffer{}
+	n, err := TranslateTemplateFile(context.Background(), strings.NewReader(src), chain, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "Title\n<h1>{{T \"Bonjour \\\"{name}\\\"\" \"Greeting\" \"name\" .name}}</h1> {{if .n}}{{TN \"{n, plural, one {# non lu} other {# non lus}}\" .n}}{{else}}{{T \"empty\"}}{{end}} {{T \"new\"}}\n"
+	if n != 2 || buf.String() != expect {
+		t.Errorf("Expected 2 IDs translated into\n%s\ngot %d\n%s", expect, n, buf.String())
+	}
+}
+
+func TestTranslatePlistFile(t *testing.T) {
+	chain := Chain{
+		{Locale: "nl", Translations: map[string]dotstrings.Message{
+			"CFBundleDisplayName":      {ID: "CFBundleDisplayName", Str: `Notities \"Pro\"`},
+			"NSCameraUsageDescription": {ID: "NSCameraUsageDescription"},
+			"UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle": {ID: "UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle", Str: "Nieuwe notitie"},
+		}},
+	}
+	src := plist.NewDict()
+	src.Set("CFBundleDisplayName", plist.String("Notes"))
+	src.Set("LSRequiresIPhoneOS", plist.Bool(true))
+	src.Set("NSCameraUsageDescription", plist.String("Scan documents"))
+	item := plist.NewDict()
+	item.Set("UIApplicationShortcutItemTitle", plist.String("New Note"))
+	src.Set("UIApplicationShortcutItems", plist.Array{item})
+
+	for _, format := range []plist.Format{plist.XMLFormat, plist.BinaryFormat} {
+		in := &bytes.Buffer{}
+		if err := plist.Encode(in, src, format); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		n, err := TranslatePlistFile(context.Background(), in, chain, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, f, err := plist.Decode(out.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []plist.Entry{
+			{ID: "CFBundleDisplayName", Str: `Notities "Pro"`},
+			{ID: "NSCameraUsageDescription", Str: "Scan documents"},
+			{ID: "UIApplicationShortcutItems/0/UIApplicationShortcutItemTitle", Str: "Nieuwe notitie"},
+		}
+		if got := plist.Strings(v); n != 2 || f != format || !reflect.DeepEqual(got, expect) {
+			t.Errorf("%v: expected 2 strings %v got %d %v %v", format, expect, n, f, got)
+		}
+		if required, _ := v.(*plist.Dict).Get("LSRequiresIPhoneOS"); required != plist.Bool(true) {
+			t.Errorf("%v: expected LSRequiresIPhoneOS to stay true got %v", format, required)
+		}
+	}
+}
+
+func TestCatalogTranslationUnits(t *testing.T) {
+	c, err := xcstrings.Load(strings.NewReader(`{"sourceLanguage": "en", "version": "1.0", "strings": {
+		"Hello & bye": {"comment": "Greeting", "localizations": {"fr": {"stringUnit": {"state": "translated", "value": "Bonjour & au revoir"}}}},
+		"files": {"localizations": {"en": {"variations": {"plural": {
+			"one": {"stringUnit": {"state": "translated", "value": "%lld file"}},
+			"other": {"stringUnit": {"state": "translated", "value": "%lld files"}}}}}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tf := &xliff.TranslationFile{Original: "Localizable.xcstrings", SourceLanguage: "en", Datatype: "x-xcstrings", TargetLanguage: "fr"}
+	unitChan, errChan := ConvertCatalogToTranslationUnits(ctx, c, tf)
+	var units []xliff.TranslationUnit
+	for tu := range unitChan {
+		units = append(units, tu)
+	}
+	if err := stage.Wait(ctx, cancel, errChan); err != nil {
+		t.Fatal(err)
+	}
+	expect := []xliff.TranslationUnit{
+		{File: tf, ID: "Hello &amp; bye", Source: "Hello &amp; bye", Target: "Bonjour &amp; au revoir", Note: "Greeting"},
+		{File: tf, ID: "files#plural=one", Source: "%lld file"},
+		{File: tf, ID: "files#plural=other", Source: "%lld files"},
+	}
+	if fmt.Sprint(units) != fmt.Sprint(expect) {
+		t.Fatalf("Expected %v got %v", expect, units)
+	}
+
+	units[1].Target = "%lld fichier"
+	unitChan2 := make(chan xliff.TranslationUnit, len(units))
+	for _, tu := range units {
+		unitChan2 <- tu
+	}
+	close(unitChan2)
+	if n, err := SetCatalogTranslationUnits(context.Background(), c, "fr", unitChan2); err != nil || n != 2 {
+		t.Fatalf("Expected 2 texts set got %d (%v)", n, err)
+	}
+	if u := c.Strings["files"].Localizations["fr"].Variations["plural"]["one"].StringUnit; u.Value != "%lld fichier" || u.State != xcstrings.Translated {
+		t.Errorf("Expected the imported plural case got %+v", u)
+	}
+	if _, ok := c.Strings["files"].Localizations["fr"].Variations["plural"]["other"]; ok {
+		t.Errorf("Expected the untranslated plural case to be skipped")
+	}
+}
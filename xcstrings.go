@@ -0,0 +1,48 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simpleapps-eu/translate/stage"
+	"github.com/simpleapps-eu/translate/xcstrings"
+	"github.com/simpleapps-eu/translate/xliff"
+)
+
+// ConvertCatalogToTranslationUnits asynchronously converts the entries of the
+// String Catalog c for the target language of tf into translation units of
+// tf, with the comment of the string as note. Without target language the
+// units hold the source texts only. The target of a unit whose text is
+// missing is empty, the ID of a variation is that of its entry, see
+// xcstrings.Entry.
+func ConvertCatalogToTranslationUnits(ctx context.Context, c *xcstrings.Catalog, tf *xliff.TranslationFile) (<-chan xliff.TranslationUnit, <-chan error) {
+	code := tf.TargetLanguage
+	if len(code) == 0 {
+		code = c.SourceLanguage
+	}
+	entries := c.Entries(code)
+	return stage.Load(ctx, func(yield func(xliff.TranslationUnit, error) bool) {
+		for _, e := range entries {
+			id, err := xliff.XMLEscapeStrict(e.ID)
+			if err != nil {
+				yield(xliff.TranslationUnit{}, fmt.Errorf("Failed to xml escape the ID %q (%v)", e.ID, err))
+				return
+			}
+			tu := xliff.TranslationUnit{File: tf, ID: id, Source: xliff.XMLEscapeLoose(e.Source), Note: xliff.XMLEscapeLoose(e.Comment)}
+			if len(tf.TargetLanguage) > 0 {
+				tu.Target = xliff.XMLEscapeLoose(e.Target)
+			}
+			if !yield(tu, nil) {
+				return
+			}
+		}
+	})
+}
+
+// SetCatalogTranslationUnits sets the texts of the locale code of the String
+// Catalog c to the targets of the translation units received from unitChan,
+// until it is closed. Units without target are skipped. It returns the
+// number of texts set.
+func SetCatalogTranslationUnits(ctx context.Context, c *xcstrings.Catalog, code string, unitChan <-chan xliff.TranslationUnit) (n int, err error) {
+	return c.SetMessages(code, ConvertTranslationUnitsToTargetMessages(ctx, unitChan))
+}
@@ -0,0 +1,316 @@
+package xcstrings
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+	"github.com/simpleapps-eu/translate/locale"
+	"github.com/simpleapps-eu/translate/plural"
+	"github.com/simpleapps-eu/translate/stage"
+)
+
+// The types of variations.
+const (
+	Plural = "plural"
+	Device = "device"
+)
+
+// Entry is a text of a string of the catalog in a locale, the string itself
+// or one of its variations.
+//
+// The ID of a variation is the key of the string followed by # and the path
+// to the variation, the variations it is in separated by commas. A variation
+// of the string is type=case, like plural=one or device=mac, a variation of a
+// substitution is prefixed by its name, like count:plural=one:
+//
+//	files#count:plural=one
+//	greeting#device=mac,plural=other
+type Entry struct {
+	ID      string
+	Comment string
+	Source  string
+	// Target and State are empty when the locale has no text yet.
+	Target string
+	State  string
+}
+
+// Entries returns the entries of c for the locale code, sorted by ID. The
+// variations are those of the source language, with the plural cases the
+// locale needs: a case the source lacks has the source text of other. The
+// strings that shouldn't be translated have no entries.
+func (c *Catalog) Entries(code string) (entries []Entry) {
+	l, _ := locale.Parse(code)
+	keys := make([]string, 0, len(c.Strings))
+	for key := range c.Strings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := c.Strings[key]
+		if s.ShouldTranslate != nil && !*s.ShouldTranslate {
+			continue
+		}
+		source := s.Localizations[c.SourceLanguage]
+		if source == nil {
+			// Xcode omits the source localization of a key that is the text
+			source = &Localization{StringUnit: &StringUnit{State: Translated, Value: key}}
+		}
+		target := s.Localizations[code]
+		walk(source, target, code != c.SourceLanguage, l, "", func(path string, src *StringUnit, tgt *StringUnit) {
+			e := Entry{ID: key, Comment: s.Comment, Source: src.Value}
+			if len(path) > 0 {
+				e.ID += "#" + path
+			}
+			if tgt != nil {
+				e.Target, e.State = tgt.Value, tgt.State
+			}
+			entries = append(entries, e)
+		})
+	}
+	return
+}
+
+// walk calls f for the path of every string unit of the source localization
+// src and the unit of the target localization tgt at the same path, nil when
+// it has none. With expand the plural cases are those of l.
+func walk(src, tgt *Localization, expand bool, l locale.Locale, path string, f func(path string, src, tgt *StringUnit)) {
+	if src.StringUnit != nil {
+		var unit *StringUnit
+		if tgt != nil {
+			unit = tgt.StringUnit
+		}
+		f(path, src.StringUnit, unit)
+	}
+	walkVariations(src.Variations, tgtVariations(tgt), expand, l, path, "", f)
+
+	names := make([]string, 0, len(src.Substitutions))
+	for name := range src.Substitutions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var variations map[string]map[string]*Localization
+		if tgt != nil && tgt.Substitutions[name] != nil {
+			variations = tgt.Substitutions[name].Variations
+		}
+		walkVariations(src.Substitutions[name].Variations, variations, expand, l, path, name+":", f)
+	}
+}
+
+func tgtVariations(tgt *Localization) map[string]map[string]*Localization {
+	if tgt == nil {
+		return nil
+	}
+	return tgt.Variations
+}
+
+func walkVariations(src, tgt map[string]map[string]*Localization, expand bool, l locale.Locale, path, prefix string, f func(path string, src, tgt *StringUnit)) {
+	types := make([]string, 0, len(src))
+	for typ := range src {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		cases := map[string]bool{}
+		for name := range src[typ] {
+			cases[name] = true
+		}
+		if expand && typ == Plural {
+			cases = map[string]bool{}
+			for _, category := range plural.Categories(l) {
+				cases[string(category)] = true
+			}
+			for name := range tgt[typ] {
+				cases[name] = true
+			}
+		}
+		for _, name := range sortedCases(typ, cases) {
+			child := src[typ][name]
+			if child == nil {
+				child = src[typ][string(plural.Other)]
+			}
+			if child == nil {
+				continue
+			}
+			seg := prefix + typ + "=" + name
+			if len(path) > 0 {
+				seg = path + "," + seg
+			}
+			walk(child, tgt[typ][name], expand, l, seg, f)
+		}
+	}
+}
+
+// sortedCases returns the cases sorted, plural categories in CLDR order.
+func sortedCases(typ string, cases map[string]bool) (sorted []string) {
+	if typ == Plural {
+		for _, category := range plural.All {
+			if cases[string(category)] {
+				sorted = append(sorted, string(category))
+				delete(cases, string(category))
+			}
+		}
+	}
+	var rest []string
+	for name := range cases {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	return append(sorted, rest...)
+}
+
+// Message returns e as message of a .strings file. For the source language
+// the message is a source message, with the comment as Ctx. For other locales
+// it is a target message with the source text as Ctx: a text that is new,
+// needs review or is stale is fuzzy, a missing text is fuzzy and missing with
+// the source text as Str, the way TranslateMessages writes them.
+func (e Entry) Message(source bool) dotstrings.Message {
+	m := dotstrings.Message{ID: dotstrings.StringsEscape(e.ID)}
+	if source {
+		m.Str = dotstrings.StringsEscape(e.Source)
+		m.Ctx = dotstrings.StringsEscape(e.Comment)
+		if len(e.Comment) == 0 {
+			m.Ctx = m.Str
+		}
+		return m
+	}
+	m.Ctx = dotstrings.StringsEscape(e.Source)
+	switch {
+	case len(e.Target) == 0:
+		m.Fuzzy, m.Missing, m.Str = true, true, m.Ctx
+	case e.State != Translated:
+		m.Fuzzy, m.Str = true, dotstrings.StringsEscape(e.Target)
+	default:
+		m.Str = dotstrings.StringsEscape(e.Target)
+	}
+	return m
+}
+
+// Messages asynchronously sends the entries of c for the locale code as
+// messages of a .strings file, see Entry.Message.
+func (c *Catalog) Messages(ctx context.Context, code string) <-chan dotstrings.Message {
+	dstChan := make(chan dotstrings.Message, stage.Buffer)
+	entries := c.Entries(code)
+	go func() {
+		defer close(dstChan)
+		for _, e := range entries {
+			if !stage.Send(ctx, dstChan, e.Message(code == c.SourceLanguage)) {
+				return
+			}
+		}
+	}()
+	return dstChan
+}
+
+// SetMessages sets the texts of the locale code to the messages received
+// from srcChan, until it is closed. The text of a fuzzy message needs review,
+// the others are translated, missing and empty messages are skipped. A fuzzy
+// message whose text is its source text is missing too, it is how Messages
+// writes a missing text to a file. A message whose ID isn't a string of c,
+// or one of its variations, is an error. It returns the number of texts set.
+func (c *Catalog) SetMessages(code string, srcChan <-chan dotstrings.Message) (n int, err error) {
+	for m := range srcChan {
+		// Keep receiving after an error, so the sender isn't blocked.
+		if m.Untranslated() || len(m.Str) == 0 || err != nil {
+			continue
+		}
+		id, e1 := dotstrings.StringsUnescape(m.ID)
+		value, e2 := dotstrings.StringsUnescape(m.Str)
+		if e1 != nil || e2 != nil {
+			err = fmt.Errorf("Failed to unescape the message %q", m.ID)
+			continue
+		}
+		state := Translated
+		if m.Fuzzy {
+			state = NeedsReview
+		}
+		if err = c.Set(code, id, value, state); err == nil {
+			n++
+		}
+	}
+	return
+}
+
+// Set sets the text of the entry id of the locale code to value in state.
+func (c *Catalog) Set(code, id, value, state string) error {
+	key, path := id, ""
+	if i := strings.LastIndexByte(id, '#'); i >= 0 && c.Strings[id[:i]] != nil && validPath(id[i+1:]) {
+		key, path = id[:i], id[i+1:]
+	}
+	s := c.Strings[key]
+	if s == nil {
+		return fmt.Errorf("Unknown string %q", id)
+	}
+	if s.Localizations == nil {
+		s.Localizations = map[string]*Localization{}
+	}
+	loc := s.Localizations[code]
+	if loc == nil {
+		loc = &Localization{}
+		s.Localizations[code] = loc
+	}
+	source := s.Localizations[c.SourceLanguage]
+
+	if len(path) > 0 {
+		for _, seg := range strings.Split(path, ",") {
+			name, variation, _ := strings.Cut(seg, "=")
+			sub, typ, isSub := strings.Cut(name, ":")
+			variations := &loc.Variations
+			if isSub {
+				if loc.Substitutions == nil {
+					loc.Substitutions = map[string]*Substitution{}
+				}
+				if loc.Substitutions[sub] == nil {
+					loc.Substitutions[sub] = &Substitution{}
+					if source != nil && source.Substitutions[sub] != nil {
+						loc.Substitutions[sub].ArgNum = source.Substitutions[sub].ArgNum
+						loc.Substitutions[sub].FormatSpecifier = source.Substitutions[sub].FormatSpecifier
+					}
+				}
+				variations = &loc.Substitutions[sub].Variations
+			} else {
+				typ = name
+			}
+			if *variations == nil {
+				*variations = map[string]map[string]*Localization{}
+			}
+			if (*variations)[typ] == nil {
+				(*variations)[typ] = map[string]*Localization{}
+			}
+			child := (*variations)[typ][variation]
+			if child == nil {
+				child = &Localization{}
+				(*variations)[typ][variation] = child
+			}
+			loc = child
+		}
+	}
+
+	if loc.StringUnit == nil {
+		loc.StringUnit = &StringUnit{}
+	}
+	if loc.StringUnit.Value == value && state == NeedsReview && loc.StringUnit.State != Translated && len(loc.StringUnit.State) > 0 {
+		// Keep new and stale, they are more specific
+		return nil
+	}
+	loc.StringUnit.Value, loc.StringUnit.State = value, state
+	return nil
+}
+
+// validPath reports whether path is the path of a variation.
+func validPath(path string) bool {
+	for _, seg := range strings.Split(path, ",") {
+		name, variation, ok := strings.Cut(seg, "=")
+		if _, typ, isSub := strings.Cut(name, ":"); isSub {
+			name = typ
+		}
+		if !ok || len(variation) == 0 || (name != Plural && name != Device) {
+			return false
+		}
+	}
+	return true
+}
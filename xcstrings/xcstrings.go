@@ -0,0 +1,308 @@
+// Package xcstrings reads and writes Xcode String Catalogs, the
+// Localizable.xcstrings JSON files that hold the strings of every locale of
+// an app, and converts them to and from .strings messages per locale.
+//
+// A catalog is read and written losslessly: fields this package doesn't know
+// are kept as they are, and the file is written the way Xcode writes it.
+package xcstrings
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// The states of a string unit.
+const (
+	New         = "new"
+	Translated  = "translated"
+	NeedsReview = "needs_review"
+	Stale       = "stale"
+)
+
+// Catalog is a String Catalog.
+type Catalog struct {
+	SourceLanguage string
+	Strings        map[string]*String
+	Version        string
+	extra          map[string]json.RawMessage
+}
+
+// String is a string of the catalog, with its localizations by locale.
+type String struct {
+	Comment         string
+	ExtractionState string
+	Localizations   map[string]*Localization
+	// ShouldTranslate is nil unless the catalog sets it, false for strings
+	// that must not be translated.
+	ShouldTranslate *bool
+	extra           map[string]json.RawMessage
+}
+
+// Localization is the text of a string for a locale, or for one of its
+// variations. Its Variations map the type of variation, plural or device, to
+// the localization of every case, like one and other.
+type Localization struct {
+	StringUnit    *StringUnit
+	Substitutions map[string]*Substitution
+	Variations    map[string]map[string]*Localization
+	extra         map[string]json.RawMessage
+}
+
+// StringUnit is a text and its state.
+type StringUnit struct {
+	State string
+	Value string
+	extra map[string]json.RawMessage
+}
+
+// Substitution is an argument of a text that varies by itself, referenced
+// as %#@name@ in the text.
+type Substitution struct {
+	ArgNum          int
+	FormatSpecifier string
+	Variations      map[string]map[string]*Localization
+	extra           map[string]json.RawMessage
+}
+
+// Load reads a String Catalog.
+func Load(r io.Reader) (c *Catalog, err error) {
+	c = &Catalog{}
+	if err = json.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	if c.Strings == nil {
+		c.Strings = map[string]*String{}
+	}
+	return
+}
+
+// Save writes c the way Xcode does: keys sorted, indented by two spaces,
+// with a space before every colon.
+func (c *Catalog) Save(w io.Writer) error {
+	data, err := marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(format(data), '\n'))
+	return err
+}
+
+func (c *Catalog) UnmarshalJSON(data []byte) (err error) {
+	c.extra, err = decodeObject(data, map[string]interface{}{
+		"sourceLanguage": &c.SourceLanguage,
+		"strings":        &c.Strings,
+		"version":        &c.Version,
+	})
+	return
+}
+
+func (c *Catalog) MarshalJSON() ([]byte, error) {
+	return encodeObject(c.extra, map[string]interface{}{
+		"sourceLanguage": c.SourceLanguage,
+		"strings":        nonNil(c.Strings),
+		"version":        c.Version,
+	})
+}
+
+func (s *String) UnmarshalJSON(data []byte) (err error) {
+	s.extra, err = decodeObject(data, map[string]interface{}{
+		"comment":         &s.Comment,
+		"extractionState": &s.ExtractionState,
+		"localizations":   &s.Localizations,
+		"shouldTranslate": &s.ShouldTranslate,
+	})
+	return
+}
+
+func (s *String) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	if len(s.Comment) > 0 {
+		fields["comment"] = s.Comment
+	}
+	if len(s.ExtractionState) > 0 {
+		fields["extractionState"] = s.ExtractionState
+	}
+	if len(s.Localizations) > 0 {
+		fields["localizations"] = s.Localizations
+	}
+	if s.ShouldTranslate != nil {
+		fields["shouldTranslate"] = *s.ShouldTranslate
+	}
+	return encodeObject(s.extra, fields)
+}
+
+func (l *Localization) UnmarshalJSON(data []byte) (err error) {
+	l.extra, err = decodeObject(data, map[string]interface{}{
+		"stringUnit":    &l.StringUnit,
+		"substitutions": &l.Substitutions,
+		"variations":    &l.Variations,
+	})
+	return
+}
+
+func (l *Localization) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	if l.StringUnit != nil {
+		fields["stringUnit"] = l.StringUnit
+	}
+	if len(l.Substitutions) > 0 {
+		fields["substitutions"] = l.Substitutions
+	}
+	if len(l.Variations) > 0 {
+		fields["variations"] = l.Variations
+	}
+	return encodeObject(l.extra, fields)
+}
+
+func (u *StringUnit) UnmarshalJSON(data []byte) (err error) {
+	u.extra, err = decodeObject(data, map[string]interface{}{
+		"state": &u.State,
+		"value": &u.Value,
+	})
+	return
+}
+
+func (u *StringUnit) MarshalJSON() ([]byte, error) {
+	return encodeObject(u.extra, map[string]interface{}{
+		"state": u.State,
+		"value": u.Value,
+	})
+}
+
+func (s *Substitution) UnmarshalJSON(data []byte) (err error) {
+	s.extra, err = decodeObject(data, map[string]interface{}{
+		"argNum":          &s.ArgNum,
+		"formatSpecifier": &s.FormatSpecifier,
+		"variations":      &s.Variations,
+	})
+	return
+}
+
+func (s *Substitution) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	if s.ArgNum != 0 {
+		fields["argNum"] = s.ArgNum
+	}
+	if len(s.FormatSpecifier) > 0 {
+		fields["formatSpecifier"] = s.FormatSpecifier
+	}
+	if len(s.Variations) > 0 {
+		fields["variations"] = s.Variations
+	}
+	return encodeObject(s.extra, fields)
+}
+
+// decodeObject decodes the JSON object data into the fields by name, the
+// members that aren't fields are returned as they are.
+func decodeObject(data []byte, fields map[string]interface{}) (extra map[string]json.RawMessage, err error) {
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(data, &members); err != nil {
+		return
+	}
+	for name, value := range members {
+		if field, ok := fields[name]; ok {
+			if err = json.Unmarshal(value, field); err != nil {
+				return
+			}
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[name] = value
+	}
+	return
+}
+
+// encodeObject encodes the fields and the extra members as JSON object, with
+// its members sorted by name.
+func encodeObject(extra map[string]json.RawMessage, fields map[string]interface{}) ([]byte, error) {
+	members := make(map[string]json.RawMessage, len(extra)+len(fields))
+	for name, value := range extra {
+		members[name] = value
+	}
+	for name, field := range fields {
+		value, err := marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		members[name] = value
+	}
+	return marshal(members)
+}
+
+// marshal returns v as JSON without escaping <, > and &, as Xcode doesn't.
+func marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func nonNil(strings map[string]*String) map[string]*String {
+	if strings == nil {
+		return map[string]*String{}
+	}
+	return strings
+}
+
+// format indents the compact JSON data the way Xcode does, empty objects
+// are written as an empty line between the braces.
+func format(data []byte) []byte {
+	var b bytes.Buffer
+	depth := 0
+	newline := func() {
+		b.WriteByte('\n')
+		for i := 0; i < depth; i++ {
+			b.WriteString("  ")
+		}
+	}
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			b.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			b.WriteByte(c)
+		case '{', '[':
+			b.WriteByte(c)
+			if i+1 < len(data) && (data[i+1] == '}' || data[i+1] == ']') {
+				b.WriteByte('\n')
+				newline()
+				b.WriteByte(data[i+1])
+				i++
+				continue
+			}
+			depth++
+			newline()
+		case '}', ']':
+			depth--
+			newline()
+			b.WriteByte(c)
+		case ',':
+			b.WriteByte(c)
+			newline()
+		case ':':
+			b.WriteString(" : ")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.Bytes()
+}
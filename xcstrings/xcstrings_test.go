@@ -0,0 +1,284 @@
+package xcstrings
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/simpleapps-eu/translate/dotstrings"
+)
+
+const catalog = `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "%lld files" : {
+      "comment" : "Number of files",
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld file"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld files"
+                }
+              }
+            }
+          }
+        },
+        "fr" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld fichier"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "Brand" : {
+      "shouldTranslate" : false
+    },
+    "Hello <b>" : {
+      "isCommentAutoGenerated" : true,
+      "localizations" : {
+        "fr" : {
+          "stringUnit" : {
+            "state" : "needs_review",
+            "value" : "Bonjour <b>"
+          }
+        }
+      }
+    },
+    "New" : {
+
+    },
+    "tap" : {
+      "extractionState" : "manual",
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "device" : {
+              "mac" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "Click"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "Tap"
+                }
+              }
+            }
+          }
+        },
+        "fr" : {
+          "variations" : {
+            "device" : {
+              "other" : {
+                "stringUnit" : {
+                  "state" : "stale",
+                  "value" : "Touchez"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "users" : {
+      "localizations" : {
+        "en" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "%#@count@ online"
+          },
+          "substitutions" : {
+            "count" : {
+              "argNum" : 1,
+              "formatSpecifier" : "lld",
+              "variations" : {
+                "plural" : {
+                  "one" : {
+                    "stringUnit" : {
+                      "state" : "translated",
+                      "value" : "%arg user"
+                    }
+                  },
+                  "other" : {
+                    "stringUnit" : {
+                      "state" : "translated",
+                      "value" : "%arg users"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0"
+}
+`
+
+func load(t *testing.T) *Catalog {
+	c, err := Load(strings.NewReader(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoadSave(t *testing.T) {
+	c := load(t)
+	if c.SourceLanguage != "en" || len(c.Strings) != 6 || *c.Strings["Brand"].ShouldTranslate {
+		t.Errorf("Expected the catalog got %+v", c)
+	}
+	buf := &bytes.Buffer{}
+	if err := c.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != catalog {
+		t.Errorf("Expected the catalog to be written unchanged got\n%s", buf.String())
+	}
+	if _, err := Load(strings.NewReader(`{"strings": []}`)); err == nil {
+		t.Errorf("Expected an error for invalid strings")
+	}
+}
+
+func messages(c *Catalog, code string) (list []dotstrings.Message) {
+	for m := range c.Messages(context.Background(), code) {
+		list = append(list, m)
+	}
+	return
+}
+
+func TestMessages(t *testing.T) {
+	c := load(t)
+	var got []string
+	for _, m := range messages(c, "en") {
+		got = append(got, m.ID+" = "+m.Str+" /* "+m.Ctx+" */")
+	}
+	expect := []string{
+		"%lld files#plural=one = %lld file /* Number of files */",
+		"%lld files#plural=other = %lld files /* Number of files */",
+		"Hello <b> = Hello <b> /* Hello <b> */",
+		"New = New /* New */",
+		"tap#device=mac = Click /* Click */",
+		"tap#device=other = Tap /* Tap */",
+		"users = %#@count@ online /* %#@count@ online */",
+		"users#count:plural=one = %arg user /* %arg user */",
+		"users#count:plural=other = %arg users /* %arg users */",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+
+	got = nil
+	for _, m := range messages(c, "ru") {
+		if strings.HasPrefix(m.ID, "%lld") {
+			got = append(got, m.ID+" = "+m.Str)
+			if !m.Fuzzy || !m.Missing {
+				t.Errorf("Expected %s to be missing", m.ID)
+			}
+		}
+	}
+	expect = []string{
+		"%lld files#plural=one = %lld file",
+		"%lld files#plural=few = %lld files",
+		"%lld files#plural=many = %lld files",
+		"%lld files#plural=other = %lld files",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected the plural cases of ru\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+
+	fr := map[string]dotstrings.Message{}
+	for _, m := range messages(c, "fr") {
+		fr[m.ID] = m
+	}
+	for id, expect := range map[string]dotstrings.Message{
+		"%lld files#plural=one":   {ID: "%lld files#plural=one", Ctx: "%lld file", Str: "%lld fichier"},
+		"%lld files#plural=other": {Fuzzy: true, Missing: true, ID: "%lld files#plural=other", Ctx: "%lld files", Str: "%lld files"},
+		"Hello <b>":               {Fuzzy: true, ID: "Hello <b>", Ctx: "Hello <b>", Str: "Bonjour <b>"},
+		"tap#device=other":        {Fuzzy: true, ID: "tap#device=other", Ctx: "Tap", Str: "Touchez"},
+		"users#count:plural=one":  {Fuzzy: true, Missing: true, ID: "users#count:plural=one", Ctx: "%arg user", Str: "%arg user"},
+	} {
+		if fr[id] != expect {
+			t.Errorf("Expected %+v got %+v", expect, fr[id])
+		}
+	}
+}
+
+func TestSetMessages(t *testing.T) {
+	c := load(t)
+	msgChan := make(chan dotstrings.Message, 10)
+	msgChan <- dotstrings.Message{ID: "%lld files#plural=many", Str: "%lld de fichiers"}
+	msgChan <- dotstrings.Message{ID: "users", Str: "%#@count@ en ligne"}
+	msgChan <- dotstrings.Message{ID: "users#count:plural=one", Str: "%arg utilisateur", Fuzzy: true}
+	msgChan <- dotstrings.Message{ID: "tap#device=other", Str: "Touchez", Fuzzy: true}
+	msgChan <- dotstrings.Message{ID: "New", Str: "Nouveau"}
+	msgChan <- dotstrings.Message{ID: "Hello <b>", Str: "Hello <b>", Fuzzy: true, Missing: true}
+	close(msgChan)
+	if n, err := c.SetMessages("fr", msgChan); err != nil || n != 5 {
+		t.Fatalf("Expected 5 texts set got %d (%v)", n, err)
+	}
+
+	fr := c.Strings["users"].Localizations["fr"]
+	if sub := fr.Substitutions["count"]; sub.ArgNum != 1 || sub.FormatSpecifier != "lld" || sub.Variations["plural"]["one"].StringUnit.State != NeedsReview {
+		t.Errorf("Expected the substitution with the format of the source got %+v", sub)
+	}
+	if u := c.Strings["tap"].Localizations["fr"].Variations["device"]["other"].StringUnit; u.State != Stale {
+		t.Errorf("Expected the unchanged stale text to stay stale got %+v", u)
+	}
+	if u := c.Strings["%lld files"].Localizations["fr"].Variations["plural"]["many"].StringUnit; u.Value != "%lld de fichiers" || u.State != Translated {
+		t.Errorf("Expected the new plural case got %+v", u)
+	}
+	if u := c.Strings["Hello <b>"].Localizations["fr"].StringUnit; u.Value != "Bonjour <b>" {
+		t.Errorf("Expected the missing message to be skipped got %+v", u)
+	}
+	if u := c.Strings["New"].Localizations["fr"].StringUnit; u.Value != "Nouveau" {
+		t.Errorf("Expected the new localization got %+v", u)
+	}
+
+	// A missing text exported to a file is still missing once imported
+	buf := &bytes.Buffer{}
+	if _, err := dotstrings.SaveMessages(c.Messages(context.Background(), "de"), buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, errChan := dotstrings.LoadMessages(context.Background(), buf)
+	if n, err := c.SetMessages("de", loaded); err != nil || n != 0 {
+		t.Errorf("Expected no texts set from an unchanged export got %d (%v)", n, err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	for key, s := range c.Strings {
+		if _, ok := s.Localizations["de"]; ok {
+			t.Errorf("Expected no de localization for %q got %+v", key, s.Localizations["de"])
+		}
+	}
+
+	msgChan = make(chan dotstrings.Message, 2)
+	msgChan <- dotstrings.Message{ID: "unknown", Str: "Inconnu"}
+	msgChan <- dotstrings.Message{ID: "New", Str: "Neuf"}
+	close(msgChan)
+	if _, err := c.SetMessages("fr", msgChan); err == nil {
+		t.Errorf("Expected an error for an unknown string")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/xml"
 	"errors"
 	"io"
-	"strings"
 )
 
 type Handler interface{}
@@ -17,7 +16,8 @@ type Decoder struct {
 	decoder      *xml.Decoder
 	handlers     map[string]Handler
 	errorHandler ErrorHandler
-	events       []string
+	path         []byte // events joined by "/"
+	ends         []int  // length of path before every event pushed
 	text         *bytes.Buffer
 }
 
@@ -25,14 +25,13 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{
 		decoder:  xml.NewDecoder(r),
 		handlers: make(map[string]Handler),
-		events:   []string{"/"},
+		path:     []byte("/"),
 		text:     new(bytes.Buffer),
 	}
 }
 
 func (d *Decoder) On(event string, handler Handler) {
-	fullEvent := strings.Join(append(d.events, event), "/")
-	d.handlers[fullEvent] = handler
+	d.handlers[string(d.path)+"/"+event] = handler
 }
 
 func (d *Decoder) OnError(handler ErrorHandler) {
@@ -40,41 +39,50 @@ func (d *Decoder) OnError(handler ErrorHandler) {
 }
 
 func (d *Decoder) Run() {
-	for d.decoder != nil {
-		token, err := d.decoder.Token()
-		if token == nil {
-			if err != io.EOF {
-				d.Error(err)
-			}
-			break
+	for d.Step() {
+	}
+}
+
+// Step decodes the next token and calls the handlers for it. It returns false
+// once the end of the document is reached or an error stopped the decoder.
+func (d *Decoder) Step() bool {
+	if d.decoder == nil {
+		return false
+	}
+	token, err := d.decoder.Token()
+	if token == nil {
+		if err != io.EOF {
+			d.Error(err)
 		}
+		d.decoder = nil
+		return false
+	}
 
-		switch t := token.(type) {
-		case xml.StartElement:
-			d.text.Reset()
-			d.events = append(d.events, t.Name.Local)
+	switch t := token.(type) {
+	case xml.StartElement:
+		d.text.Reset()
+		d.push(t.Name.Local)
+		handler := d.getHandler()
+		if handler != nil {
+			handler.(func(Attrs))(t.Attr)
+		}
+		break
+	case xml.CharData:
+		d.text.Write(t)
+		break
+	case xml.EndElement:
+		if d.text.Len() > 0 {
+			d.push("$text")
 			handler := d.getHandler()
 			if handler != nil {
-				handler.(func(Attrs))(t.Attr)
-			}
-			break
-		case xml.CharData:
-			d.text.Write(t)
-			break
-		case xml.EndElement:
-			numPop := 1
-			if d.text.Len() > 0 {
-				numPop = 2
-				d.events = append(d.events, "$text")
-				handler := d.getHandler()
-				if handler != nil {
-					handler.(func(CharData))(d.text.Bytes())
-				}
+				handler.(func(CharData))(d.text.Bytes())
 			}
-			d.events = d.events[:len(d.events)-numPop]
-			break
+			d.pop()
 		}
+		d.pop()
+		break
 	}
+	return true
 }
 
 func (d *Decoder) Assign(slot *string) func(CharData) {
@@ -89,9 +97,18 @@ func (d *Decoder) Append(a *[]string) func(CharData) {
 	}
 }
 
+func (d *Decoder) push(event string) {
+	d.ends = append(d.ends, len(d.path))
+	d.path = append(append(d.path, '/'), event...)
+}
+
+func (d *Decoder) pop() {
+	d.path = d.path[:d.ends[len(d.ends)-1]]
+	d.ends = d.ends[:len(d.ends)-1]
+}
+
 func (d *Decoder) getHandler() Handler {
-	fullEvent := strings.Join(d.events, "/")
-	return d.handlers[fullEvent]
+	return d.handlers[string(d.path)]
 }
 
 func (d *Decoder) Error(err error) {
@@ -1,9 +1,12 @@
 package xliff
 
 import (
+	"context"
 	"errors"
 	"io"
+	"iter"
 
+	"github.com/simpleapps-eu/translate/stage"
 	"github.com/simpleapps-eu/translate/xliff/exml"
 )
 
@@ -14,8 +17,11 @@ import (
 func LoadTranslationMap(reader io.Reader) (tf *TranslationFile, translation map[string]string, err error) {
 	// Read in the translation from the xlf file and store it based on Resname in a map
 	translation = make(map[string]string)
-	tuchan, echan := LoadTranslationUnits(reader)
-	for tu := range tuchan {
+	for tu, e := range NewReader(reader).All() {
+		if e != nil {
+			err = e
+			return
+		}
 		if tf == nil {
 			tf = tu.File
 		} else {
@@ -26,91 +32,127 @@ func LoadTranslationMap(reader io.Reader) (tf *TranslationFile, translation map[
 		}
 		translation[XMLUnescape(tu.ID)] = XMLUnescape(tu.Target)
 	}
-	err, _ = <-echan
 	return
 }
 
 // LoadTranslationUnits returns a channel of TranslationUnit values and will start
 // processing the xliff file passed in via the reader argument asynchronously.
 // Whenever it it has read a TranslationUnit, this will written to the channel.
-func LoadTranslationUnits(reader io.Reader) (<-chan TranslationUnit, <-chan error) {
-	tuchan := make(chan TranslationUnit)
-	echan := make(chan error, 1)
-	go func(reader io.Reader, tuchan chan<- TranslationUnit, echan chan<- error) {
-
-		defer close(tuchan)
-		defer close(echan)
+// Processing stops at the first error or when ctx is done, the error (or
+// ctx.Err()) is sent on the error channel before the unit channel is closed.
+func LoadTranslationUnits(ctx context.Context, reader io.Reader) (<-chan TranslationUnit, <-chan error) {
+	return stage.Load(ctx, NewReader(reader).All())
+}
 
-		if reader == nil {
-			echan <- errors.New("argument reader is nil")
-			return
-		}
+// Reader reads the translation units of an xliff file one at a time.
+type Reader struct {
+	decoder *exml.Decoder
+	tu      *TranslationUnit
+	units   []TranslationUnit
+	err     error
+}
 
-		decoder := exml.NewDecoder(reader)
-		if decoder == nil {
-			echan <- errors.New("exml.NewDecoder returned nil")
-			return
-		}
+// NewReader returns a Reader reading translation units from the xliff file
+// provided by reader.
+func NewReader(reader io.Reader) *Reader {
+	x := &Reader{}
+	if reader == nil {
+		x.err = errors.New("argument reader is nil")
+		return x
+	}
 
-		var tf *TranslationFile
-		var tu *TranslationUnit
-		decoder.On("xliff/file", func(attrs exml.Attrs) {
+	decoder := exml.NewDecoder(reader)
+	x.decoder = decoder
 
-			tf = &TranslationFile{}
-			original, err := attrs.Get("original")
-			if err == nil {
-				tf.Original = original
-			}
-			sourceLanguage, err := attrs.Get("source-language")
-			if err == nil {
-				tf.SourceLanguage = sourceLanguage
-			}
-			datatype, err := attrs.Get("datatype")
-			if err == nil {
-				tf.Datatype = datatype
-			}
-			targetLanguage, err := attrs.Get("target-language")
-			if err == nil {
-				tf.TargetLanguage = targetLanguage
-			}
+	var tf *TranslationFile
+	decoder.On("xliff/file", func(attrs exml.Attrs) {
 
-			decoder.On("body/trans-unit", func(attrs exml.Attrs) {
+		tf = &TranslationFile{}
+		original, err := attrs.Get("original")
+		if err == nil {
+			tf.Original = original
+		}
+		sourceLanguage, err := attrs.Get("source-language")
+		if err == nil {
+			tf.SourceLanguage = sourceLanguage
+		}
+		datatype, err := attrs.Get("datatype")
+		if err == nil {
+			tf.Datatype = datatype
+		}
+		targetLanguage, err := attrs.Get("target-language")
+		if err == nil {
+			tf.TargetLanguage = targetLanguage
+		}
 
-				if tu != nil {
-					tuchan <- *tu
-				}
-				tu = &TranslationUnit{File: tf}
+		decoder.On("body/trans-unit", func(attrs exml.Attrs) {
 
-				id, err := attrs.Get("id")
-				if err != nil {
-					decoder.Error(err)
-					return
-				}
-				tu.ID = id
+			// A translation unit is complete once the next one starts.
+			if x.tu != nil {
+				x.units = append(x.units, *x.tu)
+			}
+			x.tu = &TranslationUnit{File: tf}
 
-				decoder.On("source/$text", func(text exml.CharData) {
-					tu.Source = string(text)
-				})
+			id, err := attrs.Get("id")
+			if err != nil {
+				decoder.Error(err)
+				return
+			}
+			x.tu.ID = id
+		})
 
-				decoder.On("target/$text", func(text exml.CharData) {
-					tu.Target = string(text)
-				})
+		decoder.On("body/trans-unit/source/$text", func(text exml.CharData) {
+			x.tu.Source = string(text)
+		})
 
-				decoder.On("note/$text", func(text exml.CharData) {
-					tu.Note = string(text)
-				})
-			})
+		decoder.On("body/trans-unit/target/$text", func(text exml.CharData) {
+			x.tu.Target = string(text)
 		})
-		decoder.OnError(func(err error) {
-			echan <- err
+
+		decoder.On("body/trans-unit/note/$text", func(text exml.CharData) {
+			x.tu.Note = string(text)
 		})
-		decoder.Run()
+	})
+	decoder.OnError(func(err error) {
+		if x.err == nil {
+			x.err = err
+		}
+	})
+	return x
+}
 
-		// Make sure the final TranslationUnit is also send
-		if tu != nil {
-			tuchan <- *tu
+// Next returns the next translation unit. It returns io.EOF when there are no
+// more translation units.
+func (x *Reader) Next() (tu TranslationUnit, err error) {
+	for len(x.units) == 0 && x.err == nil && x.decoder != nil {
+		if !x.decoder.Step() {
+			x.decoder = nil
+			// Make sure the final TranslationUnit is also returned
+			if x.tu != nil && x.err == nil {
+				x.units = append(x.units, *x.tu)
+			}
 		}
+	}
+	if len(x.units) > 0 {
+		tu = x.units[0]
+		x.units = x.units[1:]
+		return
+	}
+	if err = x.err; err == nil {
+		err = io.EOF
+	}
+	return
+}
 
-	}(reader, tuchan, echan)
-	return tuchan, echan
+// All returns an iterator over the remaining translation units. The
+// iteration stops at the end of the file or after yielding the first error.
+func (x *Reader) All() iter.Seq2[TranslationUnit, error] {
+	return func(yield func(TranslationUnit, error) bool) {
+		for {
+			tu, err := x.Next()
+			if err == io.EOF || !yield(tu, err) || err != nil {
+				return
+			}
+		}
+	}
 }
@@ -1,53 +1,107 @@
 package xliff
 
 import (
+	"bufio"
 	"io"
-	"text/template"
 )
 
-const head = `<?xml version="1.0"?>
-<xliff version="1.2">
-<file original="{{.Original}}" source-language="{{.SourceLanguage}}"{{with .TargetLanguage}} target-language="{{.}}"{{end}} datatype="{{.Datatype}}">
-<body>
-`
-const unit = `<trans-unit id="{{.ID}}">
-{{- with .Source}}
-<source>{{.}}</source>
-{{- end}}
-{{- with .Target}}
-<target>{{.}}</target>
-{{- end}}
-{{- with .Note}}
-<note>{{.}}</note>
-{{- end}}
-</trans-unit>
-`
-const foot = `</body>
-</file>
-</xliff>
-`
-
 // SaveTranslationUnits will take a channel with translation units and stream
 // them to writer in xliff xml format. The function will return when all translation
 // units have been written.
 // The goroutine feeding srcChan should close the channel once it has finished.
 // The closing of the channel indicates to SaveTranslation that it can finish too.
 // The function then returns the number of translation units it has written to
-// the writer.
-func SaveTranslationUnits(srcChan <-chan TranslationUnit, writer io.Writer) (n int) {
-	headTpl := template.Must(template.New("head").Parse(head))
-	unitTpl := template.Must(template.New("unit").Parse(unit))
-	headWritten := false
+// the writer. After a write error the remaining units are received but not
+// written, the error is returned.
+func SaveTranslationUnits(srcChan <-chan TranslationUnit, writer io.Writer) (n int, err error) {
+	bw := bufio.NewWriter(writer)
+	w := NewWriter(bw)
 	for m := range srcChan {
-		if !headWritten {
-			headWritten = true
-			headTpl.Execute(writer, m.File)
+		if err != nil {
+			continue
+		}
+		if err = w.Write(m); err == nil {
+			n++
 		}
-		unitTpl.Execute(writer, m)
-		n++
 	}
-	if headWritten {
-		writer.Write([]byte(foot))
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return
+}
+
+// Writer writes translation units to an xliff file. The file element is
+// written along with the first translation unit, using its File.
+type Writer struct {
+	w           io.Writer
+	buf         []byte
+	headWritten bool
+}
+
+// NewWriter returns a Writer writing translation units to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes translation unit tu. The text in tu is expected to be XML
+// escaped already.
+func (x *Writer) Write(tu TranslationUnit) (err error) {
+	b := x.buf[:0]
+	if !x.headWritten {
+		x.headWritten = true
+		b = appendHead(b, tu.File)
+	}
+	b = append(b, "<trans-unit id=\""...)
+	b = append(b, tu.ID...)
+	b = append(b, "\">\n"...)
+	b = appendElement(b, "source", tu.Source)
+	b = appendElement(b, "target", tu.Target)
+	b = appendElement(b, "note", tu.Note)
+	b = append(b, "</trans-unit>\n"...)
+	x.buf = b
+	_, err = x.w.Write(b)
+	return
+}
+
+// Close ends the xliff file, it doesn't close the underlying writer. Nothing
+// is written when no translation units were written.
+func (x *Writer) Close() (err error) {
+	if x.headWritten {
+		_, err = io.WriteString(x.w, "</body>\n</file>\n</xliff>\n")
 	}
 	return
 }
+
+func appendHead(b []byte, tf *TranslationFile) []byte {
+	if tf == nil {
+		tf = &TranslationFile{}
+	}
+	b = append(b, "<?xml version=\"1.0\"?>\n<xliff version=\"1.2\">\n<file original=\""...)
+	b = append(b, tf.Original...)
+	b = append(b, "\" source-language=\""...)
+	b = append(b, tf.SourceLanguage...)
+	if len(tf.TargetLanguage) > 0 {
+		b = append(b, "\" target-language=\""...)
+		b = append(b, tf.TargetLanguage...)
+	}
+	b = append(b, "\" datatype=\""...)
+	b = append(b, tf.Datatype...)
+	return append(b, "\">\n<body>\n"...)
+}
+
+// appendElement appends element name with text, unless text is empty.
+func appendElement(b []byte, name, text string) []byte {
+	if len(text) == 0 {
+		return b
+	}
+	b = append(b, '<')
+	b = append(b, name...)
+	b = append(b, '>')
+	b = append(b, text...)
+	b = append(b, "</"...)
+	b = append(b, name...)
+	return append(b, ">\n"...)
+}
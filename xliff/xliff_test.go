@@ -1,6 +1,9 @@
 package xliff
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -124,7 +127,7 @@ func TestTranslationUnits(t *testing.T) {
 		t.Error("expected NewReader got nil")
 	}
 	var testcount int
-	tuchan, echan := LoadTranslationUnits(reader)
+	tuchan, echan := LoadTranslationUnits(context.Background(), reader)
 	done := make(chan struct{})
 	go func() {
 		for tu := range tuchan {
@@ -160,3 +163,90 @@ func TestTranslationUnits(t *testing.T) {
 		t.Errorf("Expected to test %d cases but only %d where actually tested", len(expectKeys), testcount)
 	}
 }
+
+func TestReaderWriter(t *testing.T) {
+	r := NewReader(strings.NewReader(xliffData))
+	buf := &strings.Builder{}
+	w := NewWriter(buf)
+	var n int
+	for tu, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := expectKeys[n]; tu.ID != expect {
+			t.Errorf("Expected id %q got %q", expect, tu.ID)
+		}
+		if err = w.Write(tu); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != len(expectKeys) {
+		t.Errorf("Expected %d translation units got %d", len(expectKeys), n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF got %v", err)
+	}
+
+	// The written file reads back the same.
+	tf, translation, err := LoadTranslationMap(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tf.TargetLanguage != "es" || len(translation) != len(expectKeys) {
+		t.Errorf("Expected %d es translations got %d %s", len(expectKeys), len(translation), tf.TargetLanguage)
+	}
+
+	r = NewReader(strings.NewReader(`<xliff><file><body><trans-unit id="a"></trans-unit><trans-unit></trans-unit></body></file></xliff>`))
+	if tu, err := r.Next(); err != nil || tu.ID != "a" {
+		t.Errorf("Expected unit a got %q (%v)", tu.ID, err)
+	}
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Expected an error for a unit without id got %v", err)
+	}
+}
+
+func xliffUnits(n int) (units []TranslationUnit) {
+	tf := &TranslationFile{Original: "Localizable.strings", SourceLanguage: "en", Datatype: "plaintext", TargetLanguage: "fr"}
+	for i := 0; i < n; i++ {
+		units = append(units, TranslationUnit{File: tf, ID: fmt.Sprintf("id%d", i), Source: fmt.Sprintf("Text %d", i), Target: fmt.Sprintf("Texte %d", i)})
+	}
+	return
+}
+
+func xliffUnitsData(units []TranslationUnit) string {
+	buf := &strings.Builder{}
+	w := NewWriter(buf)
+	for _, tu := range units {
+		w.Write(tu)
+	}
+	w.Close()
+	return buf.String()
+}
+
+func BenchmarkReader(b *testing.B) {
+	data := xliffUnitsData(xliffUnits(1000))
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		for _, err := range NewReader(strings.NewReader(data)).All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	units := xliffUnits(1000)
+	b.SetBytes(int64(len(xliffUnitsData(units))))
+	for i := 0; i < b.N; i++ {
+		w := NewWriter(io.Discard)
+		for _, tu := range units {
+			w.Write(tu)
+		}
+		w.Close()
+	}
+}